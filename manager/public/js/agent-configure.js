@@ -237,7 +237,11 @@ async function saveConfig(event) {
       enabled: document.getElementById('fileBrowserEnabled').checked,
       allowedPaths: allowedPathsArray,
       maxUploadSize: parseInt(document.getElementById('maxUploadSize').value),
-      maxListItems: parseInt(document.getElementById('maxListItems').value)
+      maxListItems: parseInt(document.getElementById('maxListItems').value),
+      deleteMode: document.getElementById('deleteMode').value,
+      trashDir: document.getElementById('trashDir').value.trim(),
+      trashRetentionHours: parseInt(document.getElementById('trashRetentionHours').value) || 0,
+      maxDeleteItems: parseInt(document.getElementById('maxDeleteItems').value) || 0
     },
     custom: customConfig
   };
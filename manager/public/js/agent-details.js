@@ -31,7 +31,8 @@ document.addEventListener('DOMContentLoaded', function() {
   commandBtns.forEach(btn => {
     btn.addEventListener('click', function() {
       const command = this.getAttribute('data-command');
-      sendCommand(command);
+      const args = this.getAttribute('data-args');
+      sendCommand(command, args ? { args: JSON.parse(args) } : {});
     });
   });
 
@@ -157,6 +158,11 @@ document.addEventListener('DOMContentLoaded', function() {
         downloadFile(e.target.dataset.path, e.target.dataset.filename);
       }
 
+      // Handle checksum button
+      if (e.target.classList.contains('file-action-btn') && e.target.dataset.action === 'checksum') {
+        showFileChecksum(e.target.dataset.path);
+      }
+
       // Handle delete button
       if (e.target.classList.contains('file-action-btn') && e.target.dataset.action === 'delete') {
         deleteFileOrFolder(e.target.dataset.path, e.target.dataset.isDir === 'true');
@@ -372,6 +378,7 @@ async function loadDeployedWorkflows() {
       const triggerIcon = {
         'file': '📁',
         'filewatcher': '📁',
+        'fileage': '⏳',
         'schedule': '📅',
         'webhook': '🔗',
         'manual': '👤'
@@ -611,6 +618,7 @@ function renderExecutionCard(exec, index) {
   const triggerIcon = {
     'file': '📁',
     'filewatcher': '📁',
+    'fileage': '⏳',
     'schedule': '📅',
     'webhook': '🔗',
     'manual': '👤'
@@ -1134,6 +1142,7 @@ function renderFileBrowser(data) {
         <span class="file-date">${dateText}</span>
         <div class="file-actions">
           ${!item.isDir ? `<button class="file-action-btn" data-action="download" data-path="${escapeHtml(itemPath)}" data-filename="${escapeHtml(item.name)}">⬇️ Download</button>` : ''}
+          ${!item.isDir ? `<button class="file-action-btn" data-action="checksum" data-path="${escapeHtml(itemPath)}">#️⃣ SHA-256</button>` : ''}
           <button class="file-action-btn delete" data-action="delete" data-path="${escapeHtml(itemPath)}" data-is-dir="${item.isDir}">🗑️ Delete</button>
         </div>
       </li>
@@ -1202,6 +1211,22 @@ async function downloadFile(path, filename) {
   }
 }
 
+async function showFileChecksum(path) {
+  try {
+    const url = `/api/agents/${agentId}/files/checksum?path=${encodeURIComponent(path)}&algo=sha256`;
+    const response = await fetch(url);
+    const data = await response.json();
+
+    if (!response.ok) {
+      throw new Error(data.error || 'Checksum failed');
+    }
+
+    await Modal.info(`${data.path}\n\nSHA-256: ${data.checksum}\nSize: ${formatBytes(data.size)}`, 'File Checksum');
+  } catch (error) {
+    await Modal.error('Checksum failed: ' + error.message);
+  }
+}
+
 async function deleteFileOrFolder(path, isDir) {
   const itemType = isDir ? 'folder' : 'file';
   const confirmed = await Modal.confirm(
@@ -1215,14 +1240,29 @@ async function deleteFileOrFolder(path, isDir) {
 
   try {
     const url = `/api/agents/${agentId}/files/delete?path=${encodeURIComponent(path)}`;
-    const response = await fetch(url, { method: 'DELETE' });
-    const data = await response.json();
+    let response = await fetch(url, { method: 'DELETE' });
+    let data = await response.json();
+
+    // Non-empty folders need a second, explicit confirmation
+    if (response.status === 409 && data.requiresConfirm) {
+      const action = data.trash ? 'move to trash' : 'permanently delete';
+      const recursive = await Modal.confirm(
+        `This folder contains ${data.items} item(s). This will ${action} all of them.\n\n${path}`,
+        'Delete Folder Contents'
+      );
+      if (!recursive) {
+        return;
+      }
+      response = await fetch(url + '&confirm=true', { method: 'DELETE' });
+      data = await response.json();
+    }
 
     if (!response.ok) {
       throw new Error(data.error || 'Delete failed');
     }
 
-    await Modal.success(`${itemType.charAt(0).toUpperCase() + itemType.slice(1)} deleted successfully`);
+    const done = data.trashedTo ? 'moved to trash' : 'deleted successfully';
+    await Modal.success(`${itemType.charAt(0).toUpperCase() + itemType.slice(1)} ${done}`);
     loadFileBrowser(currentPath);
 
   } catch (error) {
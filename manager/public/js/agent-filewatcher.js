@@ -257,6 +257,10 @@ function updateWatchModeUI() {
     dirRegexLabel.textContent = 'Directory Pattern';
     dirRegexHelper.textContent = 'Regex pattern to match subdirectories under ' + (globalSettings.scanDir || 'ScanDir');
     dirRegexInput.placeholder = 'e.g., (?i)\\\\Invoices\\\\Input$';
+  } else if (watchMode === 'file') {
+    dirRegexLabel.textContent = 'File Path';
+    dirRegexHelper.textContent = 'Full path to the file to watch; replaced files (editor saves, rotation) are picked up again';
+    dirRegexInput.placeholder = 'e.g., C:\\\\App\\\\status.json or /var/run/app/status';
   } else {
     dirRegexLabel.textContent = 'Directory Path';
     dirRegexHelper.textContent = 'Full path to the directory to watch';
@@ -286,7 +290,7 @@ function loadRules() {
         <div class="rule-details">
           <div class="rule-detail">
             <span class="rule-detail-icon">📁</span>
-            ${rule.watchMode === 'pattern' ? '<span title="Pattern Mode">🔍</span> ' : ''}${escapeHtml(rule.dirRegex || 'Any directory')}
+            ${rule.watchMode === 'pattern' ? '<span title="Pattern Mode">🔍</span> ' : ''}${rule.watchMode === 'file' ? '<span title="Single File">📄</span> ' : ''}${escapeHtml(rule.dirRegex || 'Any directory')}
           </div>
           <div class="rule-detail">
             <span class="rule-detail-icon">📄</span>
@@ -295,7 +299,7 @@ function loadRules() {
           ${rule.operations.copyToDir ? `
             <div class="rule-detail">
               <span class="rule-detail-icon">➡️</span>
-              ${escapeHtml(rule.operations.copyToDir)}
+              ${escapeHtml([rule.operations.copyToDir, ...(rule.operations.copyToDirs || [])].join(', '))}
             </div>
           ` : ''}
         </div>
@@ -335,10 +339,14 @@ function editRule(index) {
   document.getElementById('dir-regex').value = rule.dirRegex || '';
   document.getElementById('file-regex').value = rule.fileRegex || '';
   document.getElementById('content-regex').value = rule.contentRegex || '';
+  document.getElementById('content-regex-all').value = (rule.contentRegexAll || []).join('\n');
+  document.getElementById('content-regex-any').value = (rule.contentRegexAny || []).join('\n');
 
   // Operations
   const ops = rule.operations || {};
   document.getElementById('copy-to-dir').value = ops.copyToDir || '';
+  document.getElementById('copy-to-dirs').value = (ops.copyToDirs || []).join('\n');
+  document.getElementById('copy-to-template').value = ops.copyToTemplate || '';
   document.getElementById('copy-option').value = ops.copyFileOption || '21';
   document.getElementById('rename-to').value = ops.renameFileTo || '';
   document.getElementById('insert-timestamp').checked = ops.insertTimestamp || false;
@@ -346,11 +354,14 @@ function editRule(index) {
   document.getElementById('temp-ext').value = ops.copyTempExtension || '';
   document.getElementById('remove-after').checked = ops.removeAfterCopy !== false;
   document.getElementById('overwrite').checked = ops.overwrite !== false;
+  document.getElementById('collision-policy').value = ops.collisionPolicy || '';
 
   // Handle external programs (check for workflow format)
   handleExternalProgramField('exec-before', ops.execProgBefore || '');
   handleExternalProgramField('exec-after', ops.execProg || '');
   handleExternalProgramField('exec-error', ops.execProgError || '');
+  document.getElementById('on-success-dir').value = ops.onSuccessDir || '';
+  document.getElementById('on-failure-dir').value = ops.onFailureDir || '';
 
   // Timing
   const time = rule.timeRestrictions || {};
@@ -373,6 +384,12 @@ function editRule(index) {
   document.getElementById('max-retries').value = proc.maxRetries || 5;
   document.getElementById('retry-delay').value = proc.delayRetry || 1000;
   document.getElementById('delay-next').value = proc.delayNextFile || 0;
+  document.getElementById('stable-checks').value = proc.stableChecks || 0;
+  document.getElementById('stable-interval').value = proc.stableIntervalMs || 1000;
+  document.getElementById('process-existing').checked = proc.processExisting || false;
+  document.getElementById('process-order').value = proc.processOrder || '';
+  document.getElementById('serialize-rule').checked = proc.serialize || false;
+  document.getElementById('rule-log-level').value = rule.logLevel || '';
 
   document.getElementById('rule-modal').style.display = 'block';
   // Activate the first tab
@@ -392,8 +409,12 @@ async function saveRule() {
     dirRegex: document.getElementById('dir-regex').value,
     fileRegex: document.getElementById('file-regex').value,
     contentRegex: document.getElementById('content-regex').value,
+    contentRegexAll: document.getElementById('content-regex-all').value.split('\n').map(p => p.trim()).filter(p => p),
+    contentRegexAny: document.getElementById('content-regex-any').value.split('\n').map(p => p.trim()).filter(p => p),
     operations: {
       copyToDir: document.getElementById('copy-to-dir').value,
+      copyToDirs: document.getElementById('copy-to-dirs').value.split('\n').map(d => d.trim()).filter(d => d),
+      copyToTemplate: document.getElementById('copy-to-template').value.trim() || undefined,
       copyFileOption: parseInt(document.getElementById('copy-option').value),
       renameFileTo: document.getElementById('rename-to').value,
       insertTimestamp: document.getElementById('insert-timestamp').checked,
@@ -401,9 +422,12 @@ async function saveRule() {
       copyTempExtension: document.getElementById('temp-ext').value,
       removeAfterCopy: document.getElementById('remove-after').checked,
       overwrite: document.getElementById('overwrite').checked,
+      collisionPolicy: document.getElementById('collision-policy').value || undefined,
       execProgBefore: getExternalProgramValue('exec-before'),
       execProg: getExternalProgramValue('exec-after'),
-      execProgError: getExternalProgramValue('exec-error')
+      execProgError: getExternalProgramValue('exec-error'),
+      onSuccessDir: document.getElementById('on-success-dir').value || undefined,
+      onFailureDir: document.getElementById('on-failure-dir').value || undefined
     },
     timeRestrictions: {
       startHour: parseInt(document.getElementById('start-hour').value),
@@ -419,8 +443,14 @@ async function saveRule() {
       // scanSubDir is now a global setting
       maxRetries: parseInt(document.getElementById('max-retries').value),
       delayRetry: parseInt(document.getElementById('retry-delay').value),
-      delayNextFile: parseInt(document.getElementById('delay-next').value)
-    }
+      delayNextFile: parseInt(document.getElementById('delay-next').value),
+      stableChecks: parseInt(document.getElementById('stable-checks').value) || undefined,
+      stableIntervalMs: parseInt(document.getElementById('stable-interval').value) || undefined,
+      processExisting: document.getElementById('process-existing').checked || undefined,
+      processOrder: document.getElementById('process-order').value || undefined,
+      serialize: document.getElementById('serialize-rule').checked || undefined
+    },
+    logLevel: document.getElementById('rule-log-level').value || undefined
   };
 
   if (!rule.name) {
@@ -556,9 +586,13 @@ function clearForm() {
   document.getElementById('dir-regex').value = '';
   document.getElementById('file-regex').value = '';
   document.getElementById('content-regex').value = '';
+  document.getElementById('content-regex-all').value = '';
+  document.getElementById('content-regex-any').value = '';
 
   // Operations tab
   document.getElementById('copy-to-dir').value = '';
+  document.getElementById('copy-to-dirs').value = '';
+  document.getElementById('copy-to-template').value = '';
   document.getElementById('copy-option').value = '21';
   document.getElementById('rename-to').value = '';
   document.getElementById('insert-timestamp').checked = false;
@@ -566,6 +600,7 @@ function clearForm() {
   document.getElementById('temp-ext').value = '';
   document.getElementById('remove-after').checked = true;
   document.getElementById('overwrite').checked = true;
+  document.getElementById('collision-policy').value = '';
 
   // External programs - reset to default state
   document.getElementById('exec-before').value = '';
@@ -585,6 +620,8 @@ function clearForm() {
   document.getElementById('exec-error').style.display = 'block';
   document.getElementById('exec-error-select').style.display = 'none';
   document.getElementById('exec-error-select').value = '';
+  document.getElementById('on-success-dir').value = '';
+  document.getElementById('on-failure-dir').value = '';
 
   // Timing tab
   document.getElementById('start-hour').value = 0;
@@ -603,6 +640,12 @@ function clearForm() {
   document.getElementById('check-in-use').checked = true;
   document.getElementById('max-retries').value = 5;
   document.getElementById('retry-delay').value = 1000;
+  document.getElementById('stable-checks').value = 0;
+  document.getElementById('stable-interval').value = 1000;
+  document.getElementById('process-existing').checked = false;
+  document.getElementById('process-order').value = '';
+  document.getElementById('serialize-rule').checked = false;
+  document.getElementById('rule-log-level').value = '';
 }
 
 function switchFileWatcherTab(tabName, buttonElement) {
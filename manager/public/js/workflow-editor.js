@@ -287,6 +287,13 @@ function addNode(type, pos_x, pos_y) {
       outputs: 1,
       data: { cron: '0 * * * *' }
     },
+    'fileage-trigger': {
+      name: 'File Age Trigger',
+      class: 'node-trigger',
+      inputs: 0,
+      outputs: 1,
+      data: { path: '', pattern: '*', olderThan: '1h', interval: '5m' }
+    },
     'webhook-trigger': {
       name: 'Webhook Trigger',
       class: 'node-trigger',
@@ -306,7 +313,42 @@ function addNode(type, pos_x, pos_y) {
       class: 'node-action',
       inputs: 1,
       outputs: 2,
-      data: { source: '', destination: '' }
+      data: { source: '', destination: '', recursive: 'false' }
+    },
+    'list-files': {
+      name: 'List Files',
+      class: 'node-action',
+      inputs: 1,
+      outputs: 2,
+      data: { directory: '', pattern: '*', recursive: 'false', sortBy: 'name' }
+    },
+    'merge-files': {
+      name: 'Merge Files',
+      class: 'node-action',
+      inputs: 1,
+      outputs: 2,
+      data: { directory: '', pattern: '*', destination: '', sortBy: 'name', headerLines: '0', keepHeader: 'true', overwrite: 'false', deleteSources: 'false' }
+    },
+    'wait-for-file': {
+      name: 'Wait For File',
+      class: 'node-action',
+      inputs: 1,
+      outputs: 2,
+      data: { path: '', timeoutSeconds: '300', stableSeconds: '2', pollIntervalMs: '1000' }
+    },
+    'write-manifest': {
+      name: 'Write Manifest',
+      class: 'node-action',
+      inputs: 1,
+      outputs: 2,
+      data: { path: '', format: 'jsonl', sourceFile: '', destination: '', hash: 'true', fields: '' }
+    },
+    'http-download': {
+      name: 'HTTP Download',
+      class: 'node-action',
+      inputs: 1,
+      outputs: 2,
+      data: { url: '', destination: '', headers: '', sha256: '', timeoutSeconds: '300' }
     },
     'delete-file': {
       name: 'Delete File',
@@ -315,6 +357,41 @@ function addNode(type, pos_x, pos_y) {
       outputs: 2,
       data: { path: '' }
     },
+    'chown-file': {
+      name: 'Change Owner',
+      class: 'node-action',
+      inputs: 1,
+      outputs: 2,
+      data: { path: '', owner: '', group: '' }
+    },
+    'set-attributes': {
+      name: 'Set Attributes',
+      class: 'node-action',
+      inputs: 1,
+      outputs: 2,
+      data: { path: '', readOnly: '', hidden: '', archive: '' }
+    },
+    'cleanup-files': {
+      name: 'Cleanup Old Files',
+      class: 'node-action',
+      inputs: 1,
+      outputs: 2,
+      data: { directory: '', pattern: '*', olderThanHours: '720', recursive: 'false', maxDeletes: '1000', dryRun: 'false' }
+    },
+    'validate-file': {
+      name: 'Validate File',
+      class: 'node-action',
+      inputs: 1,
+      outputs: 2,
+      data: { path: '', format: '', schema: '', maxErrors: '10' }
+    },
+    'rename-sequence': {
+      name: 'Rename With Sequence',
+      class: 'node-action',
+      inputs: 1,
+      outputs: 2,
+      data: { source: '', destination: '', pattern: '{name}_{seq}{ext}', padding: '6', start: '1', sequenceKey: '' }
+    },
     'run-command': {
       name: 'Run Command',
       class: 'node-action',
@@ -364,6 +441,20 @@ function addNode(type, pos_x, pos_y) {
       outputs: 0,
       data: { level: 'info', message: '' }
     },
+    'emit-event': {
+      name: 'Emit Event',
+      class: 'node-output',
+      inputs: 1,
+      outputs: 2,
+      data: { type: '', message: '', data: '', required: 'false' }
+    },
+    'health-ping': {
+      name: 'Health Ping',
+      class: 'node-action',
+      inputs: 1,
+      outputs: 2,
+      data: { signal: '', url: '', startUrl: '', successUrl: '', failureUrl: '', method: 'GET', timeoutSeconds: '10', failOnError: 'false' }
+    },
     'javascript': {
       name: 'JavaScript',
       class: 'node-action',
@@ -855,7 +946,8 @@ function getTriggerVariablesForType(triggerType) {
       { name: 'fileName', description: 'Just the filename without path' },
       { name: 'directory', description: 'Directory containing the file' },
       { name: 'event', description: 'Event type (CREATE, WRITE, etc.)' },
-      { name: 'timestamp', description: 'When the trigger occurred' }
+      { name: 'timestamp', description: 'When the trigger occurred' },
+      { name: 'groups', description: 'File watcher only: capture groups of the rule file regex, e.g. .groups.customer; named groups are also top-level' }
     ];
   } else if (triggerType === 'schedule-trigger') {
     return [
@@ -863,6 +955,18 @@ function getTriggerVariablesForType(triggerType) {
       { name: 'timestamp', description: 'When the trigger occurred' },
       { name: 'scheduledTime', description: 'The scheduled execution time' }
     ];
+  } else if (triggerType === 'fileage-trigger') {
+    return [
+      { name: 'trigger', description: 'Trigger type (fileage)' },
+      { name: 'file', description: 'Path of the oldest aged file' },
+      { name: 'fileName', description: 'Name of the oldest aged file' },
+      { name: 'directory', description: 'Directory that was scanned' },
+      { name: 'files', description: 'Aged files, oldest first (path, name, size, modTime, ageSeconds)' },
+      { name: 'paths', description: 'Paths of the aged files' },
+      { name: 'count', description: 'Number of aged files' },
+      { name: 'oldestAgeSeconds', description: 'Age of the oldest file in seconds' },
+      { name: 'timestamp', description: 'When the trigger occurred' }
+    ];
   } else if (triggerType === 'webhook-trigger') {
     return [
       { name: 'trigger', description: 'Trigger type (webhook)' },
@@ -910,6 +1014,9 @@ const NodeTypes = {
   'schedule-trigger': {
     outputs: []  // Trigger outputs are handled separately
   },
+  'fileage-trigger': {
+    outputs: []  // Trigger outputs are handled separately
+  },
   'webhook-trigger': {
     outputs: []  // Trigger outputs are handled separately
   },
@@ -918,6 +1025,8 @@ const NodeTypes = {
   'copy-file': {
     outputs: [
       { name: 'destinationFile', description: 'Path to the copied file' },
+      { name: 'copiedFiles', description: 'Number of files copied' },
+      { name: 'copiedBytes', description: 'Total bytes copied' },
       { name: 'success', description: 'Whether the copy was successful' }
     ]
   },
@@ -927,11 +1036,81 @@ const NodeTypes = {
       { name: 'success', description: 'Whether the move was successful' }
     ]
   },
+  'list-files': {
+    outputs: [
+      { name: 'listFiles', description: 'Matching files (path, name, size, modTime)' },
+      { name: 'listPaths', description: 'Paths of the matching files' },
+      { name: 'listCount', description: 'Number of matching files' },
+      { name: 'listTotalBytes', description: 'Total size of the matching files' },
+      { name: 'listTruncated', description: 'Whether the list was cut at maxResults' }
+    ]
+  },
+  'merge-files': {
+    outputs: [
+      { name: 'mergedFile', description: 'Path of the merged file' },
+      { name: 'mergedCount', description: 'Number of files merged' },
+      { name: 'mergedBytes', description: 'Size of the merged file' },
+      { name: 'mergedPaths', description: 'Paths of the merged files, in merge order' }
+    ]
+  },
+  'wait-for-file': {
+    outputs: [
+      { name: 'foundPath', description: 'Path of the file that appeared' },
+      { name: 'foundName', description: 'Name of the file that appeared' },
+      { name: 'foundSize', description: 'Size of the file in bytes' },
+      { name: 'waitedSeconds', description: 'How long the step waited' }
+    ]
+  },
+  'write-manifest': {
+    outputs: [
+      { name: 'manifestPath', description: 'Manifest the record was appended to' },
+      { name: 'manifestRecord', description: 'The record that was written' }
+    ]
+  },
+  'http-download': {
+    outputs: [
+      { name: 'downloadedFile', description: 'Path the response body was written to' },
+      { name: 'downloadSize', description: 'Number of bytes downloaded' },
+      { name: 'downloadSha256', description: 'SHA-256 of the downloaded file' },
+      { name: 'downloadStatusCode', description: 'HTTP status code of the response' }
+    ]
+  },
   'delete-file': {
     outputs: [
       { name: 'success', description: 'Whether the deletion was successful' }
     ]
   },
+  'chown-file': {
+    outputs: [
+      { name: 'success', description: 'Whether the ownership change was successful' }
+    ]
+  },
+  'set-attributes': {
+    outputs: [
+      { name: 'attributes', description: 'Attributes that were set (readOnly, hidden, archive)' },
+      { name: 'success', description: 'Whether the attributes were set' }
+    ]
+  },
+  'cleanup-files': {
+    outputs: [
+      { name: 'cleanupDeleted', description: 'Number of files deleted (or that would be, in dry run)' },
+      { name: 'cleanupFiles', description: 'Paths of the deleted files' },
+      { name: 'cleanupDryRun', description: 'Whether this was a dry run' }
+    ]
+  },
+  'validate-file': {
+    outputs: [
+      { name: 'validationValid', description: 'Whether the file passed validation' },
+      { name: 'validationErrors', description: 'Problems found (up to maxErrors)' },
+      { name: 'validationFormat', description: 'Format the file was checked as' }
+    ]
+  },
+  'rename-sequence': {
+    outputs: [
+      { name: 'renamedTo', description: 'Path of the renamed file' },
+      { name: 'sequenceNumber', description: 'Sequence number assigned to the file' }
+    ]
+  },
   'rename-file': {
     outputs: [
       { name: 'newFile', description: 'Path to the renamed file' },
@@ -1036,6 +1215,17 @@ const NodeTypes = {
   'alert': {
     outputs: []  // Alerts don't produce outputs
   },
+  'emit-event': {
+    outputs: [
+      { name: 'eventDelivered', description: 'Whether the manager received the event' }
+    ]
+  },
+  'health-ping': {
+    outputs: [
+      { name: 'healthPingSignal', description: 'Signal sent: start, success or failure' },
+      { name: 'healthPingError', description: 'Why the monitor could not be reached, if it could not' }
+    ]
+  },
   'log': {
     outputs: []  // Logs don't produce outputs
   },
@@ -1073,32 +1263,153 @@ function getNodeFields(type) {
       { key: 'cron', label: 'Cron Expression', type: 'text', default: '0 * * * *',
         placeholder: 'min hour day month weekday (e.g. 30 8 * * * = daily 8:30 AM)' },
       { key: 'interval', label: 'Or Interval (instead of cron)', type: 'text',
-        placeholder: 'e.g. 5m, 1h, 30s (leave empty to use cron)' }
+        placeholder: 'e.g. 5m, 1h, 30s (leave empty to use cron)' },
+      { key: 'timezone', label: 'Timezone (for cron)', type: 'text',
+        placeholder: 'IANA name, e.g. America/New_York (empty = agent local time)' }
+    ],
+    'fileage-trigger': [
+      { key: 'path', label: 'Directory', type: 'text' },
+      { key: 'pattern', label: 'File Pattern', type: 'text', default: '*' },
+      { key: 'olderThan', label: 'Older Than', type: 'text', default: '1h',
+        placeholder: 'e.g. 30m, 1h, 24h' },
+      { key: 'interval', label: 'Scan Interval', type: 'text', default: '1m',
+        placeholder: 'e.g. 1m, 5m' },
+      { key: 'recursive', label: 'Include Subdirectories', type: 'select', options: ['false', 'true'] },
+      { key: 'repeat', label: 'Repeat While Aged', type: 'select', options: ['false', 'true'] }
     ],
     'run-command': [
       { key: 'command', label: 'Command', type: 'text' },
       { key: 'args', label: 'Arguments', type: 'text' },
       { key: 'workingDir', label: 'Working Directory', type: 'text',
-        placeholder: 'Directory to run command in (optional)' }
+        placeholder: 'Directory to run command in (optional)' },
+      { key: 'maxOutputBytes', label: 'Max Output Kept (bytes)', type: 'number',
+        placeholder: 'Default 1048576; longer output is truncated' },
+      { key: 'outputFile', label: 'Full Output File', type: 'text',
+        placeholder: 'Write the untruncated output here (optional)' },
+      { key: 'progressEvents', label: 'Stream Output To Manager', type: 'select', options: ['false', 'true'] }
     ],
     'move-file': [
       { key: 'source', label: 'Source Path', type: 'text' },
       { key: 'destination', label: 'Destination Path', type: 'text' }
     ],
+    'health-ping': [
+      { key: 'signal', label: 'Signal', type: 'select', options: ['', 'start', 'success', 'failure'] },
+      { key: 'url', label: 'Ping URL (healthchecks.io style)', type: 'text',
+        placeholder: 'https://hc-ping.com/<uuid>; start and failure use /start and /fail' },
+      { key: 'startUrl', label: 'Start URL (optional)', type: 'text' },
+      { key: 'successUrl', label: 'Success URL (optional)', type: 'text' },
+      { key: 'failureUrl', label: 'Failure URL (optional)', type: 'text' },
+      { key: 'method', label: 'Method', type: 'select', options: ['GET', 'POST'] },
+      { key: 'timeoutSeconds', label: 'Timeout (seconds)', type: 'number', default: '10' },
+      { key: 'failOnError', label: 'Fail If Monitor Unreachable', type: 'select', options: ['false', 'true'] }
+    ],
     'alert': [
       { key: 'level', label: 'Alert Level', type: 'select', options: ['info', 'warning', 'error', 'critical'] },
       { key: 'message', label: 'Message', type: 'textarea' }
     ],
+    'emit-event': [
+      { key: 'type', label: 'Event Type', type: 'text', placeholder: 'invoice-batch-processed' },
+      { key: 'message', label: 'Message', type: 'text', placeholder: 'Invoice batch processed: {{.count}} records' },
+      { key: 'data', label: 'Data (JSON)', type: 'textarea',
+        placeholder: '{"records": {{.count}}, "file": "{{.fileName}}"}' },
+      { key: 'required', label: 'Fail If Manager Unreachable', type: 'select', options: ['false', 'true'] }
+    ],
     'javascript': [
       { key: 'code', label: 'JavaScript Code', type: 'textarea' }
     ],
     'copy-file': [
       { key: 'source', label: 'Source Path', type: 'text' },
-      { key: 'destination', label: 'Destination Path', type: 'text' }
+      { key: 'destination', label: 'Destination Path', type: 'text' },
+      { key: 'recursive', label: 'Copy Directories Recursively', type: 'select', options: ['false', 'true'] }
+    ],
+    'list-files': [
+      { key: 'directory', label: 'Directory', type: 'text' },
+      { key: 'pattern', label: 'File Name Pattern', type: 'text', default: '*' },
+      { key: 'recursive', label: 'Include Subdirectories', type: 'select', options: ['false', 'true'] },
+      { key: 'modifiedWithinHours', label: 'Modified Within (hours, optional)', type: 'number' },
+      { key: 'sortBy', label: 'Sort By', type: 'select', options: ['name', 'modTime', 'size'] },
+      { key: 'minCount', label: 'Fail If Fewer Than', type: 'number' },
+      { key: 'maxResults', label: 'Max Files Listed', type: 'number', default: '1000' }
+    ],
+    'merge-files': [
+      { key: 'directory', label: 'Directory', type: 'text' },
+      { key: 'pattern', label: 'File Name Pattern', type: 'text', default: '*' },
+      { key: 'recursive', label: 'Include Subdirectories', type: 'select', options: ['false', 'true'] },
+      { key: 'destination', label: 'Merged File', type: 'text', placeholder: '/data/daily/invoices.csv' },
+      { key: 'sortBy', label: 'Merge Order', type: 'select', options: ['name', 'modTime', 'size'] },
+      { key: 'headerLines', label: 'Header Lines Per File (e.g. 1 for CSV)', type: 'number', default: '0' },
+      { key: 'keepHeader', label: 'Keep First File\'s Header', type: 'select', options: ['true', 'false'] },
+      { key: 'overwrite', label: 'Overwrite Existing', type: 'select', options: ['false', 'true'] },
+      { key: 'deleteSources', label: 'Delete Merged Files', type: 'select', options: ['false', 'true'] },
+      { key: 'minCount', label: 'Fail If Fewer Than', type: 'number', default: '1' }
+    ],
+    'wait-for-file': [
+      { key: 'path', label: 'File Path or Glob', type: 'text', placeholder: '/data/out/{{.fileName}}.done' },
+      { key: 'timeoutSeconds', label: 'Timeout (seconds)', type: 'number', default: '300' },
+      { key: 'stableSeconds', label: 'Unchanged For (seconds)', type: 'number', default: '2' },
+      { key: 'pollIntervalMs', label: 'Poll Interval (ms)', type: 'number', default: '1000' }
+    ],
+    'write-manifest': [
+      { key: 'path', label: 'Manifest File', type: 'text' },
+      { key: 'format', label: 'Format', type: 'select', options: ['jsonl', 'csv'] },
+      { key: 'sourceFile', label: 'Source File (default: triggering file)', type: 'text' },
+      { key: 'destination', label: 'Destination', type: 'text' },
+      { key: 'hash', label: 'Record SHA-256 and Size', type: 'select', options: ['true', 'false'] },
+      { key: 'fields', label: 'Extra Fields (JSON)', type: 'textarea',
+        placeholder: '{"customer": "acme"}' }
+    ],
+    'http-download': [
+      { key: 'url', label: 'URL', type: 'text' },
+      { key: 'destination', label: 'Destination File', type: 'text' },
+      { key: 'headers', label: 'Headers (JSON)', type: 'textarea',
+        placeholder: '{"Accept": "application/octet-stream"}' },
+      { key: 'bearerToken', label: 'Bearer Token', type: 'text' },
+      { key: 'username', label: 'Basic Auth Username', type: 'text' },
+      { key: 'password', label: 'Basic Auth Password', type: 'text' },
+      { key: 'sha256', label: 'Expected SHA-256 (optional)', type: 'text' },
+      { key: 'timeoutSeconds', label: 'Timeout (seconds)', type: 'number', default: '300' }
     ],
     'delete-file': [
       { key: 'path', label: 'File Path', type: 'text' }
     ],
+    'chown-file': [
+      { key: 'path', label: 'File Path', type: 'text' },
+      { key: 'owner', label: 'Owner (name or uid)', type: 'text' },
+      { key: 'group', label: 'Group (name or gid)', type: 'text' }
+    ],
+    'set-attributes': [
+      { key: 'path', label: 'File Path', type: 'text' },
+      { key: 'readOnly', label: 'Read-only', type: 'select', options: ['', 'true', 'false'] },
+      { key: 'hidden', label: 'Hidden (Windows only)', type: 'select', options: ['', 'true', 'false'] },
+      { key: 'archive', label: 'Archive (Windows only)', type: 'select', options: ['', 'true', 'false'] }
+    ],
+    'cleanup-files': [
+      { key: 'directory', label: 'Directory', type: 'text' },
+      { key: 'pattern', label: 'File Name Pattern', type: 'text', default: '*' },
+      { key: 'olderThanHours', label: 'Older Than (hours)', type: 'number', default: '720' },
+      { key: 'recursive', label: 'Include Subdirectories', type: 'select', options: ['false', 'true'] },
+      { key: 'maxDeletes', label: 'Max Deletes (safety cap)', type: 'number', default: '1000' },
+      { key: 'dryRun', label: 'Dry Run', type: 'select', options: ['false', 'true'] }
+    ],
+    'validate-file': [
+      { key: 'path', label: 'File Path', type: 'text',
+        placeholder: 'Defaults to the triggering file' },
+      { key: 'format', label: 'Format', type: 'select', options: ['', 'csv', 'json', 'xml'] },
+      { key: 'schema', label: 'Schema (JSON)', type: 'textarea',
+        placeholder: 'csv: {"headers":["id","name"]}; json: a JSON Schema or a schema file path; xml: {"rootElement":"Invoice"}' },
+      { key: 'maxErrors', label: 'Max Problems Reported', type: 'number', default: '10' }
+    ],
+    'rename-sequence': [
+      { key: 'source', label: 'Source Path', type: 'text' },
+      { key: 'destination', label: 'Destination Directory', type: 'text',
+        placeholder: 'Defaults to the source directory' },
+      { key: 'pattern', label: 'Name Pattern', type: 'text', default: '{name}_{seq}{ext}',
+        placeholder: 'e.g. feed_{seq}.csv; also {name}, {ext}, {filename}' },
+      { key: 'padding', label: 'Digits', type: 'number', default: '6' },
+      { key: 'start', label: 'First Number', type: 'number', default: '1' },
+      { key: 'sequenceKey', label: 'Counter Name (optional)', type: 'text',
+        placeholder: 'Defaults to the destination directory' }
+    ],
     'rename-file': [
       { key: 'source', label: 'Source Path', type: 'text' },
       { key: 'newName', label: 'New Name', type: 'text' }
@@ -78,6 +78,21 @@ class Database {
         )
       `);
 
+      // Custom events reported by emit-event workflow steps
+      this.db.run(`
+        CREATE TABLE IF NOT EXISTS events (
+          id TEXT PRIMARY KEY,
+          agent_id TEXT,
+          type TEXT,
+          message TEXT,
+          data TEXT,
+          workflow_id TEXT,
+          execution_id TEXT,
+          created_at INTEGER,
+          FOREIGN KEY (agent_id) REFERENCES agents(id)
+        )
+      `);
+
       // Logs table
       this.db.run(`
         CREATE TABLE IF NOT EXISTS logs (
@@ -302,6 +317,42 @@ class Database {
     });
   }
 
+  // Event methods
+  createEvent(agentId, event) {
+    return new Promise((resolve, reject) => {
+      const id = require('uuid').v4();
+      this.db.run(
+        'INSERT INTO events (id, agent_id, type, message, data, workflow_id, execution_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)',
+        [id, agentId, event.type, event.message || '', JSON.stringify(event.data || {}),
+          event.workflowId || null, event.executionId || null, Date.now()],
+        (err) => {
+          if (err) reject(err);
+          else resolve(id);
+        }
+      );
+    });
+  }
+
+  getEvents({ agentId, type, limit = 100, offset = 0 } = {}) {
+    return new Promise((resolve, reject) => {
+      const where = [];
+      const params = [];
+      if (agentId) {
+        where.push('agent_id = ?');
+        params.push(agentId);
+      }
+      if (type) {
+        where.push('type = ?');
+        params.push(type);
+      }
+      const query = `SELECT * FROM events${where.length ? ' WHERE ' + where.join(' AND ') : ''} ORDER BY created_at DESC LIMIT ? OFFSET ?`;
+      this.db.all(query, [...params, limit, offset], (err, rows) => {
+        if (err) reject(err);
+        else resolve(rows);
+      });
+    });
+  }
+
   // Log methods
   createLog(agentId, level, message, metadata) {
     return new Promise((resolve, reject) => {
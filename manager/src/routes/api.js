@@ -3,6 +3,7 @@
 const express = require('express');
 const { v4: uuidv4 } = require('uuid');
 const { importFromINI, exportToINI} = require('../utils/ini-converter');
+const { parseTagSelector, matchesTags } = require('../utils/tags');
 const { validatePassword } = require('./auth');
 const fetch = require('node-fetch');
 const config = require('../config');
@@ -33,15 +34,52 @@ async function fetchWithTimeout(url, options = {}, timeoutMs = config.AGENT_PROX
 }
 
 module.exports = (db, wsServer, gitServer) => {
-  // Get all agents
+  // Get all agents, optionally filtered by tag (?tag=region=eu&tag=role)
   router.get('/agents', async (req, res) => {
     try {
-      const agents = await db.getAllAgents();
-      res.json(agents.map(agent => ({
+      const selector = parseTagSelector(req.query.tag);
+      const agents = (await db.getAllAgents()).map(agent => ({
         ...agent,
         config: JSON.parse(agent.config || '{}'),
         metadata: JSON.parse(agent.metadata || '{}')
-      })));
+      }));
+      res.json(agents.filter(agent => matchesTags(agent.metadata.tags, selector)));
+    } catch (err) {
+      res.status(500).json({ error: err.message });
+    }
+  });
+
+  // Send a command to every agent matching a tag selector
+  // Body: { command, args, tags: { region: 'eu' } }. Each agent gets its own
+  // requestId; the reply maps agentId to requestId in requestIds.
+  router.post('/agents/command', async (req, res) => {
+    try {
+      const { command, args, tags } = req.body;
+      if (!command) {
+        return res.status(400).json({ error: 'command is required' });
+      }
+      const selector = parseTagSelector(tags);
+      if (Object.keys(selector).length === 0) {
+        return res.status(400).json({ error: 'tags selector is required' });
+      }
+
+      const agents = await db.getAllAgents();
+      const sent = [];
+      const notConnected = [];
+      const requestIds = {}; // agentId -> requestId the agent echoes in its reply
+      for (const agent of agents) {
+        const metadata = JSON.parse(agent.metadata || '{}');
+        if (!matchesTags(metadata.tags, selector)) continue;
+        const requestId = uuidv4();
+        if (wsServer.sendToAgent(agent.id, 'command', { command, args, requestId })) {
+          sent.push(agent.id);
+          requestIds[agent.id] = requestId;
+        } else {
+          notConnected.push(agent.id);
+        }
+      }
+
+      res.json({ success: true, matched: sent.length + notConnected.length, sent, notConnected, requestIds });
     } catch (err) {
       res.status(500).json({ error: err.message });
     }
@@ -65,16 +103,27 @@ module.exports = (db, wsServer, gitServer) => {
   });
 
   // Send command to agent
+  // Body: { command, args, requestId? }. The agent echoes requestId in its
+  // reply. A batch runs several commands in one round trip:
+  // { command: 'batch', args: { mode: 'sequential' | 'parallel', stopOnError, commands: [{ command, args, requestId? }] } }
   router.post('/agents/:id/command', async (req, res) => {
     try {
       const { command, args } = req.body;
+      if (!command) {
+        return res.status(400).json({ error: 'command is required' });
+      }
+      if (command === 'batch' && (!args || !Array.isArray(args.commands) || args.commands.length === 0)) {
+        return res.status(400).json({ error: 'batch requires args.commands' });
+      }
+      const requestId = req.body.requestId || uuidv4();
       const success = wsServer.sendToAgent(req.params.id, 'command', {
         command,
-        args
+        args,
+        requestId
       });
       
       if (success) {
-        res.json({ success: true, message: 'Command sent' });
+        res.json({ success: true, message: 'Command sent', requestId });
       } else {
         res.status(404).json({ error: 'Agent not connected' });
       }
@@ -91,10 +140,17 @@ module.exports = (db, wsServer, gitServer) => {
       const token = uuidv4();
       const expiresIn = req.body.expiresIn || 3600000; // 1 hour default
       const apiAddress = req.body.apiAddress || null;
-
-      await db.createToken(token, expiresIn, apiAddress ? { apiAddress } : null);
+      // Reusable tokens stay valid after use so agents configured with them as
+      // reRegistrationToken can re-enroll if this manager loses their record
+      const reusable = req.body.reusable === true;
+
+      const metadata = {};
+      if (apiAddress) metadata.apiAddress = apiAddress;
+      if (reusable) metadata.reusable = true;
+      await db.createToken(token, expiresIn, Object.keys(metadata).length ? metadata : null);
       res.json({
         token,
+        reusable,
         expiresAt: new Date(Date.now() + expiresIn).toISOString()
       });
     } catch (err) {
@@ -118,6 +174,25 @@ module.exports = (db, wsServer, gitServer) => {
     }
   });
 
+  // Get custom events reported by emit-event steps
+  // Query: agentId, type, limit, offset
+  router.get('/events', async (req, res) => {
+    try {
+      const events = await db.getEvents({
+        agentId: req.query.agentId,
+        type: req.query.type,
+        limit: parseInt(req.query.limit) || 100,
+        offset: parseInt(req.query.offset) || 0
+      });
+      res.json(events.map(event => ({
+        ...event,
+        data: JSON.parse(event.data || '{}')
+      })));
+    } catch (err) {
+      res.status(500).json({ error: err.message });
+    }
+  });
+
   // Acknowledge alert
   router.put('/alerts/:id/acknowledge', async (req, res) => {
     try {
@@ -655,6 +730,34 @@ module.exports = (db, wsServer, gitServer) => {
       const queryParams = new URLSearchParams(req.query).toString();
       const url = `${agentUrl}/api/workflows/executions?${queryParams}`;
 
+      const response = await fetchWithTimeout(url);
+      if (!response.ok) {
+        // Invalid filters come back as plain-text 400s
+        return res.status(response.status).json({ error: (await response.text()).trim() });
+      }
+      const data = await response.json();
+
+      res.json(data);
+    } catch (err) {
+      res.status(500).json({ error: err.message });
+    }
+  });
+
+  // Proxy agent per-workflow metrics (success rate, durations)
+  router.get('/agents/:id/workflows/metrics', async (req, res) => {
+    try {
+      const agent = await db.getAgent(req.params.id);
+      if (!agent) {
+        return res.status(404).json({ error: 'Agent not found' });
+      }
+      if (agent.status !== 'online') {
+        return res.status(503).json({ error: 'Agent is offline' });
+      }
+
+      const agentUrl = getAgentUrl(agent);
+      const queryParams = new URLSearchParams(req.query).toString();
+      const url = `${agentUrl}/api/workflows/metrics?${queryParams}`;
+
       const response = await fetchWithTimeout(url);
       const data = await response.json();
 
@@ -775,24 +878,65 @@ module.exports = (db, wsServer, gitServer) => {
       const queryParams = new URLSearchParams(req.query).toString();
       const url = `${agentUrl}/api/files/download?${queryParams}`;
 
-      const response = await fetchWithTimeout(url);
+      // Forward range and conditional headers so downloads can resume
+      const headers = {};
+      for (const name of ['range', 'if-range', 'if-modified-since', 'if-none-match']) {
+        if (req.headers[name]) {
+          headers[name] = req.headers[name];
+        }
+      }
 
+      const response = await fetchWithTimeout(url, { headers });
+
+      if (response.status === 304) {
+        return res.status(304).end();
+      }
       if (!response.ok) {
         return res.status(response.status).send(await response.text());
       }
 
       // Forward headers and stream the response
-      if (response.headers.get('content-type')) {
-        res.setHeader('Content-Type', response.headers.get('content-type'));
+      for (const name of ['content-type', 'content-disposition', 'content-length', 'content-range', 'accept-ranges', 'last-modified']) {
+        if (response.headers.get(name)) {
+          res.setHeader(name, response.headers.get(name));
+        }
       }
-      if (response.headers.get('content-disposition')) {
-        res.setHeader('Content-Disposition', response.headers.get('content-disposition'));
+
+      res.status(response.status);
+      response.body.pipe(res);
+    } catch (err) {
+      res.status(500).json({ error: err.message });
+    }
+  });
+
+  // File checksum (sha256 by default)
+  router.get('/agents/:id/files/checksum', async (req, res) => {
+    try {
+      const agent = await db.getAgent(req.params.id);
+      if (!agent) {
+        return res.status(404).json({ error: 'Agent not found' });
       }
-      if (response.headers.get('content-length')) {
-        res.setHeader('Content-Length', response.headers.get('content-length'));
+      if (agent.status !== 'online') {
+        return res.status(503).json({ error: 'Agent is offline' });
       }
 
-      response.body.pipe(res);
+      const agentUrl = getAgentUrl(agent);
+      const queryParams = new URLSearchParams(req.query).toString();
+      const url = `${agentUrl}/api/files/checksum?${queryParams}`;
+
+      // Hashing a large file can take a while
+      const response = await fetchWithTimeout(url, {}, 10 * 60 * 1000);
+      const isJSON = (response.headers.get('content-type') || '').includes('application/json');
+      if (!response.ok) {
+        // Checksum errors are JSON; auth and routing errors come back as plain text
+        const body = isJSON ? await response.json() : { error: (await response.text()).trim() };
+        return res.status(response.status).json(body);
+      }
+      if (!isJSON) {
+        return res.status(502).json({ error: 'Agent returned an unexpected checksum response' });
+      }
+
+      res.json(await response.json());
     } catch (err) {
       res.status(500).json({ error: err.message });
     }
@@ -0,0 +1,72 @@
+'use strict';
+
+/**
+ * Agent tag helpers. Agents report a flat map of labels (e.g. {region: 'eu'})
+ * that is stored in agents.metadata.tags.
+ */
+
+/**
+ * Normalize a tags payload from an agent into a plain string map
+ */
+function normalizeTags(tags) {
+  const result = {};
+  if (!tags || typeof tags !== 'object' || Array.isArray(tags)) {
+    return result;
+  }
+  for (const [key, value] of Object.entries(tags)) {
+    if (key) {
+      result[String(key)] = value == null ? '' : String(value);
+    }
+  }
+  return result;
+}
+
+/**
+ * Build a selector from query/body input. Accepts an object ({region: 'eu'}),
+ * a "key=value" string, or an array of such strings. A bare "key" matches any
+ * agent that has the tag, whatever its value.
+ */
+function parseTagSelector(input) {
+  const selector = {};
+  if (!input) {
+    return selector;
+  }
+  if (typeof input === 'object' && !Array.isArray(input)) {
+    for (const [key, value] of Object.entries(input)) {
+      selector[key] = value == null ? null : String(value);
+    }
+    return selector;
+  }
+  const items = Array.isArray(input) ? input : String(input).split(',');
+  for (const item of items) {
+    const text = String(item).trim();
+    if (!text) continue;
+    const eq = text.indexOf('=');
+    if (eq === -1) {
+      selector[text] = null;
+    } else {
+      selector[text.slice(0, eq).trim()] = text.slice(eq + 1).trim();
+    }
+  }
+  return selector;
+}
+
+/**
+ * Check whether an agent's tags satisfy every entry in the selector
+ */
+function matchesTags(tags, selector) {
+  const agentTags = normalizeTags(tags);
+  return Object.entries(selector).every(([key, value]) => {
+    if (!(key in agentTags)) return false;
+    return value === null || agentTags[key] === value;
+  });
+}
+
+function tagsEqual(a, b) {
+  const left = normalizeTags(a);
+  const right = normalizeTags(b);
+  const keys = Object.keys(left);
+  return keys.length === Object.keys(right).length && keys.every(k => left[k] === right[k]);
+}
+
+module.exports = { normalizeTags, parseTagSelector, matchesTags, tagsEqual };
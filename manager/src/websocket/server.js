@@ -3,6 +3,7 @@
 const WebSocket = require('ws');
 const { v4: uuidv4 } = require('uuid');
 const fetch = require('node-fetch');
+const { normalizeTags, tagsEqual } = require('../utils/tags');
 
 class WebSocketServer {
   constructor(server, db, logger, gitServer) {
@@ -78,6 +79,10 @@ class WebSocketServer {
       case 'status':
         await this.handleStatus(ws, agentId, payload);
         break;
+
+      case 'command_result':
+        await this.handleCommandResult(ws, agentId, payload);
+        break;
         
       case 'alert':
         await this.handleAlert(ws, agentId, payload);
@@ -86,6 +91,10 @@ class WebSocketServer {
       case 'log':
         await this.handleLog(ws, agentId, payload);
         break;
+
+      case 'event':
+        await this.handleEvent(ws, agentId, payload);
+        break;
         
       default:
         this.logger.warn(`Unknown message type: ${type}`);
@@ -93,7 +102,7 @@ class WebSocketServer {
   }
 
   async handleRegistration(ws, agentId, payload) {
-    const { publicKey, token, hostname, platform } = payload;
+    const { publicKey, token, hostname, platform, tags } = payload;
 
     // Validate token and get metadata
     const tokenRecord = await this.db.validateToken(token);
@@ -110,6 +119,20 @@ class WebSocketServer {
     const tokenMetadata = tokenRecord.metadata ? JSON.parse(tokenRecord.metadata) : {};
     const apiAddress = tokenMetadata.apiAddress || null;
 
+    // A reusable token may be shared by a fleet, so it must not let one agent
+    // take over another agent's ID that is still on record with a different key
+    if (tokenMetadata.reusable) {
+      const existing = await this.db.getAgent(agentId);
+      if (existing && existing.public_key !== publicKey) {
+        ws.send(JSON.stringify({
+          type: 'registration',
+          payload: { success: false, error: 'Public key mismatch' }
+        }));
+        ws.close();
+        return;
+      }
+    }
+
     // Register agent with connection IP and optional API address
     await this.db.registerAgent({
       id: agentId,
@@ -118,12 +141,18 @@ class WebSocketServer {
       publicKey,
       metadata: {
         connectionIp: ws.clientIp,
-        apiAddress: apiAddress  // Explicit API address from token, or null to use auto-detected connectionIp
+        apiAddress: apiAddress,  // Explicit API address from token, or null to use auto-detected connectionIp
+        tags: normalizeTags(tags)
       }
     });
+    ws.agentTags = normalizeTags(tags);
 
-    // Mark token as used
-    await this.db.useToken(token, agentId);
+    // Mark token as used; reusable re-enrollment tokens stay valid until they expire
+    if (tokenMetadata.reusable) {
+      this.logger.log(`Agent ${agentId} enrolled with reusable token`);
+    } else {
+      await this.db.useToken(token, agentId);
+    }
 
     // Initialize agent config in Git repository
     if (this.gitServer) {
@@ -172,7 +201,7 @@ class WebSocketServer {
   }
 
   async handleReconnection(ws, agentId, payload) {
-    const { publicKey, hostname, platform } = payload;
+    const { publicKey, hostname, platform, tags } = payload;
     
     // Check if agent exists in database
     const agent = await this.db.getAgent(agentId);
@@ -198,14 +227,20 @@ class WebSocketServer {
     // Update agent status
     await this.db.updateAgentStatus(agentId, 'online', Date.now());
 
-    // Update metadata if hostname, platform, or connection IP changed
+    // Update metadata if hostname, platform, connection IP or tags changed.
+    // Older agents don't report tags, so leave stored tags alone for them.
     const metadata = JSON.parse(agent.metadata || '{}');
-    if (metadata.hostname !== hostname || metadata.platform !== platform || metadata.connectionIp !== ws.clientIp) {
+    const tagsChanged = tags !== undefined && !tagsEqual(metadata.tags, tags);
+    if (metadata.hostname !== hostname || metadata.platform !== platform || metadata.connectionIp !== ws.clientIp || tagsChanged) {
       metadata.hostname = hostname;
       metadata.platform = platform;
       metadata.connectionIp = ws.clientIp;
+      if (tags !== undefined) {
+        metadata.tags = normalizeTags(tags);
+      }
       await this.db.updateAgentMetadata(agentId, metadata);
     }
+    ws.agentTags = normalizeTags(metadata.tags);
 
     // Ensure agent config exists in Git repository
     if (this.gitServer) {
@@ -254,6 +289,18 @@ class WebSocketServer {
     
     ws.isAlive = true;
     await this.db.updateAgentStatus(agentId, 'online', Date.now());
+
+    // Tags can change while connected (local config edit + reload)
+    if (payload && payload.tags !== undefined && !tagsEqual(ws.agentTags, payload.tags)) {
+      const agent = await this.db.getAgent(agentId);
+      if (agent) {
+        const metadata = JSON.parse(agent.metadata || '{}');
+        metadata.tags = normalizeTags(payload.tags);
+        await this.db.updateAgentMetadata(agentId, metadata);
+        this.logger.log(`Agent ${agentId} tags updated: ${JSON.stringify(metadata.tags)}`);
+      }
+      ws.agentTags = normalizeTags(payload.tags);
+    }
     
     // Send acknowledgment
     ws.send(JSON.stringify({
@@ -279,6 +326,38 @@ class WebSocketServer {
     }
   }
 
+  /**
+   * Record an agent's reply to a command. Every command reports the same
+   * shape: { command, requestId, success, status, message, data, timestamp }.
+   */
+  async handleCommandResult(ws, agentId, payload) {
+    const { command, requestId, success, status, message } = payload;
+    const summary = `Command ${command} on ${agentId}${requestId ? ` (${requestId})` : ''}: ${status}${message ? ` - ${message}` : ''}`;
+    if (success) {
+      this.logger.log(summary);
+    } else {
+      this.logger.warn(summary);
+    }
+    if (command === 'batch' && payload.data && Array.isArray(payload.data.results)) {
+      for (const result of payload.data.results) {
+        const line = `  ${result.command || '?'}${result.requestId ? ` (${result.requestId})` : ''}: ${result.status}${result.message ? ` - ${result.message}` : ''}`;
+        if (result.success) {
+          this.logger.log(line);
+        } else {
+          this.logger.warn(line);
+        }
+      }
+    }
+
+    const agent = await this.db.getAgent(agentId);
+    if (agent) {
+      const metadata = JSON.parse(agent.metadata || '{}');
+      metadata.lastCommandResult = payload;
+      metadata.lastCommandResultTime = Date.now();
+      await this.db.updateAgentMetadata(agentId, metadata);
+    }
+  }
+
   async handleAlert(ws, agentId, payload) {
     const { level, message, details } = payload;
     await this.db.createAlert(agentId, level, message, details);
@@ -320,6 +399,19 @@ class WebSocketServer {
     await this.db.createLog(agentId, level, message, metadata);
   }
 
+  /**
+   * Store a custom event from an emit-event workflow step:
+   * { type, message, data, workflowId, executionId, timestamp }.
+   */
+  async handleEvent(ws, agentId, payload) {
+    if (!payload || !payload.type) {
+      this.logger.warn(`Event without type from ${agentId}`);
+      return;
+    }
+    await this.db.createEvent(agentId, payload);
+    this.logger.log(`Event from ${agentId}: ${payload.type}${payload.message ? ` - ${payload.message}` : ''}`);
+  }
+
   async handleDisconnect(ws) {
     if (ws.agentId) {
       this.logger.log(`Agent disconnected: ${ws.agentId}`);
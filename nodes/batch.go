@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/your-org/controlcenter/nodes/internal/websocket"
+)
+
+// maxBatchCommands caps the number of commands in one batch message
+const maxBatchCommands = 100
+
+// batchRequest is the args of a batch command:
+//
+//	{"command": "batch", "requestId": "b1", "args": {"mode": "sequential",
+//	 "stopOnError": true, "commands": [{"command": "reload-config"}, ...]}}
+type batchRequest struct {
+	Commands    []json.RawMessage `json:"commands"`
+	Mode        string            `json:"mode"`        // "sequential" (default) or "parallel"
+	StopOnError *bool             `json:"stopOnError"` // Sequential only, default true
+}
+
+// commandReply captures the reply of a command run inside a batch, so it can
+// be returned with the batch result instead of on its own
+type commandReply struct {
+	mu     sync.Mutex
+	result *websocket.CommandResult
+	closed bool
+}
+
+// capture records the command's reply. It returns false when the reply must
+// be sent separately: a second reply, or one arriving after the batch replied
+// (e.g. the "drained" report of a drain command).
+func (r *commandReply) capture(result websocket.CommandResult) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed || r.result != nil {
+		return false
+	}
+	if result.Timestamp == 0 {
+		result.Timestamp = time.Now().Unix()
+	}
+	r.result = &result
+	return true
+}
+
+// close stops capturing and returns the captured reply, if any
+func (r *commandReply) close() (websocket.CommandResult, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	if r.result == nil {
+		return websocket.CommandResult{}, false
+	}
+	return *r.result, true
+}
+
+// batchSummary counts the outcomes of a batch
+type batchSummary struct {
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+}
+
+// runBatch runs the commands of a batch through run and returns one result
+// per command, in request order. Commands without a requestId get one derived
+// from the batch's, so every result can be correlated.
+func runBatch(batch commandRef, req batchRequest, run func(ref commandRef, cmd commandMessage, payload json.RawMessage)) ([]websocket.CommandResult, batchSummary) {
+	cmds := make([]commandMessage, len(req.Commands))
+	decodeErrs := make([]error, len(req.Commands))
+	for i, raw := range req.Commands {
+		decodeErrs[i] = json.Unmarshal(raw, &cmds[i])
+		if cmds[i].RequestID == "" && batch.RequestID != "" {
+			cmds[i].RequestID = fmt.Sprintf("%s.%d", batch.RequestID, i+1)
+		}
+	}
+
+	results := make([]websocket.CommandResult, len(cmds))
+	runOne := func(i int) bool {
+		cmd := cmds[i]
+		switch {
+		case decodeErrs[i] != nil:
+			results[i] = websocket.CommandResult{RequestID: cmd.RequestID, Status: "error", Message: fmt.Sprintf("invalid command in batch: %v", decodeErrs[i])}
+			return false
+		case cmd.Command == "":
+			results[i] = websocket.CommandResult{RequestID: cmd.RequestID, Status: "error", Message: "invalid command in batch: command is required"}
+			return false
+		case cmd.Command == "batch":
+			results[i] = websocket.CommandResult{Command: cmd.Command, RequestID: cmd.RequestID, Status: "error", Message: "nested batches are not supported"}
+			return false
+		}
+
+		ref := commandRef{Command: cmd.Command, RequestID: cmd.RequestID, reply: &commandReply{}}
+		run(ref, cmd, req.Commands[i])
+		result, ok := ref.reply.close()
+		if !ok {
+			result = websocket.CommandResult{Command: cmd.Command, RequestID: cmd.RequestID, Success: true, Status: "completed"}
+		}
+		results[i] = result
+		return result.Success
+	}
+
+	if req.Mode == "parallel" {
+		var wg sync.WaitGroup
+		for i := range cmds {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				runOne(i)
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		stopOnError := req.StopOnError == nil || *req.StopOnError
+		failed := false
+		for i, cmd := range cmds {
+			if failed && stopOnError {
+				results[i] = websocket.CommandResult{
+					Command:   cmd.Command,
+					RequestID: cmd.RequestID,
+					Status:    "skipped",
+					Message:   "Skipped after an earlier command failed",
+				}
+				continue
+			}
+			if !runOne(i) {
+				failed = true
+			}
+		}
+	}
+
+	var summary batchSummary
+	for _, result := range results {
+		switch {
+		case result.Success:
+			summary.Succeeded++
+		case result.Status == "skipped":
+			summary.Skipped++
+		default:
+			summary.Failed++
+		}
+	}
+	return results, summary
+}
+
+// handleBatch runs the commands of a batch message and replies once with the
+// result of each
+func (a *Agent) handleBatch(ref commandRef, payload json.RawMessage) {
+	var msg struct {
+		Args batchRequest `json:"args"`
+	}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		a.commandFailed(ref, fmt.Sprintf("Invalid batch: %v", err), nil)
+		return
+	}
+	req := msg.Args
+	if req.Mode == "" {
+		req.Mode = "sequential"
+	}
+	switch {
+	case len(req.Commands) == 0:
+		a.commandFailed(ref, "Batch has no commands", nil)
+		return
+	case len(req.Commands) > maxBatchCommands:
+		a.commandFailed(ref, fmt.Sprintf("Batch has %d commands, the limit is %d", len(req.Commands), maxBatchCommands), nil)
+		return
+	case req.Mode != "sequential" && req.Mode != "parallel":
+		a.commandFailed(ref, fmt.Sprintf("Invalid batch mode: %s (use sequential or parallel)", req.Mode), nil)
+		return
+	}
+
+	a.logger.Info().
+		Str("requestId", ref.RequestID).
+		Str("mode", req.Mode).
+		Int("commands", len(req.Commands)).
+		Msg("📦 Executing command batch")
+
+	results, summary := runBatch(ref, req, a.executeCommand)
+	data := map[string]interface{}{
+		"mode":      req.Mode,
+		"results":   results,
+		"succeeded": summary.Succeeded,
+		"failed":    summary.Failed,
+		"skipped":   summary.Skipped,
+	}
+	message := fmt.Sprintf("%d of %d commands succeeded", summary.Succeeded, len(results))
+	if summary.Failed > 0 || summary.Skipped > 0 {
+		a.logger.Warn().Str("requestId", ref.RequestID).Int("failed", summary.Failed).Int("skipped", summary.Skipped).Msg("📦 Command batch finished with failures")
+		a.commandFailed(ref, message, data)
+		return
+	}
+	a.logger.Info().Str("requestId", ref.RequestID).Msg("📦 Command batch completed")
+	a.commandSucceeded(ref, "batch-completed", message, data)
+}
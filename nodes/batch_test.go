@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/your-org/controlcenter/nodes/internal/websocket"
+)
+
+// fakeRun replies like the agent would: "fail" fails, "silent" does not reply
+func fakeRun(ran *[]string) func(ref commandRef, cmd commandMessage, payload json.RawMessage) {
+	return func(ref commandRef, cmd commandMessage, payload json.RawMessage) {
+		*ran = append(*ran, cmd.Command)
+		switch cmd.Command {
+		case "fail":
+			ref.reply.capture(websocket.CommandResult{Command: ref.Command, RequestID: ref.RequestID, Status: "error"})
+		case "silent":
+		default:
+			ref.reply.capture(websocket.CommandResult{Command: ref.Command, RequestID: ref.RequestID, Success: true, Status: "done"})
+		}
+	}
+}
+
+func batchOf(t *testing.T, args string) batchRequest {
+	var req batchRequest
+	if err := json.Unmarshal([]byte(args), &req); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestRunBatch_SequentialStopsOnError(t *testing.T) {
+	var ran []string
+	req := batchOf(t, `{"commands": [{"command": "reload-config"}, {"command": "fail", "requestId": "mine"}, {"command": "run-workflow"}]}`)
+	results, summary := runBatch(commandRef{Command: "batch", RequestID: "b1"}, req, fakeRun(&ran))
+
+	if len(ran) != 2 {
+		t.Fatalf("ran %v, want the commands up to the failure", ran)
+	}
+	if summary != (batchSummary{Succeeded: 1, Failed: 1, Skipped: 1}) {
+		t.Errorf("summary = %+v", summary)
+	}
+	if results[0].RequestID != "b1.1" || results[1].RequestID != "mine" || results[2].RequestID != "b1.3" {
+		t.Errorf("request IDs = %q, %q, %q", results[0].RequestID, results[1].RequestID, results[2].RequestID)
+	}
+	if results[2].Status != "skipped" || results[2].Command != "run-workflow" {
+		t.Errorf("third result = %+v, want skipped", results[2])
+	}
+}
+
+func TestRunBatch_ContinueAndParallel(t *testing.T) {
+	var ran []string
+	req := batchOf(t, `{"stopOnError": false, "commands": [{"command": "fail"}, {"command": "silent"}, {"command": "batch"}, {"bogus": 1}, {"command": 5}]}`)
+	results, summary := runBatch(commandRef{Command: "batch"}, req, fakeRun(&ran))
+	if len(ran) != 2 || summary != (batchSummary{Succeeded: 1, Failed: 4}) {
+		t.Errorf("ran %v, summary %+v", ran, summary)
+	}
+	if results[1].Status != "completed" || results[2].Message != "nested batches are not supported" || !strings.Contains(results[3].Message, "command is required") ||
+		!strings.Contains(results[4].Message, "cannot unmarshal number") {
+		t.Errorf("results = %+v", results)
+	}
+
+	req = batchOf(t, `{"mode": "parallel", "commands": [{"command": "fail"}, {"command": "a"}, {"command": "b"}]}`)
+	results, summary = runBatch(commandRef{Command: "batch"}, req, func(ref commandRef, cmd commandMessage, payload json.RawMessage) {
+		ref.reply.capture(websocket.CommandResult{Command: ref.Command, Success: cmd.Command != "fail"})
+	})
+	if summary != (batchSummary{Succeeded: 2, Failed: 1}) || results[1].Command != "a" || results[2].Command != "b" {
+		t.Errorf("parallel: summary %+v, results %+v", summary, results)
+	}
+}
+
+func TestCommandReply_LateRepliesAreSentSeparately(t *testing.T) {
+	r := &commandReply{}
+	if !r.capture(websocket.CommandResult{Status: "draining"}) {
+		t.Fatal("first reply should be captured")
+	}
+	if r.capture(websocket.CommandResult{Status: "again"}) {
+		t.Error("second reply should not be captured")
+	}
+	result, ok := r.close()
+	if !ok || result.Status != "draining" || result.Timestamp == 0 {
+		t.Errorf("close() = %+v, %v", result, ok)
+	}
+	if r.capture(websocket.CommandResult{Status: "drained"}) {
+		t.Error("reply after close should not be captured")
+	}
+}
+
+func TestHandleBatch_ParallelStateChangesAreSerialized(t *testing.T) {
+	level := zerolog.InfoLevel
+	a := &Agent{
+		wsClient: websocket.NewClient("http://manager:3000", "agent-1", zerolog.Nop()),
+		logger:   zerolog.Nop(),
+		logLevel: &level,
+	}
+	commands := make([]json.RawMessage, 20)
+	for i := range commands {
+		commands[i] = json.RawMessage(`{"command": "set-log-level", "args": {"level": "debug"}}`)
+	}
+	results, summary := runBatch(commandRef{Command: "batch"}, batchRequest{Mode: "parallel", Commands: commands}, a.executeCommand)
+	if summary != (batchSummary{Succeeded: 20}) {
+		t.Errorf("summary %+v, results %+v", summary, results)
+	}
+	if level != zerolog.DebugLevel {
+		t.Errorf("level = %s", level)
+	}
+}
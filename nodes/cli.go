@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/controlcenter/nodes/internal/gitsync"
+)
+
+// Exit codes of the one-shot CLI operations (-push-config, -merge-config,
+// -recover-backup, -list-backups, -check-changes). See STANDALONE.md.
+const (
+	exitOK          = 0
+	exitError       = 1 // any other failure
+	exitConflict    = 2 // local and remote configs need a manual merge
+	exitAuth        = 3 // manager rejected the agent's SSH key
+	exitUnavailable = 4 // manager or config repository unreachable
+	exitNotFound    = 5 // backup or repository does not exist
+	exitBusy        = 6 // another git operation holds the repository
+)
+
+// exitCodes maps gitsync failure kinds to exit codes
+var exitCodes = map[string]int{
+	gitsync.FailureConflict:    exitConflict,
+	gitsync.FailureAuth:        exitAuth,
+	gitsync.FailureUnavailable: exitUnavailable,
+	gitsync.FailureNotFound:    exitNotFound,
+	gitsync.FailureBusy:        exitBusy,
+}
+
+// cliResult is the outcome of a CLI operation, printed to stdout with -json
+type cliResult struct {
+	Operation string                 `json:"operation"`
+	Success   bool                   `json:"success"`
+	Status    string                 `json:"status"`
+	ExitCode  int                    `json:"exitCode"`
+	Message   string                 `json:"message,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// cliSuccess describes a successful operation; status says what happened
+// (e.g. "pushed" or "no-changes")
+func cliSuccess(operation, status, message string) cliResult {
+	return cliResult{Operation: operation, Success: true, Status: status, ExitCode: exitOK, Message: message}
+}
+
+// cliFailure describes a failed operation, with the status and exit code
+// derived from the kind of git error
+func cliFailure(operation, message string, err error) cliResult {
+	kind := gitsync.FailureKind(err)
+	if kind == "" {
+		kind = gitsync.FailureError
+	}
+	code, ok := exitCodes[kind]
+	if !ok {
+		code = exitError
+	}
+	result := cliResult{Operation: operation, Status: kind, ExitCode: code, Message: message}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// with adds a detail to the result
+func (r cliResult) with(key string, value interface{}) cliResult {
+	if r.Details == nil {
+		r.Details = make(map[string]interface{})
+	}
+	r.Details[key] = value
+	return r
+}
+
+// finishCLI reports the result and exits with its code. With jsonOutput the
+// result is printed to stdout, where it is the only output (logs go to stderr).
+func finishCLI(logger zerolog.Logger, jsonOutput bool, r cliResult) {
+	if r.Success {
+		logger.Info().Str("operation", r.Operation).Str("status", r.Status).Msg("✅ " + r.Message)
+	} else {
+		logger.Error().Str("operation", r.Operation).Str("status", r.Status).Int("exitCode", r.ExitCode).Str("error", r.Error).Msg("❌ " + r.Message)
+	}
+	if jsonOutput {
+		data, err := json.Marshal(r)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to encode result: %v\n", err)
+			os.Exit(exitError)
+		}
+		fmt.Println(string(data))
+	}
+	os.Exit(r.ExitCode)
+}
+
+// cliOperationName names the requested operation, in the order main handles them
+func cliOperationName(pushConfig, checkChanges, listBackups bool, recoverBackup string, mergeConfig bool) string {
+	switch {
+	case mergeConfig:
+		return "merge-config"
+	case listBackups:
+		return "list-backups"
+	case recoverBackup != "":
+		return "recover-backup"
+	case pushConfig:
+		return "push-config"
+	case checkChanges:
+		return "check-changes"
+	}
+	return ""
+}
@@ -3,17 +3,25 @@ package api
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
+	"github.com/your-org/controlcenter/nodes/internal/audit"
 	"github.com/your-org/controlcenter/nodes/internal/config"
+	"github.com/your-org/controlcenter/nodes/internal/filewatcher"
+	"github.com/your-org/controlcenter/nodes/internal/router"
+	"github.com/your-org/controlcenter/nodes/internal/websocket"
 	"github.com/your-org/controlcenter/nodes/internal/workflow"
 )
 
@@ -23,6 +31,11 @@ type Server struct {
 	executor    *workflow.Executor
 	logger      zerolog.Logger
 	logLevel    *zerolog.Level // Pointer to allow dynamic level changes
+	audit       *audit.Logger
+	provenance  func() map[string]interface{} // describes where the active config came from
+	fileWatcher *filewatcher.Watcher
+	backups     ConfigBackups
+	connection  func() (websocket.ConnectionState, bool)
 }
 
 // NewServer creates a new API server
@@ -35,14 +48,74 @@ func NewServer(cfg *config.Config, executor *workflow.Executor, logger zerolog.L
 	}
 }
 
-// RegisterHandlers registers all API endpoints
-func (s *Server) RegisterHandlers() {
-	http.HandleFunc("/api/logs", s.handleLogs)
-	http.HandleFunc("/api/logs/download", s.handleLogsDownload)
-	http.HandleFunc("/api/workflows/executions", s.handleWorkflowExecutions)
-	http.HandleFunc("/api/workflows/state", s.handleWorkflowState)
-	http.HandleFunc("/api/metrics", s.handleMetrics)
-	http.HandleFunc("/api/loglevel", s.handleLogLevel)
+// SetAuditLogger sets where log-level changes are recorded
+func (s *Server) SetAuditLogger(a *audit.Logger) {
+	s.audit = a
+}
+
+// SetProvenanceProvider sets the callback used by /api/config to report where
+// each part of the active configuration was loaded from
+func (s *Server) SetProvenanceProvider(fn func() map[string]interface{}) {
+	s.provenance = fn
+}
+
+// SetFileWatcher enables endpoints that inspect or replace file watcher rules
+func (s *Server) SetFileWatcher(fw *filewatcher.Watcher) {
+	s.fileWatcher = fw
+}
+
+// SetConnectionStateProvider sets the callback used by /api/connection to
+// report the agent's view of its manager connection. The callback returns
+// false when the agent runs without a manager.
+func (s *Server) SetConnectionStateProvider(fn func() (websocket.ConnectionState, bool)) {
+	s.connection = fn
+}
+
+// SetConfigBackups enables the config backup and restore endpoints
+func (s *Server) SetConfigBackups(b ConfigBackups) {
+	s.backups = b
+}
+
+// RegisterHandlers registers all API endpoints on rt. Every route is
+// registered even if some fail; the failures are returned together.
+func (s *Server) RegisterHandlers(rt *router.Router) error {
+	routes := []struct {
+		method  string
+		path    string
+		handler http.HandlerFunc
+	}{
+		{http.MethodGet, "/api/logs", s.handleLogs},
+		{http.MethodGet, "/api/logs/download", s.handleLogsDownload},
+		{http.MethodGet, "/api/workflows/executions", s.handleWorkflowExecutions},
+		{http.MethodGet, "/api/workflows/state", s.handleWorkflowState},
+		{http.MethodGet, "/api/workflows/metrics", s.handleWorkflowMetrics},
+		{http.MethodGet, "/api/workflows/step-types", s.handleStepTypes},
+		{http.MethodPost, "/api/workflows/validate", s.handleValidateWorkflow},
+		{http.MethodGet, "/api/metrics", s.handleMetrics},
+		{http.MethodGet, "/api/loglevel", s.handleLogLevel},
+		{http.MethodPost, "/api/loglevel", s.handleLogLevel},
+		{http.MethodGet, "/api/config", s.handleConfig},
+		{http.MethodGet, "/api/connection", s.handleConnection},
+		{http.MethodGet, "/api/drain", s.handleDrain},
+		{http.MethodPost, "/api/drain", s.requireAdminToken(s.handleDrain)},
+		{http.MethodPost, "/api/undrain", s.requireAdminToken(s.handleUndrain)},
+		{http.MethodGet, "/api/schema", s.handleSchema},
+		{http.MethodPost, "/api/filewatcher/import-ini", s.requireAdminTokenToApply(s.handleImportINI)},
+		{http.MethodGet, "/api/filewatcher/next-allowed", s.handleNextAllowed},
+		{http.MethodPost, "/api/filewatcher/next-allowed", s.handleNextAllowed},
+		{http.MethodPost, "/api/filewatcher/reprocess", s.requireAdminToken(s.handleReprocess)},
+	}
+
+	var errs []error
+	for _, route := range routes {
+		errs = append(errs, rt.HandleFunc(route.method, route.path, route.handler))
+	}
+	errs = append(errs,
+		s.registerDebugHandlers(rt),
+		s.registerBackupHandlers(rt),
+		s.registerConnectionTestHandlers(rt),
+	)
+	return errors.Join(errs...)
 }
 
 // LogEntry represents a single log line with metadata
@@ -68,7 +141,6 @@ type LogsResponse struct {
 // GET /api/logs?page=1&pageSize=100&level=error&search=workflow
 func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	// Parse query parameters
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
@@ -212,7 +284,6 @@ func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleLogsDownload(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain")
 	w.Header().Set("Content-Disposition", "attachment; filename=agent-logs.txt")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	levelFilter := strings.ToLower(r.URL.Query().Get("level"))
 	searchFilter := strings.ToLower(r.URL.Query().Get("search"))
@@ -265,14 +336,121 @@ func (s *Server) handleLogsDownload(w http.ResponseWriter, r *http.Request) {
 // WorkflowExecutionResponse represents workflow execution history
 type WorkflowExecutionResponse struct {
 	Executions []workflow.WorkflowState `json:"executions"`
-	Count      int                      `json:"count"`
+	Count      int                      `json:"count"` // executions in this page
+	Total      int                      `json:"total"` // executions matching the filters
+	Limit      int                      `json:"limit,omitempty"`
+	Offset     int                      `json:"offset,omitempty"`
+}
+
+// maxExecutionsLimit caps the page size of /api/workflows/executions
+const maxExecutionsLimit = 1000
+
+// executionFilter selects and orders executions for /api/workflows/executions
+type executionFilter struct {
+	workflowID string
+	statuses   map[string]bool // empty = any status
+	from, to   time.Time       // bounds on start time (zero = unbounded)
+	ascending  bool
+	limit      int // 0 = no limit
+	offset     int
+}
+
+// parseExecutionFilter reads the query parameters of /api/workflows/executions
+func parseExecutionFilter(q url.Values) (executionFilter, error) {
+	f := executionFilter{workflowID: q.Get("workflowId")}
+	if status := q.Get("status"); status != "" {
+		f.statuses = make(map[string]bool)
+		for _, st := range strings.Split(status, ",") {
+			if st = strings.TrimSpace(st); st != "" {
+				f.statuses[st] = true
+			}
+		}
+	}
+	for _, bound := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &f.from}, {"to", &f.to}} {
+		if v := q.Get(bound.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, fmt.Errorf("invalid %s time (use RFC3339): %v", bound.name, err)
+			}
+			*bound.dst = t
+		}
+	}
+	switch q.Get("sort") {
+	case "", "startTime:desc", "-startTime":
+	case "startTime", "startTime:asc":
+		f.ascending = true
+	default:
+		return f, fmt.Errorf("invalid sort %q (use startTime:asc or startTime:desc)", q.Get("sort"))
+	}
+	for _, n := range []struct {
+		name string
+		dst  *int
+	}{{"limit", &f.limit}, {"offset", &f.offset}} {
+		if v := q.Get(n.name); v != "" {
+			i, err := strconv.Atoi(v)
+			if err != nil || i < 0 {
+				return f, fmt.Errorf("invalid %s %q", n.name, v)
+			}
+			*n.dst = i
+		}
+	}
+	if f.limit > maxExecutionsLimit {
+		f.limit = maxExecutionsLimit
+	}
+	return f, nil
+}
+
+// apply filters, sorts and pages executions, returning the page and the
+// number of executions that matched before paging
+func (f executionFilter) apply(executions []workflow.WorkflowState) ([]workflow.WorkflowState, int) {
+	matched := []workflow.WorkflowState{}
+	for _, exec := range executions {
+		if f.workflowID != "" && exec.WorkflowID != f.workflowID {
+			continue
+		}
+		if len(f.statuses) > 0 && !f.statuses[exec.Status] {
+			continue
+		}
+		if !f.from.IsZero() && exec.StartTime.Before(f.from) {
+			continue
+		}
+		if !f.to.IsZero() && exec.StartTime.After(f.to) {
+			continue
+		}
+		matched = append(matched, exec)
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		if f.ascending {
+			return matched[i].StartTime.Before(matched[j].StartTime)
+		}
+		return matched[i].StartTime.After(matched[j].StartTime)
+	})
+
+	total := len(matched)
+	if f.offset >= total {
+		return []workflow.WorkflowState{}, total
+	}
+	matched = matched[f.offset:]
+	if f.limit > 0 && len(matched) > f.limit {
+		matched = matched[:f.limit]
+	}
+	return matched, total
 }
 
 // handleWorkflowExecutions returns workflow execution history
-// GET /api/workflows/executions?workflowId=wf-123
+// GET /api/workflows/executions?workflowId=wf-123&status=failed,running&from=RFC3339&to=RFC3339&sort=startTime:desc&limit=50&offset=0
 func (s *Server) handleWorkflowExecutions(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	filter, err := parseExecutionFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Read state file
 	stateFile := s.config.StateFilePath
@@ -286,6 +464,8 @@ func (s *Server) handleWorkflowExecutions(w http.ResponseWriter, r *http.Request
 			json.NewEncoder(w).Encode(WorkflowExecutionResponse{
 				Executions: []workflow.WorkflowState{},
 				Count:      0,
+				Limit:      filter.limit,
+				Offset:     filter.offset,
 			})
 			return
 		}
@@ -299,18 +479,18 @@ func (s *Server) handleWorkflowExecutions(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	workflowFilter := r.URL.Query().Get("workflowId")
-
-	var executions []workflow.WorkflowState
+	executions := make([]workflow.WorkflowState, 0, len(state))
 	for _, exec := range state {
-		if workflowFilter == "" || exec.WorkflowID == workflowFilter {
-			executions = append(executions, *exec)
-		}
+		executions = append(executions, *exec)
 	}
+	page, total := filter.apply(executions)
 
 	json.NewEncoder(w).Encode(WorkflowExecutionResponse{
-		Executions: executions,
-		Count:      len(executions),
+		Executions: page,
+		Count:      len(page),
+		Total:      total,
+		Limit:      filter.limit,
+		Offset:     filter.offset,
 	})
 }
 
@@ -318,7 +498,6 @@ func (s *Server) handleWorkflowExecutions(w http.ResponseWriter, r *http.Request
 // GET /api/workflows/state
 func (s *Server) handleWorkflowState(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	workflows := s.executor.GetWorkflows()
 
@@ -328,6 +507,69 @@ func (s *Server) handleWorkflowState(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleWorkflowMetrics returns per-workflow execution counts, success rate,
+// average and p95 duration and last-run status
+// GET /api/workflows/metrics?workflowId=<id>
+func (s *Server) handleWorkflowMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	metrics := s.executor.WorkflowMetrics()
+	if id := r.URL.Query().Get("workflowId"); id != "" {
+		filtered := []workflow.WorkflowMetrics{}
+		for _, m := range metrics {
+			if m.WorkflowID == id {
+				filtered = append(filtered, m)
+			}
+		}
+		metrics = filtered
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"workflows": metrics,
+		"count":     len(metrics),
+	})
+}
+
+// handleStepTypes lists registered step types, whether each is implemented
+// in this build, and the config keys it accepts
+// GET /api/workflows/step-types
+func (s *Server) handleStepTypes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	stepTypes := s.executor.StepTypes()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"stepTypes": stepTypes,
+		"count":     len(stepTypes),
+	})
+}
+
+// maxWorkflowBodySize caps workflow definitions submitted for validation
+const maxWorkflowBodySize = 1024 * 1024
+
+// WorkflowValidationResponse reports the result of a workflow pre-flight check
+type WorkflowValidationResponse struct {
+	Valid  bool                       `json:"valid"`
+	Issues []workflow.ValidationIssue `json:"issues"`
+}
+
+// handleValidateWorkflow checks a workflow definition without loading it
+// POST /api/workflows/validate
+func (s *Server) handleValidateWorkflow(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var wf config.Workflow
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWorkflowBodySize)).Decode(&wf); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid workflow JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	issues := s.executor.ValidateWorkflow(wf)
+	json.NewEncoder(w).Encode(WorkflowValidationResponse{
+		Valid:  !workflow.HasErrors(issues),
+		Issues: issues,
+	})
+}
+
 // MetricsResponse represents agent metrics
 type MetricsResponse struct {
 	AgentID          string                 `json:"agentId"`
@@ -345,32 +587,39 @@ type MetricsResponse struct {
 // GET /api/metrics
 func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(CollectMetrics(s.config, s.executor, s.fileWatcher))
+}
 
+// CollectMetrics gathers the agent metrics served by /api/metrics. The
+// file watcher may be nil.
+func CollectMetrics(cfg *config.Config, executor *workflow.Executor, fw *filewatcher.Watcher) MetricsResponse {
 	// Get file sizes
 	logSize := int64(0)
-	if info, err := os.Stat(s.config.LogFilePath); err == nil {
+	if info, err := os.Stat(cfg.LogFilePath); err == nil {
 		logSize = info.Size()
 	}
 
 	stateSize := int64(0)
-	if info, err := os.Stat(s.config.StateFilePath); err == nil {
+	if info, err := os.Stat(cfg.StateFilePath); err == nil {
 		stateSize = info.Size()
 	}
 
 	hostname, _ := os.Hostname()
 
 	metrics := MetricsResponse{
-		AgentID:         s.config.AgentID,
+		AgentID:         cfg.AgentID,
 		Hostname:        hostname,
 		Platform:        getPlatform(),
-		WorkflowsLoaded: len(s.executor.GetWorkflows()),
+		WorkflowsLoaded: len(executor.GetWorkflows()),
 		LogFileSize:     logSize,
 		StateFileSize:   stateSize,
 		Extra:           make(map[string]interface{}),
 	}
-
-	json.NewEncoder(w).Encode(metrics)
+	if fw != nil {
+		metrics.Extra["fileWatcherQueue"] = fw.QueueStats()
+		metrics.Extra["fileWatcherWatches"] = fw.WatchStats()
+	}
+	return metrics
 }
 
 func getDataDir() string {
@@ -402,7 +651,6 @@ type LogLevelRequest struct {
 // POST /api/loglevel - Set new level (body: {"level": "debug"})
 func (s *Server) handleLogLevel(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	availableLevels := []string{"debug", "info", "warn", "error"}
 
@@ -439,11 +687,17 @@ func (s *Server) handleLogLevel(w http.ResponseWriter, r *http.Request) {
 
 		// Update log level
 		if s.logLevel != nil {
+			oldLevel := s.logLevel.String()
 			*s.logLevel = newLevel
 			s.logger.Info().
-				Str("oldLevel", s.logLevel.String()).
+				Str("oldLevel", oldLevel).
 				Str("newLevel", newLevel.String()).
 				Msg("Log level changed via API")
+			s.audit.Record("loglevel.change", r.RemoteAddr, audit.OutcomeSuccess, map[string]interface{}{
+				"oldLevel": oldLevel,
+				"newLevel": newLevel.String(),
+				"via":      "api",
+			})
 		}
 
 		// Update config if available
@@ -464,3 +718,285 @@ func (s *Server) handleLogLevel(w http.ResponseWriter, r *http.Request) {
 	// Method not allowed
 	http.Error(w, "Method not allowed. Use GET or POST", http.StatusMethodNotAllowed)
 }
+
+// handleConnection reports whether the agent believes it is connected to the manager
+// GET /api/connection
+func (s *Server) handleConnection(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var state websocket.ConnectionState
+	managed := false
+	if s.connection != nil {
+		state, managed = s.connection()
+	}
+	if !managed {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"mode":      "standalone",
+			"connected": false,
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(struct {
+		Mode string `json:"mode"`
+		websocket.ConnectionState
+	}{Mode: "managed", ConnectionState: state})
+}
+
+// handleConfig returns the effective in-memory configuration with secrets redacted
+// GET /api/config
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	cfg, err := s.config.RedactedMap()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read config: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"config":          cfg,
+		"activeWorkflows": len(s.executor.GetWorkflows()),
+	}
+	if s.provenance != nil {
+		response["provenance"] = s.provenance()
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// maxINIUploadSize caps uploaded legacy INI files
+const maxINIUploadSize = 10 * 1024 * 1024
+
+// RuleValidationError describes a rule rejected during INI import
+type RuleValidationError struct {
+	Rule  string `json:"rule"`
+	Error string `json:"error"`
+}
+
+// requireAdminTokenToApply lets previews (no ?apply=true) through and
+// requires the admin token for requests that change the running rules
+func (s *Server) requireAdminTokenToApply(next http.HandlerFunc) http.HandlerFunc {
+	guarded := s.requireAdminToken(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("apply") == "true" {
+			guarded(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleImportINI converts a legacy INI file into file watcher rules
+// POST /api/filewatcher/import-ini           - Parse and validate only (preview)
+// POST /api/filewatcher/import-ini?apply=true - Also replace the running rules (admin token required,
+// since imported rules can run programs)
+// The response's "report" lists per-rule warnings and ignored sections with
+// line numbers. The INI is read from the "file" multipart field, or from the raw request body.
+func (s *Server) handleImportINI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxINIUploadSize)
+
+	var data []byte
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, ferr := r.FormFile("file")
+		if ferr != nil {
+			http.Error(w, fmt.Sprintf("Missing INI file: %v", ferr), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		data, err = io.ReadAll(file)
+	} else {
+		data, err = io.ReadAll(r.Body)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read INI: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	rules, general, report, err := filewatcher.ImportINIDataWithReport(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Rules the import left out (missing section, failed validation) make
+	// the upload invalid; applying only the rest would silently drop them
+	var invalid []RuleValidationError
+	for _, entry := range report.Rules {
+		if !entry.Imported {
+			invalid = append(invalid, RuleValidationError{Rule: entry.Name, Error: entry.Error})
+		}
+	}
+
+	apply := r.URL.Query().Get("apply") == "true"
+	response := map[string]interface{}{
+		"rules":   rules,
+		"general": general,
+		"count":   len(rules),
+		"invalid": invalid,
+		"report":  report,
+		"applied": false,
+	}
+
+	if apply {
+		if len(invalid) > 0 {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			response["error"] = "some rules are invalid; nothing was applied"
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		if s.fileWatcher == nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			response["error"] = "file watcher is not running"
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		s.fileWatcher.UpdateRules(rules)
+		if !s.fileWatcher.IsRunning() {
+			go s.fileWatcher.Start()
+		}
+
+		s.logger.Warn().
+			Int("count", len(rules)).
+			Msg("📥 File watcher rules replaced from INI import (runtime only - reset on next git pull)")
+		s.audit.Record("filewatcher.import-ini", r.RemoteAddr, audit.OutcomeSuccess, map[string]interface{}{
+			"rules": len(rules),
+		})
+		response["applied"] = true
+		response["message"] = "Rules applied at runtime only. Commit them to the config repository to persist."
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// RuleSchedulePreview reports when a rule's time restrictions next allow processing
+type RuleSchedulePreview struct {
+	RuleID      string     `json:"ruleId,omitempty"`
+	Name        string     `json:"name,omitempty"`
+	AllowedNow  bool       `json:"allowedNow"`
+	NextAllowed *time.Time `json:"nextAllowed"` // null if the restrictions never allow processing
+}
+
+// handleNextAllowed previews time restrictions
+// GET /api/filewatcher/next-allowed[?ruleId=x]  - For loaded rules
+// POST /api/filewatcher/next-allowed            - For ad-hoc restrictions (body: {"timeRestrictions": {...}, "from": "RFC3339"})
+func (s *Server) handleNextAllowed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	now := time.Now()
+
+	switch r.Method {
+	case http.MethodGet:
+		if s.fileWatcher == nil {
+			http.Error(w, "File watcher is not running", http.StatusServiceUnavailable)
+			return
+		}
+
+		ruleID := r.URL.Query().Get("ruleId")
+		previews := []RuleSchedulePreview{}
+		for _, rule := range s.fileWatcher.GetRules() {
+			if ruleID != "" && rule.ID != ruleID {
+				continue
+			}
+			preview := previewRestrictions(rule.TimeRestrictions, now)
+			preview.RuleID = rule.ID
+			preview.Name = rule.Name
+			previews = append(previews, preview)
+		}
+		if ruleID != "" && len(previews) == 0 {
+			http.Error(w, "Rule not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(previews)
+
+	case http.MethodPost:
+		var req struct {
+			TimeRestrictions filewatcher.TimeRestrictions `json:"timeRestrictions"`
+			From             string                       `json:"from"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+		from := now
+		if req.From != "" {
+			parsed, err := time.Parse(time.RFC3339, req.From)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid from time: %v", err), http.StatusBadRequest)
+				return
+			}
+			from = parsed
+		}
+		json.NewEncoder(w).Encode(previewRestrictions(req.TimeRestrictions, from))
+
+	default:
+		http.Error(w, "Method not allowed. Use GET or POST", http.StatusMethodNotAllowed)
+	}
+}
+
+func previewRestrictions(restrictions filewatcher.TimeRestrictions, from time.Time) RuleSchedulePreview {
+	preview := RuleSchedulePreview{}
+	if next := filewatcher.NextAllowedTime(restrictions, from); !next.IsZero() {
+		preview.NextAllowed = &next
+		preview.AllowedNow = next.Equal(from)
+	}
+	return preview
+}
+
+// handleReprocess forces a file through a rule again
+// POST /api/filewatcher/reprocess  (body: {"path": "/data/in/file.csv", "ruleId": "rule-1"})
+// Requires the admin token: the rule's operations run on a path the caller picks.
+func (s *Server) handleReprocess(w http.ResponseWriter, r *http.Request) {
+	if s.fileWatcher == nil {
+		http.Error(w, "File watcher is not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		Path   string `json:"path"`
+		RuleID string `json:"ruleId"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Path == "" || req.RuleID == "" {
+		http.Error(w, "path and ruleId are required", http.StatusBadRequest)
+		return
+	}
+	req.Path = filepath.Clean(req.Path)
+
+	if err := s.fileWatcher.Reprocess(req.Path, req.RuleID); err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, filewatcher.ErrRuleNotFound), errors.Is(err, os.ErrNotExist):
+			status = http.StatusNotFound
+		case errors.Is(err, filewatcher.ErrFileBusy):
+			status = http.StatusConflict
+		case errors.Is(err, filewatcher.ErrNotRunning), errors.Is(err, filewatcher.ErrPaused), errors.Is(err, filewatcher.ErrQueueFull):
+			status = http.StatusServiceUnavailable
+		}
+		s.audit.Record("filewatcher.reprocess", r.RemoteAddr, audit.OutcomeFailure, map[string]interface{}{
+			"path": req.Path, "ruleId": req.RuleID, "error": err.Error(),
+		})
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	s.logger.Info().Str("file", req.Path).Str("rule", req.RuleID).Str("remote", r.RemoteAddr).Msg("🔁 File queued for reprocessing via API")
+	s.audit.Record("filewatcher.reprocess", r.RemoteAddr, audit.OutcomeSuccess, map[string]interface{}{
+		"path": req.Path, "ruleId": req.RuleID,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"queued": true,
+		"path":   req.Path,
+		"ruleId": req.RuleID,
+	})
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/controlcenter/nodes/internal/config"
+	"github.com/your-org/controlcenter/nodes/internal/filewatcher"
+	"github.com/your-org/controlcenter/nodes/internal/router"
+	"github.com/your-org/controlcenter/nodes/internal/websocket"
+	"github.com/your-org/controlcenter/nodes/internal/workflow"
+)
+
+func TestHandleImportINI_Preview(t *testing.T) {
+	s := &Server{logger: zerolog.Nop()}
+
+	body := "[General]\nScanDir=/data/in\n\n[FileMatching]\nRule0=Good\nRule1=Bad\n\n[Good]\nFileRegEx=\\.csv$\n\n[Bad]\nFileRegEx=([\n"
+	req := httptest.NewRequest(http.MethodPost, "/api/filewatcher/import-ini", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.handleImportINI(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Count   int                      `json:"count"`
+		Applied bool                     `json:"applied"`
+		Invalid []RuleValidationError    `json:"invalid"`
+		Report  filewatcher.ImportReport `json:"report"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Count != 1 || resp.Applied {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if len(resp.Invalid) != 1 || resp.Invalid[0].Rule != "Bad" || !strings.Contains(resp.Invalid[0].Error, "regex") {
+		t.Errorf("expected Bad rule to be flagged, got %+v", resp.Invalid)
+	}
+	if len(resp.Report.Rules) != 2 || resp.Report.Rules[1].Imported || resp.Report.Rules[1].Line != 11 {
+		t.Errorf("expected the report to flag Bad at line 11, got %+v", resp.Report.Rules)
+	}
+}
+
+func TestHandleImportINI_ApplyRejectsInvalid(t *testing.T) {
+	cfg := &config.Config{APISettings: config.APISettings{AdminToken: "adm1n"}}
+	s := &Server{config: cfg, logger: zerolog.Nop()}
+	handler := s.requireAdminTokenToApply(s.handleImportINI)
+
+	body := "[FileMatching]\nRule0=Bad\n\n[Bad]\nFileRegEx=([\n"
+	req := httptest.NewRequest(http.MethodPost, "/api/filewatcher/import-ini?apply=true", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("apply without admin token: status = %d, want 401", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/filewatcher/import-ini?apply=true", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer adm1n")
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422", rec.Code)
+	}
+
+	// Previews need no token
+	req = httptest.NewRequest(http.MethodPost, "/api/filewatcher/import-ini", strings.NewReader(body))
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("preview status = %d, want 200", rec.Code)
+	}
+}
+
+func TestHandleConnection(t *testing.T) {
+	s := &Server{logger: zerolog.Nop()}
+	rec := httptest.NewRecorder()
+	s.handleConnection(rec, httptest.NewRequest(http.MethodGet, "/api/connection", nil))
+	if !strings.Contains(rec.Body.String(), `"mode":"standalone"`) {
+		t.Errorf("expected standalone mode, got %s", rec.Body.String())
+	}
+
+	connectedAt := time.Now()
+	s.SetConnectionStateProvider(func() (websocket.ConnectionState, bool) {
+		return websocket.ConnectionState{URL: "ws://manager/ws", Connected: true, LastConnect: &connectedAt, TotalConnects: 2}, true
+	})
+	rec = httptest.NewRecorder()
+	s.handleConnection(rec, httptest.NewRequest(http.MethodGet, "/api/connection", nil))
+	var got struct {
+		Mode          string `json:"mode"`
+		Connected     bool   `json:"connected"`
+		TotalConnects int    `json:"totalConnects"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Mode != "managed" || !got.Connected || got.TotalConnects != 2 {
+		t.Errorf("unexpected connection state: %+v", got)
+	}
+}
+
+func TestHandleWorkflowExecutions_Filters(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	state := map[string]workflow.WorkflowState{
+		"a": {WorkflowID: "a", Status: "failed", StartTime: base},
+		"b": {WorkflowID: "b", Status: "completed", StartTime: base.Add(time.Minute)},
+		"c": {WorkflowID: "c", Status: "failed", StartTime: base.Add(2 * time.Minute)},
+		"d": {WorkflowID: "d", Status: "failed", StartTime: base.Add(-time.Hour)},
+	}
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	data, _ := json.Marshal(state)
+	if err := os.WriteFile(stateFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &config.Config{StateFilePath: stateFile}, logger: zerolog.Nop()}
+
+	query := func(q string) (WorkflowExecutionResponse, int) {
+		rec := httptest.NewRecorder()
+		s.handleWorkflowExecutions(rec, httptest.NewRequest(http.MethodGet, "/api/workflows/executions?"+q, nil))
+		var resp WorkflowExecutionResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return resp, rec.Code
+	}
+
+	resp, _ := query("status=failed&from=" + base.Format(time.RFC3339))
+	if resp.Total != 2 || resp.Executions[0].WorkflowID != "c" || resp.Executions[1].WorkflowID != "a" {
+		t.Errorf("failed since base, newest first: %+v", resp)
+	}
+
+	resp, _ = query("sort=startTime:asc&limit=2&offset=1")
+	if resp.Total != 4 || resp.Count != 2 || resp.Executions[0].WorkflowID != "a" || resp.Executions[1].WorkflowID != "b" {
+		t.Errorf("paged ascending: %+v", resp)
+	}
+
+	if _, code := query("from=yesterday"); code != http.StatusBadRequest {
+		t.Errorf("bad from: status %d, want 400", code)
+	}
+}
+
+func TestCollectMetrics(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "agent.log")
+	if err := os.WriteFile(logPath, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	executor, err := workflow.NewExecutor(filepath.Join(dir, "state.json"), zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{AgentID: "agent-1", LogFilePath: logPath}
+
+	metrics := CollectMetrics(cfg, executor, nil)
+	if metrics.AgentID != "agent-1" || metrics.LogFileSize != 10 || metrics.Platform == "" {
+		t.Errorf("unexpected metrics: %+v", metrics)
+	}
+	if _, ok := metrics.Extra["fileWatcherQueue"]; ok {
+		t.Error("file watcher stats reported without a file watcher")
+	}
+}
+
+func TestStateChangingRoutesRequireAdminToken(t *testing.T) {
+	cfg := &config.Config{APISettings: config.APISettings{AdminToken: "adm1n"}}
+	s := &Server{config: cfg, logger: zerolog.Nop()}
+	rt := router.New()
+	if err := s.RegisterHandlers(rt); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/api/filewatcher/reprocess", "/api/drain", "/api/undrain"} {
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader("{}")))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("POST %s without token: status = %d, want 401", path, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/drain", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /api/drain without token: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestConfigRouteIsReadOnly(t *testing.T) {
+	s := &Server{config: &config.Config{}, logger: zerolog.Nop()}
+	rt := router.New()
+	if err := s.RegisterHandlers(rt); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/config", strings.NewReader("{}")))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /api/config: status = %d, want 405", rec.Code)
+	}
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/your-org/controlcenter/nodes/internal/audit"
+	"github.com/your-org/controlcenter/nodes/internal/gitsync"
+	"github.com/your-org/controlcenter/nodes/internal/router"
+)
+
+// ConfigBackups is the subset of gitsync.GitSync used by the backup endpoints
+type ConfigBackups interface {
+	ListBackups() ([]string, error)
+	BackupLocalChanges() error
+	RecoverBackup(backupID string) error
+}
+
+// ConfigBackup describes a stash or backup branch in the config repository
+type ConfigBackup struct {
+	ID          string `json:"id"`   // Value to pass to /api/config/restore
+	Type        string `json:"type"` // "stash" or "branch"
+	ThisAgent   bool   `json:"thisAgent"`
+	Description string `json:"description,omitempty"`
+}
+
+// registerBackupHandlers wires the config backup endpoints when git sync is
+// available and an admin token is configured
+func (s *Server) registerBackupHandlers(rt *router.Router) error {
+	if s.backups == nil {
+		return nil
+	}
+	if s.config.GetAPISettings().AdminToken == "" {
+		s.logger.Info().Msg("Config backup endpoints disabled (set apiSettings.adminToken to enable)")
+		return nil
+	}
+
+	return errors.Join(
+		rt.HandleFunc(http.MethodGet, "/api/config/backups", s.requireAdminToken(s.handleListBackups)),
+		rt.HandleFunc(http.MethodPost, "/api/config/backup", s.requireAdminToken(s.handleCreateBackup)),
+		rt.HandleFunc(http.MethodPost, "/api/config/restore", s.requireAdminToken(s.handleRestoreBackup)),
+	)
+}
+
+// requireAdminToken rejects requests without the configured admin token
+func (s *Server) requireAdminToken(next http.HandlerFunc) http.HandlerFunc {
+	return requireBearerToken(func() string { return s.config.GetAPISettings().AdminToken }, next)
+}
+
+// handleListBackups lists stashes and backup branches in the config repository
+// GET /api/config/backups
+func (s *Server) handleListBackups(w http.ResponseWriter, r *http.Request) {
+
+	entries, err := s.backups.ListBackups()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list backups: %v", err), http.StatusInternalServerError)
+		return
+	}
+	backups := make([]ConfigBackup, 0, len(entries))
+	for _, entry := range entries {
+		backups = append(backups, parseBackupEntry(entry))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"backups": backups,
+		"count":   len(backups),
+	})
+}
+
+// handleCreateBackup stashes (or branches) uncommitted config changes
+// POST /api/config/backup
+func (s *Server) handleCreateBackup(w http.ResponseWriter, r *http.Request) {
+
+	if err := s.backups.BackupLocalChanges(); err != nil {
+		s.audit.Record("config.backup", r.RemoteAddr, audit.OutcomeFailure, map[string]interface{}{"error": err.Error()})
+		http.Error(w, fmt.Sprintf("Failed to back up config: %v", err), backupErrorStatus(err))
+		return
+	}
+	s.audit.Record("config.backup", r.RemoteAddr, audit.OutcomeSuccess, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Local config changes backed up",
+	})
+}
+
+// handleRestoreBackup recovers a backup into the config repository working copy.
+// Like -recover-backup, restored changes stay local until pushed to the manager.
+// POST /api/config/restore {"id": "stash@{0}" | "backup/<agent>/<timestamp>" | "latest"}
+func (s *Server) handleRestoreBackup(w http.ResponseWriter, r *http.Request) {
+
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	req.ID = strings.TrimSpace(req.ID)
+	if !isBackupID(req.ID) {
+		http.Error(w, `id must be "latest", a stash reference (stash@{N}) or a backup/ branch`, http.StatusBadRequest)
+		return
+	}
+
+	if err := s.backups.RecoverBackup(req.ID); err != nil {
+		s.audit.Record("config.restore", r.RemoteAddr, audit.OutcomeFailure, map[string]interface{}{"id": req.ID, "error": err.Error()})
+		http.Error(w, fmt.Sprintf("Failed to restore backup: %v", err), backupErrorStatus(err))
+		return
+	}
+	s.logger.Warn().Str("backup", req.ID).Str("remote", r.RemoteAddr).Msg("♻️ Config backup restored via API")
+	s.audit.Record("config.restore", r.RemoteAddr, audit.OutcomeSuccess, map[string]interface{}{"id": req.ID})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"id":      req.ID,
+		"message": "Backup restored to the local config repository. Push the config to keep it across the next git pull.",
+	})
+}
+
+// isBackupID reports whether id names something RecoverBackup can restore
+func isBackupID(id string) bool {
+	return id == "latest" ||
+		(strings.HasPrefix(id, "stash@{") && strings.HasSuffix(id, "}")) ||
+		strings.Contains(id, "backup/")
+}
+
+// parseBackupEntry converts a ListBackups line such as
+// "STASH (this agent): stash@{0}: On main: Agent-x-backup-20240101-120000"
+// into a ConfigBackup
+func parseBackupEntry(entry string) ConfigBackup {
+	backup := ConfigBackup{Description: entry}
+	kind, rest, found := strings.Cut(entry, ": ")
+	if !found {
+		return backup
+	}
+	backup.ThisAgent = strings.Contains(kind, "(this agent)")
+
+	switch {
+	case strings.HasPrefix(kind, "STASH"):
+		backup.Type = "stash"
+		id, desc, _ := strings.Cut(rest, ": ")
+		backup.ID = id
+		backup.Description = desc
+	case strings.HasPrefix(kind, "BRANCH"):
+		backup.Type = "branch"
+		backup.ID = strings.TrimSpace(strings.TrimPrefix(rest, "* "))
+		backup.Description = ""
+	}
+	return backup
+}
+
+// backupErrorStatus maps a backup or restore failure to an HTTP status
+func backupErrorStatus(err error) int {
+	if errors.Is(err, gitsync.ErrBusy) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
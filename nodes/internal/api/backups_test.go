@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/controlcenter/nodes/internal/config"
+)
+
+type fakeBackups struct {
+	entries   []string
+	backedUp  bool
+	recovered string
+}
+
+func (f *fakeBackups) ListBackups() ([]string, error) { return f.entries, nil }
+func (f *fakeBackups) BackupLocalChanges() error      { f.backedUp = true; return nil }
+func (f *fakeBackups) RecoverBackup(id string) error  { f.recovered = id; return nil }
+
+func TestParseBackupEntry(t *testing.T) {
+	stash := parseBackupEntry("STASH (this agent): stash@{1}: On main: Agent-a1-backup-20240101-120000")
+	if stash.Type != "stash" || stash.ID != "stash@{1}" || !stash.ThisAgent || stash.Description != "On main: Agent-a1-backup-20240101-120000" {
+		t.Errorf("unexpected stash: %+v", stash)
+	}
+	branch := parseBackupEntry("BRANCH: backup/other/20240101-120000")
+	if branch.Type != "branch" || branch.ID != "backup/other/20240101-120000" || branch.ThisAgent {
+		t.Errorf("unexpected branch: %+v", branch)
+	}
+}
+
+func TestBackupEndpoints(t *testing.T) {
+	cfg := &config.Config{APISettings: config.APISettings{AdminToken: "adm1n"}}
+	fake := &fakeBackups{entries: []string{"STASH (this agent): stash@{0}: On main: Agent-a1-backup"}}
+	s := &Server{config: cfg, logger: zerolog.Nop(), backups: fake}
+
+	rec := httptest.NewRecorder()
+	s.requireAdminToken(s.handleListBackups)(rec, httptest.NewRequest(http.MethodGet, "/api/config/backups", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("missing token status = %d, want 401", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/config/backups", nil)
+	req.Header.Set("Authorization", "Bearer adm1n")
+	s.requireAdminToken(s.handleListBackups)(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"id":"stash@{0}"`) {
+		t.Errorf("list status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleCreateBackup(rec, httptest.NewRequest(http.MethodPost, "/api/config/backup", nil))
+	if rec.Code != http.StatusOK || !fake.backedUp {
+		t.Errorf("backup status = %d, backedUp = %v", rec.Code, fake.backedUp)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleRestoreBackup(rec, httptest.NewRequest(http.MethodPost, "/api/config/restore", strings.NewReader(`{"id":"main"}`)))
+	if rec.Code != http.StatusBadRequest || fake.recovered != "" {
+		t.Errorf("invalid id status = %d, recovered = %q", rec.Code, fake.recovered)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleRestoreBackup(rec, httptest.NewRequest(http.MethodPost, "/api/config/restore", strings.NewReader(`{"id":"stash@{0}"}`)))
+	if rec.Code != http.StatusOK || fake.recovered != "stash@{0}" {
+		t.Errorf("restore status = %d, recovered = %q", rec.Code, fake.recovered)
+	}
+}
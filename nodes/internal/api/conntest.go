@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/your-org/controlcenter/nodes/internal/audit"
+	"github.com/your-org/controlcenter/nodes/internal/router"
+	"github.com/your-org/controlcenter/nodes/internal/workflow"
+)
+
+// registerConnectionTestHandlers wires the integration test endpoints. They
+// accept credentials and open outbound connections, so they require the admin token.
+func (s *Server) registerConnectionTestHandlers(rt *router.Router) error {
+	if s.config.GetAPISettings().AdminToken == "" {
+		s.logger.Info().Msg("Connection test endpoints disabled (set apiSettings.adminToken to enable)")
+		return nil
+	}
+	return rt.HandleFunc(http.MethodPost, "/api/test/", s.requireAdminToken(s.handleConnectionTest))
+}
+
+// handleConnectionTest checks connectivity and credentials for an integration
+// without transferring data. The body is the step-style config to test.
+// POST /api/test/s3    {"bucket", "region", "accessKeyId", "secretAccessKey"}
+// POST /api/test/http  {"url", "method", "headers", "bearerToken" | "username"/"password"}
+// POST /api/test/sftp  {"host", "port", "username", "password" | "privateKey" | "privateKeyPath", "hostKeyFingerprint"}
+// POST /api/test/smtp  {"host", "port", "tls", "username", "password"}
+func (s *Server) handleConnectionTest(w http.ResponseWriter, r *http.Request) {
+
+	kind := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/test/"), "/")
+	known := false
+	for _, t := range workflow.ConnectionTestTypes() {
+		known = known || t == kind
+	}
+	if !known {
+		http.Error(w, fmt.Sprintf("Unknown connection type %q (supported: %s)",
+			kind, strings.Join(workflow.ConnectionTestTypes(), ", ")), http.StatusNotFound)
+		return
+	}
+
+	var cfg map[string]interface{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&cfg); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	result := workflow.TestConnection(kind, cfg, s.logger)
+	outcome := audit.OutcomeSuccess
+	if !result.Success {
+		outcome = audit.OutcomeFailure
+	}
+	s.audit.Record("integration.test", r.RemoteAddr, outcome, map[string]interface{}{
+		"type":    kind,
+		"message": result.Message,
+	})
+	s.logger.Info().
+		Str("type", kind).
+		Bool("success", result.Success).
+		Str("message", result.Message).
+		Int64("durationMs", result.DurationMs).
+		Msg("🔌 Connection test")
+
+	// The test itself ran, so failures are reported in the body rather than
+	// as HTTP errors
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your-org/controlcenter/nodes/internal/router"
+)
+
+// maxCPUProfileSeconds caps CPU profiling requests
+const maxCPUProfileSeconds = 60
+
+// registerDebugHandlers wires the profiling and stats endpoints when enabled.
+// net/http/pprof is deliberately not imported: its init registers unguarded
+// /debug/pprof/ handlers on http.DefaultServeMux.
+func (s *Server) registerDebugHandlers(rt *router.Router) error {
+	settings := s.config.GetAPISettings()
+	if !settings.EnableDebugEndpoints {
+		return nil
+	}
+	if settings.DebugToken == "" {
+		s.logger.Warn().Msg("⚠️ Debug endpoints enabled but apiSettings.debugToken is empty, not registering them")
+		return nil
+	}
+
+	if err := errors.Join(
+		rt.HandleFunc(http.MethodGet, "/api/debug/stats", s.requireDebugToken(s.handleDebugStats)),
+		rt.HandleFunc(http.MethodGet, "/api/debug/pprof/", s.requireDebugToken(s.handlePprof)),
+	); err != nil {
+		return err
+	}
+	s.logger.Info().Msg("🐞 Debug endpoints enabled at /api/debug/stats and /api/debug/pprof/")
+	return nil
+}
+
+// requireDebugToken rejects requests without the configured debug token
+func (s *Server) requireDebugToken(next http.HandlerFunc) http.HandlerFunc {
+	return requireBearerToken(func() string { return s.config.GetAPISettings().DebugToken }, next)
+}
+
+// requireBearerToken rejects requests whose bearer token does not match the
+// current value of token. An empty token rejects every request.
+func requireBearerToken(token func() string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		expected := token()
+		provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if expected == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(expected)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// DebugStats is a lightweight snapshot of agent resource usage
+type DebugStats struct {
+	Goroutines      int    `json:"goroutines"`
+	HeapAllocBytes  uint64 `json:"heapAllocBytes"`
+	HeapInuseBytes  uint64 `json:"heapInuseBytes"`
+	HeapObjects     uint64 `json:"heapObjects"`
+	SysBytes        uint64 `json:"sysBytes"`
+	NumGC           uint32 `json:"numGC"`
+	ActiveWatchers  int    `json:"activeWatchers"`
+	LoadedWorkflows int    `json:"loadedWorkflows"`
+}
+
+// handleDebugStats returns goroutine, heap and component counts
+// GET /api/debug/stats
+func (s *Server) handleDebugStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := DebugStats{
+		Goroutines:     runtime.NumGoroutine(),
+		HeapAllocBytes: mem.HeapAlloc,
+		HeapInuseBytes: mem.HeapInuse,
+		HeapObjects:    mem.HeapObjects,
+		SysBytes:       mem.Sys,
+		NumGC:          mem.NumGC,
+	}
+	if s.fileWatcher != nil {
+		stats.ActiveWatchers = s.fileWatcher.WatcherCount()
+	}
+	if s.executor != nil {
+		stats.LoadedWorkflows = len(s.executor.GetWorkflows())
+	}
+
+	json.NewEncoder(w).Encode(stats)
+}
+
+// handlePprof serves runtime profiles in the same formats as net/http/pprof
+// GET /api/debug/pprof/                  - list available profiles
+// GET /api/debug/pprof/profile?seconds=N - CPU profile
+// GET /api/debug/pprof/<name>?debug=N    - goroutine, heap, allocs, block, mutex, threadcreate
+func (s *Server) handlePprof(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/debug/pprof/")
+
+	switch name {
+	case "":
+		w.Header().Set("Content-Type", "application/json")
+		profiles := make(map[string]int)
+		for _, p := range pprof.Profiles() {
+			profiles[p.Name()] = p.Count()
+		}
+		profiles["profile"] = 0
+		json.NewEncoder(w).Encode(map[string]interface{}{"profiles": profiles})
+	case "profile":
+		seconds, _ := strconv.Atoi(r.URL.Query().Get("seconds"))
+		if seconds <= 0 {
+			seconds = 30
+		}
+		if seconds > maxCPUProfileSeconds {
+			seconds = maxCPUProfileSeconds
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="profile"`)
+		if err := pprof.StartCPUProfile(w); err != nil {
+			http.Error(w, fmt.Sprintf("Could not enable CPU profiling: %v", err), http.StatusConflict)
+			return
+		}
+		select {
+		case <-time.After(time.Duration(seconds) * time.Second):
+		case <-r.Context().Done():
+		}
+		pprof.StopCPUProfile()
+	default:
+		profile := pprof.Lookup(name)
+		if profile == nil {
+			http.Error(w, fmt.Sprintf("Unknown profile: %s", name), http.StatusNotFound)
+			return
+		}
+		debug, _ := strconv.Atoi(r.URL.Query().Get("debug"))
+		if debug > 0 {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+		}
+		profile.WriteTo(w, debug)
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/controlcenter/nodes/internal/config"
+)
+
+func TestDebugEndpoints_RequireToken(t *testing.T) {
+	cfg := &config.Config{APISettings: config.APISettings{EnableDebugEndpoints: true, DebugToken: "s3cret"}}
+	s := &Server{config: cfg, logger: zerolog.Nop()}
+	handler := s.requireDebugToken(s.handleDebugStats)
+
+	for _, auth := range []string{"", "Bearer wrong"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/debug/stats", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		handler(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("auth %q: status = %d, want 401", auth, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/debug/stats", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	handler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var stats DebugStats
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Goroutines == 0 || stats.HeapAllocBytes == 0 {
+		t.Errorf("expected runtime stats, got %+v", stats)
+	}
+}
+
+func TestHandlePprof_GoroutineProfile(t *testing.T) {
+	s := &Server{logger: zerolog.Nop()}
+	rec := httptest.NewRecorder()
+	s.handlePprof(rec, httptest.NewRequest(http.MethodGet, "/api/debug/pprof/goroutine?debug=1", nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+		t.Errorf("status = %d, body length = %d", rec.Code, rec.Body.Len())
+	}
+
+	rec = httptest.NewRecorder()
+	s.handlePprof(rec, httptest.NewRequest(http.MethodGet, "/api/debug/pprof/nope", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown profile status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/your-org/controlcenter/nodes/internal/audit"
+	"github.com/your-org/controlcenter/nodes/internal/filewatcher"
+	"github.com/your-org/controlcenter/nodes/internal/workflow"
+)
+
+// DrainStatus reports progress while the agent stops taking new work
+type DrainStatus struct {
+	Draining         bool `json:"draining"`
+	Drained          bool `json:"drained"` // draining and nothing left in flight
+	ActiveExecutions int  `json:"activeExecutions"`
+	ActiveFiles      int  `json:"activeFiles"`
+}
+
+// SetDraining pauses (or resumes) workflow triggers and file watcher intake.
+// Either component may be nil.
+func SetDraining(executor *workflow.Executor, fw *filewatcher.Watcher, draining bool) {
+	if executor != nil {
+		executor.SetDraining(draining)
+	}
+	if fw != nil {
+		if draining {
+			fw.Pause()
+		} else {
+			fw.Resume()
+		}
+	}
+}
+
+// GetDrainStatus reports whether the agent is draining and what is still running
+func GetDrainStatus(executor *workflow.Executor, fw *filewatcher.Watcher) DrainStatus {
+	var status DrainStatus
+	if executor != nil {
+		status.Draining = executor.IsDraining()
+		status.ActiveExecutions = executor.ActiveExecutions()
+	}
+	if fw != nil {
+		status.Draining = status.Draining || fw.IsPaused()
+		status.ActiveFiles = fw.InFlight()
+	}
+	status.Drained = status.Draining && status.ActiveExecutions == 0 && status.ActiveFiles == 0
+	return status
+}
+
+// handleDrain reports or starts draining
+// GET /api/drain   - Current drain status
+// POST /api/drain  - Stop accepting new work; running jobs finish (admin token)
+func (s *Server) handleDrain(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		SetDraining(s.executor, s.fileWatcher, true)
+		s.logger.Warn().Str("remote", r.RemoteAddr).Msg("⏸️ Agent draining: new work paused, running jobs will finish")
+		s.audit.Record("agent.drain", r.RemoteAddr, audit.OutcomeSuccess, nil)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GetDrainStatus(s.executor, s.fileWatcher))
+}
+
+// handleUndrain resumes taking new work
+// POST /api/undrain (admin token)
+func (s *Server) handleUndrain(w http.ResponseWriter, r *http.Request) {
+	SetDraining(s.executor, s.fileWatcher, false)
+	s.logger.Info().Str("remote", r.RemoteAddr).Msg("▶️ Agent undrained: accepting new work")
+	s.audit.Record("agent.undrain", r.RemoteAddr, audit.OutcomeSuccess, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GetDrainStatus(s.executor, s.fileWatcher))
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/your-org/controlcenter/nodes/internal/config"
+)
+
+func TestCORS(t *testing.T) {
+	cfg := &config.Config{ManagerURL: "ws://manager.local:3000"}
+	handler := CORS(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		method     string
+		origin     string
+		preflight  bool
+		wantStatus int
+		wantOrigin string
+	}{
+		{"manager origin", http.MethodGet, "http://manager.local:3000", false, http.StatusOK, "http://manager.local:3000"},
+		{"other origin", http.MethodGet, "http://evil.example", false, http.StatusOK, ""},
+		{"no origin", http.MethodGet, "", false, http.StatusOK, ""},
+		{"preflight allowed", http.MethodOptions, "http://manager.local:3000", true, http.StatusNoContent, "http://manager.local:3000"},
+		{"preflight denied", http.MethodOptions, "http://evil.example", true, http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/api/files/delete", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if tt.preflight {
+			req.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.wantStatus)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+			t.Errorf("%s: Allow-Origin = %q, want %q", tt.name, got, tt.wantOrigin)
+		}
+	}
+}
+
+func TestCORS_ExplicitWildcard(t *testing.T) {
+	cfg := &config.Config{APISettings: config.APISettings{AllowedOrigins: []string{"*"}}}
+	handler := CORS(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/logs", nil)
+	req.Header.Set("Origin", "http://anywhere.example")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "http://anywhere.example" {
+		t.Errorf("Allow-Origin = %q, want request origin", got)
+	}
+}
+
+func TestGzip(t *testing.T) {
+	body := strings.Repeat(`{"level":"info","message":"hello"}`, 100)
+	handler := Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/logs", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("expected gzip-encoded response")
+	}
+	gr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(gr)
+	if string(got) != body {
+		t.Error("decompressed body does not match")
+	}
+
+	// Downloads are never compressed
+	req = httptest.NewRequest(http.MethodGet, "/api/files/download?path=/x", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != body {
+		t.Error("download endpoint should not be compressed")
+	}
+}
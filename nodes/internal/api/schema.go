@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/your-org/controlcenter/nodes/internal/config"
+	"github.com/your-org/controlcenter/nodes/internal/filewatcher"
+	"github.com/your-org/controlcenter/nodes/internal/workflow"
+)
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// BuildSchema returns a JSON Schema document describing workflows, triggers,
+// steps and filewatcher rules. Step config schemas are derived from the
+// registered step types, so new steps are documented automatically.
+func BuildSchema(stepTypes []workflow.StepTypeInfo) map[string]interface{} {
+	workflowSchema := schemaForType(reflect.TypeOf(config.Workflow{}))
+	workflowSchema["required"] = []string{"id", "name", "trigger", "steps"}
+	props := workflowSchema["properties"].(map[string]interface{})
+	props["trigger"] = map[string]interface{}{"$ref": "#/$defs/Trigger"}
+	props["steps"] = map[string]interface{}{
+		"type":  "array",
+		"items": map[string]interface{}{"$ref": "#/$defs/Step"},
+	}
+
+	triggerSchema := schemaForType(reflect.TypeOf(config.Trigger{}))
+	triggerSchema["required"] = []string{"type"}
+
+	return map[string]interface{}{
+		"$schema": schemaDialect,
+		"$defs": map[string]interface{}{
+			"Workflow": workflowSchema,
+			"Trigger":  triggerSchema,
+			"Step":     stepSchema(stepTypes),
+			"Rule":     schemaForType(reflect.TypeOf(filewatcher.Rule{})),
+		},
+	}
+}
+
+// stepSchema describes config.Step, constraining config per step type
+func stepSchema(stepTypes []workflow.StepTypeInfo) map[string]interface{} {
+	schema := schemaForType(reflect.TypeOf(config.Step{}))
+	schema["required"] = []string{"id", "type"}
+
+	names := make([]string, 0, len(stepTypes))
+	conditions := make([]interface{}, 0, len(stepTypes))
+	for _, st := range stepTypes {
+		names = append(names, st.Type)
+		conditions = append(conditions, map[string]interface{}{
+			"if": map[string]interface{}{
+				"properties": map[string]interface{}{"type": map[string]interface{}{"const": st.Type}},
+			},
+			"then": map[string]interface{}{
+				"properties": map[string]interface{}{"config": stepConfigSchema(st.Params)},
+			},
+		})
+	}
+
+	props := schema["properties"].(map[string]interface{})
+	props["type"] = map[string]interface{}{"type": "string", "enum": names}
+	schema["allOf"] = conditions
+	return schema
+}
+
+// stepConfigSchema converts step params into an object schema
+func stepConfigSchema(params []workflow.StepParam) map[string]interface{} {
+	props := make(map[string]interface{}, len(params))
+	required := []string{}
+	for _, p := range params {
+		prop := map[string]interface{}{"type": p.Type}
+		if p.Description != "" {
+			prop["description"] = p.Description
+		}
+		props[p.Name] = prop
+		if p.Required {
+			required = append(required, p.Name)
+		}
+	}
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+		"required":   required,
+	}
+}
+
+// schemaForType generates a JSON Schema for a Go type using its json tags
+func schemaForType(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaForType(t.Elem())}
+	case reflect.Map:
+		schema := map[string]interface{}{"type": "object"}
+		if t.Elem().Kind() != reflect.Interface {
+			schema["additionalProperties"] = schemaForType(t.Elem())
+		}
+		return schema
+	case reflect.Struct:
+		props := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := field.Name
+			if tag := field.Tag.Get("json"); tag != "" {
+				tagName := strings.Split(tag, ",")[0]
+				if tagName == "-" {
+					continue
+				}
+				if tagName != "" {
+					name = tagName
+				}
+			}
+			props[name] = schemaForType(field.Type)
+		}
+		return map[string]interface{}{"type": "object", "properties": props}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// handleSchema serves the JSON Schema for workflow and rule definitions
+func (s *Server) handleSchema(w http.ResponseWriter, r *http.Request) {
+
+	var stepTypes []workflow.StepTypeInfo
+	if s.executor != nil {
+		stepTypes = s.executor.StepTypes()
+	}
+
+	w.Header().Set("Content-Type", "application/schema+json")
+	json.NewEncoder(w).Encode(BuildSchema(stepTypes))
+}
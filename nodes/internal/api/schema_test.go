@@ -0,0 +1,48 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/controlcenter/nodes/internal/workflow"
+)
+
+func TestBuildSchema(t *testing.T) {
+	registry := workflow.NewStepRegistry(zerolog.Nop(), nil)
+	doc := BuildSchema(registry.Describe())
+
+	defs := doc["$defs"].(map[string]interface{})
+	for _, name := range []string{"Workflow", "Trigger", "Step", "Rule"} {
+		if _, ok := defs[name]; !ok {
+			t.Errorf("missing definition %s", name)
+		}
+	}
+
+	// Rule fields use their json names, including nested structs
+	rule := defs["Rule"].(map[string]interface{})["properties"].(map[string]interface{})
+	ops, ok := rule["operations"].(map[string]interface{})
+	if !ok {
+		t.Fatal("expected operations property on Rule")
+	}
+	if _, ok := ops["properties"].(map[string]interface{})["copyToDirs"]; !ok {
+		t.Error("expected copyToDirs in operations schema")
+	}
+
+	// Step config is constrained per registered type
+	step := defs["Step"].(map[string]interface{})
+	var copyConfig map[string]interface{}
+	for _, c := range step["allOf"].([]interface{}) {
+		cond := c.(map[string]interface{})
+		typeConst := cond["if"].(map[string]interface{})["properties"].(map[string]interface{})["type"].(map[string]interface{})["const"]
+		if typeConst == "copy-file" {
+			copyConfig = cond["then"].(map[string]interface{})["properties"].(map[string]interface{})["config"].(map[string]interface{})
+		}
+	}
+	if copyConfig == nil {
+		t.Fatal("expected schema for copy-file step config")
+	}
+	required := copyConfig["required"].([]string)
+	if len(required) != 2 || required[0] != "source" || required[1] != "destination" {
+		t.Errorf("copy-file required = %v, want [source destination]", required)
+	}
+}
@@ -0,0 +1,131 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Outcome values for audit events
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied"
+)
+
+// Event is a single audit record. Each record carries the hash of the
+// previous one, so deleting or editing a line breaks the chain.
+type Event struct {
+	Time     string                 `json:"time"`
+	Action   string                 `json:"action"`
+	Source   string                 `json:"source,omitempty"`
+	Outcome  string                 `json:"outcome"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+	PrevHash string                 `json:"prevHash"`
+	Hash     string                 `json:"hash"`
+}
+
+// Logger writes hash-chained audit events as JSON lines.
+// A nil *Logger is valid and discards all events.
+type Logger struct {
+	mu       sync.Mutex
+	w        io.Writer
+	lastHash string
+}
+
+// New creates an audit logger writing to w. lastHash continues the chain
+// from a previous run (see LastHash); pass "" to start a new chain.
+func New(w io.Writer, lastHash string) *Logger {
+	return &Logger{w: w, lastHash: lastHash}
+}
+
+// Record writes an audit event. source is the remote address or component
+// that initiated the action.
+func (l *Logger) Record(action, source, outcome string, details map[string]interface{}) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	event := Event{
+		Time:     time.Now().UTC().Format(time.RFC3339Nano),
+		Action:   action,
+		Source:   source,
+		Outcome:  outcome,
+		Details:  details,
+		PrevHash: l.lastHash,
+	}
+	event.Hash = event.computeHash()
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	if _, err := l.w.Write(append(data, '\n')); err != nil {
+		return
+	}
+	l.lastHash = event.Hash
+}
+
+// computeHash hashes the event with its Hash field cleared
+func (e Event) computeHash() string {
+	e.Hash = ""
+	data, _ := json.Marshal(e)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks that each event's hash is intact and links to the one before it.
+// It returns the 1-based line number of the first broken record, or 0 if the
+// chain is valid.
+func Verify(r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	line := 0
+	prev := ""
+	first := true
+	for scanner.Scan() {
+		line++
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return line, nil
+		}
+		// The first record may continue a chain from a rotated file
+		if (!first && event.PrevHash != prev) || event.computeHash() != event.Hash {
+			return line, nil
+		}
+		prev = event.Hash
+		first = false
+	}
+	return 0, scanner.Err()
+}
+
+// LastHash returns the hash of the last event in an existing audit file so a
+// restarted agent can continue the chain. Missing or unreadable files yield "".
+func LastHash(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	last := ""
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err == nil && event.Hash != "" {
+			last = event.Hash
+		}
+	}
+	return last
+}
@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndVerify(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, "")
+	l.Record("ssh.auth", "10.0.0.1:5000", OutcomeSuccess, map[string]interface{}{"user": "ops"})
+	l.Record("filebrowser.delete", "10.0.0.2:6000", OutcomeDenied, map[string]interface{}{"path": "/etc"})
+
+	if line, err := Verify(strings.NewReader(buf.String())); err != nil || line != 0 {
+		t.Fatalf("expected valid chain, got line %d err %v", line, err)
+	}
+
+	tampered := strings.Replace(buf.String(), "/etc", "/tmp", 1)
+	if line, _ := Verify(strings.NewReader(tampered)); line != 2 {
+		t.Errorf("expected tampering detected on line 2, got %d", line)
+	}
+
+	lines := strings.SplitN(buf.String(), "\n", 2)
+	if line, _ := Verify(strings.NewReader(lines[1])); line != 0 {
+		t.Errorf("chain starting mid-stream should verify, got line %d", line)
+	}
+}
+
+func TestNilLogger(t *testing.T) {
+	var l *Logger
+	l.Record("noop", "", OutcomeSuccess, nil)
+}
+
+func TestLastHashContinuesChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	New(f, "").Record("config.reload", "manager", OutcomeSuccess, nil)
+	f.Close()
+
+	last := LastHash(path)
+	if last == "" {
+		t.Fatal("expected last hash from existing file")
+	}
+
+	f, _ = os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	New(f, last).Record("loglevel.change", "api", OutcomeSuccess, nil)
+	f.Close()
+
+	data, _ := os.ReadFile(path)
+	if line, _ := Verify(bytes.NewReader(data)); line != 0 {
+		t.Errorf("continued chain should verify, broke at line %d", line)
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"os"
+)
+
+// WriteFileAtomic replaces path with data without ever leaving a partial
+// file behind: data goes to path+".tmp", is flushed to disk, and the temp
+// file is renamed over path. A crash or full disk mid-write leaves the old
+// file intact.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
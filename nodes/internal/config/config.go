@@ -2,8 +2,14 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"os/user"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/google/uuid"
@@ -14,7 +20,9 @@ type Config struct {
 
 	AgentID          string   `json:"agentId"`
 	ManagerURL       string   `json:"managerUrl"`
+	Environment      string   `json:"environment,omitempty"` // Selects the agent-config.<env>.json overlay (AGENT_ENVIRONMENT overrides)
 	RegistrationToken string   `json:"registrationToken,omitempty"`
+	ReRegistrationToken string `json:"reRegistrationToken,omitempty"` // Reusable token to re-enroll with if the manager no longer knows this agent (local only)
 	Registered       bool     `json:"registered"`
 	SSHPrivateKeyPath string   `json:"sshPrivateKeyPath"`
 	SSHPublicKeyPath  string   `json:"sshPublicKeyPath"`
@@ -34,6 +42,45 @@ type Config struct {
 	// File Browser Settings
 	FileBrowserSettings FileBrowserSettings `json:"fileBrowserSettings,omitempty"`
 
+	// Transfer Settings (bandwidth limits for copies and uploads)
+	TransferSettings TransferSettings `json:"transferSettings,omitempty"`
+
+	// Command Policy (local only - never loaded from git)
+	CommandPolicy CommandPolicy `json:"commandPolicy,omitempty"`
+
+	// Local HTTP API settings (local only - never loaded from git)
+	APISettings APISettings `json:"apiSettings,omitempty"`
+
+	// Manager connection tuning (local only - never loaded from git)
+	ConnectionSettings ConnectionSettings `json:"connectionSettings,omitempty"`
+
+	// Addresses the agent API (:8088) and SSH server listen on: an IP, a
+	// hostname such as localhost, or an interface name such as eth1.
+	// Empty binds all interfaces. (local only - never loaded from git)
+	APIBindAddr string `json:"apiBindAddr,omitempty"`
+	SSHBindAddr string `json:"sshBindAddr,omitempty"`
+
+	// Exec and sftp requests one SSH connection may make (0 = default 100,
+	// -1 = unlimited). (local only - never loaded from git)
+	SSHMaxCommandsPerSession int `json:"sshMaxCommandsPerSession,omitempty"`
+
+	// Workflow variables available to step templates as {{ .vars.name }}.
+	// Values of the form "secret:<name>" or "env:<NAME>" are resolved on the agent.
+	Variables map[string]string `json:"variables,omitempty"`
+
+	// Local file holding named secrets for "secret:" variables (local only)
+	SecretsFilePath string `json:"secretsFilePath,omitempty"`
+
+	// How config changes pulled from git are applied: apply, destructive or all (local only)
+	ConfigChangePolicy string `json:"configChangePolicy,omitempty"`
+
+	// Outbound proxy for the manager connection, S3 and HTTP steps (local only)
+	ProxySettings ProxySettings `json:"proxySettings,omitempty"`
+
+	// Labels reported to the manager for grouping and targeting agents,
+	// e.g. {"region": "eu", "role": "ingest"} (local only)
+	Tags map[string]string `json:"tags,omitempty"`
+
 	Extra            map[string]interface{} `json:"extra,omitempty"`
 }
 
@@ -43,12 +90,42 @@ type LogSettings struct {
 	MaxAgeDays   int    `json:"maxAgeDays"`   // Max days to retain logs (default: 30)
 	MaxBackups   int    `json:"maxBackups"`   // Max number of old log files (default: 5)
 	Compress     bool   `json:"compress"`     // Compress rotated logs (default: true)
+	WorkflowEvents string `json:"workflowEvents,omitempty"` // Lifecycle and command-output events sent to manager: off, failures, all (default: off)
+	ConsoleFormat  string `json:"consoleFormat,omitempty"`  // Stdout format: json or console (default: console)
+	FileFormat     string `json:"fileFormat,omitempty"`     // agent.log format: json or console (default: json; the manager log viewer only reads json)
+}
+
+// ConsoleLogFormat returns the stdout log format, defaulting to console
+func (ls LogSettings) ConsoleLogFormat() string {
+	if ls.ConsoleFormat == "" {
+		return "console"
+	}
+	return ls.ConsoleFormat
+}
+
+// FileLogFormat returns the log file format, defaulting to json
+func (ls LogSettings) FileLogFormat() string {
+	if ls.FileFormat == "" {
+		return "json"
+	}
+	return ls.FileFormat
+}
+
+type TransferSettings struct {
+	MaxBytesPerSecond int64 `json:"maxBytesPerSecond,omitempty"` // Combined limit for file copies and uploads (0 = unlimited)
 }
 
 type FileWatcherSettings struct {
 	ScanDir       string `json:"scanDir"`       // Root directory for pattern-based watching
 	ScanSubDir    bool   `json:"scanSubDir"`    // Whether to recursively watch matched directories
 	MaxConcurrent int    `json:"maxConcurrent"` // Max concurrent file processing workers (default: 3)
+	LedgerTTLHours int   `json:"ledgerTtlHours,omitempty"` // How long processed files are remembered across restarts (default: 168)
+	QueueSize     int    `json:"queueSize,omitempty"` // Work queue capacity (default: 2 x maxConcurrent)
+	BackpressurePolicy string `json:"backpressurePolicy,omitempty"` // When the queue is full: "block" (default), "drop-oldest" or "reject-with-alert"
+	MaxWatches    int    `json:"maxWatches,omitempty"`    // Cap on directory watches across rules (default: 90% of the Linux inotify limit)
+	WatchOverflow string `json:"watchOverflow,omitempty"` // Directories past the cap: "skip" (default, reported) or "poll"
+	PollIntervalSecs int `json:"pollIntervalSecs,omitempty"` // How often overflow directories are polled (default: 30)
+	FileEventLogLevel string `json:"fileEventLogLevel,omitempty"` // Level matched-file events are logged at for rules without logLevel (default: info)
 }
 
 type FileBrowserSettings struct {
@@ -56,6 +133,119 @@ type FileBrowserSettings struct {
 	AllowedPaths   []string `json:"allowedPaths"`   // Whitelist of allowed base paths (default: agent data dir only)
 	MaxUploadSize  int64    `json:"maxUploadSize"`  // Max upload file size in bytes (default: 100MB)
 	MaxListItems   int      `json:"maxListItems"`   // Max items to list per directory (default: 1000)
+	FilePerm       string   `json:"filePerm,omitempty"` // Octal mode for uploaded files, e.g. "0660" (default: 0644)
+	DirPerm        string   `json:"dirPerm,omitempty"`  // Octal mode for created directories, e.g. "0770" (default: 0755)
+	DeleteMode     string   `json:"deleteMode,omitempty"`     // "delete" (default) or "trash" to move deleted items to trashDir
+	TrashDir       string   `json:"trashDir,omitempty"`       // Where trash mode moves items (default: ~/.controlcenter-agent/trash)
+	TrashRetentionHours int `json:"trashRetentionHours,omitempty"` // Trashed items older than this are purged (default: 168)
+	MaxDeleteItems int      `json:"maxDeleteItems,omitempty"` // Max entries one recursive delete may remove (default: 10000)
+	DeleteRetries  int      `json:"deleteRetries,omitempty"`  // Extra attempts when a delete fails, e.g. on a locked file (default: 2)
+}
+
+// ParseFileMode parses an octal permission string such as "0660" or "755".
+// An empty string returns def.
+func ParseFileMode(s string, def os.FileMode) (os.FileMode, error) {
+	if s == "" {
+		return def, nil
+	}
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0777 {
+		return def, fmt.Errorf("invalid permission %q: expected octal like 0644", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// MkdirAllPerm is os.MkdirAll, except that the directories it creates get
+// exactly perm instead of perm masked by the process umask. Directories that
+// already exist are left alone.
+func MkdirAllPerm(path string, perm os.FileMode) error {
+	var missing []string
+	for dir := filepath.Clean(path); ; {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			break
+		}
+		missing = append(missing, dir)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	if err := os.MkdirAll(path, perm); err != nil {
+		return err
+	}
+	for _, dir := range missing {
+		if err := os.Chmod(dir, perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResolveOwnership converts an owner and group (name or numeric id) to a
+// uid/gid pair for os.Chown. Empty values resolve to -1 (leave unchanged).
+func ResolveOwnership(owner, group string) (uid, gid int, err error) {
+	uid, gid = -1, -1
+	if owner != "" {
+		if uid, err = strconv.Atoi(owner); err != nil {
+			u, lookupErr := user.Lookup(owner)
+			if lookupErr != nil {
+				return -1, -1, fmt.Errorf("unknown owner %q: %w", owner, lookupErr)
+			}
+			if uid, err = strconv.Atoi(u.Uid); err != nil {
+				return -1, -1, fmt.Errorf("owner %q has non-numeric uid %q", owner, u.Uid)
+			}
+		}
+	}
+	if group != "" {
+		if gid, err = strconv.Atoi(group); err != nil {
+			g, lookupErr := user.LookupGroup(group)
+			if lookupErr != nil {
+				return -1, -1, fmt.Errorf("unknown group %q: %w", group, lookupErr)
+			}
+			if gid, err = strconv.Atoi(g.Gid); err != nil {
+				return -1, -1, fmt.Errorf("group %q has non-numeric gid %q", group, g.Gid)
+			}
+		}
+	}
+	return uid, gid, nil
+}
+
+// CommandPolicy restricts what the run-command step may execute. It is a
+// machine-local setting so a compromised config repo cannot relax it.
+type CommandPolicy struct {
+	AllowedCommands         []string `json:"allowedCommands,omitempty"`         // Binary names or absolute paths run-command may invoke (empty = unrestricted)
+	DenyShellMetacharacters bool     `json:"denyShellMetacharacters,omitempty"` // Reject commands whose template substitution introduced ;|&$ etc.
+	MaxOutputBytes          int64    `json:"maxOutputBytes,omitempty"`          // Ceiling on run-command output kept in workflow context (0 = 1MB default, steps may lower it)
+}
+
+// APISettings controls the agent's local HTTP API on :8088
+type APISettings struct {
+	AllowedOrigins       []string `json:"allowedOrigins,omitempty"`       // CORS origins allowed to call the API ("*" = any; default: manager origin)
+	EnableDebugEndpoints bool     `json:"enableDebugEndpoints,omitempty"` // Serve /api/debug/* profiling endpoints (requires debugToken)
+	DebugToken           string   `json:"debugToken,omitempty"`           // Bearer token required by /api/debug/* endpoints
+	AdminToken           string   `json:"adminToken,omitempty"`           // Bearer token required by /api/config/backup* and /api/config/restore
+}
+
+// ConnectionSettings tunes the WebSocket link and git config sync with the manager
+type ConnectionSettings struct {
+	PingIntervalSeconds      int `json:"pingIntervalSeconds,omitempty"`      // Heartbeat interval (default: 30)
+	ReconnectIntervalSeconds int `json:"reconnectIntervalSeconds,omitempty"` // Delay between connection attempts (default: 5)
+	MaxMissedHeartbeats      int `json:"maxMissedHeartbeats,omitempty"`      // Reconnect after this many unacknowledged heartbeats (default: 3, -1 = never)
+	GitRetryAttempts         int `json:"gitRetryAttempts,omitempty"`         // Tries for git clone/fetch/push on network errors (default: 4, 1 = no retry)
+	GitRetryDelaySeconds     int `json:"gitRetryDelaySeconds,omitempty"`     // First retry delay, doubled each attempt (default: 2)
+	GitRetryMaxDelaySeconds  int `json:"gitRetryMaxDelaySeconds,omitempty"`  // Cap on the retry delay (default: 30)
+	GitInsecureSSL           bool   `json:"gitInsecureSSL,omitempty"`        // Skip TLS certificate verification for HTTPS git remotes (testing only)
+	GitCAPath                string `json:"gitCaPath,omitempty"`             // PEM CA bundle to verify an HTTPS git remote signed by a private CA
+	MaxConfigStalenessSeconds int  `json:"maxConfigStalenessSeconds,omitempty"` // Pull config when the last successful git sync is older than this, alert if that keeps failing (0 = off)
+}
+
+// ProxySettings routes outbound connections through a proxy. Empty fields
+// fall back to the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+type ProxySettings struct {
+	HTTPProxy  string `json:"httpProxy,omitempty"`  // e.g. "http://proxy.corp:3128" or "socks5://proxy.corp:1080"
+	HTTPSProxy string `json:"httpsProxy,omitempty"` // Proxy for https/wss URLs (default: httpProxy)
+	NoProxy    string `json:"noProxy,omitempty"`    // Comma-separated hosts, .domains, IPs or CIDRs reached directly
 }
 
 type Workflow struct {
@@ -65,6 +255,8 @@ type Workflow struct {
 	Enabled     bool        `json:"enabled"`
 	Trigger     Trigger     `json:"trigger"`
 	Steps       []Step      `json:"steps"`
+	Variables   map[string]string `json:"variables,omitempty"` // Overrides global variables of the same name
+	Finally     []string    `json:"finally,omitempty"` // Steps run after the main chain whether it completed or failed
 }
 
 type Trigger struct {
@@ -80,6 +272,16 @@ type Step struct {
 	Config  map[string]interface{} `json:"config"`
 	Next    []string               `json:"next,omitempty"`
 	OnError []string               `json:"onError,omitempty"`
+
+	// When is a template evaluated against the workflow context before the
+	// step runs, e.g. "{{ eq .exitCode 0 }}". If it renders false (or empty,
+	// "0", "no") the step is skipped and its Next steps run as usual.
+	When string `json:"when,omitempty"`
+
+	// Retries re-runs the step after transient failures (network errors,
+	// timeouts, 5xx responses). Validation and permanent errors never retry.
+	Retries           int `json:"retries,omitempty"`
+	RetryDelaySeconds int `json:"retryDelaySeconds,omitempty"` // default 5, doubled per attempt up to 5 minutes
 }
 
 func Load(path string) (*Config, error) {
@@ -90,18 +292,19 @@ func Load(path string) (*Config, error) {
 		ConfigRepoPath:   filepath.Join(getDataDir(), "config-repo"),
 		StateFilePath:    filepath.Join(getDataDir(), "state.json"),
 		LogFilePath:      filepath.Join(getDataDir(), "agent.log"),
+		SecretsFilePath:  filepath.Join(getDataDir(), "secrets.json"),
 		SSHServerPort:    2222,
 	}
 
 	if path != "" {
-		data, err := os.ReadFile(path)
+		data, err := readConfigFile(path)
 		if err != nil {
 			if !os.IsNotExist(err) {
 				return nil, err
 			}
 		} else {
 			if err := json.Unmarshal(data, cfg); err != nil {
-				return nil, err
+				return nil, &CorruptError{Path: path, Err: err}
 			}
 		}
 	}
@@ -114,6 +317,69 @@ func Load(path string) (*Config, error) {
 	return cfg, nil
 }
 
+// readConfigFile reads the config at path and, when an environment is set
+// (AGENT_ENVIRONMENT or the file's "environment" key), deep-merges the overlay
+// file next to it: agent-config.json + "prod" -> agent-config.prod.json.
+// Objects merge key by key, arrays and scalars in the overlay replace the base
+// value, and a null in the overlay removes the key. A missing overlay is ignored.
+func readConfigFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var base map[string]interface{}
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, &CorruptError{Path: path, Err: err}
+	}
+	env := os.Getenv("AGENT_ENVIRONMENT")
+	if env == "" {
+		env, _ = base["environment"].(string)
+	}
+	if env == "" {
+		return data, nil
+	}
+
+	var overlay map[string]interface{}
+	overlayPath := OverlayPath(path, env)
+	overlayData, err := os.ReadFile(overlayPath)
+	if err == nil {
+		if err := json.Unmarshal(overlayData, &overlay); err != nil {
+			return nil, fmt.Errorf("failed to parse overlay %s: %w", overlayPath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s overlay: %w", env, err)
+	}
+
+	merged := mergeOverlay(base, overlay)
+	merged["environment"] = env
+	return json.Marshal(merged)
+}
+
+// OverlayPath returns the overlay file for env next to the base config
+func OverlayPath(path, env string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + env + ext
+}
+
+// mergeOverlay deep-merges overlay into base (see readConfigFile for semantics)
+func mergeOverlay(base, overlay map[string]interface{}) map[string]interface{} {
+	for key, value := range overlay {
+		if value == nil {
+			delete(base, key)
+			continue
+		}
+		overlayMap, isMap := value.(map[string]interface{})
+		baseMap, baseIsMap := base[key].(map[string]interface{})
+		if isMap && baseIsMap {
+			base[key] = mergeOverlay(baseMap, overlayMap)
+			continue
+		}
+		base[key] = value
+	}
+	return base
+}
+
 func (c *Config) Save(path string) error {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -123,31 +389,101 @@ func (c *Config) Save(path string) error {
 	toSave := struct {
 		AgentID           string `json:"agentId"`
 		ManagerURL        string `json:"managerUrl"`
+		Environment       string `json:"environment,omitempty"`
 		RegistrationToken string `json:"registrationToken,omitempty"`
+		ReRegistrationToken string `json:"reRegistrationToken,omitempty"`
 		Registered        bool   `json:"registered"`
 		SSHPrivateKeyPath string `json:"sshPrivateKeyPath"`
 		SSHPublicKeyPath  string `json:"sshPublicKeyPath"`
 		ConfigRepoPath    string `json:"configRepoPath"`
 		StateFilePath     string `json:"stateFilePath"`
 		LogFilePath       string `json:"logFilePath"`
+		CommandPolicy     CommandPolicy `json:"commandPolicy,omitempty"`
+		APISettings       APISettings   `json:"apiSettings,omitempty"`
+		ConnectionSettings ConnectionSettings `json:"connectionSettings,omitempty"`
+		APIBindAddr       string `json:"apiBindAddr,omitempty"`
+		SSHBindAddr       string `json:"sshBindAddr,omitempty"`
+		SSHMaxCommandsPerSession int `json:"sshMaxCommandsPerSession,omitempty"`
+		SecretsFilePath   string `json:"secretsFilePath,omitempty"`
+		ConfigChangePolicy string `json:"configChangePolicy,omitempty"`
+		ProxySettings     ProxySettings `json:"proxySettings,omitempty"`
+		Tags              map[string]string `json:"tags,omitempty"`
 	}{
 		AgentID:           c.AgentID,
 		ManagerURL:        c.ManagerURL,
+		Environment:       c.Environment,
 		RegistrationToken: c.RegistrationToken,
+		ReRegistrationToken: c.ReRegistrationToken,
 		Registered:        c.Registered,
 		SSHPrivateKeyPath: c.SSHPrivateKeyPath,
 		SSHPublicKeyPath:  c.SSHPublicKeyPath,
 		ConfigRepoPath:    c.ConfigRepoPath,
 		StateFilePath:     c.StateFilePath,
 		LogFilePath:       c.LogFilePath,
+		CommandPolicy:     c.CommandPolicy,
+		APISettings:       c.APISettings,
+		ConnectionSettings: c.ConnectionSettings,
+		APIBindAddr:       c.APIBindAddr,
+		SSHBindAddr:       c.SSHBindAddr,
+		SSHMaxCommandsPerSession: c.SSHMaxCommandsPerSession,
+		SecretsFilePath:   c.SecretsFilePath,
+		ConfigChangePolicy: c.ConfigChangePolicy,
+		ProxySettings:     c.ProxySettings,
+		Tags:              c.Tags,
 	}
 
 	data, err := json.MarshalIndent(toSave, "", "  ")
 	if err != nil {
 		return err
 	}
+	if c.Environment != "" {
+		if data, err = keepBaseValues(path, c.Environment, data); err != nil {
+			return err
+		}
+	}
+
+	if err := WriteFileAtomic(path, data, 0600); err != nil {
+		return err
+	}
+	// Keep the last known good config for Recover
+	return WriteFileAtomic(path+".bak", data, 0600)
+}
+
+// keepBaseValues stops Save from writing overlay values into the base file:
+// top-level keys set by the environment overlay keep whatever the base file
+// had for them (or are left out if it had nothing)
+func keepBaseValues(path, env string, data []byte) ([]byte, error) {
+	overlayData, err := os.ReadFile(OverlayPath(path, env))
+	if err != nil {
+		return data, nil
+	}
+	var overlay, out map[string]interface{}
+	if json.Unmarshal(overlayData, &overlay) != nil {
+		return data, nil
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	base := map[string]interface{}{}
+	if baseData, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(baseData, &base)
+	}
 
-	return os.WriteFile(path, data, 0600)
+	// An environment chosen by AGENT_ENVIRONMENT is not persisted either
+	keys := overlay
+	if os.Getenv("AGENT_ENVIRONMENT") != "" {
+		keys["environment"] = env
+	} else {
+		delete(keys, "environment")
+	}
+	for key := range keys {
+		if original, ok := base[key]; ok {
+			out[key] = original
+		} else {
+			delete(out, key)
+		}
+	}
+	return json.MarshalIndent(out, "", "  ")
 }
 
 func (c *Config) Reload(path string) error {
@@ -155,7 +491,7 @@ func (c *Config) Reload(path string) error {
 	defer c.mu.Unlock()
 
 	// Read the file directly instead of using Load to avoid mutex issues
-	data, err := os.ReadFile(path)
+	data, err := readConfigFile(path)
 	if err != nil {
 		return err
 	}
@@ -169,7 +505,9 @@ func (c *Config) Reload(path string) error {
 	// Copy only the fields, not the mutex
 	c.AgentID = tempCfg.AgentID
 	c.ManagerURL = tempCfg.ManagerURL
+	c.Environment = tempCfg.Environment
 	c.RegistrationToken = tempCfg.RegistrationToken
+	c.ReRegistrationToken = tempCfg.ReRegistrationToken
 	c.Registered = tempCfg.Registered
 	c.SSHPrivateKeyPath = tempCfg.SSHPrivateKeyPath
 	c.SSHPublicKeyPath = tempCfg.SSHPublicKeyPath
@@ -182,17 +520,287 @@ func (c *Config) Reload(path string) error {
 	c.FileWatcherSettings = tempCfg.FileWatcherSettings
 	c.LogSettings = tempCfg.LogSettings
 	c.FileBrowserSettings = tempCfg.FileBrowserSettings
+	c.TransferSettings = tempCfg.TransferSettings
+	c.CommandPolicy = tempCfg.CommandPolicy
+	c.APISettings = tempCfg.APISettings
+	c.ConnectionSettings = tempCfg.ConnectionSettings
+	c.APIBindAddr = tempCfg.APIBindAddr
+	c.SSHBindAddr = tempCfg.SSHBindAddr
+	c.SSHMaxCommandsPerSession = tempCfg.SSHMaxCommandsPerSession
+	c.Variables = tempCfg.Variables
+	c.ConfigChangePolicy = tempCfg.ConfigChangePolicy
+	c.ProxySettings = tempCfg.ProxySettings
+	c.Tags = tempCfg.Tags
+	if tempCfg.SecretsFilePath != "" {
+		c.SecretsFilePath = tempCfg.SecretsFilePath
+	}
 	c.Extra = tempCfg.Extra
 	
 	return nil
 }
 
+// GetLogSettings returns the agent's logging settings
+func (c *Config) GetLogSettings() LogSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.LogSettings
+}
+
 func (c *Config) GetFileBrowserSettings() FileBrowserSettings {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	return c.FileBrowserSettings
 }
 
+func (c *Config) GetTransferSettings() TransferSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.TransferSettings
+}
+
+// SetWorkflowEnabled enables or disables the workflow with the given ID and
+// reports whether it exists
+func (c *Config) SetWorkflowEnabled(id string, enabled bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.Workflows {
+		if c.Workflows[i].ID == id {
+			c.Workflows[i].Enabled = enabled
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveWorkflow drops the workflow with the given ID and reports whether it existed
+func (c *Config) RemoveWorkflow(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	kept := make([]Workflow, 0, len(c.Workflows))
+	for _, w := range c.Workflows {
+		if w.ID != id {
+			kept = append(kept, w)
+		}
+	}
+	removed := len(kept) < len(c.Workflows)
+	c.Workflows = kept
+	return removed
+}
+
+// GetConfigChangePolicy returns how config pulled from git is applied
+func (c *Config) GetConfigChangePolicy() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.ConfigChangePolicy == "" {
+		return ChangePolicyApply
+	}
+	return c.ConfigChangePolicy
+}
+
+// GetProxySettings returns the outbound proxy settings
+func (c *Config) GetProxySettings() ProxySettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ProxySettings
+}
+
+// GetTags returns a copy of the agent's tags
+func (c *Config) GetTags() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	tags := make(map[string]string, len(c.Tags))
+	for k, v := range c.Tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+// GetVariables returns a copy of the global workflow variables
+func (c *Config) GetVariables() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	vars := make(map[string]string, len(c.Variables))
+	for k, v := range c.Variables {
+		vars[k] = v
+	}
+	return vars
+}
+
+func (c *Config) GetCommandPolicy() CommandPolicy {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.CommandPolicy
+}
+
+// secretKeyMarkers identify config keys whose values must not leave the agent
+var secretKeyMarkers = []string{"password", "secret", "token", "apikey", "accesskey", "privatekey", "credential", "authorization", "cookie"}
+
+// RedactedMap returns the config as a generic map with secret values replaced
+// by "[REDACTED]". Keys ending in "path" (e.g. sshPrivateKeyPath) are kept.
+// Every value of a "headers" map is masked, whatever the header is called.
+func (c *Config) RedactedMap() (map[string]interface{}, error) {
+	c.mu.RLock()
+	data, err := json.Marshal(c)
+	c.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	redactSecrets(m)
+	return m, nil
+}
+
+// redactSecrets walks v in place and masks values under secret-looking keys
+func redactSecrets(v interface{}) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if isSecretKey(k) {
+				if s, ok := child.(string); ok && s == "" {
+					continue
+				}
+				val[k] = "[REDACTED]"
+				continue
+			}
+			if headers, ok := child.(map[string]interface{}); ok && strings.EqualFold(k, "headers") {
+				for name, value := range headers {
+					if s, ok := value.(string); !ok || s != "" {
+						headers[name] = "[REDACTED]"
+					}
+				}
+				continue
+			}
+			if s, ok := child.(string); ok && strings.Contains(s, "://") {
+				// Mask passwords embedded in URLs such as proxy settings
+				if u, err := url.Parse(s); err == nil && u.User != nil {
+					if _, hasPassword := u.User.Password(); hasPassword {
+						val[k] = u.Redacted()
+					}
+				}
+				continue
+			}
+			redactSecrets(child)
+		}
+	case []interface{}:
+		for _, child := range val {
+			redactSecrets(child)
+		}
+	}
+}
+
+func isSecretKey(key string) bool {
+	k := strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+	if strings.HasSuffix(k, "path") {
+		return false
+	}
+	for _, marker := range secretKeyMarkers {
+		if strings.Contains(k, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAllowedOrigins returns the CORS origins allowed to call the local API.
+// Without an explicit list, only the manager's origin is allowed.
+func (c *Config) GetAllowedOrigins() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.APISettings.AllowedOrigins) > 0 {
+		return append([]string(nil), c.APISettings.AllowedOrigins...)
+	}
+	if origin := originFromURL(c.ManagerURL); origin != "" {
+		return []string{origin}
+	}
+	return nil
+}
+
+// GetConnectionSettings returns the manager connection tuning
+func (c *Config) GetConnectionSettings() ConnectionSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ConnectionSettings
+}
+
+// GetAPISettings returns the local HTTP API settings
+func (c *Config) GetAPISettings() APISettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.APISettings
+}
+
+// APIListenAddr returns the host:port the agent API listens on
+func (c *Config) APIListenAddr(port int) (string, error) {
+	c.mu.RLock()
+	bind := c.APIBindAddr
+	c.mu.RUnlock()
+	return ListenAddr(bind, port)
+}
+
+// SSHListenAddr returns the host:port the SSH server listens on
+func (c *Config) SSHListenAddr() (string, error) {
+	c.mu.RLock()
+	bind, port := c.SSHBindAddr, c.SSHServerPort
+	c.mu.RUnlock()
+	return ListenAddr(bind, port)
+}
+
+// ListenAddr joins a bind address and port. An interface name is resolved
+// to its first IPv4 address (or IPv6 if it has none); IPs and hostnames are
+// used as given, and an empty bind listens on all interfaces.
+func ListenAddr(bind string, port int) (string, error) {
+	bind = strings.TrimSpace(bind)
+	if bind == "" || net.ParseIP(strings.Trim(bind, "[]")) != nil {
+		return net.JoinHostPort(strings.Trim(bind, "[]"), strconv.Itoa(port)), nil
+	}
+	iface, err := net.InterfaceByName(bind)
+	if err != nil {
+		// Not an interface; let the listener resolve it as a hostname
+		return net.JoinHostPort(bind, strconv.Itoa(port)), nil
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", fmt.Errorf("failed to read addresses of interface %s: %w", bind, err)
+	}
+	var fallback net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return net.JoinHostPort(ipNet.IP.String(), strconv.Itoa(port)), nil
+		}
+		if fallback == nil {
+			fallback = ipNet.IP
+		}
+	}
+	if fallback != nil {
+		return net.JoinHostPort(fallback.String(), strconv.Itoa(port)), nil
+	}
+	return "", fmt.Errorf("interface %s has no usable address", bind)
+}
+
+// originFromURL converts a manager URL (http/https/ws/wss) to a browser origin
+func originFromURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	scheme := u.Scheme
+	switch scheme {
+	case "ws":
+		scheme = "http"
+	case "wss":
+		scheme = "https"
+	}
+	return scheme + "://" + u.Host
+}
+
 func getDataDir() string {
 	dir := os.Getenv("AGENT_DATA_DIR")
 	if dir == "" {
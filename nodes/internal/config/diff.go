@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Change policies for config pulled from git (ConfigChangePolicy)
+const (
+	ChangePolicyApply       = "apply"       // apply every change (default)
+	ChangePolicyDestructive = "destructive" // hold changes that remove workflows or rules for approval
+	ChangePolicyAll         = "all"         // hold every change for approval
+)
+
+// ConfigDiff summarizes what applying a managed config from git would change
+type ConfigDiff struct {
+	WorkflowsAdded   []string `json:"workflowsAdded,omitempty"`
+	WorkflowsRemoved []string `json:"workflowsRemoved,omitempty"`
+	WorkflowsChanged []string `json:"workflowsChanged,omitempty"`
+	RulesAdded       []string `json:"rulesAdded,omitempty"`
+	RulesRemoved     []string `json:"rulesRemoved,omitempty"`
+	RulesChanged     []string `json:"rulesChanged,omitempty"`
+	SettingsChanged  []string `json:"settingsChanged,omitempty"` // top-level keys such as fileWatcherSettings
+}
+
+// Empty reports whether applying the config would change nothing
+func (d ConfigDiff) Empty() bool {
+	return len(d.WorkflowsAdded)+len(d.WorkflowsRemoved)+len(d.WorkflowsChanged)+
+		len(d.RulesAdded)+len(d.RulesRemoved)+len(d.RulesChanged)+len(d.SettingsChanged) == 0
+}
+
+// Destructive reports whether the change removes workflows or file watcher rules
+func (d ConfigDiff) Destructive() bool {
+	return len(d.WorkflowsRemoved) > 0 || len(d.RulesRemoved) > 0
+}
+
+// NeedsApproval reports whether policy requires approval before applying d
+func (d ConfigDiff) NeedsApproval(policy string) bool {
+	switch policy {
+	case ChangePolicyAll:
+		return !d.Empty()
+	case ChangePolicyDestructive:
+		return d.Destructive()
+	}
+	return false
+}
+
+// String renders a one-line summary for logs
+func (d ConfigDiff) String() string {
+	if d.Empty() {
+		return "no changes"
+	}
+	var parts []string
+	add := func(label string, ids []string) {
+		if len(ids) > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %s", label, strings.Join(ids, ", ")))
+		}
+	}
+	add("workflows added", d.WorkflowsAdded)
+	add("workflows removed", d.WorkflowsRemoved)
+	add("workflows changed", d.WorkflowsChanged)
+	add("rules added", d.RulesAdded)
+	add("rules removed", d.RulesRemoved)
+	add("rules changed", d.RulesChanged)
+	add("settings changed", d.SettingsChanged)
+	return strings.Join(parts, "; ")
+}
+
+// DiffManaged compares the current managed config with one loaded from git.
+// Both are generic JSON maps and should be normalized the same way (decoded
+// through the same types) so defaults do not show up as changes. Only keys
+// present in incoming are compared, since sections missing from the git config
+// are left untouched when it is applied. Workflows and fileWatcherRules are
+// compared item by item using their "id".
+func DiffManaged(current, incoming map[string]interface{}) ConfigDiff {
+	var d ConfigDiff
+	for key, next := range incoming {
+		switch key {
+		case "workflows":
+			d.WorkflowsAdded, d.WorkflowsRemoved, d.WorkflowsChanged = diffByID(current[key], next)
+		case "fileWatcherRules":
+			d.RulesAdded, d.RulesRemoved, d.RulesChanged = diffByID(current[key], next)
+		default:
+			if !reflect.DeepEqual(current[key], next) && !(isEmptyJSON(current[key]) && isEmptyJSON(next)) {
+				d.SettingsChanged = append(d.SettingsChanged, key)
+			}
+		}
+	}
+	sort.Strings(d.SettingsChanged)
+	return d
+}
+
+// diffByID compares two lists of objects keyed by their "id" field
+func diffByID(current, incoming interface{}) (added, removed, changed []string) {
+	before := indexByID(current)
+	after := indexByID(incoming)
+	for id, item := range after {
+		old, ok := before[id]
+		switch {
+		case !ok:
+			added = append(added, id)
+		case !reflect.DeepEqual(old, item):
+			changed = append(changed, id)
+		}
+	}
+	for id := range before {
+		if _, ok := after[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
+
+// isEmptyJSON treats null, {} and [] alike so an unset section is not a change
+func isEmptyJSON(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(val) == 0
+	case []interface{}:
+		return len(val) == 0
+	}
+	return false
+}
+
+func indexByID(list interface{}) map[string]interface{} {
+	items, _ := list.([]interface{})
+	index := make(map[string]interface{}, len(items))
+	for i, item := range items {
+		id := ""
+		if obj, ok := item.(map[string]interface{}); ok {
+			id, _ = obj["id"].(string)
+		}
+		if id == "" {
+			id = fmt.Sprintf("#%d", i)
+		}
+		index[id] = item
+	}
+	return index
+}
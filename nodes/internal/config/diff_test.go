@@ -0,0 +1,45 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffManaged(t *testing.T) {
+	current := map[string]interface{}{
+		"workflows": []interface{}{
+			map[string]interface{}{"id": "ingest", "enabled": true},
+			map[string]interface{}{"id": "cleanup", "enabled": true},
+		},
+		"fileWatcherSettings": map[string]interface{}{"scanDir": "/data"},
+		"variables":           nil,
+	}
+	incoming := map[string]interface{}{
+		"workflows": []interface{}{
+			map[string]interface{}{"id": "ingest", "enabled": false},
+			map[string]interface{}{"id": "report", "enabled": true},
+		},
+		"fileWatcherSettings": map[string]interface{}{"scanDir": "/data"},
+		"variables":           map[string]interface{}{},
+		"fileWatcherRules":    []interface{}{map[string]interface{}{"id": "csv"}},
+	}
+
+	diff := DiffManaged(current, incoming)
+	want := ConfigDiff{
+		WorkflowsAdded:   []string{"report"},
+		WorkflowsRemoved: []string{"cleanup"},
+		WorkflowsChanged: []string{"ingest"},
+		RulesAdded:       []string{"csv"},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("DiffManaged = %+v, want %+v", diff, want)
+	}
+	if !diff.Destructive() || !diff.NeedsApproval(ChangePolicyDestructive) || diff.NeedsApproval(ChangePolicyApply) {
+		t.Error("removing a workflow should need approval under the destructive policy only")
+	}
+
+	same := DiffManaged(current, map[string]interface{}{"fileWatcherSettings": map[string]interface{}{"scanDir": "/data"}})
+	if !same.Empty() || same.NeedsApproval(ChangePolicyAll) {
+		t.Errorf("identical settings should produce an empty diff, got %v", same)
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// EntryError describes a list entry from a config file that could not be decoded
+type EntryError struct {
+	Index int    `json:"index"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+func (e EntryError) String() string {
+	if e.ID != "" {
+		return fmt.Sprintf("entry %d (%s): %s", e.Index, e.ID, e.Error)
+	}
+	return fmt.Sprintf("entry %d: %s", e.Index, e.Error)
+}
+
+// DecodeEntries decodes each element of a generic JSON list (such as the
+// workflows or fileWatcherRules of a git config) into T. Entries that fail to
+// decode are reported rather than silently dropped, so one bad workflow does
+// not quietly disappear while the rest run.
+func DecodeEntries[T any](raw []interface{}) ([]T, []EntryError) {
+	decoded := make([]T, 0, len(raw))
+	var errs []EntryError
+	for i, item := range raw {
+		id := ""
+		if obj, ok := item.(map[string]interface{}); ok {
+			id, _ = obj["id"].(string)
+		}
+		data, err := json.Marshal(item)
+		if err == nil {
+			var entry T
+			if err = json.Unmarshal(data, &entry); err == nil {
+				decoded = append(decoded, entry)
+				continue
+			}
+		}
+		errs = append(errs, EntryError{Index: i, ID: id, Error: err.Error()})
+	}
+	return decoded, errs
+}
+
+// SummarizeEntryErrors joins entry errors into one line for logs and alerts
+func SummarizeEntryErrors(errs []EntryError) string {
+	parts := make([]string, len(errs))
+	for i, e := range errs {
+		parts[i] = e.String()
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDecodeEntries_MixedWorkflows(t *testing.T) {
+	var raw []interface{}
+	err := json.Unmarshal([]byte(`[
+		{"id": "ingest", "name": "Ingest", "enabled": true, "steps": []},
+		{"id": "broken", "enabled": "yes"},
+		"not an object",
+		{"id": "report", "steps": "oops"},
+		{"id": "cleanup", "enabled": false, "trigger": {"type": "schedule"}}
+	]`), &raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workflows, errs := DecodeEntries[Workflow](raw)
+	if len(workflows) != 2 || workflows[0].ID != "ingest" || workflows[1].ID != "cleanup" {
+		t.Errorf("decoded workflows = %+v, want ingest and cleanup", workflows)
+	}
+	if len(errs) != 3 {
+		t.Fatalf("got %d entry errors, want 3: %v", len(errs), errs)
+	}
+	if errs[0].Index != 1 || errs[0].ID != "broken" || errs[1].Index != 2 || errs[2].ID != "report" {
+		t.Errorf("entry errors = %+v", errs)
+	}
+	if summary := SummarizeEntryErrors(errs); !strings.Contains(summary, "entry 3 (report)") {
+		t.Errorf("summary = %q", summary)
+	}
+}
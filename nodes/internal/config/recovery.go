@@ -0,0 +1,81 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// CorruptError reports a config file that exists but cannot be parsed,
+// e.g. after a partial write
+type CorruptError struct {
+	Path string
+	Err  error
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("config file %s is corrupt: %v", e.Path, e.Err)
+}
+
+func (e *CorruptError) Unwrap() error {
+	return e.Err
+}
+
+// Recovery describes how LoadOrRecover got past a corrupt config file
+type Recovery struct {
+	Err         error  // Why the config file could not be loaded
+	CorruptPath string // Where the corrupt file was moved
+	Source      string // "backup" (the .bak written by the last Save) or "defaults"
+	BackupErr   error  // Why the backup could not be used, when Source is "defaults"
+}
+
+// LoadOrRecover loads the config like Load. If the file is corrupt it is
+// moved aside to <path>.corrupt-<timestamp> and replaced with the last known
+// good copy (<path>.bak), or the agent starts from defaults when there is no
+// usable backup. The returned Recovery is nil when no recovery was needed.
+// Other errors (e.g. permissions) are returned as they are.
+func LoadOrRecover(path string) (*Config, *Recovery, error) {
+	cfg, err := Load(path)
+	var corrupt *CorruptError
+	if err == nil || !errors.As(err, &corrupt) || corrupt.Path != path {
+		return cfg, nil, err
+	}
+
+	recovery := &Recovery{Err: err, CorruptPath: fmt.Sprintf("%s.corrupt-%s", path, time.Now().Format("20060102-150405"))}
+	if err := os.Rename(path, recovery.CorruptPath); err != nil {
+		return nil, nil, fmt.Errorf("%w (and moving it aside failed: %v)", corrupt, err)
+	}
+
+	if recovery.BackupErr = restoreBackup(path); recovery.BackupErr == nil {
+		cfg, err := Load(path)
+		if err == nil {
+			recovery.Source = "backup"
+			return cfg, recovery, nil
+		}
+		recovery.BackupErr = err
+		os.Remove(path)
+	}
+
+	// With the file gone Load starts from defaults
+	cfg, err = Load(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	recovery.Source = "defaults"
+	return cfg, recovery, nil
+}
+
+// restoreBackup copies <path>.bak to path if it holds a valid config
+func restoreBackup(path string) error {
+	data, err := os.ReadFile(path + ".bak")
+	if err != nil {
+		return err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("backup is corrupt too: %w", err)
+	}
+	return WriteFileAtomic(path, data, 0600)
+}
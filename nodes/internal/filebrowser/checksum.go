@@ -0,0 +1,155 @@
+package filebrowser
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/your-org/controlcenter/nodes/internal/audit"
+)
+
+// maxConcurrentChecksums bounds how many files are hashed at once so large
+// checksum requests can't saturate the disk
+const maxConcurrentChecksums = 2
+
+// checksumSlots limits concurrent checksum computations
+var checksumSlots = make(chan struct{}, maxConcurrentChecksums)
+
+// checksumAlgorithms maps the algo parameter to a hash constructor
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// ChecksumResponse is the digest of a file
+type ChecksumResponse struct {
+	Path       string `json:"path"`
+	Algorithm  string `json:"algorithm"`
+	Checksum   string `json:"checksum"` // Lowercase hex
+	Size       int64  `json:"size"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// contextReader stops a read loop once the request is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// handleChecksum streams a file through a hash and returns its digest so a
+// download can be verified. The file is never held in memory, and hashing
+// stops if the client goes away.
+// GET /api/files/checksum?path=/some/file&algo=sha256
+func (fb *FileBrowser) handleChecksum(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if !fb.isEnabled() {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "file browser is disabled", Enabled: false})
+		return
+	}
+
+	requestedPath := r.URL.Query().Get("path")
+	if requestedPath == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "path parameter required", Enabled: true})
+		return
+	}
+
+	algo := strings.ToLower(r.URL.Query().Get("algo"))
+	if algo == "" {
+		algo = "sha256"
+	}
+	newHash, ok := checksumAlgorithms[algo]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "algo must be md5, sha1, sha256 or sha512", Enabled: true})
+		return
+	}
+
+	validPath, err := fb.validatePath(requestedPath)
+	if err != nil {
+		fb.logger.Warn().Err(err).Str("path", requestedPath).Msg("Path validation failed")
+		fb.audit.Record("filebrowser.checksum", r.RemoteAddr, audit.OutcomeDenied, map[string]interface{}{"path": requestedPath, "error": err.Error()})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error(), Enabled: true})
+		return
+	}
+
+	info, err := os.Stat(validPath)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "file not found", Enabled: true})
+		return
+	}
+	if info.IsDir() {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "cannot checksum a directory", Enabled: true})
+		return
+	}
+
+	select {
+	case checksumSlots <- struct{}{}:
+		defer func() { <-checksumSlots }()
+	default:
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "too many checksum requests in progress, try again shortly", Enabled: true})
+		return
+	}
+
+	file, err := os.Open(validPath)
+	if err != nil {
+		fb.logger.Error().Err(err).Str("path", validPath).Msg("Failed to open file")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "failed to open file", Enabled: true})
+		return
+	}
+	defer file.Close()
+
+	start := time.Now()
+	h := newHash()
+	size, err := io.Copy(h, &contextReader{ctx: r.Context(), r: file})
+	if err != nil {
+		fb.logger.Warn().Err(err).Str("path", validPath).Msg("Checksum aborted")
+		fb.audit.Record("filebrowser.checksum", r.RemoteAddr, audit.OutcomeFailure, map[string]interface{}{"path": validPath, "error": err.Error()})
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "failed to read file", Enabled: true})
+		return
+	}
+
+	response := ChecksumResponse{
+		Path:       validPath,
+		Algorithm:  algo,
+		Checksum:   hex.EncodeToString(h.Sum(nil)),
+		Size:       size,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	fb.logger.Info().
+		Str("path", validPath).
+		Str("algo", algo).
+		Int64("size", size).
+		Int64("durationMs", response.DurationMs).
+		Msg("Checksum computed")
+	fb.audit.Record("filebrowser.checksum", r.RemoteAddr, audit.OutcomeSuccess, map[string]interface{}{"path": validPath, "algo": algo})
+	json.NewEncoder(w).Encode(response)
+}
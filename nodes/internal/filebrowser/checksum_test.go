@@ -0,0 +1,50 @@
+package filebrowser
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/controlcenter/nodes/internal/config"
+)
+
+func TestHandleChecksum(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "data.txt")
+	if err := os.WriteFile(file, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fb := New(&config.Config{FileBrowserSettings: config.FileBrowserSettings{Enabled: true, AllowedPaths: []string{dir}}}, zerolog.Nop())
+
+	get := func(path, algo string) (*httptest.ResponseRecorder, ChecksumResponse) {
+		q := url.Values{"path": {path}, "algo": {algo}}
+		rec := httptest.NewRecorder()
+		fb.handleChecksum(rec, httptest.NewRequest(http.MethodGet, "/api/files/checksum?"+q.Encode(), nil))
+		var resp ChecksumResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return rec, resp
+	}
+
+	rec, resp := get(file, "")
+	if rec.Code != http.StatusOK || resp.Algorithm != "sha256" || resp.Size != 5 ||
+		resp.Checksum != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("sha256: %d %+v", rec.Code, resp)
+	}
+	if rec, resp := get(file, "MD5"); rec.Code != http.StatusOK || resp.Checksum != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("md5: %d %+v", rec.Code, resp)
+	}
+	if rec, _ := get(file, "crc32"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown algo: status %d, want 400", rec.Code)
+	}
+	if rec, _ := get(dir, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("directory: status %d, want 400", rec.Code)
+	}
+	if rec, _ := get(filepath.Join(t.TempDir(), "outside"), ""); rec.Code != http.StatusForbidden {
+		t.Errorf("outside allowed paths: status %d, want 403", rec.Code)
+	}
+}
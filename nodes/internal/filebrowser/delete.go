@@ -0,0 +1,173 @@
+package filebrowser
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/your-org/controlcenter/nodes/internal/config"
+)
+
+// Delete modes (FileBrowserSettings.DeleteMode)
+const (
+	DeleteModePermanent = "delete"
+	DeleteModeTrash     = "trash"
+)
+
+const (
+	defaultMaxDeleteItems = 10000
+	defaultTrashRetention = 7 * 24 * time.Hour
+	defaultDeleteRetries  = 2
+	deleteRetryDelay      = 500 * time.Millisecond
+
+	// trashStampLayout prefixes trashed names so retention does not depend on
+	// the item's own modification time
+	trashStampLayout = "20060102-150405"
+)
+
+// errTooManyItems stops countItems once the limit is passed
+var errTooManyItems = errors.New("too many items")
+
+// deleteSettings resolves the delete-related file browser settings with defaults
+type deleteSettings struct {
+	mode      string
+	trashDir  string
+	retention time.Duration
+	maxItems  int
+	retries   int
+}
+
+func resolveDeleteSettings(settings config.FileBrowserSettings) deleteSettings {
+	ds := deleteSettings{
+		mode:      DeleteModePermanent,
+		trashDir:  settings.TrashDir,
+		retention: time.Duration(settings.TrashRetentionHours) * time.Hour,
+		maxItems:  settings.MaxDeleteItems,
+		retries:   settings.DeleteRetries,
+	}
+	if strings.EqualFold(settings.DeleteMode, DeleteModeTrash) {
+		ds.mode = DeleteModeTrash
+	}
+	if ds.trashDir == "" {
+		home, _ := os.UserHomeDir()
+		ds.trashDir = filepath.Join(home, ".controlcenter-agent", "trash")
+	} else if strings.HasPrefix(ds.trashDir, "~") {
+		home, _ := os.UserHomeDir()
+		ds.trashDir = filepath.Join(home, ds.trashDir[1:])
+	}
+	if abs, err := filepath.Abs(ds.trashDir); err == nil {
+		ds.trashDir = abs
+	}
+	if ds.retention <= 0 {
+		ds.retention = defaultTrashRetention
+	}
+	if ds.maxItems <= 0 {
+		ds.maxItems = defaultMaxDeleteItems
+	}
+	if ds.retries == 0 {
+		ds.retries = defaultDeleteRetries
+	} else if ds.retries < 0 {
+		ds.retries = 0
+	}
+	return ds
+}
+
+// inTrash reports whether path is the trash directory or inside it.
+// Deleting there empties the trash, so it is always permanent.
+func (ds deleteSettings) inTrash(path string) bool {
+	rel, err := filepath.Rel(ds.trashDir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// countItems counts the entries below root, stopping with errTooManyItems
+// once there are more than limit
+func countItems(root string, limit int) (int, error) {
+	count := 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		count++
+		if count > limit {
+			return errTooManyItems
+		}
+		return nil
+	})
+	return count, err
+}
+
+// removeWithRetry deletes path, retrying failures that may be transient,
+// such as a file held open by another process on Windows
+func removeWithRetry(path string, isDir bool, retries int) error {
+	remove := os.Remove
+	if isDir {
+		remove = os.RemoveAll
+	}
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(deleteRetryDelay)
+		}
+		if err = remove(path); err == nil || errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+	}
+	return err
+}
+
+// moveToTrash moves path into the trash directory under a timestamped name
+// and returns where it went
+func moveToTrash(path string, ds deleteSettings, now time.Time) (string, error) {
+	if err := os.MkdirAll(ds.trashDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create trash directory: %w", err)
+	}
+	base := now.Format(trashStampLayout) + "-" + filepath.Base(path)
+	dest := filepath.Join(ds.trashDir, base)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(dest); errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		dest = filepath.Join(ds.trashDir, fmt.Sprintf("%s.%d", base, i))
+	}
+	if err := os.Rename(path, dest); err != nil {
+		return "", fmt.Errorf("failed to move to trash %s (it must be on the same filesystem): %w", ds.trashDir, err)
+	}
+	return dest, nil
+}
+
+// purgeTrash permanently deletes trashed items older than the retention
+// period and returns how many it removed
+func purgeTrash(ds deleteSettings, now time.Time) (int, error) {
+	entries, err := os.ReadDir(ds.trashDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	purged := 0
+	var errs []error
+	for _, entry := range entries {
+		name := entry.Name()
+		if len(name) < len(trashStampLayout) {
+			continue
+		}
+		trashedAt, err := time.ParseInLocation(trashStampLayout, name[:len(trashStampLayout)], now.Location())
+		if err != nil || now.Sub(trashedAt) < ds.retention {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(ds.trashDir, name)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		purged++
+	}
+	return purged, errors.Join(errs...)
+}
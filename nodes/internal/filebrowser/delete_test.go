@@ -0,0 +1,91 @@
+package filebrowser
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/controlcenter/nodes/internal/config"
+)
+
+func TestHandleDelete_ConfirmCapAndTrash(t *testing.T) {
+	dir := t.TempDir()
+	trashDir := filepath.Join(t.TempDir(), "trash")
+	settings := config.FileBrowserSettings{Enabled: true, AllowedPaths: []string{dir, trashDir}, MaxDeleteItems: 3}
+	cfg := &config.Config{FileBrowserSettings: settings}
+	fb := New(cfg, zerolog.Nop())
+
+	del := func(path string, confirm bool) (*httptest.ResponseRecorder, map[string]interface{}) {
+		q := url.Values{"path": {path}}
+		if confirm {
+			q.Set("confirm", "true")
+		}
+		rec := httptest.NewRecorder()
+		fb.handleDelete(rec, httptest.NewRequest(http.MethodDelete, "/api/files/delete?"+q.Encode(), nil))
+		var resp map[string]interface{}
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return rec, resp
+	}
+	mkTree := func(name string, files int) string {
+		root := filepath.Join(dir, name)
+		os.MkdirAll(root, 0755)
+		for i := 0; i < files; i++ {
+			os.WriteFile(filepath.Join(root, strings.Repeat("f", i+1)), []byte("x"), 0644)
+		}
+		return root
+	}
+
+	// Non-empty directories need confirm=true
+	small := mkTree("small", 2)
+	if rec, resp := del(small, false); rec.Code != http.StatusConflict || resp["requiresConfirm"] != true || resp["items"] != float64(2) {
+		t.Errorf("unconfirmed recursive delete: %d %v", rec.Code, resp)
+	}
+	if rec, _ := del(small, true); rec.Code != http.StatusOK {
+		t.Errorf("confirmed delete: status %d", rec.Code)
+	}
+	if _, err := os.Stat(small); !os.IsNotExist(err) {
+		t.Error("confirmed delete should remove the directory")
+	}
+
+	// Directories past maxDeleteItems are refused even when confirmed
+	big := mkTree("big", 4)
+	if rec, _ := del(big, true); rec.Code != http.StatusForbidden {
+		t.Errorf("delete past maxDeleteItems: status %d, want 403", rec.Code)
+	}
+
+	// Trash mode moves items instead and purges expired ones
+	cfg.FileBrowserSettings.DeleteMode = DeleteModeTrash
+	cfg.FileBrowserSettings.TrashDir = trashDir
+	expired := filepath.Join(trashDir, time.Now().Add(-8*24*time.Hour).Format(trashStampLayout)+"-old.txt")
+	os.MkdirAll(trashDir, 0700)
+	os.WriteFile(expired, []byte("x"), 0644)
+
+	file := filepath.Join(dir, "report.csv")
+	os.WriteFile(file, []byte("a,b"), 0644)
+	rec, resp := del(file, false)
+	trashedTo, _ := resp["trashedTo"].(string)
+	if rec.Code != http.StatusOK || !strings.HasSuffix(trashedTo, "-report.csv") || filepath.Dir(trashedTo) != trashDir {
+		t.Fatalf("trash delete: %d %v", rec.Code, resp)
+	}
+	if data, err := os.ReadFile(trashedTo); err != nil || string(data) != "a,b" {
+		t.Errorf("trashed file should keep its content: %v", err)
+	}
+	if _, err := os.Stat(expired); !os.IsNotExist(err) {
+		t.Error("trash older than the retention period should be purged")
+	}
+
+	// Deleting inside the trash empties it for good
+	if rec, resp := del(trashedTo, false); rec.Code != http.StatusOK || resp["trashedTo"] != nil {
+		t.Errorf("delete inside trash: %d %v", rec.Code, resp)
+	}
+	if _, err := os.Stat(trashedTo); !os.IsNotExist(err) {
+		t.Error("delete inside trash should be permanent")
+	}
+}
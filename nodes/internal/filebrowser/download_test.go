@@ -0,0 +1,49 @@
+package filebrowser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/controlcenter/nodes/internal/config"
+)
+
+func TestHandleDownload_Range(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "data.bin")
+	if err := os.WriteFile(file, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fb := New(&config.Config{FileBrowserSettings: config.FileBrowserSettings{Enabled: true, AllowedPaths: []string{dir}}}, zerolog.Nop())
+
+	get := func(rangeHeader string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/files/download?"+url.Values{"path": {file}}.Encode(), nil)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		rec := httptest.NewRecorder()
+		fb.handleDownload(rec, req)
+		return rec
+	}
+
+	rec := get("")
+	if rec.Code != http.StatusOK || rec.Body.String() != "0123456789" || rec.Header().Get("Accept-Ranges") != "bytes" {
+		t.Errorf("full download: %d %q %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+	if rec.Header().Get("Content-Disposition") != `attachment; filename="data.bin"` {
+		t.Errorf("Content-Disposition = %q", rec.Header().Get("Content-Disposition"))
+	}
+
+	rec = get("bytes=4-")
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "456789" || rec.Header().Get("Content-Range") != "bytes 4-9/10" {
+		t.Errorf("resumed download: %d %q %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+
+	if rec := get("bytes=20-"); rec.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("unsatisfiable range: status %d, want 416", rec.Code)
+	}
+}
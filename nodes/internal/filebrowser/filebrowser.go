@@ -2,6 +2,7 @@ package filebrowser
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,7 +11,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/your-org/controlcenter/nodes/internal/audit"
 	"github.com/your-org/controlcenter/nodes/internal/config"
+	"github.com/your-org/controlcenter/nodes/internal/router"
 	"github.com/rs/zerolog"
 )
 
@@ -18,6 +21,7 @@ import (
 type FileBrowser struct {
 	config *config.Config
 	logger zerolog.Logger
+	audit  *audit.Logger
 }
 
 // FileInfo represents a file or directory
@@ -52,13 +56,21 @@ func New(cfg *config.Config, logger zerolog.Logger) *FileBrowser {
 	}
 }
 
-// RegisterHandlers registers all file browser HTTP handlers
-func (fb *FileBrowser) RegisterHandlers() {
-	http.HandleFunc("/api/files/browse", fb.handleBrowse)
-	http.HandleFunc("/api/files/download", fb.handleDownload)
-	http.HandleFunc("/api/files/upload", fb.handleUpload)
-	http.HandleFunc("/api/files/mkdir", fb.handleMkdir)
-	http.HandleFunc("/api/files/delete", fb.handleDelete)
+// SetAuditLogger sets where downloads, uploads and deletes are recorded
+func (fb *FileBrowser) SetAuditLogger(a *audit.Logger) {
+	fb.audit = a
+}
+
+// RegisterHandlers registers all file browser HTTP handlers on rt
+func (fb *FileBrowser) RegisterHandlers(rt *router.Router) error {
+	return errors.Join(
+		rt.HandleFunc(http.MethodGet, "/api/files/browse", fb.handleBrowse),
+		rt.HandleFunc(http.MethodGet, "/api/files/download", fb.handleDownload),
+		rt.HandleFunc(http.MethodPost, "/api/files/upload", fb.handleUpload),
+		rt.HandleFunc(http.MethodPost, "/api/files/mkdir", fb.handleMkdir),
+		rt.HandleFunc(http.MethodDelete, "/api/files/delete", fb.handleDelete),
+		rt.HandleFunc(http.MethodGet, "/api/files/checksum", fb.handleChecksum),
+	)
 }
 
 // isEnabled checks if file browser is enabled
@@ -72,6 +84,21 @@ func (fb *FileBrowser) getSettings() config.FileBrowserSettings {
 	return fb.config.GetFileBrowserSettings()
 }
 
+// permissions returns the configured file and directory modes, falling back
+// to 0644/0755 when unset or invalid
+func (fb *FileBrowser) permissions() (fileMode, dirMode os.FileMode) {
+	settings := fb.getSettings()
+	fileMode, err := config.ParseFileMode(settings.FilePerm, 0644)
+	if err != nil {
+		fb.logger.Warn().Err(err).Msg("Invalid filePerm in file browser settings, using default")
+	}
+	dirMode, err = config.ParseFileMode(settings.DirPerm, 0755)
+	if err != nil {
+		fb.logger.Warn().Err(err).Msg("Invalid dirPerm in file browser settings, using default")
+	}
+	return fileMode, dirMode
+}
+
 // validatePath validates that a path is allowed and safe
 func (fb *FileBrowser) validatePath(requestedPath string) (string, error) {
 	if !fb.isEnabled() {
@@ -140,13 +167,6 @@ func (fb *FileBrowser) validatePath(requestedPath string) (string, error) {
 // GET /api/files/browse?path=/some/path
 func (fb *FileBrowser) handleBrowse(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-
-	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "method not allowed", Enabled: fb.isEnabled()})
-		return
-	}
 
 	if !fb.isEnabled() {
 		w.WriteHeader(http.StatusForbidden)
@@ -247,12 +267,6 @@ func (fb *FileBrowser) handleBrowse(w http.ResponseWriter, r *http.Request) {
 // handleDownload handles file download requests
 // GET /api/files/download?path=/some/file.txt
 func (fb *FileBrowser) handleDownload(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		w.Write([]byte("method not allowed"))
-		return
-	}
-
 	if !fb.isEnabled() {
 		w.WriteHeader(http.StatusForbidden)
 		w.Write([]byte("file browser is disabled"))
@@ -269,6 +283,7 @@ func (fb *FileBrowser) handleDownload(w http.ResponseWriter, r *http.Request) {
 	validPath, err := fb.validatePath(requestedPath)
 	if err != nil {
 		fb.logger.Warn().Err(err).Str("path", requestedPath).Msg("Path validation failed")
+		fb.audit.Record("filebrowser.download", r.RemoteAddr, audit.OutcomeDenied, map[string]interface{}{"path": requestedPath, "error": err.Error()})
 		w.WriteHeader(http.StatusForbidden)
 		w.Write([]byte(err.Error()))
 		return
@@ -303,24 +318,24 @@ func (fb *FileBrowser) handleDownload(w http.ResponseWriter, r *http.Request) {
 	filename := filepath.Base(validPath)
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
 	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", info.Size()))
 
-	// Stream file to response
-	fb.logger.Info().Str("path", validPath).Int64("size", info.Size()).Msg("Download request")
-	io.Copy(w, file)
+	// ServeContent handles Range (resumed downloads, media seeking) and
+	// If-Modified-Since/If-Range, and sets Content-Length and Accept-Ranges
+	rangeHeader := r.Header.Get("Range")
+	fb.logger.Info().Str("path", validPath).Int64("size", info.Size()).Str("range", rangeHeader).Msg("Download request")
+	http.ServeContent(w, r, filename, info.ModTime(), file)
+
+	details := map[string]interface{}{"path": validPath, "size": info.Size()}
+	if rangeHeader != "" {
+		details["range"] = rangeHeader
+	}
+	fb.audit.Record("filebrowser.download", r.RemoteAddr, audit.OutcomeSuccess, details)
 }
 
 // handleUpload handles file upload requests
 // POST /api/files/upload?path=/some/directory
 func (fb *FileBrowser) handleUpload(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "method not allowed", Enabled: fb.isEnabled()})
-		return
-	}
 
 	if !fb.isEnabled() {
 		w.WriteHeader(http.StatusForbidden)
@@ -387,12 +402,14 @@ func (fb *FileBrowser) handleUpload(w http.ResponseWriter, r *http.Request) {
 	_, err = fb.validatePath(destPath)
 	if err != nil {
 		fb.logger.Warn().Err(err).Str("path", destPath).Msg("Destination path validation failed")
+		fb.audit.Record("filebrowser.upload", r.RemoteAddr, audit.OutcomeDenied, map[string]interface{}{"path": destPath, "error": err.Error()})
 		w.WriteHeader(http.StatusForbidden)
 		json.NewEncoder(w).Encode(ErrorResponse{Error: "invalid destination path", Enabled: true})
 		return
 	}
 
-	destFile, err := os.Create(destPath)
+	fileMode, _ := fb.permissions()
+	destFile, err := os.OpenFile(destPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, fileMode)
 	if err != nil {
 		fb.logger.Error().Err(err).Str("path", destPath).Msg("Failed to create destination file")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -405,13 +422,22 @@ func (fb *FileBrowser) handleUpload(w http.ResponseWriter, r *http.Request) {
 	written, err := io.Copy(destFile, file)
 	if err != nil {
 		fb.logger.Error().Err(err).Str("path", destPath).Msg("Failed to write file")
+		fb.audit.Record("filebrowser.upload", r.RemoteAddr, audit.OutcomeFailure, map[string]interface{}{"path": destPath, "error": err.Error()})
 		os.Remove(destPath) // Clean up partial file
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ErrorResponse{Error: "failed to write file", Enabled: true})
 		return
 	}
 
+	// Apply the mode explicitly so the process umask doesn't strip group bits
+	if settings.FilePerm != "" {
+		if err := os.Chmod(destPath, fileMode); err != nil {
+			fb.logger.Warn().Err(err).Str("path", destPath).Msg("Failed to set file permissions")
+		}
+	}
+
 	fb.logger.Info().Str("path", destPath).Int64("size", written).Msg("File uploaded successfully")
+	fb.audit.Record("filebrowser.upload", r.RemoteAddr, audit.OutcomeSuccess, map[string]interface{}{"path": destPath, "size": written})
 
 	response := map[string]interface{}{
 		"success":  true,
@@ -426,13 +452,6 @@ func (fb *FileBrowser) handleUpload(w http.ResponseWriter, r *http.Request) {
 // POST /api/files/mkdir?path=/some/new/directory
 func (fb *FileBrowser) handleMkdir(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "method not allowed", Enabled: fb.isEnabled()})
-		return
-	}
 
 	if !fb.isEnabled() {
 		w.WriteHeader(http.StatusForbidden)
@@ -450,21 +469,30 @@ func (fb *FileBrowser) handleMkdir(w http.ResponseWriter, r *http.Request) {
 	validPath, err := fb.validatePath(requestedPath)
 	if err != nil {
 		fb.logger.Warn().Err(err).Str("path", requestedPath).Msg("Path validation failed")
+		fb.audit.Record("filebrowser.mkdir", r.RemoteAddr, audit.OutcomeDenied, map[string]interface{}{"path": requestedPath, "error": err.Error()})
 		w.WriteHeader(http.StatusForbidden)
 		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error(), Enabled: true})
 		return
 	}
 
 	// Create directory
-	err = os.MkdirAll(validPath, 0755)
+	_, dirMode := fb.permissions()
+	err = os.MkdirAll(validPath, dirMode)
 	if err != nil {
 		fb.logger.Error().Err(err).Str("path", validPath).Msg("Failed to create directory")
+		fb.audit.Record("filebrowser.mkdir", r.RemoteAddr, audit.OutcomeFailure, map[string]interface{}{"path": validPath, "error": err.Error()})
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ErrorResponse{Error: "failed to create directory", Enabled: true})
 		return
 	}
+	if fb.getSettings().DirPerm != "" {
+		if err := os.Chmod(validPath, dirMode); err != nil {
+			fb.logger.Warn().Err(err).Str("path", validPath).Msg("Failed to set directory permissions")
+		}
+	}
 
 	fb.logger.Info().Str("path", validPath).Msg("Directory created")
+	fb.audit.Record("filebrowser.mkdir", r.RemoteAddr, audit.OutcomeSuccess, map[string]interface{}{"path": validPath})
 
 	response := map[string]interface{}{
 		"success": true,
@@ -474,16 +502,9 @@ func (fb *FileBrowser) handleMkdir(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleDelete handles file/directory deletion requests
-// DELETE /api/files/delete?path=/some/file/or/directory
+// DELETE /api/files/delete?path=/some/file/or/directory[&confirm=true]
 func (fb *FileBrowser) handleDelete(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-
-	if r.Method != http.MethodDelete {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "method not allowed", Enabled: fb.isEnabled()})
-		return
-	}
 
 	if !fb.isEnabled() {
 		w.WriteHeader(http.StatusForbidden)
@@ -501,6 +522,7 @@ func (fb *FileBrowser) handleDelete(w http.ResponseWriter, r *http.Request) {
 	validPath, err := fb.validatePath(requestedPath)
 	if err != nil {
 		fb.logger.Warn().Err(err).Str("path", requestedPath).Msg("Path validation failed")
+		fb.audit.Record("filebrowser.delete", r.RemoteAddr, audit.OutcomeDenied, map[string]interface{}{"path": requestedPath, "error": err.Error()})
 		w.WriteHeader(http.StatusForbidden)
 		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error(), Enabled: true})
 		return
@@ -515,25 +537,75 @@ func (fb *FileBrowser) handleDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete file or directory
+	ds := resolveDeleteSettings(fb.getSettings())
+	trash := ds.mode == DeleteModeTrash && !ds.inTrash(validPath)
+
+	// Recursive deletes are capped and must be confirmed explicitly
+	items := 0
 	if info.IsDir() {
-		err = os.RemoveAll(validPath)
+		items, err = countItems(validPath, ds.maxItems)
+		if errors.Is(err, errTooManyItems) {
+			fb.logger.Warn().Str("path", validPath).Int("maxDeleteItems", ds.maxItems).Msg("🚫 Recursive delete refused, too many items")
+			fb.audit.Record("filebrowser.delete", r.RemoteAddr, audit.OutcomeDenied, map[string]interface{}{"path": validPath, "error": "too many items"})
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: fmt.Sprintf("directory holds more than %d items (fileBrowserSettings.maxDeleteItems)", ds.maxItems), Enabled: true})
+			return
+		}
+		if err != nil {
+			fb.logger.Error().Err(err).Str("path", validPath).Msg("Failed to scan directory for delete")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "failed to scan directory", Enabled: true})
+			return
+		}
+		if items > 0 && r.URL.Query().Get("confirm") != "true" {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":           fmt.Sprintf("directory is not empty (%d items); repeat with confirm=true to delete it recursively", items),
+				"enabled":         true,
+				"requiresConfirm": true,
+				"items":           items,
+				"trash":           trash,
+			})
+			return
+		}
+	}
+
+	// Move to trash or delete file or directory
+	var trashedTo string
+	if trash {
+		trashedTo, err = moveToTrash(validPath, ds, time.Now())
 	} else {
-		err = os.Remove(validPath)
+		err = removeWithRetry(validPath, info.IsDir(), ds.retries)
 	}
 
 	if err != nil {
 		fb.logger.Error().Err(err).Str("path", validPath).Msg("Failed to delete")
+		fb.audit.Record("filebrowser.delete", r.RemoteAddr, audit.OutcomeFailure, map[string]interface{}{"path": validPath, "error": err.Error()})
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ErrorResponse{Error: "failed to delete", Enabled: true})
 		return
 	}
 
-	fb.logger.Info().Str("path", validPath).Bool("isDir", info.IsDir()).Msg("Deleted successfully")
+	details := map[string]interface{}{"path": validPath, "isDir": info.IsDir(), "items": items}
+	if trash {
+		details["trashedTo"] = trashedTo
+		fb.logger.Info().Str("path", validPath).Str("trashedTo", trashedTo).Msg("🗑️ Moved to trash")
+		if purged, err := purgeTrash(ds, time.Now()); err != nil {
+			fb.logger.Warn().Err(err).Str("trashDir", ds.trashDir).Msg("Failed to purge expired trash")
+		} else if purged > 0 {
+			fb.logger.Info().Int("purged", purged).Str("trashDir", ds.trashDir).Msg("Purged expired trash")
+		}
+	} else {
+		fb.logger.Info().Str("path", validPath).Bool("isDir", info.IsDir()).Msg("Deleted successfully")
+	}
+	fb.audit.Record("filebrowser.delete", r.RemoteAddr, audit.OutcomeSuccess, details)
 
 	response := map[string]interface{}{
 		"success": true,
 		"path":    validPath,
 	}
+	if trash {
+		response["trashedTo"] = trashedTo
+	}
 	json.NewEncoder(w).Encode(response)
 }
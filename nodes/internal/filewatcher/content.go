@@ -0,0 +1,64 @@
+package filewatcher
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// contentPatterns returns every content regex a rule checks
+func (r Rule) contentPatterns() []string {
+	patterns := make([]string, 0, 1+len(r.ContentRegExAll)+len(r.ContentRegExAny))
+	if r.ContentRegEx != "" {
+		patterns = append(patterns, r.ContentRegEx)
+	}
+	patterns = append(patterns, r.ContentRegExAll...)
+	return append(patterns, r.ContentRegExAny...)
+}
+
+// matchesContent reports whether the file at path satisfies the rule's content
+// checks: ContentRegEx and every ContentRegExAll pattern must match, and at
+// least one ContentRegExAny pattern when that list is set. The file is streamed
+// through each pattern rather than read into memory, and evaluation stops as
+// soon as the outcome is known.
+func (w *Watcher) matchesContent(path string, rule Rule) (bool, error) {
+	required := rule.ContentRegExAll
+	if rule.ContentRegEx != "" {
+		required = append([]string{rule.ContentRegEx}, required...)
+	}
+	for _, pattern := range required {
+		matched, err := streamMatch(path, pattern)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	if len(rule.ContentRegExAny) == 0 {
+		return true, nil
+	}
+	for _, pattern := range rule.ContentRegExAny {
+		matched, err := streamMatch(path, pattern)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// streamMatch reports whether pattern matches anywhere in the file at path
+func streamMatch(path, pattern string) (bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, fmt.Errorf("invalid content regex %q: %w", pattern, err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	return re.MatchReader(bufio.NewReader(file)), nil
+}
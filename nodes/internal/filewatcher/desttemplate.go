@@ -0,0 +1,105 @@
+package filewatcher
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// parseDestTemplate parses a copyToTemplate. Unknown keys are errors so a
+// typo cannot silently route files into a "<no value>" directory.
+func parseDestTemplate(text string) (*template.Template, error) {
+	return template.New("copyToTemplate").Option("missingkey=error").Parse(text)
+}
+
+// fileRegexGroups returns the capture groups of pattern matched against
+// fileName, keyed by name for named groups and by index ("1", "2", ...) for all
+func fileRegexGroups(pattern, fileName string) map[string]string {
+	groups := make(map[string]string)
+	if pattern == "" {
+		return groups
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return groups
+	}
+	match := re.FindStringSubmatch(fileName)
+	if match == nil {
+		return groups
+	}
+	for i, name := range re.SubexpNames() {
+		if i == 0 {
+			continue
+		}
+		groups[strconv.Itoa(i)] = match[i]
+		if name != "" {
+			groups[name] = match[i]
+		}
+	}
+	return groups
+}
+
+// isGroupIndex reports whether a fileRegexGroups key is a group number
+func isGroupIndex(name string) bool {
+	_, err := strconv.Atoi(name)
+	return err == nil
+}
+
+// fileTemplateData is what copyToTemplate can use. Date components are the
+// processing time in the rule's timezone; modTime and now allow custom
+// layouts, e.g. {{ .modTime.Format "2006-01" }}.
+func fileTemplateData(filePath string, rule Rule, now time.Time) map[string]interface{} {
+	if loc, err := loadLocation(rule.TimeRestrictions.Timezone); err == nil && loc != nil {
+		now = now.In(loc)
+	}
+	fileName := filepath.Base(filePath)
+	ext := filepath.Ext(fileName)
+	data := map[string]interface{}{
+		"fileName":  fileName,
+		"name":      strings.TrimSuffix(fileName, ext),
+		"ext":       ext,
+		"directory": filepath.Dir(filePath),
+		"rule":      rule.Name,
+		"groups":    fileRegexGroups(rule.FileRegEx, fileName),
+		"now":       now,
+		"modTime":   now,
+		"year":      now.Format("2006"),
+		"month":     now.Format("01"),
+		"day":       now.Format("02"),
+		"hour":      now.Format("15"),
+		"minute":    now.Format("04"),
+		"second":    now.Format("05"),
+		"date":      now.Format("2006-01-02"),
+	}
+	if info, err := os.Stat(filePath); err == nil {
+		data["modTime"] = info.ModTime().In(now.Location())
+	}
+	return data
+}
+
+// renderDestination renders copyToTemplate into a full destination path.
+// A result ending in a separator is a directory and gets the file name appended.
+func renderDestination(text, filePath string, rule Rule, now time.Time) (string, error) {
+	tmpl, err := parseDestTemplate(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid copyToTemplate: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, fileTemplateData(filePath, rule, now)); err != nil {
+		return "", fmt.Errorf("failed to render copyToTemplate: %w", err)
+	}
+	dest := strings.TrimSpace(buf.String())
+	if dest == "" {
+		return "", fmt.Errorf("copyToTemplate rendered an empty path")
+	}
+	if strings.HasSuffix(dest, "/") || strings.HasSuffix(dest, string(filepath.Separator)) {
+		dest = filepath.Join(dest, filepath.Base(filePath))
+	}
+	return filepath.Clean(dest), nil
+}
@@ -2,118 +2,367 @@ package filewatcher
 
 import (
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
 
 	"gopkg.in/ini.v1"
 )
 
-// ImportINI imports file watcher rules from an INI file
-func ImportINI(filePath string) ([]Rule, error) {
-	cfg, err := ini.Load(filePath)
+// GeneralSettings mirrors the [General] section of a legacy INI file.
+// Its processing values are the defaults for rules that don't override them.
+type GeneralSettings struct {
+	ScanDir        string `json:"scanDir"`
+	ScanSubDir     bool   `json:"scanSubDir"`
+	CheckFileInUse bool   `json:"checkFileInUse"`
+	MaxRetries     int    `json:"maxRetries"`
+	DelayRetry     int    `json:"delayRetry"` // Milliseconds
+}
+
+// ImportIssue is something the INI importer skipped, defaulted or ignored.
+// Line is 1-based, 0 when unknown.
+type ImportIssue struct {
+	Section string `json:"section"`
+	Key     string `json:"key,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// RuleImport reports the import of one [FileMatching] entry
+type RuleImport struct {
+	Name     string        `json:"name"`
+	Line     int           `json:"line,omitempty"`
+	Imported bool          `json:"imported"`
+	Warnings []ImportIssue `json:"warnings,omitempty"`
+	Error    string        `json:"error,omitempty"` // Why the rule was not imported
+}
+
+// ImportReport lists what an INI import did with each rule and section
+type ImportReport struct {
+	Rules           []RuleImport  `json:"rules"`
+	Warnings        []ImportIssue `json:"warnings,omitempty"` // [General] and [FileMatching]
+	IgnoredSections []ImportIssue `json:"ignoredSections,omitempty"`
+}
+
+// ImportINI imports file watcher rules and the [General] settings from an INI file
+func ImportINI(filePath string) ([]Rule, GeneralSettings, error) {
+	rules, general, _, err := parseINI(filePath)
+	return rules, general, err
+}
+
+// ImportINIData imports rules from INI content already in memory (e.g. an upload)
+func ImportINIData(data []byte) ([]Rule, GeneralSettings, error) {
+	rules, general, _, err := parseINI(data)
+	return rules, general, err
+}
+
+// ImportINIWithReport imports an INI file like ImportINI but leaves out rules
+// that could not be imported instead of failing, and reports per rule what
+// was skipped, defaulted or ignored, with line numbers. Use it to migrate a
+// large legacy INI iteratively.
+func ImportINIWithReport(filePath string) ([]Rule, GeneralSettings, ImportReport, error) {
+	return importINIWithReport(filePath)
+}
+
+// ImportINIDataWithReport is ImportINIWithReport for INI content in memory
+func ImportINIDataWithReport(data []byte) ([]Rule, GeneralSettings, ImportReport, error) {
+	return importINIWithReport(data)
+}
+
+func importINIWithReport(source interface{}) ([]Rule, GeneralSettings, ImportReport, error) {
+	rules, general, report, err := parseINI(source)
+	if err != nil {
+		return nil, general, report, err
+	}
+	imported := []Rule{}
+	for i, rule := range rules {
+		if report.Rules[i].Imported {
+			imported = append(imported, rule)
+		}
+	}
+	return imported, general, report, nil
+}
+
+// parseINI parses an INI source (file path or []byte) into rules. Every
+// [FileMatching] entry yields a rule, even when its section is missing or the
+// rule is invalid, as ImportINI always did; report.Rules (one per rule) marks
+// those as not imported.
+func parseINI(source interface{}) ([]Rule, GeneralSettings, ImportReport, error) {
+	report := ImportReport{Rules: []RuleImport{}}
+	data, ok := source.([]byte)
+	if !ok {
+		var err error
+		if data, err = os.ReadFile(source.(string)); err != nil {
+			return nil, GeneralSettings{}, report, fmt.Errorf("failed to load INI file: %w", err)
+		}
+	}
+	cfg, err := ini.Load(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load INI file: %w", err)
+		return nil, GeneralSettings{}, report, fmt.Errorf("failed to load INI file: %w", err)
 	}
+	lines := indexINILines(data)
 	
 	rules := []Rule{}
 	
 	// Get general settings
-	generalSection := cfg.Section("General")
-	scanDir := generalSection.Key("ScanDir").String()
-	scanSubDir := generalSection.Key("ScanSubDir").MustBool(false)
-	checkFileInUse := generalSection.Key("ScanCheckFileInUse").MustBool(true)
-	maxRetries := generalSection.Key("MaxRetries").MustInt(5)
-	delayRetry := generalSection.Key("DelayRetry").MustInt(1000)
+	generalSection := newSectionReader(cfg, "General", lines, &report.Warnings)
+	general := GeneralSettings{
+		ScanDir:        generalSection.str("ScanDir"),
+		ScanSubDir:     generalSection.boolean("ScanSubDir", false),
+		CheckFileInUse: generalSection.boolean("ScanCheckFileInUse", true),
+		MaxRetries:     generalSection.integer("MaxRetries", 5),
+		DelayRetry:     generalSection.integer("DelayRetry", 1000),
+	}
+	generalSection.reportUnknownKeys(generalINIKeys)
 	
 	// Get file matching rules
 	fileMatchSection := cfg.Section("FileMatching")
+	used := map[string]bool{"General": true, "FileMatching": true}
 	
 	// Process each rule
 	for i := 0; ; i++ {
 		ruleKey := fmt.Sprintf("Rule%d", i)
+		if !fileMatchSection.HasKey(ruleKey) {
+			break
+		}
 		ruleName := fileMatchSection.Key(ruleKey).String()
 		
 		if ruleName == "" {
 			break
 		}
+		used[ruleKey] = true
+		used[ruleName] = true
 		
 		// Get rule section
-		ruleSection := cfg.Section(ruleName)
-		if ruleSection == nil {
-			continue
+		var issues []ImportIssue
+		entry := RuleImport{Name: ruleName, Line: lines.section(ruleName), Imported: true}
+		if !cfg.HasSection(ruleName) {
+			entry.Imported = false
+			entry.Line = lines.key("FileMatching", ruleKey)
+			entry.Error = fmt.Sprintf("%s names section [%s], which does not exist", ruleKey, ruleName)
 		}
+		ruleSection := newSectionReader(cfg, ruleName, lines, &issues)
 		
 		// Parse rule configuration
 		rule := Rule{
 			ID:          fmt.Sprintf("imported_%d", i),
 			Name:        ruleName,
-			Enabled:     !ruleSection.Key("Locked").MustBool(false),
-			Description: ruleSection.Key("Description").String(),
+			Enabled:     !ruleSection.boolean("Locked", false),
+			Description: ruleSection.str("Description"),
 			
 			// Matching criteria
-			DirRegEx:    ruleSection.Key("DirRegEx").String(),
-			FileRegEx:   ruleSection.Key("FileRegEx").String(),
-			ContentRegEx: ruleSection.Key("ContentRegEx").String(),
+			DirRegEx:    ruleSection.str("DirRegEx"),
+			FileRegEx:   ruleSection.str("FileRegEx"),
+			ContentRegEx: ruleSection.str("ContentRegEx"),
 			
 			// File operations
 			Operations: FileOperations{
-				CopyToDir:         ruleSection.Key("CopyToDir").String(),
-				CopyFileOption:    ruleSection.Key("CopyFileOption").MustInt(21),
-				CopyTempExtension: ruleSection.Key("CopyTempExtension").String(),
-				RenameFileTo:      ruleSection.Key("RenameFileTo").String(),
-				InsertTimestamp:   ruleSection.Key("InsertTimestamp").MustBool(false),
-				BackupToDir:       ruleSection.Key("BkpToDir").String(),
-				BackupFileOption:  ruleSection.Key("BkpFileOption").MustInt(21),
-				RemoveAfterCopy:   ruleSection.Key("RemoveAfterCopy").MustBool(true),
-				RemoveAfterHours:  ruleSection.Key("RemoveAfterHours").MustInt(0),
-				Overwrite:         ruleSection.Key("Overwrite").MustBool(true),
-				ExecProgBefore:    ruleSection.Key("ExecProgBefore").String(),
-				ExecProg:          ruleSection.Key("ExecProg").String(),
-				ExecProgError:     ruleSection.Key("ExecProgError").String(),
+				CopyToDir:         ruleSection.str("CopyToDir"),
+				CopyFileOption:    ruleSection.integer("CopyFileOption", 21),
+				CopyTempExtension: ruleSection.str("CopyTempExtension"),
+				RenameFileTo:      ruleSection.str("RenameFileTo"),
+				InsertTimestamp:   ruleSection.boolean("InsertTimestamp", false),
+				BackupToDir:       ruleSection.str("BkpToDir"),
+				BackupFileOption:  ruleSection.integer("BkpFileOption", 21),
+				RemoveAfterCopy:   ruleSection.boolean("RemoveAfterCopy", true),
+				RemoveAfterHours:  ruleSection.integer("RemoveAfterHours", 0),
+				Overwrite:         ruleSection.boolean("Overwrite", true),
+				ExecProgBefore:    ruleSection.str("ExecProgBefore"),
+				ExecProg:          ruleSection.str("ExecProg"),
+				ExecProgError:     ruleSection.str("ExecProgError"),
 			},
 			
 			// Time restrictions
 			TimeRestrictions: TimeRestrictions{
-				StartHour:        ruleSection.Key("StartDateHour").MustInt(0),
-				StartMinute:      ruleSection.Key("StartDateMinute").MustInt(0),
-				EndHour:          ruleSection.Key("EndDateHour").MustInt(23),
-				EndMinute:        ruleSection.Key("EndDateMinute").MustInt(59),
-				WeekDayInterval:  ruleSection.Key("WeekDayInterval").MustInt(127),
-				ProcessAfterSecs: ruleSection.Key("ProcessAfterSeconds").MustInt(0),
+				StartHour:        ruleSection.integer("StartDateHour", 0),
+				StartMinute:      ruleSection.integer("StartDateMinute", 0),
+				EndHour:          ruleSection.integer("EndDateHour", 23),
+				EndMinute:        ruleSection.integer("EndDateMinute", 59),
+				WeekDayInterval:  ruleSection.integer("WeekDayInterval", 127),
+				ProcessAfterSecs: ruleSection.integer("ProcessAfterSeconds", 0),
 			},
 			
-			// Processing options
+			// Processing options (per-rule keys override [General])
 			ProcessingOptions: ProcessingOptions{
-				CheckFileInUse: checkFileInUse,
-				MaxRetries:     maxRetries,
-				DelayRetry:     delayRetry,
-				DelayNextFile:  ruleSection.Key("DelayNextFileProcess").MustInt(0),
-				ScanSubDir:     scanSubDir,
+				CheckFileInUse: ruleSection.boolean("ScanCheckFileInUse", general.CheckFileInUse),
+				MaxRetries:     ruleSection.integer("MaxRetries", general.MaxRetries),
+				DelayRetry:     ruleSection.integer("DelayRetry", general.DelayRetry),
+				DelayNextFile:  ruleSection.integer("DelayNextFileProcess", 0),
+				ScanSubDir:     ruleSection.boolean("ScanSubDir", general.ScanSubDir),
 			},
 		}
+		ruleSection.reportUnknownKeys(ruleINIKeys)
 		
 		// If no DirRegEx specified, use the scan directory
-		if rule.DirRegEx == "" && scanDir != "" {
-			rule.DirRegEx = escapeRegex(scanDir)
+		if rule.DirRegEx == "" && general.ScanDir != "" {
+			rule.DirRegEx = escapeRegex(general.ScanDir)
 		}
+		if entry.Imported {
+			if rule.FileRegEx == "" {
+				issues = append(issues, ImportIssue{Section: ruleName, Key: "FileRegEx", Line: entry.Line,
+					Message: "no FileRegEx, the rule matches every file"})
+			}
+			if err := ValidateRule(rule); err != nil {
+				entry.Imported = false
+				entry.Error = err.Error()
+			}
+		}
+		entry.Warnings = issues
 		
 		rules = append(rules, rule)
+		report.Rules = append(report.Rules, entry)
+	}
+
+	// Entries past a gap in the RuleN numbering are never read
+	for _, key := range fileMatchSection.Keys() {
+		if !used[key.Name()] {
+			report.Warnings = append(report.Warnings, ImportIssue{Section: "FileMatching", Key: key.Name(),
+				Line: lines.key("FileMatching", key.Name()),
+				Message: fmt.Sprintf("ignored: rules are read from Rule0 up to the first missing number (Rule%d)", len(report.Rules))})
+		}
+	}
+	for _, section := range cfg.Sections() {
+		name := section.Name()
+		if used[name] || (name == ini.DefaultSection && len(section.Keys()) == 0) {
+			continue
+		}
+		report.IgnoredSections = append(report.IgnoredSections, ImportIssue{Section: name, Line: lines.section(name),
+			Message: "section is not listed in [FileMatching]"})
 	}
 	
-	return rules, nil
+	return rules, general, report, nil
+}
+
+// Keys read from each kind of section; anything else is reported as unknown
+var (
+	generalINIKeys = []string{"ScanDir", "ScanSubDir", "ScanCheckFileInUse", "MaxRetries", "DelayRetry"}
+	ruleINIKeys    = []string{
+		"Locked", "Description", "DirRegEx", "FileRegEx", "ContentRegEx",
+		"CopyToDir", "CopyFileOption", "CopyTempExtension", "RenameFileTo", "InsertTimestamp",
+		"BkpToDir", "BkpFileOption", "RemoveAfterCopy", "RemoveAfterHours", "Overwrite",
+		"ExecProgBefore", "ExecProg", "ExecProgError",
+		"StartDateHour", "StartDateMinute", "EndDateHour", "EndDateMinute", "WeekDayInterval", "ProcessAfterSeconds",
+		"ScanCheckFileInUse", "MaxRetries", "DelayRetry", "DelayNextFileProcess", "ScanSubDir",
+	}
+)
+
+// iniLines maps sections and keys to the line they are on
+type iniLines map[string]int
+
+func (l iniLines) section(name string) int {
+	return l["["+name+"]"]
+}
+
+func (l iniLines) key(section, key string) int {
+	return l["["+section+"]"+key]
+}
+
+// indexINILines records the first line of each section header and key. Keys
+// before any header belong to the DEFAULT section, as in ini.Load.
+func indexINILines(data []byte) iniLines {
+	lines := iniLines{}
+	section := ini.DefaultSection
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			if end := strings.IndexByte(line, ']'); end > 0 {
+				section = strings.TrimSpace(line[1:end])
+				if _, ok := lines["["+section+"]"]; !ok {
+					lines["["+section+"]"] = i + 1
+				}
+			}
+			continue
+		}
+		if end := strings.IndexAny(line, "=:"); end > 0 {
+			k := "[" + section + "]" + strings.TrimSpace(line[:end])
+			if _, ok := lines[k]; !ok {
+				lines[k] = i + 1
+			}
+		}
+	}
+	return lines
+}
+
+// sectionReader reads typed keys from a section like the Must* helpers do,
+// recording values that had to be replaced by their default
+type sectionReader struct {
+	section *ini.Section
+	lines   iniLines
+	issues  *[]ImportIssue
+}
+
+func newSectionReader(cfg *ini.File, name string, lines iniLines, issues *[]ImportIssue) sectionReader {
+	return sectionReader{section: cfg.Section(name), lines: lines, issues: issues}
+}
+
+func (r sectionReader) report(key, format string, args ...interface{}) {
+	*r.issues = append(*r.issues, ImportIssue{
+		Section: r.section.Name(),
+		Key:     key,
+		Line:    r.lines.key(r.section.Name(), key),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (r sectionReader) str(key string) string {
+	return r.section.Key(key).String()
+}
+
+func (r sectionReader) integer(key string, def int) int {
+	if !r.section.HasKey(key) {
+		return def
+	}
+	v, err := r.section.Key(key).Int()
+	if err != nil {
+		r.report(key, "%q is not a number, using default %d", r.str(key), def)
+		return def
+	}
+	return v
+}
+
+func (r sectionReader) boolean(key string, def bool) bool {
+	if !r.section.HasKey(key) {
+		return def
+	}
+	v, err := r.section.Key(key).Bool()
+	if err != nil {
+		r.report(key, "%q is not a boolean, using default %d", r.str(key), boolToInt(def))
+		return def
+	}
+	return v
+}
+
+// reportUnknownKeys records keys of the section the importer does not read
+func (r sectionReader) reportUnknownKeys(known []string) {
+	for _, key := range r.section.Keys() {
+		found := false
+		for _, k := range known {
+			if k == key.Name() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			r.report(key.Name(), "unknown key, ignored")
+		}
+	}
 }
 
-// ExportINI exports file watcher rules to INI format
-func ExportINI(rules []Rule, filePath string) error {
+// ExportINI exports file watcher rules and general settings to INI format.
+// Output re-imports via ImportINI to the same rules.
+func ExportINI(rules []Rule, general GeneralSettings, filePath string) error {
 	cfg := ini.Empty()
 	
 	// Add general section
 	generalSection, _ := cfg.NewSection("General")
-	generalSection.NewKey("ScanDir", "C:\\FileWatch")
-	generalSection.NewKey("ScanSubDir", "0")
-	generalSection.NewKey("ScanCheckFileInUse", "1")
-	generalSection.NewKey("MaxRetries", "5")
-	generalSection.NewKey("DelayRetry", "1000")
+	generalSection.NewKey("ScanDir", general.ScanDir)
+	generalSection.NewKey("ScanSubDir", strconv.Itoa(boolToInt(general.ScanSubDir)))
+	generalSection.NewKey("ScanCheckFileInUse", strconv.Itoa(boolToInt(general.CheckFileInUse)))
+	generalSection.NewKey("MaxRetries", strconv.Itoa(general.MaxRetries))
+	generalSection.NewKey("DelayRetry", strconv.Itoa(general.DelayRetry))
 	
 	// Add file matching section
 	fileMatchSection, _ := cfg.NewSection("FileMatching")
@@ -158,6 +407,10 @@ func ExportINI(rules []Rule, filePath string) error {
 		
 		// Processing options
 		ruleSection.NewKey("DelayNextFileProcess", strconv.Itoa(rule.ProcessingOptions.DelayNextFile))
+		ruleSection.NewKey("ScanCheckFileInUse", strconv.Itoa(boolToInt(rule.ProcessingOptions.CheckFileInUse)))
+		ruleSection.NewKey("MaxRetries", strconv.Itoa(rule.ProcessingOptions.MaxRetries))
+		ruleSection.NewKey("DelayRetry", strconv.Itoa(rule.ProcessingOptions.DelayRetry))
+		ruleSection.NewKey("ScanSubDir", strconv.Itoa(boolToInt(rule.ProcessingOptions.ScanSubDir)))
 	}
 	
 	return cfg.SaveTo(filePath)
@@ -0,0 +1,141 @@
+package filewatcher
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestINIRoundTrip(t *testing.T) {
+	rules, general, err := ImportINI(filepath.Join("testdata", "legacy.ini"))
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(rules))
+	}
+	if general.ScanDir != `C:\FileWatch\Inbound` || !general.ScanSubDir || general.MaxRetries != 7 {
+		t.Errorf("unexpected general settings: %+v", general)
+	}
+
+	// Per-rule overrides win over [General]
+	if rules[1].ProcessingOptions.MaxRetries != 2 || rules[1].ProcessingOptions.ScanSubDir {
+		t.Errorf("rule overrides not applied: %+v", rules[1].ProcessingOptions)
+	}
+	if rules[0].ProcessingOptions.DelayRetry != 2500 {
+		t.Errorf("rule should inherit DelayRetry from General, got %d", rules[0].ProcessingOptions.DelayRetry)
+	}
+
+	exported := filepath.Join(t.TempDir(), "export.ini")
+	if err := ExportINI(rules, general, exported); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	rules2, general2, err := ImportINI(exported)
+	if err != nil {
+		t.Fatalf("re-import failed: %v", err)
+	}
+	if general2 != general {
+		t.Errorf("general settings changed on round trip:\n got %+v\nwant %+v", general2, general)
+	}
+	if !reflect.DeepEqual(rules2, rules) {
+		t.Errorf("rules changed on round trip:\n got %+v\nwant %+v", rules2, rules)
+	}
+}
+
+func TestImportINIWithReport(t *testing.T) {
+	data := []byte(`[General]
+ScanDir=/data/in
+MaxRetries=lots
+
+[FileMatching]
+Rule0=Good
+Rule1=NoPattern
+Rule2=Missing
+Rule3=Broken
+Rule5=AfterGap
+
+[Good]
+FileRegEx=\.csv$
+DelayRetry=1s
+Colour=blue
+
+[NoPattern]
+CopyToDir=/data/out
+
+[Broken]
+FileRegEx=([
+
+[AfterGap]
+FileRegEx=\.txt$
+
+[Orphan]
+FileRegEx=\.xml$
+`)
+
+	rules, general, report, err := ImportINIDataWithReport(data)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if general.MaxRetries != 5 {
+		t.Errorf("unparseable MaxRetries should default to 5, got %d", general.MaxRetries)
+	}
+	if len(rules) != 2 || rules[0].Name != "Good" || rules[1].Name != "NoPattern" {
+		t.Fatalf("expected Good and NoPattern to be imported, got %+v", rules)
+	}
+	if rules[0].ProcessingOptions.DelayRetry != 1000 {
+		t.Errorf("unparseable DelayRetry should fall back to [General], got %d", rules[0].ProcessingOptions.DelayRetry)
+	}
+
+	if len(report.Warnings) != 2 ||
+		report.Warnings[0].Key != "MaxRetries" || report.Warnings[0].Line != 3 ||
+		report.Warnings[1].Key != "Rule5" || report.Warnings[1].Line != 10 {
+		t.Errorf("unexpected general warnings: %+v", report.Warnings)
+	}
+
+	if len(report.Rules) != 4 {
+		t.Fatalf("expected 4 rule reports, got %+v", report.Rules)
+	}
+	good := report.Rules[0]
+	if !good.Imported || good.Line != 12 || len(good.Warnings) != 2 ||
+		good.Warnings[0].Key != "DelayRetry" || good.Warnings[0].Line != 14 ||
+		good.Warnings[1].Key != "Colour" || good.Warnings[1].Line != 15 {
+		t.Errorf("unexpected report for Good: %+v", good)
+	}
+	if noPattern := report.Rules[1]; !noPattern.Imported || len(noPattern.Warnings) != 1 || noPattern.Warnings[0].Key != "FileRegEx" {
+		t.Errorf("expected a missing FileRegEx warning: %+v", noPattern)
+	}
+	if missing := report.Rules[2]; missing.Imported || missing.Line != 8 || missing.Error == "" {
+		t.Errorf("expected Missing to be reported as not imported: %+v", missing)
+	}
+	if broken := report.Rules[3]; broken.Imported || broken.Line != 20 || broken.Error == "" {
+		t.Errorf("expected Broken to fail validation: %+v", broken)
+	}
+
+	if len(report.IgnoredSections) != 2 ||
+		report.IgnoredSections[0].Section != "AfterGap" || report.IgnoredSections[0].Line != 23 ||
+		report.IgnoredSections[1].Section != "Orphan" || report.IgnoredSections[1].Line != 26 {
+		t.Errorf("unexpected ignored sections: %+v", report.IgnoredSections)
+	}
+
+	// ImportINI keeps returning every listed rule
+	all, _, err := ImportINIData(data)
+	if err != nil || len(all) != 4 {
+		t.Errorf("ImportINIData returned %d rules, err %v", len(all), err)
+	}
+}
+
+func TestImportINIWithReport_Clean(t *testing.T) {
+	rules, _, report, err := ImportINIWithReport(filepath.Join("testdata", "legacy.ini"))
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if len(rules) != 2 || len(report.Warnings) != 0 || len(report.IgnoredSections) != 0 {
+		t.Errorf("expected a clean report, got %+v", report)
+	}
+	for _, r := range report.Rules {
+		if !r.Imported || len(r.Warnings) != 0 {
+			t.Errorf("unexpected report for %s: %+v", r.Name, r)
+		}
+	}
+}
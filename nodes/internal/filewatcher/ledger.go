@@ -0,0 +1,275 @@
+package filewatcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLedgerTTL is how long processed file identities are remembered
+const DefaultLedgerTTL = 7 * 24 * time.Hour
+
+// fileIdentity identifies a specific version of a file on disk
+type fileIdentity struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Hash    string    `json:"hash"`
+}
+
+// ledgerEntry records when a file version was processed
+type ledgerEntry struct {
+	fileIdentity
+	ProcessedAt time.Time `json:"processedAt"`
+}
+
+// hashEntry records where and when a rule last saw some content
+type hashEntry struct {
+	Path   string    `json:"path"`
+	SeenAt time.Time `json:"seenAt"`
+}
+
+// ledgerFile is the on-disk ledger format
+type ledgerFile struct {
+	Files    map[string]ledgerEntry `json:"files"`              // keyed by rule + path
+	Hashes   map[string]hashEntry   `json:"hashes,omitempty"`   // keyed by rule + content hash
+	Removals map[string]time.Time   `json:"removals,omitempty"` // delivered file path -> when to delete it
+}
+
+// ledger persists the identities of processed files so a restart does not
+// hand the same file version to a rule again, plus content hashes for rules
+// that dedup by content and delivered files awaiting RemoveAfterHours cleanup.
+// It is saved periodically by the cleanup loop and on Stop.
+type ledger struct {
+	mu       sync.Mutex
+	path     string
+	ttl      time.Duration
+	entries  map[string]ledgerEntry
+	hashes   map[string]hashEntry
+	removals map[string]time.Time
+	dirty    bool
+}
+
+// loadLedger reads the ledger at path, dropping expired entries.
+// A missing file yields an empty ledger.
+func loadLedger(path string, ttl time.Duration) (*ledger, error) {
+	if ttl <= 0 {
+		ttl = DefaultLedgerTTL
+	}
+	l := &ledger{
+		path:     path,
+		ttl:      ttl,
+		entries:  make(map[string]ledgerEntry),
+		hashes:   make(map[string]hashEntry),
+		removals: make(map[string]time.Time),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return l, nil
+		}
+		return nil, fmt.Errorf("failed to read ledger: %w", err)
+	}
+	var file ledgerFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse ledger: %w", err)
+	}
+	if file.Files != nil {
+		l.entries = file.Files
+	}
+	if file.Hashes != nil {
+		l.hashes = file.Hashes
+	}
+	if file.Removals != nil {
+		l.removals = file.Removals
+	}
+	l.prune()
+	return l, nil
+}
+
+// identify computes the identity of the file at path
+func identify(path string) (fileIdentity, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileIdentity{}, err
+	}
+	hash, err := fileSHA256(path)
+	if err != nil {
+		return fileIdentity{}, err
+	}
+	return fileIdentity{Size: info.Size(), ModTime: info.ModTime(), Hash: hash}, nil
+}
+
+// entryKey keys processed records by rule as well as path, so rules watching
+// overlapping directories each get to process the file
+func entryKey(ruleKey, path string) string {
+	return ruleKey + ":" + path
+}
+
+// contains reports whether the rule already processed this version of the
+// file. Entries written before records were keyed by rule are still honored
+// until they expire.
+func (l *ledger) contains(ruleKey, path string, id fileIdentity) bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.entries[entryKey(ruleKey, path)]
+	if !ok {
+		entry, ok = l.entries[path]
+	}
+	if !ok || time.Since(entry.ProcessedAt) > l.ttl {
+		return false
+	}
+	return entry.Size == id.Size && entry.ModTime.Equal(id.ModTime) && entry.Hash == id.Hash
+}
+
+// record remembers that the rule processed this version of the file
+func (l *ledger) record(ruleKey, path string, id fileIdentity) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[entryKey(ruleKey, path)] = ledgerEntry{fileIdentity: id, ProcessedAt: time.Now()}
+	l.dirty = true
+}
+
+// seenHash returns the path where the rule last saw this content, if within window
+func (l *ledger) seenHash(ruleKey, hash string, window time.Duration) (string, bool) {
+	if l == nil {
+		return "", false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.hashes[ruleKey+":"+hash]
+	if !ok || time.Since(entry.SeenAt) > window || time.Since(entry.SeenAt) > l.ttl {
+		return "", false
+	}
+	return entry.Path, true
+}
+
+// recordHash remembers that the rule processed this content at path
+func (l *ledger) recordHash(ruleKey, hash, path string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hashes[ruleKey+":"+hash] = hashEntry{Path: path, SeenAt: time.Now()}
+	l.dirty = true
+}
+
+// forget drops the processed record for path and any content hashes the
+// rule saw at path, so the file is handed to the rule again
+func (l *ledger) forget(path, ruleKey string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, entryKey(ruleKey, path))
+	delete(l.entries, path)
+	prefix := ruleKey + ":"
+	for key, entry := range l.hashes {
+		if entry.Path == path && strings.HasPrefix(key, prefix) {
+			delete(l.hashes, key)
+		}
+	}
+	l.dirty = true
+}
+
+// scheduleRemoval records that path should be deleted at due
+func (l *ledger) scheduleRemoval(path string, due time.Time) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.removals[path] = due
+	l.dirty = true
+}
+
+// dueRemovals returns the scheduled paths whose removal time has passed
+func (l *ledger) dueRemovals(now time.Time) []string {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var due []string
+	for path, at := range l.removals {
+		if !now.Before(at) {
+			due = append(due, path)
+		}
+	}
+	return due
+}
+
+// completeRemoval forgets a scheduled removal
+func (l *ledger) completeRemoval(path string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.removals, path)
+	l.dirty = true
+}
+
+// prune drops entries older than the TTL. Callers other than loadLedger
+// must hold l.mu.
+func (l *ledger) prune() int {
+	count := 0
+	for path, entry := range l.entries {
+		if time.Since(entry.ProcessedAt) > l.ttl {
+			delete(l.entries, path)
+			count++
+		}
+	}
+	for key, entry := range l.hashes {
+		if time.Since(entry.SeenAt) > l.ttl {
+			delete(l.hashes, key)
+			count++
+		}
+	}
+	if count > 0 {
+		l.dirty = true
+	}
+	return count
+}
+
+// save prunes expired entries and writes the ledger if it changed
+func (l *ledger) save() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune()
+	if !l.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(ledgerFile{Files: l.entries, Hashes: l.hashes, Removals: l.removals})
+	if err != nil {
+		return fmt.Errorf("failed to encode ledger: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create ledger directory: %w", err)
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write ledger: %w", err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("failed to replace ledger: %w", err)
+	}
+	l.dirty = false
+	return nil
+}
@@ -0,0 +1,254 @@
+package filewatcher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestLedger_PersistsAcrossRestart(t *testing.T) {
+	dir := t.TempDir()
+	ledgerPath := filepath.Join(dir, "ledger.json")
+	src := filepath.Join(dir, "in", "data.csv")
+	out := filepath.Join(dir, "out")
+	if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, []byte("a,b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rule := Rule{Name: "copy", Operations: FileOperations{CopyToDir: out, CopyFileOption: 22, Overwrite: true}}
+
+	w := NewWatcher(zerolog.Nop(), nil)
+	if err := w.SetLedger(ledgerPath, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	w.processFile(src, rule)
+	if err := w.ledger.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh watcher loading the same ledger must skip the unchanged file
+	if err := os.Remove(filepath.Join(out, "data.csv")); err != nil {
+		t.Fatal(err)
+	}
+	w = NewWatcher(zerolog.Nop(), nil)
+	if err := w.SetLedger(ledgerPath, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	w.processFile(src, rule)
+	if _, err := os.Stat(filepath.Join(out, "data.csv")); !os.IsNotExist(err) {
+		t.Error("unchanged file should not be reprocessed after restart")
+	}
+
+	// A modified file is a new version and is processed again
+	if err := os.WriteFile(src, []byte("a,b,c"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.processFile(src, rule)
+	if _, err := os.Stat(filepath.Join(out, "data.csv")); err != nil {
+		t.Errorf("modified file should be processed: %v", err)
+	}
+}
+
+func TestLedger_ExpiresEntries(t *testing.T) {
+	l, err := loadLedger(filepath.Join(t.TempDir(), "ledger.json"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := fileIdentity{Size: 1, Hash: "abc"}
+	l.entries[entryKey("r1", "/old")] = ledgerEntry{fileIdentity: id, ProcessedAt: time.Now().Add(-2 * time.Hour)}
+
+	if l.contains("r1", "/old", id) {
+		t.Error("expired entry should not match")
+	}
+	if err := l.save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := l.entries[entryKey("r1", "/old")]; ok {
+		t.Error("expired entry should be pruned on save")
+	}
+}
+
+func TestLedger_OverlappingRules(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in", "data.csv")
+	if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, []byte("a,b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	archive := Rule{ID: "archive", Name: "archive", Operations: FileOperations{CopyToDir: filepath.Join(dir, "archive"), CopyFileOption: 22, Overwrite: true}}
+	upload := Rule{ID: "upload", Name: "upload", Operations: FileOperations{CopyToDir: filepath.Join(dir, "upload"), CopyFileOption: 22, Overwrite: true}}
+
+	w := NewWatcher(zerolog.Nop(), nil)
+	if err := w.SetLedger(filepath.Join(dir, "ledger.json"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	w.processFile(src, archive)
+	w.processFile(src, upload)
+
+	for _, out := range []string{"archive", "upload"} {
+		if _, err := os.Stat(filepath.Join(dir, out, "data.csv")); err != nil {
+			t.Errorf("rule %s should process the file: %v", out, err)
+		}
+	}
+
+	// Each rule still skips the version it already handled
+	if err := os.Remove(filepath.Join(dir, "upload", "data.csv")); err != nil {
+		t.Fatal(err)
+	}
+	w.processFile(src, upload)
+	if _, err := os.Stat(filepath.Join(dir, "upload", "data.csv")); !os.IsNotExist(err) {
+		t.Error("unchanged file should not be processed twice by the same rule")
+	}
+}
+
+func TestDedupByContentHash(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in")
+	out := filepath.Join(dir, "out")
+	dupes := filepath.Join(dir, "dupes")
+	if err := os.MkdirAll(in, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWatcher(zerolog.Nop(), nil)
+	if err := w.SetLedger(filepath.Join(dir, "ledger.json"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	rule := Rule{
+		ID:                 "feed",
+		Name:               "feed",
+		DedupByContentHash: true,
+		DuplicateToDir:     dupes,
+		Operations:         FileOperations{CopyToDir: out, CopyFileOption: 21, DirPerm: "0750"},
+	}
+
+	first := filepath.Join(in, "export_0900.csv")
+	second := filepath.Join(in, "export_1000.csv")
+	for _, f := range []string{first, second} {
+		if err := os.WriteFile(f, []byte("same rows"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w.processFile(first, rule)
+	w.processFile(second, rule)
+
+	if _, err := os.Stat(filepath.Join(out, "export_0900.csv")); err != nil {
+		t.Errorf("first file should be delivered: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "export_1000.csv")); !os.IsNotExist(err) {
+		t.Error("duplicate content should not be delivered")
+	}
+	if _, err := os.Stat(filepath.Join(dupes, "export_1000.csv")); err != nil {
+		t.Errorf("duplicate should be moved to duplicateToDir: %v", err)
+	}
+	if info, err := os.Stat(dupes); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0750 {
+		t.Errorf("duplicateToDir mode = %v, want dirPerm 0750", info.Mode().Perm())
+	}
+
+	// A second duplicate of the same name must not replace the first
+	if err := os.WriteFile(second, []byte("same rows"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(second, later, later); err != nil {
+		t.Fatal(err)
+	}
+	w.processFile(second, rule)
+	if _, err := os.Stat(filepath.Join(dupes, "export_1000(1).csv")); err != nil {
+		t.Errorf("second duplicate should be moved under a new name: %v", err)
+	}
+}
+
+func TestRemoveAfterHours(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in", "data.csv")
+	out := filepath.Join(dir, "out")
+	if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, []byte("a,b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rule := Rule{Name: "copy", Operations: FileOperations{CopyToDir: out, CopyFileOption: 22, Overwrite: true, RemoveAfterHours: 1}}
+
+	w := NewWatcher(zerolog.Nop(), nil)
+	if err := w.SetLedger(filepath.Join(dir, "ledger.json"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	w.processFile(src, rule)
+	dest := filepath.Join(out, "data.csv")
+	if _, ok := w.ledger.removals[dest]; !ok {
+		t.Fatal("delivered file should be scheduled for removal")
+	}
+
+	// Not yet due: the file stays
+	w.removeExpiredFiles()
+	if _, err := os.Stat(dest); err != nil {
+		t.Fatalf("file removed before removeAfterHours elapsed: %v", err)
+	}
+
+	w.ledger.scheduleRemoval(dest, time.Now().Add(-time.Minute))
+	w.removeExpiredFiles()
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("expired file should be removed")
+	}
+	if _, ok := w.ledger.removals[dest]; ok {
+		t.Error("completed removal should be forgotten")
+	}
+}
+
+func TestReprocessClearsLedger(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in", "data.csv")
+	out := filepath.Join(dir, "out")
+	if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, []byte("a,b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rule := Rule{ID: "r1", Name: "copy", Operations: FileOperations{CopyToDir: out, CopyFileOption: 22, Overwrite: true}}
+
+	w := NewWatcher(zerolog.Nop(), nil)
+	if err := w.SetLedger(filepath.Join(dir, "ledger.json"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	w.rules = []Rule{rule}
+	if err := w.Reprocess(src, "r1"); err != ErrNotRunning {
+		t.Fatalf("Reprocess on stopped watcher = %v, want ErrNotRunning", err)
+	}
+
+	w.stopped = false
+	w.stopChan = make(chan struct{})
+	defer close(w.stopChan)
+	w.workChan = make(chan fileJob, 1)
+
+	w.markFileProcessing(src)
+	w.processFile(src, rule)
+	if err := os.Remove(filepath.Join(out, "data.csv")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Reprocess(src, "missing"); !errors.Is(err, ErrRuleNotFound) {
+		t.Errorf("unknown rule: err = %v, want ErrRuleNotFound", err)
+	}
+	if err := w.Reprocess(src, "r1"); err != nil {
+		t.Fatalf("Reprocess: %v", err)
+	}
+	job := <-w.workChan
+	w.processFile(job.filePath, job.rule)
+	if _, err := os.Stat(filepath.Join(out, "data.csv")); err != nil {
+		t.Errorf("reprocessed file was not delivered again: %v", err)
+	}
+}
@@ -0,0 +1,318 @@
+package filewatcher
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Watch overflow policies, applied to directories past the watch limit
+const (
+	OverflowSkip = "skip" // leave them unwatched and report them (default)
+	OverflowPoll = "poll" // poll them for new and changed files
+)
+
+const (
+	// defaultPollInterval is how often overflow directories are polled
+	defaultPollInterval = 30 * time.Second
+
+	// watchWarnRatio is the share of the watch limit that triggers a warning
+	watchWarnRatio = 0.8
+
+	// inotifyMaxWatchesPath holds the Linux per-user inotify watch limit
+	inotifyMaxWatchesPath = "/proc/sys/fs/inotify/max_user_watches"
+)
+
+// WatchStats reports fsnotify watch usage against the limit
+type WatchStats struct {
+	Watches    int                 `json:"watches"`
+	MaxWatches int                 `json:"maxWatches"` // 0 = unlimited
+	Overflow   string              `json:"overflow"`
+	Polled     map[string][]string `json:"polled,omitempty"`    // rule name -> directories polled instead of watched
+	Unwatched  map[string][]string `json:"unwatched,omitempty"` // rule name -> directories not watched at all
+}
+
+// overflowDirs records the directories of one rule that did not get a watch
+type overflowDirs struct {
+	rule   string
+	dirs   []string
+	polled bool
+	stop   chan struct{} // closes the poller (polled only)
+}
+
+// fileStamp identifies a version of a polled file
+type fileStamp struct {
+	size    int64
+	modTime int64 // UnixNano
+}
+
+// SetWatchLimits caps the fsnotify watches the agent adds across all rules
+// and sets what happens to directories past the cap. maxWatches of 0 uses 90%
+// of the OS inotify limit where it can be read (Linux) and no cap elsewhere.
+// Changes take effect for rules started afterwards.
+func (w *Watcher) SetWatchLimits(maxWatches int, overflow string, pollInterval time.Duration) error {
+	switch overflow {
+	case "":
+		overflow = OverflowSkip
+	case OverflowSkip, OverflowPoll:
+	default:
+		return fmt.Errorf("unknown watch overflow policy %q (use %s or %s)", overflow, OverflowSkip, OverflowPoll)
+	}
+	if maxWatches <= 0 {
+		maxWatches = defaultMaxWatches()
+	}
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.maxWatches = maxWatches
+	w.watchOverflow = overflow
+	w.pollInterval = pollInterval
+	return nil
+}
+
+// defaultMaxWatches returns 90% of the per-user inotify watch limit, leaving
+// headroom for other processes, or 0 (no cap) if the limit cannot be read
+func defaultMaxWatches() int {
+	data, err := os.ReadFile(inotifyMaxWatchesPath)
+	if err != nil {
+		return 0
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || limit <= 0 {
+		return 0
+	}
+	return limit * 9 / 10
+}
+
+// isWatchLimitError reports whether err means the OS ran out of watches or
+// file descriptors
+func isWatchLimitError(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE)
+}
+
+// WatchStats returns the current watch usage and the directories that are
+// polled or unwatched because of the limit
+func (w *Watcher) WatchStats() WatchStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stats := WatchStats{
+		Watches:    w.watchesInUseLocked(),
+		MaxWatches: w.maxWatches,
+		Overflow:   w.watchOverflow,
+	}
+	if stats.Overflow == "" {
+		stats.Overflow = OverflowSkip
+	}
+	for _, o := range w.overflow {
+		target := &stats.Unwatched
+		if o.polled {
+			target = &stats.Polled
+		}
+		if *target == nil {
+			*target = make(map[string][]string)
+		}
+		(*target)[o.rule] = append((*target)[o.rule], o.dirs...)
+	}
+	return stats
+}
+
+// watchesInUseLocked counts the watches held by all fsnotify watchers.
+// Callers must hold w.mu.
+func (w *Watcher) watchesInUseLocked() int {
+	n := 0
+	for _, watcher := range w.watchers {
+		n += len(watcher.WatchList())
+	}
+	return n
+}
+
+// watchRoom returns how many more watches fit under the limit, or -1 if
+// there is no limit
+func (w *Watcher) watchRoom() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.maxWatches <= 0 {
+		return -1
+	}
+	if room := w.maxWatches - w.watchesInUseLocked(); room > 0 {
+		return room
+	}
+	return 0
+}
+
+// checkWatchUsage warns once when watch usage crosses watchWarnRatio of the limit
+func (w *Watcher) checkWatchUsage() {
+	w.mu.Lock()
+	if w.maxWatches <= 0 {
+		w.mu.Unlock()
+		return
+	}
+	used := w.watchesInUseLocked()
+	high := float64(used) >= watchWarnRatio*float64(w.maxWatches)
+	warn := high && !w.watchWarned
+	w.watchWarned = high
+	maxWatches := w.maxWatches
+	w.mu.Unlock()
+
+	if warn {
+		w.logger.Warn().
+			Int("watches", used).
+			Int("maxWatches", maxWatches).
+			Msg("⚠️ File watcher is approaching its watch limit; raise fileWatcherSettings.maxWatches (and fs.inotify.max_user_watches) or narrow the rules")
+	}
+}
+
+// handleOverflow reports directories of a rule that could not be watched and,
+// with the poll policy, starts polling them
+func (w *Watcher) handleOverflow(rule Rule, dirs []string, dirRegex, fileRegex *regexp.Regexp, cause string) {
+	w.mu.Lock()
+	dirs = w.unwatchedDirsLocked(dirs)
+	if len(dirs) == 0 {
+		w.mu.Unlock()
+		return
+	}
+	policy := w.watchOverflow
+	interval := w.pollInterval
+	maxWatches := w.maxWatches
+	alert := w.alertHandler
+	o := &overflowDirs{rule: rule.Name, dirs: dirs, polled: policy == OverflowPoll}
+	if o.polled {
+		o.stop = make(chan struct{})
+	}
+	if prev, ok := w.overflow[rule.key()]; ok && prev.stop != nil {
+		close(prev.stop)
+	}
+	w.overflow[rule.key()] = o
+	w.mu.Unlock()
+
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	sample := dirs
+	if len(sample) > 10 {
+		sample = sample[:10]
+	}
+	event := w.logger.Warn().
+		Str("rule", rule.Name).
+		Str("cause", cause).
+		Int("maxWatches", maxWatches).
+		Int("count", len(dirs)).
+		Strs("dirs", sample)
+	if o.polled {
+		event.Dur("pollInterval", interval).Msg("⚠️ Watch limit reached, polling the remaining directories")
+		seen := snapshotDirs(dirs)
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			w.pollDirs(rule, dirs, seen, dirRegex, fileRegex, interval, o.stop)
+		}()
+	} else {
+		event.Msg("⚠️ Watch limit reached, remaining directories are NOT watched")
+	}
+
+	if alert != nil {
+		alert("warning", fmt.Sprintf("File watcher rule %q: %d directories exceed the watch limit", rule.Name, len(dirs)), map[string]interface{}{
+			"rule":       rule.Name,
+			"cause":      cause,
+			"count":      len(dirs),
+			"dirs":       sample,
+			"polled":     o.polled,
+			"maxWatches": maxWatches,
+		})
+	}
+}
+
+// unwatchedDirsLocked returns dirs sorted and without duplicates or
+// directories another watcher already covers. Callers must hold w.mu.
+func (w *Watcher) unwatchedDirsLocked(dirs []string) []string {
+	watched := make(map[string]bool)
+	for _, watcher := range w.watchers {
+		for _, path := range watcher.WatchList() {
+			watched[path] = true
+		}
+	}
+	var result []string
+	for _, dir := range dirs {
+		if !watched[dir] {
+			watched[dir] = true
+			result = append(result, dir)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// stopOverflowLocked stops polling a rule's overflow directories and forgets
+// them. Callers must hold w.mu.
+func (w *Watcher) stopOverflowLocked(key string) {
+	if o, ok := w.overflow[key]; ok {
+		if o.stop != nil {
+			close(o.stop)
+		}
+		delete(w.overflow, key)
+	}
+}
+
+// pollDirs checks dirs for new and changed files every interval and hands
+// matching ones on like fsnotify events. Files in the seen snapshot taken when
+// polling started are not processed, as with a live watch.
+func (w *Watcher) pollDirs(rule Rule, dirs []string, seen map[string]fileStamp, dirRegex, fileRegex *regexp.Regexp, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			current := snapshotDirs(dirs)
+			var changed []string
+			for path, stamp := range current {
+				if prev, ok := seen[path]; ok && prev == stamp {
+					continue
+				}
+				changed = append(changed, path)
+			}
+			for _, path := range sortPaths(changed, rule.ProcessingOptions.ProcessOrder) {
+				if !w.dispatchWatchedFile(path, rule, dirRegex, fileRegex, "Poll") {
+					return
+				}
+			}
+			seen = current
+		case <-stop:
+			return
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+// snapshotDirs returns the size and modification time of the regular files
+// directly inside dirs
+func snapshotDirs(dirs []string) map[string]fileStamp {
+	files := make(map[string]fileStamp)
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			files[filepath.Join(dir, entry.Name())] = fileStamp{size: info.Size(), modTime: info.ModTime().UnixNano()}
+		}
+	}
+	return files
+}
@@ -0,0 +1,133 @@
+package filewatcher
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// Process orders for files handed on in a batch (existing files at start,
+// polled directories, files deferred while paused)
+const (
+	OrderName  = "name"  // by file name, e.g. sequential batch numbers
+	OrderMTime = "mtime" // oldest first
+	OrderSize  = "size"  // smallest first
+)
+
+func validProcessOrder(order string) bool {
+	switch order {
+	case "", OrderName, OrderMTime, OrderSize:
+		return true
+	}
+	return false
+}
+
+// sortPaths returns paths in the given process order, ties broken by name.
+// Without an order they are sorted by name so batches are still processed
+// deterministically. Files that can no longer be read are dropped.
+func sortPaths(paths []string, order string) []string {
+	type entry struct {
+		path string
+		info os.FileInfo
+	}
+	entries := make([]entry, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry{path, info})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch order {
+		case OrderMTime:
+			if !a.info.ModTime().Equal(b.info.ModTime()) {
+				return a.info.ModTime().Before(b.info.ModTime())
+			}
+		case OrderSize:
+			if a.info.Size() != b.info.Size() {
+				return a.info.Size() < b.info.Size()
+			}
+		}
+		if a.info.Name() != b.info.Name() {
+			return a.info.Name() < b.info.Name()
+		}
+		return a.path < b.path
+	})
+	sorted := make([]string, len(entries))
+	for i, e := range entries {
+		sorted[i] = e.path
+	}
+	return sorted
+}
+
+// existingFiles lists the regular files in dirs, descending into
+// subdirectories when recursive is set
+func existingFiles(dirs []string, recursive bool) []string {
+	var files []string
+	for _, dir := range dirs {
+		if !recursive {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				if entry.Type().IsRegular() {
+					files = append(files, filepath.Join(dir, entry.Name()))
+				}
+			}
+			continue
+		}
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && d.Type().IsRegular() {
+				files = append(files, path)
+			}
+			return nil
+		})
+	}
+	return files
+}
+
+// processExisting hands on the files already in a rule's directories when it
+// starts, in the rule's process order. Files arriving meanwhile are picked up
+// by the watchers, which are set up first; one seen twice is skipped as
+// already being processed.
+func (w *Watcher) processExisting(rule Rule, dirs []string, recursive bool, dirRegex, fileRegex *regexp.Regexp) {
+	files := sortPaths(existingFiles(dirs, recursive), rule.ProcessingOptions.ProcessOrder)
+	w.logger.Info().
+		Str("rule", rule.Name).
+		Int("files", len(files)).
+		Str("order", rule.ProcessingOptions.ProcessOrder).
+		Msg("📂 Processing existing files")
+	for _, path := range files {
+		if !w.dispatchWatchedFile(path, rule, dirRegex, fileRegex, "Existing") {
+			return
+		}
+	}
+}
+
+// queueFor returns the queue for a rule's files: the shared worker pool, or
+// for a serialized rule a queue of its own drained by a single worker, so its
+// files are processed one at a time in the order they were queued
+func (w *Watcher) queueFor(rule Rule) chan fileJob {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !rule.ProcessingOptions.Serialize || w.stopped {
+		return w.workChan
+	}
+	key := rule.key()
+	if queue, ok := w.serialQueues[key]; ok {
+		return queue
+	}
+	queue := make(chan fileJob, cap(w.workChan))
+	w.serialQueues[key] = queue
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.runWorker(queue)
+	}()
+	return queue
+}
@@ -0,0 +1,125 @@
+package filewatcher
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestSortPaths(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	files := []struct {
+		name string
+		size int
+		age  time.Duration
+	}{
+		{"batch-003.csv", 1, time.Hour},
+		{"batch-001.csv", 30, time.Minute},
+		{"batch-002.csv", 20, 2 * time.Hour},
+	}
+	var paths []string
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, make([]byte, f.size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-f.age), now.Add(-f.age)); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	paths = append(paths, filepath.Join(dir, "gone.csv"))
+
+	names := func(paths []string) []string {
+		var result []string
+		for _, p := range paths {
+			result = append(result, filepath.Base(p))
+		}
+		return result
+	}
+	for order, want := range map[string][]string{
+		"":         {"batch-001.csv", "batch-002.csv", "batch-003.csv"},
+		OrderName:  {"batch-001.csv", "batch-002.csv", "batch-003.csv"},
+		OrderMTime: {"batch-002.csv", "batch-003.csv", "batch-001.csv"},
+		OrderSize:  {"batch-003.csv", "batch-002.csv", "batch-001.csv"},
+	} {
+		if got := names(sortPaths(paths, order)); !reflect.DeepEqual(got, want) {
+			t.Errorf("order %q: %v, want %v", order, got, want)
+		}
+	}
+}
+
+// orderedWorkflows records the files workflows ran for
+type orderedWorkflows struct {
+	mu    sync.Mutex
+	files []string
+}
+
+func (o *orderedWorkflows) ExecuteWorkflow(name string, context map[string]interface{}) error {
+	return o.ExecuteWorkflowSync(name, context)
+}
+
+func (o *orderedWorkflows) ExecuteWorkflowSync(name string, context map[string]interface{}) error {
+	time.Sleep(5 * time.Millisecond) // give a parallel worker the chance to overtake
+	o.mu.Lock()
+	o.files = append(o.files, filepath.Base(context["file"].(string)))
+	o.mu.Unlock()
+	return nil
+}
+
+func TestProcessExisting_SerializedInOrder(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"txn-05.dat", "txn-01.dat", "txn-04.dat", "txn-02.dat", "txn-03.dat"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"txn-01.dat", "txn-02.dat", "txn-03.dat", "txn-04.dat", "txn-05.dat"}
+
+	workflows := &orderedWorkflows{}
+	w := NewWatcher(zerolog.Nop(), workflows)
+	rule := Rule{
+		ID:               "txn",
+		Name:             "transactions",
+		Enabled:          true,
+		DirRegEx:         dir,
+		FileRegEx:        `\.dat$`,
+		Operations:       FileOperations{ExecProg: "WF:import"},
+		TimeRestrictions: TimeRestrictions{EndHour: 23, EndMinute: 59, WeekDayInterval: 127},
+		ProcessingOptions: ProcessingOptions{
+			ProcessExisting: true,
+			ProcessOrder:    OrderName,
+			Serialize:       true,
+		},
+	}
+	if err := ValidateRule(rule); err != nil {
+		t.Fatal(err)
+	}
+	w.LoadRules([]Rule{rule})
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		workflows.mu.Lock()
+		n := len(workflows.files)
+		workflows.mu.Unlock()
+		if n == len(want) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	workflows.mu.Lock()
+	defer workflows.mu.Unlock()
+	if !reflect.DeepEqual(workflows.files, want) {
+		t.Errorf("processed %v, want %v", workflows.files, want)
+	}
+}
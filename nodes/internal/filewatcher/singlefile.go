@@ -0,0 +1,142 @@
+package filewatcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fileRewatchInterval is how often a file-mode rule retries its watch after
+// the file was renamed, removed or not there yet
+const fileRewatchInterval = 500 * time.Millisecond
+
+// startWatchingFile watches the single file named by a file-mode rule's DirRegEx.
+// The file does not have to exist yet; the watch is added once it appears.
+func (w *Watcher) startWatchingFile(rule Rule) error {
+	path := filepath.Clean(rule.DirRegEx)
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return fmt.Errorf("file mode path %s is a directory (use absolute mode to watch directories)", path)
+	}
+
+	watcherKey := rule.key() + ":" + path
+	w.mu.Lock()
+	if _, exists := w.watchers[watcherKey]; exists {
+		w.mu.Unlock()
+		w.logger.Debug().Str("file", path).Msg("Watcher already exists for file, skipping")
+		return nil
+	}
+	w.mu.Unlock()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+
+	w.mu.Lock()
+	w.watchers[watcherKey] = watcher
+	w.mu.Unlock()
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.handleFileEvents(watcher, rule, path)
+	}()
+
+	w.logger.Info().
+		Str("rule", rule.Name).
+		Str("file", path).
+		Msg("Started watching file")
+	return nil
+}
+
+// handleFileEvents processes events for a file-mode rule. Editors and log
+// rotation often replace a file by renaming a new one over it, which drops the
+// watch on the old inode, so after a Rename or Remove the watch is re-added
+// as soon as the path exists again and the replacement is processed.
+func (w *Watcher) handleFileEvents(watcher *fsnotify.Watcher, rule Rule, path string) {
+	var rewatch <-chan time.Time
+	if err := watcher.Add(path); err != nil {
+		w.logger.Warn().
+			Err(err).
+			Str("rule", rule.Name).
+			Str("file", path).
+			Msg("Watched file is not available yet, waiting for it")
+		rewatch = time.After(fileRewatchInterval)
+	}
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			w.logger.Debug().
+				Str("file", event.Name).
+				Str("event", event.Op.String()).
+				Str("rule", rule.Name).
+				Msg("📂 File event detected")
+
+			switch {
+			case event.Has(fsnotify.Rename) || event.Has(fsnotify.Remove):
+				watcher.Remove(path)
+				rewatch = time.After(fileRewatchInterval)
+			case event.Has(fsnotify.Write) || event.Has(fsnotify.Create):
+				if !w.dispatchWatchedFile(path, rule, nil, nil, event.Op.String()) {
+					return
+				}
+			}
+
+		case <-rewatch:
+			if err := watcher.Add(path); err != nil {
+				rewatch = time.After(fileRewatchInterval)
+				continue
+			}
+			rewatch = nil
+			w.logger.Info().
+				Str("rule", rule.Name).
+				Str("file", path).
+				Msg("👀 Watching replaced file")
+			if !w.dispatchWatchedFile(path, rule, nil, nil, "Replace") {
+				return
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Error().Err(err).Str("rule", rule.Name).Msg("Watcher error")
+
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+// dispatchWatchedFile applies a rule's match and time checks to a file seen
+// without an fsnotify directory event (file mode, polling) and hands it on for
+// processing. It returns false if the watcher was stopped.
+func (w *Watcher) dispatchWatchedFile(path string, rule Rule, dirRegex, fileRegex *regexp.Regexp, trigger string) bool {
+	if !w.matchesFile(path, rule, dirRegex, fileRegex) {
+		w.logger.Debug().
+			Str("file", path).
+			Str("rule", rule.Name).
+			Msg("❌ File did not match criteria")
+		return true
+	}
+	if !w.checkTimeRestrictions(rule.TimeRestrictions) {
+		w.eventLog(rule).
+			Str("file", path).
+			Msg("⏰ File matched but outside time window")
+		return true
+	}
+	if rule.ProcessingOptions.DebounceMs > 0 {
+		w.debounceFile(path, rule)
+		return true
+	}
+	return w.enqueueFile(path, rule, trigger)
+}
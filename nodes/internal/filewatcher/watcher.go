@@ -1,6 +1,10 @@
 package filewatcher
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -8,12 +12,16 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/rs/zerolog"
+	"github.com/your-org/controlcenter/nodes/internal/config"
+	"github.com/your-org/controlcenter/nodes/internal/throttle"
 )
 
 // Rule represents a file watching rule
@@ -24,14 +32,17 @@ type Rule struct {
 	Description       string            `json:"description"`
 
 	// Watch Mode Configuration
-	WatchMode         string            `json:"watchMode"`         // "absolute" or "pattern" (default: "absolute" for backward compat)
+	WatchMode         string            `json:"watchMode"`         // "absolute", "pattern" or "file" (default: "absolute" for backward compat)
 
 	// Matching criteria
 	// In pattern mode: DirRegEx is used to find directories under agent's ScanDir
 	// In absolute mode: DirRegEx is the direct path to watch (backward compatible)
+	// In file mode: DirRegEx is the path of a single file to watch
 	DirRegEx          string            `json:"dirRegex"`          // Regex for directory path or pattern
 	FileRegEx         string            `json:"fileRegex"`         // Regex for filename
 	ContentRegEx      string            `json:"contentRegex"`      // Regex for file content
+	ContentRegExAll   []string          `json:"contentRegexAll,omitempty"` // Content regexes that must all match
+	ContentRegExAny   []string          `json:"contentRegexAny,omitempty"` // Content regexes of which at least one must match
 	
 	// File operations
 	Operations        FileOperations    `json:"operations"`
@@ -41,6 +52,21 @@ type Rule struct {
 	
 	// Processing options
 	ProcessingOptions ProcessingOptions `json:"processingOptions"`
+
+	// Companion file guard ("trigger file" pattern)
+	RequireCompanionFile string `json:"requireCompanionFile"` // e.g. "{name}.done" - must exist next to the file before processing
+	CompanionTimeoutSecs int    `json:"companionTimeoutSecs"` // Max seconds to wait for the companion (0 = check once)
+	RemoveCompanionFile  bool   `json:"removeCompanionFile"`  // Delete the companion after successful processing
+
+	// Content dedup: skip files whose content this rule already processed under any name
+	DedupByContentHash bool   `json:"dedupByContentHash,omitempty"`
+	DedupWindowHours   int    `json:"dedupWindowHours,omitempty"` // How long content is remembered (default: ledger TTL)
+	DuplicateToDir     string `json:"duplicateToDir,omitempty"`   // Move duplicates here instead of leaving them in place
+
+	// Level matched-file events are logged at, e.g. "debug" for noisy rules
+	// (default: the global fileEventLogLevel, else info). Events for files
+	// that do not match are always logged at debug.
+	LogLevel string `json:"logLevel,omitempty"`
 }
 
 type FileOperations struct {
@@ -48,6 +74,9 @@ type FileOperations struct {
 	CopyToDir         string `json:"copyToDir"`
 	CopyFileOption    int    `json:"copyFileOption"`    // 21 = move, 22 = copy
 	CopyTempExtension string `json:"copyTempExtension"`
+	CopyToDirs        []string `json:"copyToDirs,omitempty"` // Additional destinations; the file is copied to CopyToDir and each of these
+	CopyToTemplate    string   `json:"copyToTemplate,omitempty"` // Full destination path rendered per file, e.g. "/archive/{{.year}}/{{.month}}/{{.day}}/{{.fileName}}"; used instead of CopyToDir and RenameFileTo
+	MaxBytesPerSecond int64    `json:"maxBytesPerSecond,omitempty"` // Bandwidth limit for this rule's copies (0 = unlimited)
 	
 	// Rename operations
 	RenameFileTo      string `json:"renameFileTo"`
@@ -59,13 +88,22 @@ type FileOperations struct {
 	
 	// Post-processing
 	RemoveAfterCopy   bool   `json:"removeAfterCopy"`
-	RemoveAfterHours  int    `json:"removeAfterHours"`
+	RemoveAfterHours  int    `json:"removeAfterHours"` // Delete delivered and backup copies this many hours after processing (0 = keep)
 	Overwrite         bool   `json:"overwrite"`
+	CollisionPolicy   string `json:"collisionPolicy,omitempty"` // overwrite, skip or rename when the destination exists (default: from overwrite)
+	PreserveTimestamps *bool `json:"preserveTimestamps,omitempty"` // Keep source mtime on copies (default: true)
+	VerifyChecksum    bool   `json:"verifyChecksum"`    // Compare SHA-256 of source and destination after copy/move
+	FilePerm          string `json:"filePerm,omitempty"` // Octal mode for written files, e.g. "0660" (default: 0644)
+	DirPerm           string `json:"dirPerm,omitempty"`  // Octal mode for created directories, e.g. "0770" (default: 0755)
+	Owner             string `json:"owner,omitempty"`    // User name or uid to chown written files to (Unix only)
+	Group             string `json:"group,omitempty"`    // Group name or gid to chown written files to (Unix only)
 	
 	// External programs
 	ExecProgBefore    string `json:"execProgBefore"`
 	ExecProg          string `json:"execProg"`
 	ExecProgError     string `json:"execProgError"`
+	OnSuccessDir      string `json:"onSuccessDir,omitempty"` // Move the file here after a WF: execProg workflow completes
+	OnFailureDir      string `json:"onFailureDir,omitempty"` // Move the file here after a WF: execProg workflow fails
 }
 
 type TimeRestrictions struct {
@@ -75,6 +113,7 @@ type TimeRestrictions struct {
 	EndMinute         int    `json:"endMinute"`
 	WeekDayInterval   int    `json:"weekDayInterval"`  // Bitmask for days
 	ProcessAfterSecs  int    `json:"processAfterSecs"`
+	Timezone          string `json:"timezone,omitempty"` // IANA zone the window is evaluated in, e.g. "America/New_York" (default: agent local time)
 }
 
 type ProcessingOptions struct {
@@ -83,6 +122,21 @@ type ProcessingOptions struct {
 	DelayRetry        int    `json:"delayRetry"`        // Milliseconds
 	DelayNextFile     int    `json:"delayNextFile"`     // Milliseconds
 	ScanSubDir        bool   `json:"scanSubDir"`
+	DebounceMs        int    `json:"debounceMs,omitempty"` // Wait until writes to a file have been quiet this long before processing (0 = off)
+	StableChecks      int    `json:"stableChecks,omitempty"`     // Samples of size+mtime that must all match before processing (0 = off; works on network shares, unlike checkFileInUse)
+	StableIntervalMs  int    `json:"stableIntervalMs,omitempty"` // Spacing between stableChecks samples (default: 1000)
+	ProcessExisting   bool   `json:"processExisting,omitempty"`  // Process files already in the watched directories when the rule starts
+	ProcessOrder      string `json:"processOrder,omitempty"`     // Order of batches (existing, polled or deferred files): name, mtime or size (default: name)
+	Serialize         bool   `json:"serialize,omitempty"`        // Process this rule's files one at a time, in the order they were queued
+}
+
+// defaultStableInterval spaces stableChecks samples when stableIntervalMs is unset
+const defaultStableInterval = 1000 * time.Millisecond
+
+// waitsForReadiness reports whether files must pass a lock or stability
+// check before processing
+func (p ProcessingOptions) waitsForReadiness() bool {
+	return p.CheckFileInUse || p.StableChecks > 0
 }
 
 // ProcessingFile tracks a file being processed
@@ -113,6 +167,51 @@ type Watcher struct {
 	maxConcurrent    int          // Max concurrent file processing workers (default: 3)
 	workChan         chan fileJob // Channel for worker pool jobs
 	wg               sync.WaitGroup // WaitGroup for worker pool shutdown
+	debounceMu       sync.Mutex
+	debounceTimers   map[string]*time.Timer // pending per-file debounce timers, keyed by rule + path
+	ledger           *ledger                // persisted identities of processed files (nil = disabled)
+	activeRules      map[string]string      // rule key -> fingerprint of rules with live watchers
+	transferLimiter  *throttle.Limiter      // agent-wide bandwidth limit shared with workflow steps (nil = unlimited)
+	pauseMu          sync.Mutex
+	paused           bool                   // intake paused (agent draining); matched files are deferred
+	deferred         map[string]Rule        // files matched while paused, enqueued again on Resume
+	inFlight         atomic.Int64           // files queued for or being processed by workers
+	queueSize        int                    // work queue capacity (0 = maxConcurrent*2)
+	startedPool      [2]int                 // maxConcurrent and queueSize the running workers were started with
+	backpressure     string                 // what to do when the work queue is full (BackpressureBlock by default)
+	alertHandler     func(level, message string, details map[string]interface{})
+	queueSaturated   atomic.Int64           // times a file found the work queue full
+	queueDropped     atomic.Int64           // queued files dropped to make room (drop-oldest)
+	queueRejected    atomic.Int64           // files rejected because the queue was full (reject-with-alert)
+	lastQueueAlert   atomic.Int64           // unix nanos of the last saturation alert
+	maxWatches       int                    // cap on fsnotify watches across rules (0 = unlimited)
+	watchOverflow    string                 // what to do with directories past the cap (OverflowSkip by default)
+	pollInterval     time.Duration          // how often overflow directories are polled
+	watchWarned      bool                   // usage is above watchWarnRatio and has been warned about
+	overflow         map[string]*overflowDirs // rule key -> directories without a watch
+	fileEventLevel   zerolog.Level          // default level for matched-file events (info)
+	serialQueues     map[string]chan fileJob // rule key -> queue of a serialized rule, each with one worker
+}
+
+// Backpressure policies for a full work queue
+const (
+	BackpressureBlock      = "block"             // wait for room; holds up the rule's event loop (default)
+	BackpressureDropOldest = "drop-oldest"       // drop the oldest queued file to make room
+	BackpressureReject     = "reject-with-alert" // skip the new file and raise an alert
+)
+
+// queueAlertInterval limits how often queue saturation alerts are raised
+const queueAlertInterval = time.Minute
+
+// QueueStats reports the state of the worker pool queue
+type QueueStats struct {
+	Length    int    `json:"length"`
+	Capacity  int    `json:"capacity"`
+	Policy    string `json:"policy"`
+	InFlight  int    `json:"inFlight"`
+	Saturated int64  `json:"saturated"` // times a file found the queue full
+	Dropped   int64  `json:"dropped"`   // queued files dropped by drop-oldest
+	Rejected  int64  `json:"rejected"`  // files rejected by reject-with-alert
 }
 
 // WorkflowExecutor interface for executing workflows
@@ -131,12 +230,57 @@ func NewWatcher(logger zerolog.Logger, executor WorkflowExecutor) *Watcher {
 		stopped:          true, // Start in stopped state so first Start() works cleanly
 		workflowExecutor: executor,
 		maxConcurrent:    3, // Default: 3 concurrent file processing workers
+		debounceTimers:   make(map[string]*time.Timer),
+		activeRules:      make(map[string]string),
+		overflow:         make(map[string]*overflowDirs),
+		fileEventLevel:   zerolog.InfoLevel,
+		serialQueues:     make(map[string]chan fileJob),
 	}
 
 	return w
 }
 
-// SetMaxConcurrent sets the maximum number of concurrent file processing workers
+// SetFileEventLogLevel sets the level matched-file events are logged at for
+// rules without their own logLevel. Empty means info.
+func (w *Watcher) SetFileEventLogLevel(level string) error {
+	parsed := zerolog.InfoLevel
+	if level != "" {
+		var err error
+		if parsed, err = parseEventLogLevel(level); err != nil {
+			return err
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.fileEventLevel = parsed
+	return nil
+}
+
+// parseEventLogLevel accepts the levels a rule's file events may be logged at
+func parseEventLogLevel(level string) (zerolog.Level, error) {
+	parsed, err := zerolog.ParseLevel(strings.ToLower(level))
+	if err != nil || parsed < zerolog.TraceLevel || parsed > zerolog.ErrorLevel {
+		return zerolog.NoLevel, fmt.Errorf("invalid log level %q (use trace, debug, info, warn or error)", level)
+	}
+	return parsed, nil
+}
+
+// eventLog starts a log entry for a matched-file event of rule, at the rule's
+// logLevel or the watcher's default
+func (w *Watcher) eventLog(rule Rule) *zerolog.Event {
+	w.mu.Lock()
+	level := w.fileEventLevel
+	w.mu.Unlock()
+	if rule.LogLevel != "" {
+		if parsed, err := parseEventLogLevel(rule.LogLevel); err == nil {
+			level = parsed
+		}
+	}
+	return w.logger.WithLevel(level)
+}
+
+// SetMaxConcurrent sets the maximum number of concurrent file processing
+// workers. Changes take effect the next time the watcher is started.
 func (w *Watcher) SetMaxConcurrent(n int) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
@@ -146,6 +290,60 @@ func (w *Watcher) SetMaxConcurrent(n int) {
 	w.maxConcurrent = n
 }
 
+// SetQueueSettings sets the work queue capacity and the policy applied when it
+// is full. A size of 0 keeps the default of twice the worker count. Changes
+// take effect the next time the watcher is started.
+func (w *Watcher) SetQueueSettings(size int, policy string) error {
+	switch policy {
+	case "":
+		policy = BackpressureBlock
+	case BackpressureBlock, BackpressureDropOldest, BackpressureReject:
+	default:
+		return fmt.Errorf("unknown backpressure policy %q (use %s, %s or %s)",
+			policy, BackpressureBlock, BackpressureDropOldest, BackpressureReject)
+	}
+	if size < 0 {
+		size = 0
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.queueSize = size
+	w.backpressure = policy
+	return nil
+}
+
+// SetAlertHandler sets the callback used to raise alerts, e.g. when the
+// work queue is saturated
+func (w *Watcher) SetAlertHandler(handler func(level, message string, details map[string]interface{})) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.alertHandler = handler
+}
+
+// QueueStats returns the current work queue length and saturation counters
+func (w *Watcher) QueueStats() QueueStats {
+	w.mu.Lock()
+	workChan := w.workChan
+	policy := w.backpressure
+	length := len(workChan)
+	for _, queue := range w.serialQueues {
+		length += len(queue)
+	}
+	w.mu.Unlock()
+	if policy == "" {
+		policy = BackpressureBlock
+	}
+	return QueueStats{
+		Length:    length,
+		Capacity:  cap(workChan),
+		Policy:    policy,
+		InFlight:  w.InFlight(),
+		Saturated: w.queueSaturated.Load(),
+		Dropped:   w.queueDropped.Load(),
+		Rejected:  w.queueRejected.Load(),
+	}
+}
+
 // SetGlobalSettings updates the global file watcher settings
 func (w *Watcher) SetGlobalSettings(scanDir string, scanSubDir bool) {
 	w.mu.Lock()
@@ -158,6 +356,38 @@ func (w *Watcher) SetGlobalSettings(scanDir string, scanSubDir bool) {
 		Msg("Updated global file watcher settings")
 }
 
+// WatcherCount returns the number of active fsnotify watchers
+func (w *Watcher) WatcherCount() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.watchers)
+}
+
+// SetTransferLimiter sets the agent-wide bandwidth limiter used for copies
+func (w *Watcher) SetTransferLimiter(l *throttle.Limiter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.transferLimiter = l
+}
+
+// SetLedger enables the processed-file ledger stored at path, so file versions
+// handled before a restart are not processed again. Call before Start.
+func (w *Watcher) SetLedger(path string, ttl time.Duration) error {
+	l, err := loadLedger(path, ttl)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.ledger = l
+	w.mu.Unlock()
+	w.logger.Info().
+		Str("path", path).
+		Int("entries", len(l.entries)).
+		Dur("ttl", l.ttl).
+		Msg("📒 Loaded processed file ledger")
+	return nil
+}
+
 // LoadRules loads file watching rules
 func (w *Watcher) LoadRules(rules []Rule) error {
 	w.rules = rules
@@ -165,17 +395,191 @@ func (w *Watcher) LoadRules(rules []Rule) error {
 	return nil
 }
 
-// UpdateRules updates the file watching rules and restarts watching
+// ValidateRule checks a rule for errors that would stop it from working
+// (bad regexes, invalid modes or out-of-range time windows)
+func ValidateRule(rule Rule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("rule name is required")
+	}
+
+	switch rule.WatchMode {
+	case "", "absolute", "pattern":
+	case "file":
+		if rule.DirRegEx == "" {
+			return fmt.Errorf("file mode requires the path of the file to watch")
+		}
+	default:
+		return fmt.Errorf("invalid watch mode %q", rule.WatchMode)
+	}
+
+	if rule.WatchMode == "pattern" && rule.DirRegEx != "" {
+		if _, err := regexp.Compile(rule.DirRegEx); err != nil {
+			return fmt.Errorf("invalid directory regex: %w", err)
+		}
+	}
+	if rule.FileRegEx != "" {
+		if _, err := regexp.Compile(rule.FileRegEx); err != nil {
+			return fmt.Errorf("invalid file regex: %w", err)
+		}
+	}
+	for _, pattern := range rule.contentPatterns() {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid content regex %q: %w", pattern, err)
+		}
+	}
+	if rule.LogLevel != "" {
+		if _, err := parseEventLogLevel(rule.LogLevel); err != nil {
+			return err
+		}
+	}
+	if !validProcessOrder(rule.ProcessingOptions.ProcessOrder) {
+		return fmt.Errorf("invalid process order %q (use name, mtime or size)", rule.ProcessingOptions.ProcessOrder)
+	}
+
+	if _, err := config.ParseFileMode(rule.Operations.FilePerm, 0644); err != nil {
+		return fmt.Errorf("invalid filePerm: %w", err)
+	}
+	if _, err := config.ParseFileMode(rule.Operations.DirPerm, 0755); err != nil {
+		return fmt.Errorf("invalid dirPerm: %w", err)
+	}
+	if !validCollisionPolicy(rule.Operations.CollisionPolicy) {
+		return fmt.Errorf("invalid collision policy %q", rule.Operations.CollisionPolicy)
+	}
+	if rule.Operations.CopyToTemplate != "" {
+		if _, err := parseDestTemplate(rule.Operations.CopyToTemplate); err != nil {
+			return fmt.Errorf("invalid copyToTemplate: %w", err)
+		}
+	}
+	if (rule.Operations.OnSuccessDir != "" || rule.Operations.OnFailureDir != "") && !strings.HasPrefix(rule.Operations.ExecProg, "WF:") {
+		return fmt.Errorf("onSuccessDir and onFailureDir require execProg to run a workflow (WF:)")
+	}
+
+	tr := rule.TimeRestrictions
+	if tr.StartHour < 0 || tr.StartHour > 23 || tr.EndHour < 0 || tr.EndHour > 23 {
+		return fmt.Errorf("time restriction hours must be 0-23")
+	}
+	if tr.StartMinute < 0 || tr.StartMinute > 59 || tr.EndMinute < 0 || tr.EndMinute > 59 {
+		return fmt.Errorf("time restriction minutes must be 0-59")
+	}
+	if tr.WeekDayInterval < 0 || tr.WeekDayInterval > 127 {
+		return fmt.Errorf("weekDayInterval must be a 7-bit day mask (0-127)")
+	}
+	if _, err := loadLocation(tr.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", tr.Timezone, err)
+	}
+
+	return nil
+}
+
+// GetRules returns a copy of the currently loaded rules
+func (w *Watcher) GetRules() []Rule {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Rule(nil), w.rules...)
+}
+
+// UpdateRules replaces the rule set. While running, only watchers of added,
+// removed or changed rules are touched; unchanged rules keep their watchers
+// so no events are missed during a config push.
 func (w *Watcher) UpdateRules(rules []Rule) {
 	w.mu.Lock()
 	w.rules = rules
-	// Reset the stop channel if it was closed
-	if w.stopped {
-		w.stopChan = make(chan struct{})
-		w.stopped = false
-	}
+	running := !w.stopped
 	w.mu.Unlock()
 	w.logger.Info().Int("count", len(rules)).Msg("Updated file watching rules")
+
+	if running {
+		w.syncRules(rules)
+	}
+}
+
+// PoolChanged reports whether the worker count or queue size was changed
+// since the running watcher was started, so it needs a restart to apply them
+func (w *Watcher) PoolChanged() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.stopped && w.startedPool != [2]int{w.maxConcurrent, w.queueSize}
+}
+
+// IsRunning reports whether the watcher has been started and not stopped
+func (w *Watcher) IsRunning() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.stopped
+}
+
+// syncRules stops watchers of rules that were removed or changed and starts
+// watchers for new or changed rules
+func (w *Watcher) syncRules(rules []Rule) {
+	desired := make(map[string]Rule)
+	for _, rule := range rules {
+		if rule.Enabled {
+			desired[rule.key()] = rule
+		}
+	}
+
+	w.mu.Lock()
+	var stale []string
+	for key, fingerprint := range w.activeRules {
+		rule, ok := desired[key]
+		if !ok || w.fingerprint(rule) != fingerprint {
+			stale = append(stale, key)
+		}
+	}
+	for _, key := range stale {
+		w.stopRuleLocked(key)
+	}
+	var added []Rule
+	for key, rule := range desired {
+		if _, ok := w.activeRules[key]; !ok {
+			added = append(added, rule)
+		}
+	}
+	w.mu.Unlock()
+
+	for _, rule := range added {
+		w.startRule(rule)
+	}
+
+	w.logger.Info().
+		Int("restarted", len(stale)).
+		Int("started", len(added)).
+		Int("unchanged", len(desired)-len(added)).
+		Msg("🔄 Applied rule changes")
+}
+
+// fingerprint captures everything that determines a rule's watchers and
+// behaviour. Callers must hold w.mu.
+func (w *Watcher) fingerprint(rule Rule) string {
+	data, _ := json.Marshal(rule)
+	if rule.WatchMode == "pattern" {
+		return fmt.Sprintf("%s|%s|%t", data, w.scanDir, w.scanSubDir)
+	}
+	return string(data)
+}
+
+// startRule starts watching a rule and records it as active
+func (w *Watcher) startRule(rule Rule) {
+	if err := w.startWatchingRule(rule); err != nil {
+		w.logger.Error().Err(err).Str("rule", rule.Name).Msg("Failed to start watching rule")
+		return
+	}
+	w.mu.Lock()
+	w.activeRules[rule.key()] = w.fingerprint(rule)
+	w.mu.Unlock()
+}
+
+// stopRuleLocked closes every watcher belonging to the rule. Callers must hold w.mu.
+func (w *Watcher) stopRuleLocked(key string) {
+	prefix := key + ":"
+	for watcherKey, watcher := range w.watchers {
+		if strings.HasPrefix(watcherKey, prefix) {
+			watcher.Close()
+			delete(w.watchers, watcherKey)
+		}
+	}
+	delete(w.activeRules, key)
+	w.stopOverflowLocked(key)
 }
 
 // Start begins watching based on configured rules
@@ -193,7 +597,13 @@ func (w *Watcher) Start() error {
 	w.stopChan = make(chan struct{})
 
 	// Create worker pool channel and start workers
-	w.workChan = make(chan fileJob, w.maxConcurrent*2)
+	queueSize := w.queueSize
+	if queueSize <= 0 {
+		queueSize = w.maxConcurrent * 2
+	}
+	w.workChan = make(chan fileJob, queueSize)
+	w.serialQueues = make(map[string]chan fileJob)
+	w.startedPool = [2]int{w.maxConcurrent, w.queueSize}
 	for i := 0; i < w.maxConcurrent; i++ {
 		w.wg.Add(1)
 		go w.fileWorker(i)
@@ -205,16 +615,16 @@ func (w *Watcher) Start() error {
 
 	w.mu.Unlock()
 
-	for _, rule := range w.rules {
+	w.mu.Lock()
+	rules := w.rules
+	w.mu.Unlock()
+
+	for _, rule := range rules {
 		if !rule.Enabled {
 			w.logger.Debug().Str("rule", rule.Name).Msg("Skipping disabled rule")
 			continue
 		}
-
-		if err := w.startWatchingRule(rule); err != nil {
-			w.logger.Error().Err(err).Str("rule", rule.Name).Msg("Failed to start watching rule")
-			continue
-		}
+		w.startRule(rule)
 	}
 
 	return nil
@@ -223,13 +633,19 @@ func (w *Watcher) Start() error {
 // fileWorker processes file jobs from the work channel
 func (w *Watcher) fileWorker(id int) {
 	defer w.wg.Done()
+	w.runWorker(w.workChan)
+}
+
+// runWorker processes jobs from queue until the watcher stops
+func (w *Watcher) runWorker(queue chan fileJob) {
 	for {
 		select {
-		case job, ok := <-w.workChan:
+		case job, ok := <-queue:
 			if !ok {
 				return
 			}
 			w.processFile(job.filePath, job.rule)
+			w.inFlight.Add(-1)
 		case <-w.stopChan:
 			return
 		}
@@ -253,12 +669,30 @@ func (w *Watcher) Stop() {
 		watcher.Close()
 	}
 	w.watchers = make(map[string]*fsnotify.Watcher)
+	w.activeRules = make(map[string]string)
+	// Pollers exit on stopChan
+	w.overflow = make(map[string]*overflowDirs)
 
 	w.mu.Unlock()
 
+	// Drop pending debounced events. They are not queued again on restart
+	// unless the rule processes existing files.
+	w.debounceMu.Lock()
+	for key, timer := range w.debounceTimers {
+		if timer.Stop() {
+			w.wg.Done()
+		}
+		delete(w.debounceTimers, key)
+	}
+	w.debounceMu.Unlock()
+
 	// Wait for all goroutines (workers, event handlers, cleanup) to finish
 	w.wg.Wait()
 
+	if err := w.ledger.save(); err != nil {
+		w.logger.Warn().Err(err).Msg("Failed to save processed file ledger")
+	}
+
 	w.logger.Info().Msg("File watchers stopped")
 }
 
@@ -279,9 +713,38 @@ func (w *Watcher) startWatchingRule(rule Rule) error {
 		}
 	}
 
+	if _, err := config.ParseFileMode(rule.Operations.FilePerm, 0644); err != nil {
+		return fmt.Errorf("invalid filePerm: %w", err)
+	}
+	if _, err := config.ParseFileMode(rule.Operations.DirPerm, 0755); err != nil {
+		return fmt.Errorf("invalid dirPerm: %w", err)
+	}
+	if !validCollisionPolicy(rule.Operations.CollisionPolicy) {
+		return fmt.Errorf("invalid collision policy %q", rule.Operations.CollisionPolicy)
+	}
+	if !validProcessOrder(rule.ProcessingOptions.ProcessOrder) {
+		return fmt.Errorf("invalid process order %q", rule.ProcessingOptions.ProcessOrder)
+	}
+	if rule.Operations.CopyToTemplate != "" {
+		if _, err := parseDestTemplate(rule.Operations.CopyToTemplate); err != nil {
+			return fmt.Errorf("invalid copyToTemplate: %w", err)
+		}
+	}
+	if _, err := loadLocation(rule.TimeRestrictions.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", rule.TimeRestrictions.Timezone, err)
+	}
+	if rule.DedupByContentHash && w.ledger == nil {
+		w.logger.Warn().
+			Str("rule", rule.Name).
+			Msg("⚠️ dedupByContentHash needs the processed file ledger, which is disabled; duplicates will not be detected")
+	}
+
 	var dirsToWatch []string
 
 	switch rule.WatchMode {
+	case "file":
+		return w.startWatchingFile(rule)
+
 	case "pattern":
 		// Pattern mode: scan agent's ScanDir for directories matching DirRegEx
 		if w.scanDir == "" {
@@ -349,9 +812,19 @@ func (w *Watcher) startWatchingRule(rule Rule) error {
 		Strs("directories", dirsToWatch).
 		Msg("Found directories to watch")
 
-	for _, dir := range dirsToWatch {
+	// Directories that could not get a watch because of the watch limit or
+	// the OS running out of watches/descriptors
+	var overflow []string
+	overflowCause := "maxWatches"
+	recursive := rule.WatchMode == "pattern" && w.scanSubDir
+	defer func() {
+		w.handleOverflow(rule, overflow, dirRegex, fileRegex, overflowCause)
+		w.checkWatchUsage()
+	}()
+
+	for i, dir := range dirsToWatch {
 		// Check if we already have a watcher for this directory+rule combo
-		watcherKey := rule.ID + ":" + dir
+		watcherKey := rule.key() + ":" + dir
 		w.mu.Lock()
 		if _, exists := w.watchers[watcherKey]; exists {
 			w.mu.Unlock()
@@ -360,8 +833,19 @@ func (w *Watcher) startWatchingRule(rule Rule) error {
 		}
 		w.mu.Unlock()
 
+		room := w.watchRoom()
+		if room == 0 {
+			overflow = append(overflow, w.collectOverflow(dirsToWatch[i:], recursive)...)
+			break
+		}
+
 		watcher, err := fsnotify.NewWatcher()
 		if err != nil {
+			if isWatchLimitError(err) {
+				overflowCause = err.Error()
+				overflow = append(overflow, w.collectOverflow(dirsToWatch[i:], recursive)...)
+				break
+			}
 			return fmt.Errorf("failed to create watcher: %w", err)
 		}
 
@@ -369,18 +853,31 @@ func (w *Watcher) startWatchingRule(rule Rule) error {
 		err = watcher.Add(dir)
 		if err != nil {
 			watcher.Close()
+			if isWatchLimitError(err) {
+				overflowCause = err.Error()
+				overflow = append(overflow, w.collectOverflow(dirsToWatch[i:], recursive)...)
+				break
+			}
 			return fmt.Errorf("failed to watch directory %s: %w", dir, err)
 		}
 
 		// If agent's ScanSubDir is true in pattern mode, add all subdirectories recursively
-		if rule.WatchMode == "pattern" && w.scanSubDir {
-			err = w.addSubdirsRecursive(watcher, dir)
-			if err != nil {
-				w.logger.Warn().Err(err).Str("dir", dir).Msg("Failed to add some subdirectories")
+		if recursive {
+			if room > 0 {
+				room--
+			}
+			skipped, cause := w.addSubdirsRecursive(watcher, dir, room)
+			if len(skipped) > 0 {
+				overflow = append(overflow, skipped...)
+				if cause != "" {
+					overflowCause = cause
+				}
 			}
 		}
 
+		w.mu.Lock()
 		w.watchers[watcherKey] = watcher
+		w.mu.Unlock()
 
 		// Start goroutine to handle events for this watcher
 		w.wg.Add(1)
@@ -399,6 +896,14 @@ func (w *Watcher) startWatchingRule(rule Rule) error {
 			Msg("Started watching directory")
 	}
 
+	if rule.ProcessingOptions.ProcessExisting {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			w.processExisting(rule, dirsToWatch, recursive, dirRegex, fileRegex)
+		}()
+	}
+
 	return nil
 }
 
@@ -410,8 +915,8 @@ func (w *Watcher) handleEvents(watcher *fsnotify.Watcher, rule Rule, dirRegex, f
 				return
 			}
 
-			// Log ALL file events at INFO level for troubleshooting
-			w.logger.Info().
+			// Every raw event is available at debug level for troubleshooting
+			w.logger.Debug().
 				Str("file", event.Name).
 				Str("event", event.Op.String()).
 				Str("rule", rule.Name).
@@ -419,7 +924,7 @@ func (w *Watcher) handleEvents(watcher *fsnotify.Watcher, rule Rule, dirRegex, f
 
 			// Check if file matches criteria
 			if !w.matchesFile(event.Name, rule, dirRegex, fileRegex) {
-				w.logger.Info().
+				w.logger.Debug().
 					Str("file", event.Name).
 					Str("rule", rule.Name).
 					Str("fileRegex", rule.FileRegEx).
@@ -428,14 +933,14 @@ func (w *Watcher) handleEvents(watcher *fsnotify.Watcher, rule Rule, dirRegex, f
 				continue
 			}
 
-			w.logger.Info().
+			w.eventLog(rule).
 				Str("file", event.Name).
 				Str("rule", rule.Name).
 				Msg("✅ File matched criteria")
 
 			// Check time restrictions
 			if !w.checkTimeRestrictions(rule.TimeRestrictions) {
-				w.logger.Info().
+				w.eventLog(rule).
 					Str("file", event.Name).
 					Msg("⏰ File matched but outside time window")
 				continue
@@ -443,39 +948,11 @@ func (w *Watcher) handleEvents(watcher *fsnotify.Watcher, rule Rule, dirRegex, f
 
 			// Process file
 			if event.Op&fsnotify.Create == fsnotify.Create || event.Op&fsnotify.Write == fsnotify.Write {
-				// Check if file is already being processed or was recently processed
-				if w.isFileBeingProcessed(event.Name) {
-					w.logger.Info().
-						Str("file", event.Name).
-						Str("rule", rule.Name).
-						Msg("⏸️ File is being processed or in cooldown period, skipping")
+				if rule.ProcessingOptions.DebounceMs > 0 {
+					w.debounceFile(event.Name, rule)
 					continue
 				}
-
-				w.logger.Info().
-					Str("rule", rule.Name).
-					Str("file", event.Name).
-					Str("event", event.Op.String()).
-					Str("dirRegex", rule.DirRegEx).
-					Str("fileRegex", rule.FileRegEx).
-					Msg("✅ File matched all criteria! Starting processing")
-
-				// Wait if configured
-				if rule.TimeRestrictions.ProcessAfterSecs > 0 {
-					w.logger.Info().
-						Str("file", event.Name).
-						Int("delaySecs", rule.TimeRestrictions.ProcessAfterSecs).
-						Msg("⏳ Waiting before processing file")
-					time.Sleep(time.Duration(rule.TimeRestrictions.ProcessAfterSecs) * time.Second)
-				}
-
-				// Mark file as being processed
-				w.markFileProcessing(event.Name)
-
-				// Send to worker pool for processing
-				select {
-				case w.workChan <- fileJob{filePath: event.Name, rule: rule}:
-				case <-w.stopChan:
+				if !w.enqueueFile(event.Name, rule, event.Op.String()) {
 					return
 				}
 			}
@@ -492,13 +969,392 @@ func (w *Watcher) handleEvents(watcher *fsnotify.Watcher, rule Rule, dirRegex, f
 	}
 }
 
+// enqueueFile hands a matched file to the worker pool unless it is already
+// being processed. It returns false if the watcher was stopped while waiting.
+func (w *Watcher) enqueueFile(filePath string, rule Rule, trigger string) bool {
+	// Check if file is already being processed or was recently processed
+	if w.isFileBeingProcessed(filePath) {
+		w.eventLog(rule).
+			Str("file", filePath).
+			Str("rule", rule.Name).
+			Msg("⏸️ File is being processed or in cooldown period, skipping")
+		return true
+	}
+	if w.deferIfPaused(filePath, rule) {
+		return true
+	}
+
+	w.eventLog(rule).
+		Str("rule", rule.Name).
+		Str("file", filePath).
+		Str("event", trigger).
+		Str("dirRegex", rule.DirRegEx).
+		Str("fileRegex", rule.FileRegEx).
+		Msg("✅ File matched all criteria! Starting processing")
+
+	// Wait if configured
+	if rule.TimeRestrictions.ProcessAfterSecs > 0 {
+		w.eventLog(rule).
+			Str("file", filePath).
+			Int("delaySecs", rule.TimeRestrictions.ProcessAfterSecs).
+			Msg("⏳ Waiting before processing file")
+		time.Sleep(time.Duration(rule.TimeRestrictions.ProcessAfterSecs) * time.Second)
+	}
+
+	// Mark file as being processed
+	w.markFileProcessing(filePath)
+
+	// Send to worker pool for processing
+	return w.submit(fileJob{filePath: filePath, rule: rule})
+}
+
+// submit hands a job to the workers, applying the backpressure policy when
+// the queue is full. It returns false if the watcher was stopped while waiting.
+func (w *Watcher) submit(job fileJob) bool {
+	queue := w.queueFor(job.rule)
+	w.inFlight.Add(1)
+	select {
+	case queue <- job:
+		return true
+	default:
+	}
+
+	w.mu.Lock()
+	policy := w.backpressure
+	w.mu.Unlock()
+	w.reportSaturation(job, policy)
+
+	switch policy {
+	case BackpressureReject:
+		w.inFlight.Add(-1)
+		w.processingFiles.Delete(job.filePath)
+		w.queueRejected.Add(1)
+		w.logger.Warn().
+			Str("file", job.filePath).
+			Str("rule", job.rule.Name).
+			Msg("🚫 Work queue full, file rejected")
+		return true
+
+	case BackpressureDropOldest:
+		for {
+			select {
+			case queue <- job:
+				return true
+			case <-w.stopChan:
+				w.inFlight.Add(-1)
+				return false
+			default:
+			}
+			select {
+			case old := <-queue:
+				w.inFlight.Add(-1)
+				w.processingFiles.Delete(old.filePath)
+				w.queueDropped.Add(1)
+				w.logger.Warn().
+					Str("file", old.filePath).
+					Str("rule", old.rule.Name).
+					Msg("🗑️ Work queue full, dropped oldest queued file")
+			default:
+			}
+		}
+	}
+
+	select {
+	case queue <- job:
+		return true
+	case <-w.stopChan:
+		w.inFlight.Add(-1)
+		return false
+	}
+}
+
+// reportSaturation counts a full queue and raises an alert at most once per
+// queueAlertInterval
+func (w *Watcher) reportSaturation(job fileJob, policy string) {
+	w.queueSaturated.Add(1)
+	now := time.Now().UnixNano()
+	last := w.lastQueueAlert.Load()
+	if now-last < int64(queueAlertInterval) || !w.lastQueueAlert.CompareAndSwap(last, now) {
+		return
+	}
+
+	stats := w.QueueStats()
+	w.logger.Warn().
+		Int("capacity", stats.Capacity).
+		Str("policy", policy).
+		Int64("saturated", stats.Saturated).
+		Msg("⚠️ File watcher work queue is full, files are arriving faster than they are processed")
+
+	w.mu.Lock()
+	handler := w.alertHandler
+	w.mu.Unlock()
+	if handler != nil {
+		handler("warning", "File watcher work queue is full", map[string]interface{}{
+			"file":      job.filePath,
+			"rule":      job.rule.Name,
+			"policy":    policy,
+			"capacity":  stats.Capacity,
+			"saturated": stats.Saturated,
+			"dropped":   stats.Dropped,
+			"rejected":  stats.Rejected,
+		})
+	}
+}
+
+// reportDeadLetter alerts that a file was given up on after maxRetries
+// readiness checks, so a stuck lock or permission problem does not go unnoticed
+func (w *Watcher) reportDeadLetter(filePath string, rule Rule, retries int) {
+	w.mu.Lock()
+	handler := w.alertHandler
+	w.mu.Unlock()
+	if handler == nil {
+		return
+	}
+	reason := "file was still changing"
+	if _, err := os.Stat(filePath); err != nil {
+		reason = err.Error()
+	} else if rule.ProcessingOptions.CheckFileInUse && isFileLocked(filePath) {
+		reason = "file is locked or not accessible"
+	}
+	handler("error", "File could not be processed after retries", map[string]interface{}{
+		"file":    filePath,
+		"rule":    rule.Name,
+		"ruleId":  rule.ID,
+		"retries": retries,
+		"reason":  reason,
+	})
+}
+
+// reportPartialDelivery alerts that a file reached only some destinations.
+// The source is kept but not retried automatically, so an operator has to
+// fix the failed destinations and reprocess it.
+func (w *Watcher) reportPartialDelivery(filePath string, rule Rule, delivered, failed []string) {
+	w.mu.Lock()
+	handler := w.alertHandler
+	w.mu.Unlock()
+	if handler == nil {
+		return
+	}
+	handler("error", "File was delivered to some destinations only", map[string]interface{}{
+		"file":      filePath,
+		"rule":      rule.Name,
+		"ruleId":    rule.ID,
+		"delivered": delivered,
+		"failed":    failed,
+	})
+}
+
+// isStopping reports whether the watcher is shutting down
+func (w *Watcher) isStopping() bool {
+	select {
+	case <-w.stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// deferIfPaused remembers the file for Resume and returns true while intake is paused
+func (w *Watcher) deferIfPaused(filePath string, rule Rule) bool {
+	w.pauseMu.Lock()
+	defer w.pauseMu.Unlock()
+	if !w.paused {
+		return false
+	}
+	if w.deferred == nil {
+		w.deferred = make(map[string]Rule)
+	}
+	w.deferred[filePath] = rule
+	w.logger.Info().
+		Str("file", filePath).
+		Str("rule", rule.Name).
+		Msg("⏸️ Intake paused, deferring file until resumed")
+	return true
+}
+
+// Pause stops handing newly matched files to the workers. Files already
+// queued or being processed are finished; new matches are held until Resume.
+func (w *Watcher) Pause() {
+	w.pauseMu.Lock()
+	w.paused = true
+	w.pauseMu.Unlock()
+	w.logger.Info().Msg("⏸️ File watcher intake paused")
+}
+
+// Resume restarts intake and enqueues files that matched while paused
+func (w *Watcher) Resume() {
+	w.pauseMu.Lock()
+	w.paused = false
+	deferred := w.deferred
+	w.deferred = nil
+	w.pauseMu.Unlock()
+
+	w.logger.Info().Int("deferred", len(deferred)).Msg("▶️ File watcher intake resumed")
+	if len(deferred) == 0 {
+		return
+	}
+	go func() {
+		// Hand files on rule by rule, each in its rule's process order
+		byRule := make(map[string][]string)
+		rules := make(map[string]Rule)
+		for filePath, rule := range deferred {
+			byRule[rule.key()] = append(byRule[rule.key()], filePath)
+			rules[rule.key()] = rule
+		}
+		keys := make([]string, 0, len(byRule))
+		for key := range byRule {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			rule := rules[key]
+			for _, filePath := range sortPaths(byRule[key], rule.ProcessingOptions.ProcessOrder) {
+				if !w.enqueueFile(filePath, rule, "resumed") {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// Errors returned by Reprocess
+var (
+	ErrNotRunning   = errors.New("file watcher is not running")
+	ErrPaused       = errors.New("file watcher intake is paused")
+	ErrRuleNotFound = errors.New("rule not found")
+	ErrFileBusy     = errors.New("file is currently being processed")
+	ErrQueueFull    = errors.New("work queue is full")
+)
+
+// reprocessQueueTimeout bounds how long Reprocess waits for room in the queue
+const reprocessQueueTimeout = 10 * time.Second
+
+// Reprocess forces filePath through the rule with ruleID again. The file's
+// cooldown and ledger records are cleared so it is not skipped as already
+// processed, and it is queued immediately without the rule's processing delay.
+func (w *Watcher) Reprocess(filePath, ruleID string) error {
+	w.mu.Lock()
+	running := !w.stopped && w.workChan != nil
+	stopChan := w.stopChan
+	var rule Rule
+	found := false
+	for _, r := range w.rules {
+		if r.ID == ruleID || r.key() == ruleID {
+			rule, found = r, true
+			break
+		}
+	}
+	w.mu.Unlock()
+
+	if !running {
+		return ErrNotRunning
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", ErrRuleNotFound, ruleID)
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("cannot reprocess %s: %w", filePath, err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("cannot reprocess %s: not a regular file", filePath)
+	}
+	if val, exists := w.processingFiles.Load(filePath); exists && val.(*ProcessingFile).endTime.IsZero() {
+		return ErrFileBusy
+	}
+	if w.IsPaused() {
+		return ErrPaused
+	}
+
+	w.processingFiles.Delete(filePath)
+	w.ledger.forget(filePath, rule.key())
+
+	w.logger.Info().
+		Str("file", filePath).
+		Str("rule", rule.Name).
+		Msg("🔁 Reprocessing file on request")
+
+	w.markFileProcessing(filePath)
+	queue := w.queueFor(rule)
+	w.inFlight.Add(1)
+	select {
+	case queue <- fileJob{filePath: filePath, rule: rule}:
+		return nil
+	case <-stopChan:
+		err = ErrNotRunning
+	case <-time.After(reprocessQueueTimeout):
+		err = ErrQueueFull
+	}
+	w.inFlight.Add(-1)
+	w.processingFiles.Delete(filePath)
+	return err
+}
+
+// IsPaused reports whether intake is paused
+func (w *Watcher) IsPaused() bool {
+	w.pauseMu.Lock()
+	defer w.pauseMu.Unlock()
+	return w.paused
+}
+
+// InFlight returns the number of files queued for or being processed
+func (w *Watcher) InFlight() int {
+	return int(w.inFlight.Load())
+}
+
+// debounceFile (re)starts the quiet-period timer for a file. The file is only
+// enqueued once no further events have arrived for DebounceMs. Pending timers
+// count towards the worker WaitGroup so Stop waits for them.
+func (w *Watcher) debounceFile(filePath string, rule Rule) {
+	delay := time.Duration(rule.ProcessingOptions.DebounceMs) * time.Millisecond
+	key := rule.key() + ":" + filePath
+
+	w.mu.Lock()
+	stopChan := w.stopChan
+	w.mu.Unlock()
+
+	w.debounceMu.Lock()
+	defer w.debounceMu.Unlock()
+
+	pending, exists := w.debounceTimers[key]
+	if !exists {
+		w.logger.Debug().
+			Str("file", filePath).
+			Dur("debounce", delay).
+			Msg("⏱️ Debouncing file events")
+	}
+	// A pending timer that is stopped in time hands its slot to the new one
+	if !exists || !pending.Stop() {
+		w.wg.Add(1)
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		defer w.wg.Done()
+
+		w.debounceMu.Lock()
+		if w.debounceTimers[key] == timer {
+			delete(w.debounceTimers, key)
+		}
+		w.debounceMu.Unlock()
+
+		select {
+		case <-stopChan:
+			return
+		default:
+		}
+		w.enqueueFile(filePath, rule, "debounced")
+	})
+	w.debounceTimers[key] = timer
+}
+
 func (w *Watcher) processFile(filePath string, rule Rule) {
 	// Ensure we mark the file as done processing when this function exits
 	defer w.markFileProcessed(filePath)
 
 	// Wait for file to become stable/unlocked in worker context to avoid
 	// blocking the fsnotify event loop.
-	if rule.ProcessingOptions.CheckFileInUse {
+	if rule.ProcessingOptions.waitsForReadiness() {
 		maxRetries := rule.ProcessingOptions.MaxRetries
 		if maxRetries <= 0 {
 			maxRetries = 5
@@ -508,11 +1364,71 @@ func (w *Watcher) processFile(filePath string, rule Rule) {
 			retryDelay = 1000 * time.Millisecond
 		}
 
-		if !w.waitForFileReady(filePath, maxRetries, retryDelay) {
+		if !w.waitForFileReady(filePath, rule.ProcessingOptions, maxRetries, retryDelay) {
+			if w.isStopping() {
+				return
+			}
 			w.logger.Warn().
 				Str("file", filePath).
 				Int("retries", maxRetries).
 				Msg("🔒 File still in use/unstable after retries, skipping")
+			w.reportDeadLetter(filePath, rule, maxRetries)
+			return
+		}
+	}
+
+	// Wait for the companion/marker file if the rule requires one
+	companionPath := ""
+	if rule.RequireCompanionFile != "" {
+		companionPath = w.companionPath(filePath, rule.RequireCompanionFile)
+		timeout := time.Duration(rule.CompanionTimeoutSecs) * time.Second
+		if !w.waitForCompanion(companionPath, timeout) {
+			w.logger.Warn().
+				Str("file", filePath).
+				Str("companion", companionPath).
+				Int("timeoutSecs", rule.CompanionTimeoutSecs).
+				Msg("🔗 Companion file not found, skipping")
+			return
+		}
+		w.logger.Info().
+			Str("file", filePath).
+			Str("companion", companionPath).
+			Msg("🔗 Companion file present")
+	}
+
+	// Skip file versions that were already handled, possibly before a restart.
+	// Hashing happens here in the worker rather than in the event loop.
+	var identity fileIdentity
+	tracked := false
+	if w.ledger != nil {
+		if id, err := identify(filePath); err == nil {
+			if w.ledger.contains(rule.key(), filePath, id) {
+				w.logger.Info().
+					Str("file", filePath).
+					Str("rule", rule.Name).
+					Msg("📒 File already processed (ledger), skipping")
+				return
+			}
+			identity, tracked = id, true
+		}
+	}
+
+	// Skip content this rule already handled under another name
+	if rule.DedupByContentHash && tracked {
+		window := time.Duration(rule.DedupWindowHours) * time.Hour
+		if window <= 0 {
+			window = w.ledger.ttl
+		}
+		if original, seen := w.ledger.seenHash(rule.key(), identity.Hash, window); seen {
+			w.logger.Info().
+				Str("file", filePath).
+				Str("rule", rule.Name).
+				Str("original", original).
+				Str("sha256", identity.Hash).
+				Msg("♻️ Duplicate content, skipping")
+			if rule.DuplicateToDir != "" {
+				w.moveDuplicate(filePath, rule.DuplicateToDir, rule.Operations)
+			}
 			return
 		}
 	}
@@ -524,34 +1440,49 @@ func (w *Watcher) processFile(filePath string, rule Rule) {
 
 	ops := rule.Operations
 
+	// Capture groups come from the name the rule matched, before any rename
+	groups := fileRegexGroups(rule.FileRegEx, filepath.Base(filePath))
+
 	// Execute pre-processing program
 	if ops.ExecProgBefore != "" {
 		w.logger.Info().
 			Str("file", filePath).
 			Str("program", ops.ExecProgBefore).
 			Msg("⚙️ Executing pre-processing program")
-		w.executeProgram(ops.ExecProgBefore, filePath)
+		w.executeProgram(ops.ExecProgBefore, filePath, groups)
 	}
 
-	// Prepare destination path
+	// Prepare destination file name (shared by all destinations)
 	destPath := filePath
-	if ops.CopyToDir != "" {
-		fileName := filepath.Base(filePath)
+	destDirs := ops.destinationDirs()
+	fileName := filepath.Base(filePath)
+	if len(destDirs) > 0 && ops.RenameFileTo != "" {
+		oldName := fileName
+		fileName = w.applyRename(fileName, ops.RenameFileTo, ops.InsertTimestamp)
+		w.logger.Info().
+			Str("oldName", oldName).
+			Str("newName", fileName).
+			Msg("📝 Applying rename")
+	}
 
-		// Apply rename if configured
-		if ops.RenameFileTo != "" {
-			oldName := fileName
-			fileName = w.applyRename(fileName, ops.RenameFileTo, ops.InsertTimestamp)
-			w.logger.Info().
-				Str("oldName", oldName).
-				Str("newName", fileName).
-				Msg("📝 Applying rename")
+	// copyToTemplate replaces CopyToDir; CopyToDirs still get the file too
+	var dests []string
+	if ops.CopyToTemplate != "" {
+		dest, err := renderDestination(ops.CopyToTemplate, filePath, rule, time.Now())
+		if err != nil {
+			w.logger.Error().Err(err).Str("file", filePath).Str("rule", rule.Name).Msg("❌ Failed to build destination path")
+			if ops.ExecProgError != "" {
+				w.executeProgram(ops.ExecProgError, filePath, groups)
+			}
+			return
 		}
-
-		destPath = filepath.Join(ops.CopyToDir, fileName)
-		w.logger.Info().
-			Str("destPath", destPath).
-			Msg("📍 Prepared destination path")
+		dests = append(dests, dest)
+	}
+	for _, dir := range destDirs {
+		if ops.CopyToTemplate != "" && dir == ops.CopyToDir {
+			continue
+		}
+		dests = append(dests, filepath.Join(dir, fileName))
 	}
 	
 	// Backup file if configured
@@ -561,110 +1492,368 @@ func (w *Watcher) processFile(filePath string, rule Rule) {
 			Str("file", filePath).
 			Str("backupPath", backupPath).
 			Msg("💾 Creating backup")
-		if err := w.copyFile(filePath, backupPath); err != nil {
+		if err := w.copyFile(filePath, backupPath, ops); err != nil {
 			w.logger.Error().Err(err).Str("file", filePath).Msg("❌ Failed to backup file")
 		} else {
 			w.logger.Info().Str("file", filePath).Str("backup", backupPath).Msg("✅ File backed up successfully")
+			w.scheduleRemoval(backupPath, ops)
 		}
 	}
 
-	// Copy or move file
-	if ops.CopyToDir != "" {
-		var err error
+	// Copy or move file to each destination
+	partial := false
+	if len(dests) > 0 {
+		// A file can only be moved to one place; with several destinations it
+		// is copied everywhere and the source removed once all copies succeed.
+		move := ops.CopyFileOption == 21 && len(dests) == 1
+
+		var delivered []string
+		var failed []string
+		for _, dest := range dests {
+			w.logger.Info().
+				Str("destPath", dest).
+				Msg("📍 Prepared destination path")
+
+			written, err := w.deliverFile(filePath, dest, ops, rule.ProcessingOptions, move)
+			if err != nil {
+				w.logger.Error().
+					Err(err).
+					Str("file", filePath).
+					Str("dest", dest).
+					Msg("❌ Failed to process file")
+				failed = append(failed, dest)
+				continue
+			}
+			if written != "" {
+				delivered = append(delivered, written)
+			}
+		}
+
+		if len(delivered) == 0 {
+			if len(failed) > 0 && ops.ExecProgError != "" {
+				w.logger.Info().
+					Str("program", ops.ExecProgError).
+					Msg("⚙️ Executing error handler program")
+				w.executeProgram(ops.ExecProgError, filePath, groups)
+			}
+			return
+		}
+		destPath = delivered[0]
+
+		if len(failed) > 0 {
+			partial = true
+			w.logger.Warn().
+				Str("file", filePath).
+				Strs("delivered", delivered).
+				Strs("failed", failed).
+				Msg("⚠️ File delivered to some destinations only, keeping source")
+			w.reportPartialDelivery(filePath, rule, delivered, failed)
+		} else if !move && (ops.RemoveAfterCopy || ops.CopyFileOption == 21) {
+			// Remove source if configured (and not already moved)
+			w.logger.Info().
+				Str("file", filePath).
+				Msg("🗑️ Removing source file after copy")
+			os.Remove(filePath)
+		}
+
+		w.logger.Info().
+			Str("source", filePath).
+			Strs("dest", delivered).
+			Msg("✅ File processed successfully")
+
+		for _, dest := range delivered {
+			w.scheduleRemoval(dest, ops)
+		}
+	}
+
+	// Remove companion file now that the data file has been handled
+	if companionPath != "" && rule.RemoveCompanionFile {
+		if err := os.Remove(companionPath); err != nil && !os.IsNotExist(err) {
+			w.logger.Warn().Err(err).Str("companion", companionPath).Msg("Failed to remove companion file")
+		} else {
+			w.logger.Info().Str("companion", companionPath).Msg("🗑️ Removed companion file")
+		}
+	}
+
+	// Execute post-processing program
+	if ops.ExecProg != "" {
+		w.logger.Info().
+			Str("file", destPath).
+			Str("program", ops.ExecProg).
+			Msg("⚙️ Executing post-processing program")
+		err := w.executeProgram(ops.ExecProg, destPath, groups)
+		if strings.HasPrefix(ops.ExecProg, "WF:") {
+			w.routeWorkflowInput(destPath, ops, err)
+		}
+	}
+
+	// Remember this file version unless some destinations still need it
+	if tracked && !partial {
+		w.ledger.record(rule.key(), filePath, identity)
+		if rule.DedupByContentHash {
+			w.ledger.recordHash(rule.key(), identity.Hash, filePath)
+		}
+	}
+	
+	// Delay before next file if configured
+	if rule.ProcessingOptions.DelayNextFile > 0 {
+		time.Sleep(time.Duration(rule.ProcessingOptions.DelayNextFile) * time.Millisecond)
+	}
+}
+
+// scheduleRemoval queues a delivered or backup copy for deletion once
+// RemoveAfterHours have passed. Schedules live in the ledger so they survive restarts.
+func (w *Watcher) scheduleRemoval(path string, ops FileOperations) {
+	if ops.RemoveAfterHours <= 0 {
+		return
+	}
+	if w.ledger == nil {
+		w.logger.Warn().Str("file", path).Msg("⚠️ removeAfterHours needs the processed file ledger, file will be kept")
+		return
+	}
+	due := time.Now().Add(time.Duration(ops.RemoveAfterHours) * time.Hour)
+	w.ledger.scheduleRemoval(path, due)
+	w.logger.Debug().Str("file", path).Time("removeAt", due).Msg("Scheduled file removal")
+}
+
+// removeExpiredFiles deletes files whose RemoveAfterHours age has passed
+func (w *Watcher) removeExpiredFiles() {
+	for _, path := range w.ledger.dueRemovals(time.Now()) {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			w.logger.Warn().Err(err).Str("file", path).Msg("Failed to remove expired file, will retry")
+			continue
+		}
+		w.ledger.completeRemoval(path)
+		w.logger.Info().Str("file", path).Msg("🗑️ Removed file after removeAfterHours")
+	}
+}
+
+// Collision policies for destinations that already exist
+const (
+	CollisionOverwrite = "overwrite"
+	CollisionSkip      = "skip"
+	CollisionRename    = "rename"
+)
+
+// collisionPolicy returns the effective policy, falling back to Overwrite
+func (ops FileOperations) collisionPolicy() string {
+	if ops.CollisionPolicy != "" {
+		return ops.CollisionPolicy
+	}
+	if ops.Overwrite {
+		return CollisionOverwrite
+	}
+	return CollisionSkip
+}
+
+// validCollisionPolicy reports whether p is empty or a known collision policy
+func validCollisionPolicy(p string) bool {
+	switch p {
+	case "", CollisionOverwrite, CollisionSkip, CollisionRename:
+		return true
+	}
+	return false
+}
+
+// key identifies the rule in watcher and ledger bookkeeping
+func (r Rule) key() string {
+	if r.ID != "" {
+		return r.ID
+	}
+	return r.Name
+}
+
+// moveDuplicate moves a duplicate file into dir, keeping its name unless a
+// file of that name is already there
+func (w *Watcher) moveDuplicate(filePath, dir string, ops FileOperations) {
+	if err := ops.makeDirs(dir); err != nil {
+		w.logger.Error().Err(err).Str("dir", dir).Msg("❌ Failed to create duplicate directory")
+		return
+	}
+	dest, err := w.reservePath(filepath.Join(dir, filepath.Base(filePath)), ops.fileMode())
+	if err != nil {
+		w.logger.Error().Err(err).Str("file", filePath).Str("dir", dir).Msg("❌ Failed to move duplicate")
+		return
+	}
+	if err := os.Rename(filePath, dest); err != nil {
+		// Fall back to copy + remove across filesystems
+		if err := w.copyFile(filePath, dest, ops); err != nil {
+			os.Remove(dest)
+			w.logger.Error().Err(err).Str("file", filePath).Str("dest", dest).Msg("❌ Failed to move duplicate")
+			return
+		}
+		os.Remove(filePath)
+	}
+	w.logger.Info().Str("file", filePath).Str("dest", dest).Msg("📦 Moved duplicate file")
+}
 
-		// Ensure destination directory exists
-		destDir := filepath.Dir(destPath)
-		if err := os.MkdirAll(destDir, 0755); err != nil {
-			w.logger.Error().
-				Err(err).
-				Str("dir", destDir).
-				Msg("❌ Failed to create destination directory")
+// routeWorkflowInput moves a file handed to a WF: execProg workflow into
+// OnSuccessDir or OnFailureDir depending on runErr. An existing file of the
+// same name is never overwritten; the moved file gets a numbered name instead.
+func (w *Watcher) routeWorkflowInput(filePath string, ops FileOperations, runErr error) {
+	dir, outcome := ops.OnSuccessDir, "success"
+	if runErr != nil {
+		dir, outcome = ops.OnFailureDir, "failure"
+	}
+	if dir == "" {
+		return
+	}
+	if !w.fileExists(filePath) {
+		w.logger.Warn().Str("file", filePath).Str("outcome", outcome).Msg("⚠️ Workflow input no longer exists, nothing to route")
+		return
+	}
+	if err := ops.makeDirs(dir); err != nil {
+		w.logger.Error().Err(err).Str("dir", dir).Msg("❌ Failed to create workflow outcome directory")
+		return
+	}
+	dest, err := w.reservePath(filepath.Join(dir, filepath.Base(filePath)), ops.fileMode())
+	if err != nil {
+		w.logger.Error().Err(err).Str("file", filePath).Str("dir", dir).Msg("❌ Failed to route workflow input")
+		return
+	}
+	if err := os.Rename(filePath, dest); err != nil {
+		// Fall back to copy + remove across filesystems
+		if err := w.copyFile(filePath, dest, ops); err != nil {
+			os.Remove(dest)
+			w.logger.Error().Err(err).Str("file", filePath).Str("dest", dest).Msg("❌ Failed to route workflow input")
 			return
 		}
+		os.Remove(filePath)
+	}
+	w.logger.Info().Str("file", filePath).Str("dest", dest).Str("outcome", outcome).Msg("📦 Routed workflow input")
+}
+
+// deliverFile copies or moves filePath to destPath, honouring collision policy,
+// temp extension, checksum and ownership options. It returns the path actually
+// written, or "" without error when the destination exists and is skipped.
+func (w *Watcher) deliverFile(filePath, destPath string, ops FileOperations, opts ProcessingOptions, move bool) (string, error) {
+	// Ensure destination directory exists
+	destDir := filepath.Dir(destPath)
+	if err := ops.makeDirs(destDir); err != nil {
+		return "", fmt.Errorf("failed to create destination directory: %w", err)
+	}
 
-		// Check if destination exists and overwrite setting
-		if !ops.Overwrite && w.fileExists(destPath) {
+	// Resolve an existing destination according to the collision policy
+	reserved := ""
+	if w.fileExists(destPath) {
+		switch ops.collisionPolicy() {
+		case CollisionSkip:
 			w.logger.Info().
 				Str("file", filePath).
 				Str("dest", destPath).
-				Msg("⚠️ Destination exists and overwrite is disabled, skipping")
-			return
-		}
-
-		// Use temp extension if configured
-		tempPath := destPath
-		if ops.CopyTempExtension != "" {
-			tempPath = destPath + ops.CopyTempExtension
+				Msg("⚠️ Destination exists and collision policy is skip, skipping")
+			return "", nil
+		case CollisionRename:
+			unique, err := w.reservePath(destPath, ops.fileMode())
+			if err != nil {
+				return "", err
+			}
 			w.logger.Info().
-				Str("tempPath", tempPath).
-				Msg("📝 Using temporary extension during copy")
+				Str("file", filePath).
+				Str("dest", destPath).
+				Str("renamedTo", unique).
+				Msg("📝 Destination exists, writing under a new name")
+			destPath, reserved = unique, unique
 		}
+	}
 
-		if ops.CopyFileOption == 21 { // Move
-			w.logger.Info().
-				Str("source", filePath).
-				Str("dest", tempPath).
-				Msg("📦 Moving file")
-			err = os.Rename(filePath, tempPath)
-		} else { // Copy
-			w.logger.Info().
-				Str("source", filePath).
-				Str("dest", tempPath).
-				Msg("📋 Copying file")
-			err = w.copyFile(filePath, tempPath)
-		}
+	// Use temp extension if configured
+	tempPath := destPath
+	if ops.CopyTempExtension != "" {
+		tempPath = destPath + ops.CopyTempExtension
+		w.logger.Info().
+			Str("tempPath", tempPath).
+			Msg("📝 Using temporary extension during copy")
+	}
 
-		if err != nil {
-			w.logger.Error().
-				Err(err).
-				Str("file", filePath).
-				Str("dest", tempPath).
-				Msg("❌ Failed to process file")
-			if ops.ExecProgError != "" {
-				w.logger.Info().
-					Str("program", ops.ExecProgError).
-					Msg("⚙️ Executing error handler program")
-				w.executeProgram(ops.ExecProgError, filePath)
+	var err error
+	if move && !ops.VerifyChecksum {
+		w.logger.Info().
+			Str("source", filePath).
+			Str("dest", tempPath).
+			Msg("📦 Moving file")
+		err = os.Rename(filePath, tempPath)
+	} else if move { // Verified move: copy, verify, then remove source
+		w.logger.Info().
+			Str("source", filePath).
+			Str("dest", tempPath).
+			Msg("📦 Moving file (copy + verify)")
+		if err = w.copyFileVerified(filePath, tempPath, ops, opts); err == nil {
+			if rmErr := os.Remove(filePath); rmErr != nil {
+				w.logger.Warn().Err(rmErr).Str("file", filePath).Msg("⚠️ Failed to remove source after verified move")
 			}
-			return
 		}
-
-		// Rename temp file to final name
-		if ops.CopyTempExtension != "" {
-			w.logger.Info().
-				Str("tempPath", tempPath).
-				Str("finalPath", destPath).
-				Msg("📝 Renaming temporary file to final name")
-			os.Rename(tempPath, destPath)
+	} else { // Copy
+		w.logger.Info().
+			Str("source", filePath).
+			Str("dest", tempPath).
+			Msg("📋 Copying file")
+		if ops.VerifyChecksum {
+			err = w.copyFileVerified(filePath, tempPath, ops, opts)
+		} else {
+			err = w.copyFile(filePath, tempPath, ops)
 		}
-
-		// Remove source if configured (and not already moved)
-		if ops.RemoveAfterCopy && ops.CopyFileOption != 21 {
-			w.logger.Info().
-				Str("file", filePath).
-				Msg("🗑️ Removing source file after copy")
-			os.Remove(filePath)
+	}
+	if err != nil {
+		if reserved != "" {
+			os.Remove(reserved)
 		}
+		return "", err
+	}
 
-		w.logger.Info().
-			Str("source", filePath).
-			Str("dest", destPath).
-			Msg("✅ File processed successfully")
+	// Hand off ownership before the file appears under its final name
+	if ops.Owner != "" || ops.Group != "" {
+		w.applyOwnership(tempPath, ops)
 	}
 
-	// Execute post-processing program
-	if ops.ExecProg != "" {
+	// Rename temp file to final name
+	if ops.CopyTempExtension != "" {
 		w.logger.Info().
-			Str("file", destPath).
-			Str("program", ops.ExecProg).
-			Msg("⚙️ Executing post-processing program")
-		w.executeProgram(ops.ExecProg, destPath)
+			Str("tempPath", tempPath).
+			Str("finalPath", destPath).
+			Msg("📝 Renaming temporary file to final name")
+		os.Rename(tempPath, destPath)
 	}
-	
-	// Delay before next file if configured
-	if rule.ProcessingOptions.DelayNextFile > 0 {
-		time.Sleep(time.Duration(rule.ProcessingOptions.DelayNextFile) * time.Millisecond)
+
+	return destPath, nil
+}
+
+// reservePath claims path, or the first free "(N)" variant of it before the
+// extension (e.g. report(1).csv), by creating an empty placeholder with
+// O_EXCL, so concurrent deliveries never pick the same name. Callers write
+// over the placeholder and remove it if they fail.
+func (w *Watcher) reservePath(path string, mode os.FileMode) (string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	candidate := path
+	for i := 1; ; i++ {
+		f, err := os.OpenFile(candidate, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+		if err == nil {
+			return candidate, f.Close()
+		}
+		if !os.IsExist(err) {
+			return "", fmt.Errorf("failed to reserve %s: %w", candidate, err)
+		}
+		if i > 9999 {
+			candidate = fmt.Sprintf("%s_%s%s", base, time.Now().Format("20060102_150405.000000000"), ext)
+			continue
+		}
+		candidate = fmt.Sprintf("%s(%d)%s", base, i, ext)
+	}
+}
+
+// destinationDirs returns CopyToDir followed by CopyToDirs, without duplicates
+func (ops FileOperations) destinationDirs() []string {
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, dir := range append([]string{ops.CopyToDir}, ops.CopyToDirs...) {
+		if dir == "" || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		dirs = append(dirs, dir)
 	}
+	return dirs
 }
 
 func (w *Watcher) matchesFile(filePath string, rule Rule, dirRegex, fileRegex *regexp.Regexp) bool {
@@ -697,19 +1886,18 @@ func (w *Watcher) matchesFile(filePath string, rule Rule, dirRegex, fileRegex *r
 		}
 	}
 	
-	// Check content regex if configured
-	if rule.ContentRegEx != "" {
-		content, err := os.ReadFile(filePath)
-		if err != nil {
-			return false
-		}
-		
-		contentRegex, err := regexp.Compile(rule.ContentRegEx)
+	// Check content patterns if configured
+	if len(rule.contentPatterns()) > 0 {
+		matched, err := w.matchesContent(filePath, rule)
 		if err != nil {
+			w.logger.Debug().Err(err).Str("file", filePath).Msg("Content check failed")
 			return false
 		}
-		
-		if !contentRegex.Match(content) {
+		w.logger.Debug().
+			Str("file", filePath).
+			Bool("matched", matched).
+			Msg("Content regex check")
+		if !matched {
 			return false
 		}
 	}
@@ -718,45 +1906,128 @@ func (w *Watcher) matchesFile(filePath string, rule Rule, dirRegex, fileRegex *r
 }
 
 func (w *Watcher) checkTimeRestrictions(restrictions TimeRestrictions) bool {
+	return restrictions.allowedAt(time.Now())
+}
+
+// allowedAt reports whether the restrictions permit processing at t.
+// A window whose end is before its start (e.g. 22:00-02:00) spans midnight.
+func (r TimeRestrictions) allowedAt(t time.Time) bool {
 	// Zero values mean "no restrictions" — allow all times
-	if restrictions.StartHour == 0 && restrictions.StartMinute == 0 &&
-		restrictions.EndHour == 0 && restrictions.EndMinute == 0 &&
-		restrictions.WeekDayInterval == 0 {
+	if r.StartHour == 0 && r.StartMinute == 0 &&
+		r.EndHour == 0 && r.EndMinute == 0 &&
+		r.WeekDayInterval == 0 {
 		return true
 	}
 
-	now := time.Now()
+	// Invalid zones are rejected by ValidateRule; fall back to local time here
+	if loc, err := loadLocation(r.Timezone); err == nil {
+		t = t.In(loc)
+	}
+
+	currentMinutes := t.Hour()*60 + t.Minute()
+	startMinutes := r.StartHour*60 + r.StartMinute
+	endMinutes := r.EndHour*60 + r.EndMinute
+
+	// The window "belongs" to the day it opens on: in the after-midnight part
+	// of an overnight window, the day mask is checked against the previous day.
+	windowDay := t.Weekday()
+
+	// Check time of day
+	if endMinutes < startMinutes {
+		switch {
+		case currentMinutes >= startMinutes:
+		case currentMinutes <= endMinutes:
+			windowDay = t.AddDate(0, 0, -1).Weekday()
+		default:
+			return false
+		}
+	} else if currentMinutes < startMinutes || currentMinutes > endMinutes {
+		return false
+	}
 
 	// Check day of week
-	if restrictions.WeekDayInterval > 0 {
-		dayMask := 1 << uint(now.Weekday())
-		if restrictions.WeekDayInterval&dayMask == 0 {
+	if r.WeekDayInterval > 0 {
+		dayMask := 1 << uint(windowDay)
+		if r.WeekDayInterval&dayMask == 0 {
 			return false
 		}
 	}
 
-	// Check time of day
-	currentMinutes := now.Hour()*60 + now.Minute()
-	startMinutes := restrictions.StartHour*60 + restrictions.StartMinute
-	endMinutes := restrictions.EndHour*60 + restrictions.EndMinute
+	return true
+}
 
-	if currentMinutes < startMinutes || currentMinutes > endMinutes {
-		return false
+// locationCache avoids re-reading tzdata for every file event
+var locationCache sync.Map // map[string]*time.Location
+
+// loadLocation resolves an IANA timezone name; "" means the agent's local zone
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	if loc, ok := locationCache.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
 	}
+	locationCache.Store(name, loc)
+	return loc, nil
+}
 
-	return true
+// NextAllowedTime returns the earliest time at or after from at which the
+// restrictions allow processing, at minute resolution. It returns the zero
+// time if the restrictions never allow processing (e.g. an empty day mask).
+func NextAllowedTime(restrictions TimeRestrictions, from time.Time) time.Time {
+	if restrictions.allowedAt(from) {
+		return from
+	}
+
+	// Windows repeat weekly, so searching eight days covers every case
+	t := from.Truncate(time.Minute)
+	for limit := t.Add(8 * 24 * time.Hour); t.Before(limit); t = t.Add(time.Minute) {
+		if t.After(from) && restrictions.allowedAt(t) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// companionPath resolves a companion pattern ({filename}, {name}, {ext}) to a
+// path in the same directory as filePath
+func (w *Watcher) companionPath(filePath, pattern string) string {
+	fileName := filepath.Base(filePath)
+	name := strings.ReplaceAll(pattern, "{filename}", fileName)
+	name = strings.ReplaceAll(name, "{name}", strings.TrimSuffix(fileName, filepath.Ext(fileName)))
+	name = strings.ReplaceAll(name, "{ext}", filepath.Ext(fileName))
+	return filepath.Join(filepath.Dir(filePath), name)
+}
+
+// waitForCompanion polls for a companion file until it exists or timeout elapses
+func (w *Watcher) waitForCompanion(path string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if w.fileExists(path) {
+			return true
+		}
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		select {
+		case <-time.After(500 * time.Millisecond):
+		case <-w.stopChan:
+			return false
+		}
+	}
 }
 
-func (w *Watcher) waitForFileReady(filePath string, maxRetries int, retryDelay time.Duration) bool {
+func (w *Watcher) waitForFileReady(filePath string, opts ProcessingOptions, maxRetries int, retryDelay time.Duration) bool {
 	if retryDelay <= 0 {
 		retryDelay = 1000 * time.Millisecond
 	}
 
-	// Probe window for stability checks (size/mtime should stop changing).
-	stabilityWindow := 500 * time.Millisecond
-
 	for attempt := 0; attempt < maxRetries; attempt++ {
-		if !w.isFileInUse(filePath, stabilityWindow) {
+		if w.isFileReady(filePath, opts) {
 			w.logger.Info().
 				Str("file", filePath).
 				Int("attempt", attempt+1).
@@ -780,26 +2051,55 @@ func (w *Watcher) waitForFileReady(filePath string, maxRetries int, retryDelay t
 	return false
 }
 
+// isFileReady runs the checks the rule asks for: the lock probe when
+// checkFileInUse is set, then either stableChecks samples stableIntervalMs
+// apart or, for the lock probe alone, a single 500ms stability window
+func (w *Watcher) isFileReady(filePath string, opts ProcessingOptions) bool {
+	if opts.StableChecks <= 0 {
+		return !w.isFileInUse(filePath, 500*time.Millisecond)
+	}
+	if opts.CheckFileInUse && isFileLocked(filePath) {
+		return false
+	}
+	interval := time.Duration(opts.StableIntervalMs) * time.Millisecond
+	if interval <= 0 {
+		interval = defaultStableInterval
+	}
+	return w.isFileStable(filePath, opts.StableChecks, interval)
+}
+
+// isFileStable samples the file's size and mtime checks times, interval
+// apart, and reports whether every sample matched. It needs no open handle,
+// so it also works for SMB/NFS shares where lock probing is unreliable.
+func (w *Watcher) isFileStable(filePath string, checks int, interval time.Duration) bool {
+	if checks < 2 {
+		checks = 2
+	}
+	first, err := os.Stat(filePath)
+	if err != nil {
+		return false
+	}
+	for i := 1; i < checks; i++ {
+		select {
+		case <-time.After(interval):
+		case <-w.stopChan:
+			return false
+		}
+		info, err := os.Stat(filePath)
+		if err != nil || info.Size() != first.Size() || !info.ModTime().Equal(first.ModTime()) {
+			return false
+		}
+	}
+	return true
+}
+
 func (w *Watcher) isFileInUse(filePath string, stabilityWindow time.Duration) bool {
 	// Missing file is treated as "in use/not ready" for this cycle.
 	info1, err := os.Stat(filePath)
 	if err != nil {
 		return true
 	}
-
-	// Lock probe:
-	// - Try read/write open first (detects many active write locks)
-	// - If permission denied, fallback to read-only open so permission alone
-	//   doesn't look like a write lock.
-	if f, err := os.OpenFile(filePath, os.O_RDWR, 0); err == nil {
-		f.Close()
-	} else if os.IsPermission(err) {
-		rf, rerr := os.Open(filePath)
-		if rerr != nil {
-			return true
-		}
-		rf.Close()
-	} else {
+	if isFileLocked(filePath) {
 		return true
 	}
 
@@ -821,7 +2121,27 @@ func (w *Watcher) isFileInUse(filePath string, stabilityWindow time.Duration) bo
 	return info1.Size() != info2.Size() || info1.ModTime() != info2.ModTime()
 }
 
-func (w *Watcher) copyFile(src, dst string) error {
+// isFileLocked probes whether another process holds the file open for writing
+func isFileLocked(filePath string) bool {
+	// Lock probe:
+	// - Try read/write open first (detects many active write locks)
+	// - If permission denied, fallback to read-only open so permission alone
+	//   doesn't look like a write lock.
+	if f, err := os.OpenFile(filePath, os.O_RDWR, 0); err == nil {
+		f.Close()
+	} else if os.IsPermission(err) {
+		rf, rerr := os.Open(filePath)
+		if rerr != nil {
+			return true
+		}
+		rf.Close()
+	} else {
+		return true
+	}
+	return false
+}
+
+func (w *Watcher) copyFile(src, dst string, ops FileOperations) error {
 	sourceFile, err := os.Open(src)
 	if err != nil {
 		return err
@@ -830,18 +2150,172 @@ func (w *Watcher) copyFile(src, dst string) error {
 	
 	// Create destination directory if it doesn't exist
 	destDir := filepath.Dir(dst)
-	if err := os.MkdirAll(destDir, 0755); err != nil {
+	if err := ops.makeDirs(destDir); err != nil {
 		return err
 	}
 	
-	destFile, err := os.Create(dst)
+	destFile, err := os.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, ops.fileMode())
 	if err != nil {
 		return err
 	}
-	defer destFile.Close()
 	
-	_, err = io.Copy(destFile, sourceFile)
-	return err
+	w.mu.Lock()
+	global := w.transferLimiter
+	w.mu.Unlock()
+	reader := throttle.NewReader(sourceFile, global, throttle.NewLimiter(ops.MaxBytesPerSecond))
+	if _, err := io.Copy(destFile, reader); err != nil {
+		destFile.Close()
+		return err
+	}
+	if err := destFile.Close(); err != nil {
+		return err
+	}
+
+	// Apply an explicitly configured mode so the process umask doesn't strip bits
+	if ops.FilePerm != "" {
+		if err := os.Chmod(dst, ops.fileMode()); err != nil {
+			w.logger.Warn().Err(err).Str("file", dst).Msg("Failed to set file permissions")
+		}
+	}
+
+	// Carry the source modification time over so age-based processing downstream
+	// sees when the file was received, not when it was copied.
+	if ops.preserveTimestamps() {
+		if info, err := sourceFile.Stat(); err == nil {
+			if err := os.Chtimes(dst, time.Time{}, info.ModTime()); err != nil {
+				w.logger.Warn().Err(err).Str("file", dst).Msg("Failed to preserve file timestamps")
+			}
+		}
+	}
+	return nil
+}
+
+// copyFileVerified copies src to dst and compares SHA-256 checksums afterwards.
+// A mismatching destination is deleted and the copy retried up to MaxRetries times.
+func (w *Watcher) copyFileVerified(src, dst string, ops FileOperations, opts ProcessingOptions) error {
+	maxRetries := opts.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 3
+	}
+	retryDelay := time.Duration(opts.DelayRetry) * time.Millisecond
+	if retryDelay <= 0 {
+		retryDelay = 1000 * time.Millisecond
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-w.stopChan:
+				return fmt.Errorf("watcher stopped while retrying copy: %w", lastErr)
+			case <-time.After(retryDelay):
+			}
+		}
+
+		if err := w.copyFile(src, dst, ops); err != nil {
+			lastErr = err
+			continue
+		}
+
+		srcSum, err := fileSHA256(src)
+		if err != nil {
+			return fmt.Errorf("failed to checksum source: %w", err)
+		}
+		dstSum, err := fileSHA256(dst)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to checksum destination: %w", err)
+			os.Remove(dst)
+			continue
+		}
+		if srcSum == dstSum {
+			w.logger.Debug().
+				Str("file", dst).
+				Str("sha256", dstSum).
+				Msg("🔐 Checksum verified")
+			return nil
+		}
+
+		lastErr = fmt.Errorf("checksum mismatch (source %s, destination %s)", srcSum, dstSum)
+		w.logger.Error().
+			Str("source", src).
+			Str("dest", dst).
+			Int("attempt", attempt).
+			Int("maxRetries", maxRetries).
+			Msg("❌ Checksum mismatch after copy, removing destination")
+		os.Remove(dst)
+	}
+
+	return fmt.Errorf("copy verification failed after %d attempts: %w", maxRetries, lastErr)
+}
+
+// fileSHA256 returns the hex-encoded SHA-256 digest of a file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fileMode returns the configured mode for written files (default: 0644)
+func (ops FileOperations) fileMode() os.FileMode {
+	mode, _ := config.ParseFileMode(ops.FilePerm, 0644)
+	return mode
+}
+
+// dirMode returns the configured mode for created directories (default: 0755)
+func (ops FileOperations) dirMode() os.FileMode {
+	mode, _ := config.ParseFileMode(ops.DirPerm, 0755)
+	return mode
+}
+
+// makeDirs creates dir and its missing parents. An explicit DirPerm is
+// applied to the created directories as is, without the process umask.
+func (ops FileOperations) makeDirs(dir string) error {
+	if ops.DirPerm != "" {
+		return config.MkdirAllPerm(dir, ops.dirMode())
+	}
+	return os.MkdirAll(dir, ops.dirMode())
+}
+
+// preserveTimestamps reports whether copies should keep the source mtime (default: true)
+func (ops FileOperations) preserveTimestamps() bool {
+	return ops.PreserveTimestamps == nil || *ops.PreserveTimestamps
+}
+
+// applyOwnership chowns a written file to the configured owner/group.
+// Ownership changes are not supported on Windows and are skipped with a warning.
+func (w *Watcher) applyOwnership(path string, ops FileOperations) {
+	if runtime.GOOS == "windows" {
+		w.logger.Warn().Str("file", path).Msg("⚠️ owner/group is not supported on Windows, skipping chown")
+		return
+	}
+
+	uid, gid, err := config.ResolveOwnership(ops.Owner, ops.Group)
+	if err != nil {
+		w.logger.Error().Err(err).Str("file", path).Msg("❌ Failed to resolve file ownership")
+		return
+	}
+	if err := os.Chown(path, uid, gid); err != nil {
+		w.logger.Error().
+			Err(err).
+			Str("file", path).
+			Str("owner", ops.Owner).
+			Str("group", ops.Group).
+			Msg("❌ Failed to change file ownership")
+		return
+	}
+	w.logger.Info().
+		Str("file", path).
+		Str("owner", ops.Owner).
+		Str("group", ops.Group).
+		Msg("👤 File ownership changed")
 }
 
 func (w *Watcher) fileExists(path string) bool {
@@ -873,7 +2347,11 @@ func (w *Watcher) applyRename(fileName, renameTo string, insertTimestamp bool) s
 	return result
 }
 
-func (w *Watcher) executeProgram(program, filePath string) {
+// executeProgram runs an external program or, with the WF: prefix, a workflow
+// against filePath. It returns the error from the program or failed workflow.
+// groups are the rule's fileRegex capture groups, passed to workflows as
+// {{.groups.NAME}} and, for named groups, {{.NAME}}.
+func (w *Watcher) executeProgram(program, filePath string, groups map[string]string) error {
 	// Replace {file} placeholder with actual file path
 	program = strings.ReplaceAll(program, "{file}", filePath)
 	
@@ -889,18 +2367,30 @@ func (w *Watcher) executeProgram(program, filePath string) {
 				"fileName":  filepath.Base(filePath),
 				"directory": filepath.Dir(filePath),
 			}
+			if len(groups) > 0 {
+				groupValues := make(map[string]interface{}, len(groups))
+				for name, value := range groups {
+					groupValues[name] = value
+					// Named groups are also top-level keys, unless they would
+					// shadow one of the trigger's own
+					if _, taken := context[name]; !taken && name != "vars" && !isGroupIndex(name) {
+						context[name] = value
+					}
+				}
+				context["groups"] = groupValues
+			}
 
 			// Use synchronous execution to wait for workflow completion
 			// This prevents file operations from happening while workflow is still running
 			if err := w.workflowExecutor.ExecuteWorkflowSync(workflowName, context); err != nil {
 				w.logger.Error().Err(err).Str("workflow", workflowName).Msg("❌ Failed to execute workflow")
-			} else {
-				w.logger.Info().Str("workflow", workflowName).Msg("✅ Workflow completed successfully")
+				return err
 			}
-		} else {
-			w.logger.Warn().Msg("Workflow executor not available")
+			w.logger.Info().Str("workflow", workflowName).Msg("✅ Workflow completed successfully")
+			return nil
 		}
-		return
+		w.logger.Warn().Msg("Workflow executor not available")
+		return fmt.Errorf("workflow executor not available")
 	}
 	
 	// Execute external program
@@ -933,6 +2423,7 @@ func (w *Watcher) executeProgram(program, filePath string) {
 			Str("output", string(output)).
 			Msg("Program executed successfully")
 	}
+	return err
 }
 
 func (w *Watcher) findDirectoriesToWatch(dirRegEx string) []string {
@@ -1173,15 +2664,28 @@ func (w *Watcher) walkDirectoryRecursive(path string, fn func(string, os.FileInf
 	return nil
 }
 
-// addSubdirsRecursive adds all subdirectories of a path to the watcher
-func (w *Watcher) addSubdirsRecursive(watcher *fsnotify.Watcher, root string) error {
-	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+// addSubdirsRecursive adds all subdirectories of a path to the watcher, at
+// most room of them (-1 = no limit). It returns the subdirectories left
+// without a watch and, if the OS ran out of watches, the error text.
+func (w *Watcher) addSubdirsRecursive(watcher *fsnotify.Watcher, root string, room int) ([]string, string) {
+	var skipped []string
+	var cause string
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // Skip paths with errors
 		}
 
 		if info.IsDir() && path != root {
+			if room == 0 || cause != "" {
+				skipped = append(skipped, path)
+				return nil
+			}
 			if err := watcher.Add(path); err != nil {
+				if isWatchLimitError(err) {
+					cause = err.Error()
+					skipped = append(skipped, path)
+					return nil
+				}
 				w.logger.Warn().
 					Err(err).
 					Str("path", path).
@@ -1190,10 +2694,32 @@ func (w *Watcher) addSubdirsRecursive(watcher *fsnotify.Watcher, root string) er
 				w.logger.Debug().
 					Str("path", path).
 					Msg("Added subdirectory to watcher")
+				if room > 0 {
+					room--
+				}
 			}
 		}
 		return nil
 	})
+	return skipped, cause
+}
+
+// collectOverflow returns dirs, plus their subdirectories when the rule
+// watches recursively, for directories that get no watch
+func (w *Watcher) collectOverflow(dirs []string, recursive bool) []string {
+	if !recursive {
+		return append([]string(nil), dirs...)
+	}
+	var all []string
+	for _, dir := range dirs {
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && info.IsDir() {
+				all = append(all, path)
+			}
+			return nil
+		})
+	}
+	return all
 }
 
 // isFileBeingProcessed checks if a file is currently being processed or in cooldown
@@ -1252,6 +2778,10 @@ func (w *Watcher) cleanupProcessedFiles() {
 			if count > 0 {
 				w.logger.Debug().Int("count", count).Msg("Cleaned up processed files from tracking")
 			}
+			w.removeExpiredFiles()
+			if err := w.ledger.save(); err != nil {
+				w.logger.Warn().Err(err).Msg("Failed to save processed file ledger")
+			}
 		case <-w.stopChan:
 			return
 		}
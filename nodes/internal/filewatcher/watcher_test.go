@@ -1,7 +1,17 @@
 package filewatcher
 
 import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog"
 )
 
 func TestCheckTimeRestrictions_ZeroValues(t *testing.T) {
@@ -42,3 +52,927 @@ func TestCheckTimeRestrictions_NarrowWindow(t *testing.T) {
 	// but we can verify it doesn't panic
 	_ = w.checkTimeRestrictions(restrictions)
 }
+
+func TestCompanionPath(t *testing.T) {
+	w := &Watcher{}
+	dir := filepath.Join("data", "in")
+
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{"{name}.done", filepath.Join(dir, "report.done")},
+		{"{filename}.ok", filepath.Join(dir, "report.csv.ok")},
+		{"READY{ext}", filepath.Join(dir, "READY.csv")},
+	}
+
+	for _, tt := range tests {
+		got := w.companionPath(filepath.Join(dir, "report.csv"), tt.pattern)
+		if got != tt.expected {
+			t.Errorf("pattern %q: expected %q, got %q", tt.pattern, tt.expected, got)
+		}
+	}
+}
+
+func TestWaitForCompanion(t *testing.T) {
+	w := &Watcher{stopChan: make(chan struct{})}
+	companion := filepath.Join(t.TempDir(), "data.done")
+
+	if w.waitForCompanion(companion, 0) {
+		t.Error("missing companion should not be reported present")
+	}
+
+	if err := os.WriteFile(companion, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !w.waitForCompanion(companion, 0) {
+		t.Error("existing companion should be reported present")
+	}
+}
+
+func TestCopyFile_PreservesModTime(t *testing.T) {
+	w := &Watcher{}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	dst := filepath.Join(dir, "out", "dst.txt")
+
+	if err := os.WriteFile(src, []byte("payload"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(src, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.copyFile(src, dst, FileOperations{}); err != nil {
+		t.Fatalf("copy failed: %v", err)
+	}
+
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("expected mtime %v, got %v", mtime, info.ModTime())
+	}
+}
+
+func TestCopyFileVerified(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in.dat")
+	dst := filepath.Join(dir, "out", "in.dat")
+	if err := os.WriteFile(src, []byte("payload"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &Watcher{stopChan: make(chan struct{})}
+	if err := w.copyFileVerified(src, dst, FileOperations{}, ProcessingOptions{MaxRetries: 1}); err != nil {
+		t.Fatalf("verified copy failed: %v", err)
+	}
+
+	srcSum, _ := fileSHA256(src)
+	dstSum, _ := fileSHA256(dst)
+	if srcSum != dstSum {
+		t.Errorf("checksums differ: %s vs %s", srcSum, dstSum)
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	valid := Rule{Name: "ok", FileRegEx: `\.csv$`, TimeRestrictions: TimeRestrictions{EndHour: 23, EndMinute: 59, WeekDayInterval: 127}}
+	if err := ValidateRule(valid); err != nil {
+		t.Errorf("expected valid rule, got %v", err)
+	}
+
+	invalid := []Rule{
+		{},
+		{Name: "bad regex", FileRegEx: `([`},
+		{Name: "bad mode", WatchMode: "recursive"},
+		{Name: "file mode without path", WatchMode: "file"},
+		{Name: "bad hour", TimeRestrictions: TimeRestrictions{EndHour: 24}},
+		{Name: "bad perm", Operations: FileOperations{FilePerm: "999"}},
+		{Name: "bad content pattern", ContentRegExAny: []string{"BEGIN", "(["}},
+		{Name: "outcome dir without workflow", Operations: FileOperations{ExecProg: "echo {file}", OnSuccessDir: "/done"}},
+		{Name: "bad log level", LogLevel: "loud"},
+	}
+	for _, rule := range invalid {
+		if err := ValidateRule(rule); err == nil {
+			t.Errorf("rule %q: expected validation error", rule.Name)
+		}
+	}
+}
+
+func TestNextAllowedTime(t *testing.T) {
+	// Weekdays only (Mon-Fri = bits 1-5), 09:00-17:00
+	weekdays := TimeRestrictions{StartHour: 9, EndHour: 17, WeekDayInterval: 0b0111110}
+
+	// Saturday 2026-10-17 12:00 -> Monday 09:00
+	from := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	want := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	if got := NextAllowedTime(weekdays, from); !got.Equal(want) {
+		t.Errorf("next allowed = %v, want %v", got, want)
+	}
+
+	// Already inside the window -> from itself
+	inside := time.Date(2026, 10, 19, 10, 30, 0, 0, time.UTC)
+	if got := NextAllowedTime(weekdays, inside); !got.Equal(inside) {
+		t.Errorf("next allowed inside window = %v, want %v", got, inside)
+	}
+
+	// Window spanning midnight: 22:00-02:00, checked at 20:00
+	overnight := TimeRestrictions{StartHour: 22, EndHour: 2}
+	from = time.Date(2026, 10, 19, 20, 0, 0, 0, time.UTC)
+	want = time.Date(2026, 10, 19, 22, 0, 0, 0, time.UTC)
+	if got := NextAllowedTime(overnight, from); !got.Equal(want) {
+		t.Errorf("next allowed overnight = %v, want %v", got, want)
+	}
+
+	// Mask with no valid day bits never allows processing
+	if got := NextAllowedTime(TimeRestrictions{EndHour: 23, WeekDayInterval: 128}, from); !got.IsZero() {
+		t.Errorf("expected zero time for impossible mask, got %v", got)
+	}
+}
+
+func TestTimeRestrictions_OvernightWindow(t *testing.T) {
+	overnight := TimeRestrictions{StartHour: 22, EndHour: 6}
+
+	tests := []struct {
+		hour    int
+		allowed bool
+	}{
+		{23, true},
+		{3, true},
+		{22, true},
+		{6, true},
+		{7, false},
+		{12, false},
+		{21, false},
+	}
+	for _, tt := range tests {
+		at := time.Date(2026, 10, 20, tt.hour, 0, 0, 0, time.UTC)
+		if got := overnight.allowedAt(at); got != tt.allowed {
+			t.Errorf("22:00-06:00 at %02d:00: allowed = %v, want %v", tt.hour, got, tt.allowed)
+		}
+	}
+}
+
+func TestTimeRestrictions_OvernightWindowUsesStartDay(t *testing.T) {
+	// Friday nights only (bit 5): Friday 23:00 and Saturday 03:00 belong to
+	// the same batch window; Friday 03:00 belongs to Thursday's window.
+	fridayNight := TimeRestrictions{StartHour: 22, EndHour: 6, WeekDayInterval: 1 << uint(time.Friday)}
+
+	friday23 := time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC)
+	saturday03 := time.Date(2026, 10, 17, 3, 0, 0, 0, time.UTC)
+	friday03 := time.Date(2026, 10, 16, 3, 0, 0, 0, time.UTC)
+
+	if !fridayNight.allowedAt(friday23) {
+		t.Error("Friday 23:00 should be allowed")
+	}
+	if !fridayNight.allowedAt(saturday03) {
+		t.Error("Saturday 03:00 is part of Friday's window and should be allowed")
+	}
+	if fridayNight.allowedAt(friday03) {
+		t.Error("Friday 03:00 is part of Thursday's window and should be rejected")
+	}
+}
+
+func TestTimeRestrictions_Timezone(t *testing.T) {
+	// 02:00-03:00 New York time (EDT, UTC-4 in October)
+	eastern := TimeRestrictions{StartHour: 2, EndHour: 3, Timezone: "America/New_York"}
+
+	at := time.Date(2026, 10, 20, 6, 30, 0, 0, time.UTC) // 02:30 EDT
+	if !eastern.allowedAt(at) {
+		t.Error("06:30 UTC is 02:30 Eastern and should be allowed")
+	}
+	at = time.Date(2026, 10, 20, 2, 30, 0, 0, time.UTC) // 22:30 EDT previous day
+	if eastern.allowedAt(at) {
+		t.Error("02:30 UTC is 22:30 Eastern and should be rejected")
+	}
+
+	if err := ValidateRule(Rule{Name: "tz", TimeRestrictions: TimeRestrictions{Timezone: "Mars/Olympus"}}); err == nil {
+		t.Error("expected invalid timezone to fail validation")
+	}
+}
+
+func TestDebounceFile_CoalescesEvents(t *testing.T) {
+	w := NewWatcher(zerolog.Nop(), nil)
+	w.workChan = make(chan fileJob, 10)
+	w.stopChan = make(chan struct{})
+	defer close(w.stopChan)
+
+	rule := Rule{Name: "debounced", ProcessingOptions: ProcessingOptions{DebounceMs: 50}}
+	path := filepath.Join(t.TempDir(), "big.dat")
+
+	// A burst of writes, each arriving before the quiet period elapses
+	for i := 0; i < 5; i++ {
+		w.debounceFile(path, rule)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case <-w.workChan:
+		t.Fatal("file enqueued before writes went quiet")
+	default:
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if got := len(w.workChan); got != 1 {
+		t.Errorf("expected exactly one job after debounce, got %d", got)
+	}
+}
+
+func TestDebounceFile_PerRule(t *testing.T) {
+	w := NewWatcher(zerolog.Nop(), nil)
+	w.workChan = make(chan fileJob, 10)
+	w.stopChan = make(chan struct{})
+	defer close(w.stopChan)
+
+	path := filepath.Join(t.TempDir(), "big.dat")
+	w.debounceFile(path, Rule{ID: "archive", ProcessingOptions: ProcessingOptions{DebounceMs: 20}})
+	w.debounceFile(path, Rule{ID: "upload", ProcessingOptions: ProcessingOptions{DebounceMs: 20}})
+
+	w.debounceMu.Lock()
+	pending := len(w.debounceTimers)
+	w.debounceMu.Unlock()
+	if pending != 2 {
+		t.Errorf("expected a debounce timer per rule, got %d", pending)
+	}
+	w.wg.Wait()
+}
+
+func TestDebounceFile_StopDropsPending(t *testing.T) {
+	w := NewWatcher(zerolog.Nop(), nil)
+	w.workChan = make(chan fileJob, 10)
+	w.stopChan = make(chan struct{})
+	w.stopped = false
+
+	rule := Rule{Name: "debounced", ProcessingOptions: ProcessingOptions{DebounceMs: 20}}
+	w.debounceFile(filepath.Join(t.TempDir(), "big.dat"), rule)
+	w.Stop()
+
+	time.Sleep(50 * time.Millisecond)
+	if got := len(w.workChan); got != 0 {
+		t.Errorf("debounced file enqueued after Stop, got %d jobs", got)
+	}
+}
+
+func TestIsFileReady_StableChecks(t *testing.T) {
+	w := NewWatcher(zerolog.Nop(), nil)
+	w.stopChan = make(chan struct{})
+	defer close(w.stopChan)
+
+	path := filepath.Join(t.TempDir(), "share.dat")
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := ProcessingOptions{StableChecks: 3, StableIntervalMs: 20}
+	if !w.isFileReady(path, opts) {
+		t.Error("unchanged file should be stable")
+	}
+
+	// A writer appending between samples keeps the file unstable
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		for i := 0; i < 6; i++ {
+			time.Sleep(10 * time.Millisecond)
+			f.WriteString("more")
+		}
+	}()
+	if w.isFileReady(path, opts) {
+		t.Error("file being appended should not be stable")
+	}
+	<-done
+
+	if w.isFileReady(filepath.Join(t.TempDir(), "missing"), opts) {
+		t.Error("missing file should not be ready")
+	}
+	if !(ProcessingOptions{StableChecks: 2}).waitsForReadiness() {
+		t.Error("stableChecks alone should enable the readiness wait")
+	}
+}
+
+func TestProcessFile_DeadLetterAlert(t *testing.T) {
+	w := NewWatcher(zerolog.Nop(), nil)
+	w.stopChan = make(chan struct{})
+	defer close(w.stopChan)
+	var alerts []map[string]interface{}
+	w.SetAlertHandler(func(level, message string, details map[string]interface{}) {
+		if level == "error" {
+			alerts = append(alerts, details)
+		}
+	})
+
+	// A file that never appears can never become ready
+	path := filepath.Join(t.TempDir(), "stuck.csv")
+	rule := Rule{ID: "r1", Name: "stuck", ProcessingOptions: ProcessingOptions{
+		StableChecks: 2, StableIntervalMs: 1, MaxRetries: 2, DelayRetry: 1,
+	}}
+	w.processFile(path, rule)
+
+	if len(alerts) != 1 {
+		t.Fatalf("expected one dead-letter alert, got %v", alerts)
+	}
+	if a := alerts[0]; a["file"] != path || a["rule"] != "stuck" || a["ruleId"] != "r1" || a["retries"] != 2 {
+		t.Errorf("unexpected alert details: %v", a)
+	}
+}
+
+func TestProcessFile_DirPermIgnoresUmask(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permission bits")
+	}
+	w := NewWatcher(zerolog.Nop(), nil)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in", "data.csv")
+	if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, []byte("a,b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 0777 is never left intact by the usual 022 umask
+	out := filepath.Join(dir, "shared", "out")
+	w.processFile(src, Rule{Name: "copy", Operations: FileOperations{CopyToDir: out, CopyFileOption: 22, DirPerm: "0777"}})
+	for _, d := range []string{filepath.Join(dir, "shared"), out} {
+		info, err := os.Stat(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0777 {
+			t.Errorf("%s mode = %o, want 0777", d, info.Mode().Perm())
+		}
+	}
+}
+
+func TestProcessFile_MultipleDestinations(t *testing.T) {
+	w := NewWatcher(zerolog.Nop(), nil)
+	w.stopChan = make(chan struct{})
+	defer close(w.stopChan)
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in", "data.csv")
+	archive := filepath.Join(dir, "archive")
+	inbox := filepath.Join(dir, "inbox")
+	if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, []byte("a,b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A regular file where a destination directory should be makes that
+	// destination fail; the source is kept and an alert raised.
+	blocked := filepath.Join(dir, "blocked")
+	if err := os.WriteFile(blocked, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var alerts []map[string]interface{}
+	w.SetAlertHandler(func(level, message string, details map[string]interface{}) {
+		alerts = append(alerts, details)
+	})
+	rule := Rule{Name: "fanout", Operations: FileOperations{
+		CopyToDir:      archive,
+		CopyToDirs:     []string{inbox, blocked, archive},
+		CopyFileOption: 21,
+	}}
+	w.processFile(src, rule)
+	if len(alerts) != 1 || alerts[0]["file"] != src {
+		t.Errorf("expected one partial delivery alert, got %v", alerts)
+	} else if failed := alerts[0]["failed"].([]string); len(failed) != 1 || failed[0] != filepath.Join(blocked, "data.csv") {
+		t.Errorf("unexpected failed destinations: %v", failed)
+	}
+
+	for _, d := range []string{archive, inbox} {
+		if _, err := os.Stat(filepath.Join(d, "data.csv")); err != nil {
+			t.Errorf("expected copy in %s: %v", d, err)
+		}
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Error("source should be kept after partial delivery")
+	}
+
+	// Once every destination succeeds the move removes the source
+	rule.Operations.CopyToDirs = []string{inbox}
+	rule.Operations.Overwrite = true
+	w.processFile(src, rule)
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Error("source should be removed after successful move to all destinations")
+	}
+}
+
+func TestProcessFile_CopyToTemplate(t *testing.T) {
+	w := NewWatcher(zerolog.Nop(), nil)
+	w.stopChan = make(chan struct{})
+	defer close(w.stopChan)
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in", "acme_20250115.csv")
+	if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, []byte("a,b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rule := Rule{
+		Name:      "partitioned",
+		FileRegEx: `^(?P<customer>[a-z]+)_(\d{8})\.csv$`,
+		Operations: FileOperations{
+			CopyToTemplate: filepath.Join(dir, "archive") + `/{{.groups.customer}}/{{.year}}/{{.month}}/{{.day}}/{{.name}}-{{index .groups "2"}}{{.ext}}`,
+			CopyFileOption: 21,
+		},
+	}
+	w.processFile(src, rule)
+
+	now := time.Now()
+	want := filepath.Join(dir, "archive", "acme", now.Format("2006"), now.Format("01"), now.Format("02"), "acme_20250115-20250115.csv")
+	if _, err := os.Stat(want); err != nil {
+		t.Errorf("expected file at %s: %v", want, err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Error("source should be moved")
+	}
+
+	// A key the template does not know fails instead of writing "<no value>"
+	if _, err := renderDestination("/x/{{.customer}}", src, rule, now); err == nil {
+		t.Error("unknown template key should be an error")
+	}
+	if got, _ := renderDestination(dir+"/{{.date}}/", src, rule, now); got != filepath.Join(dir, now.Format("2006-01-02"), "acme_20250115.csv") {
+		t.Errorf("trailing slash should append the file name, got %s", got)
+	}
+}
+
+func TestProcessFile_CollisionRename(t *testing.T) {
+	w := NewWatcher(zerolog.Nop(), nil)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in", "data.csv")
+	out := filepath.Join(dir, "out")
+	if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+		t.Fatal(err)
+	}
+	rule := Rule{Name: "rename", Operations: FileOperations{CopyToDir: out, CopyFileOption: 21, CollisionPolicy: CollisionRename}}
+
+	for i, content := range []string{"first", "second", "third"} {
+		if err := os.WriteFile(src, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		w.processFile(src, rule)
+		if _, err := os.Stat(src); !os.IsNotExist(err) {
+			t.Fatalf("run %d: source should have been moved", i)
+		}
+	}
+
+	for name, want := range map[string]string{"data.csv": "first", "data(1).csv": "second", "data(2).csv": "third"} {
+		got, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+
+	rule.Operations.CollisionPolicy = "keep-both"
+	if err := ValidateRule(rule); err == nil {
+		t.Error("unknown collision policy should be rejected")
+	}
+}
+
+func TestReservePath_Concurrent(t *testing.T) {
+	w := NewWatcher(zerolog.Nop(), nil)
+	path := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(path, []byte("existing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20
+	names := make(chan string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name, err := w.reservePath(path, 0644)
+			if err != nil {
+				t.Error(err)
+			}
+			names <- name
+		}()
+	}
+	wg.Wait()
+	close(names)
+
+	seen := make(map[string]bool)
+	for name := range names {
+		if name == path || seen[name] {
+			t.Errorf("name %s handed out twice", name)
+		}
+		seen[name] = true
+	}
+	if got, _ := os.ReadFile(path); string(got) != "existing" {
+		t.Error("existing file was touched")
+	}
+}
+
+func TestMatchesFile_ContentPatterns(t *testing.T) {
+	w := NewWatcher(zerolog.Nop(), nil)
+	dir := t.TempDir()
+	files := map[string]string{
+		"complete.txt":  "BEGIN\nrow 1\nrow 2\nEND\n",
+		"truncated.txt": "BEGIN\nrow 1\n",
+		"trailer.txt":   "row 1\nTRAILER 1\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		rule Rule
+		want map[string]bool
+	}{
+		{"all", Rule{ContentRegExAll: []string{`(?m)^BEGIN$`, `(?m)^END$`}},
+			map[string]bool{"complete.txt": true, "truncated.txt": false, "trailer.txt": false}},
+		{"any", Rule{ContentRegExAny: []string{`(?m)^END$`, `TRAILER \d+`}},
+			map[string]bool{"complete.txt": true, "truncated.txt": false, "trailer.txt": true}},
+		{"single and any", Rule{ContentRegEx: `row 1`, ContentRegExAny: []string{`BEGIN`}},
+			map[string]bool{"complete.txt": true, "truncated.txt": true, "trailer.txt": false}},
+		{"multiline pattern", Rule{ContentRegExAll: []string{`(?s)BEGIN.*END`}},
+			map[string]bool{"complete.txt": true, "truncated.txt": false, "trailer.txt": false}},
+	} {
+		for name, want := range tc.want {
+			if got := w.matchesFile(filepath.Join(dir, name), tc.rule, nil, nil); got != want {
+				t.Errorf("%s: %s matched = %v, want %v", tc.name, name, got, want)
+			}
+		}
+	}
+}
+
+// fakeWorkflows fails the workflows named in failing and records the
+// context of the last run
+type fakeWorkflows struct {
+	failing map[string]bool
+	last    map[string]interface{}
+}
+
+func (f *fakeWorkflows) ExecuteWorkflow(name string, context map[string]interface{}) error {
+	return f.ExecuteWorkflowSync(name, context)
+}
+
+func (f *fakeWorkflows) ExecuteWorkflowSync(name string, context map[string]interface{}) error {
+	f.last = context
+	if f.failing[name] {
+		return errors.New("step failed")
+	}
+	return nil
+}
+
+func TestProcessFile_RoutesWorkflowInputByOutcome(t *testing.T) {
+	w := NewWatcher(zerolog.Nop(), &fakeWorkflows{failing: map[string]bool{"broken": true}})
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in", "batch.csv")
+	done := filepath.Join(dir, "success")
+	failed := filepath.Join(dir, "failed")
+	if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		workflow string
+		want     string
+	}{
+		{"import", filepath.Join(done, "batch.csv")},
+		{"broken", filepath.Join(failed, "batch.csv")},
+		{"import", filepath.Join(done, "batch(1).csv")},
+	} {
+		if err := os.WriteFile(src, []byte(tc.workflow), 0644); err != nil {
+			t.Fatal(err)
+		}
+		rule := Rule{Name: "batch", Operations: FileOperations{ExecProg: "WF:" + tc.workflow, OnSuccessDir: done, OnFailureDir: failed}}
+		if err := ValidateRule(rule); err != nil {
+			t.Fatal(err)
+		}
+		w.processFile(src, rule)
+
+		if _, err := os.Stat(src); !os.IsNotExist(err) {
+			t.Errorf("%s: source should have been routed", tc.workflow)
+		}
+		if got, err := os.ReadFile(tc.want); err != nil || string(got) != tc.workflow {
+			t.Errorf("%s: %s = %q, %v", tc.workflow, tc.want, got, err)
+		}
+	}
+}
+
+func TestProcessFile_PassesRegexGroupsToWorkflow(t *testing.T) {
+	workflows := &fakeWorkflows{}
+	w := NewWatcher(zerolog.Nop(), workflows)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "acme_20250115.csv")
+	if err := os.WriteFile(src, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The workflow sees the groups of the matched name, not the renamed one
+	rule := Rule{
+		Name:      "orders",
+		FileRegEx: `^(?P<customer>\w+?)_(?P<date>\d{8})\.csv$`,
+		Operations: FileOperations{
+			CopyToDir: filepath.Join(dir, "out"), CopyFileOption: 21,
+			RenameFileTo: "order.csv", ExecProg: "WF:import",
+		},
+	}
+	w.processFile(src, rule)
+
+	ctx := workflows.last
+	if ctx == nil || ctx["customer"] != "acme" || ctx["date"] != "20250115" || ctx["fileName"] != "order.csv" {
+		t.Fatalf("unexpected workflow context: %v", ctx)
+	}
+	groups, _ := ctx["groups"].(map[string]interface{})
+	if groups["customer"] != "acme" || groups["1"] != "acme" || groups["2"] != "20250115" {
+		t.Errorf("unexpected groups: %v", groups)
+	}
+	if _, ok := ctx["1"]; ok {
+		t.Error("numbered groups should only be under groups")
+	}
+}
+
+func TestUpdateRules_PreservesUnchangedWatchers(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	ruleA := Rule{ID: "a", Name: "a", Enabled: true, DirRegEx: dirA}
+	ruleB := Rule{ID: "b", Name: "b", Enabled: true, DirRegEx: dirB}
+
+	w := NewWatcher(zerolog.Nop(), nil)
+	w.UpdateRules([]Rule{ruleA, ruleB})
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	watcherFor := func(rule Rule, dir string) *fsnotify.Watcher {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.watchers[rule.key()+":"+dir]
+	}
+	beforeA := watcherFor(ruleA, dirA)
+	beforeB := watcherFor(ruleB, dirB)
+	if beforeA == nil || beforeB == nil {
+		t.Fatal("expected watchers for both rules")
+	}
+
+	// Change rule B only; rule A's watcher must stay live
+	ruleB.FileRegEx = `\.csv$`
+	w.UpdateRules([]Rule{ruleA, ruleB})
+
+	if watcherFor(ruleA, dirA) != beforeA {
+		t.Error("unchanged rule's watcher was recreated")
+	}
+	if after := watcherFor(ruleB, dirB); after == nil || after == beforeB {
+		t.Error("changed rule's watcher should be replaced")
+	}
+
+	// Removing rule B closes its watcher and leaves A alone
+	w.UpdateRules([]Rule{ruleA})
+	if watcherFor(ruleB, dirB) != nil {
+		t.Error("removed rule's watcher should be closed")
+	}
+	if w.WatcherCount() != 1 {
+		t.Errorf("expected 1 active watcher, got %d", w.WatcherCount())
+	}
+}
+
+func TestPoolChanged(t *testing.T) {
+	w := NewWatcher(zerolog.Nop(), nil)
+	w.UpdateRules([]Rule{{ID: "a", Name: "a", Enabled: true, DirRegEx: t.TempDir()}})
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	if w.PoolChanged() {
+		t.Error("pool reported changed right after start")
+	}
+	w.SetMaxConcurrent(8)
+	if !w.PoolChanged() {
+		t.Error("new worker count should require a restart")
+	}
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if w.PoolChanged() {
+		t.Error("restart should apply the new worker count")
+	}
+	if err := w.SetQueueSettings(100, BackpressureReject); err != nil {
+		t.Fatal(err)
+	}
+	if !w.PoolChanged() {
+		t.Error("new queue size should require a restart")
+	}
+}
+
+func TestPauseDefersFilesUntilResume(t *testing.T) {
+	w := NewWatcher(zerolog.Nop(), nil)
+	w.stopChan = make(chan struct{})
+	defer close(w.stopChan)
+	w.workChan = make(chan fileJob, 1)
+
+	file := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rule := Rule{Name: "drain"}
+
+	w.Pause()
+	w.enqueueFile(file, rule, "CREATE")
+	if len(w.workChan) != 0 || w.InFlight() != 0 {
+		t.Fatal("paused watcher should not hand files to workers")
+	}
+
+	w.Resume()
+	select {
+	case job := <-w.workChan:
+		if job.filePath != file {
+			t.Errorf("resumed job = %s, want %s", job.filePath, file)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("deferred file was not enqueued on resume")
+	}
+	if w.InFlight() != 1 {
+		t.Errorf("InFlight = %d, want 1", w.InFlight())
+	}
+}
+
+func TestSubmit_BackpressurePolicies(t *testing.T) {
+	newFull := func(policy string) *Watcher {
+		w := NewWatcher(zerolog.Nop(), nil)
+		if err := w.SetQueueSettings(1, policy); err != nil {
+			t.Fatal(err)
+		}
+		w.stopChan = make(chan struct{})
+		w.workChan = make(chan fileJob, 1)
+		if !w.submit(fileJob{filePath: "first"}) {
+			t.Fatal("first submit failed")
+		}
+		return w
+	}
+
+	w := newFull(BackpressureDropOldest)
+	w.markFileProcessing("second")
+	if !w.submit(fileJob{filePath: "second"}) {
+		t.Fatal("drop-oldest submit failed")
+	}
+	if job := <-w.workChan; job.filePath != "second" {
+		t.Errorf("queued job = %s, want second", job.filePath)
+	}
+	if stats := w.QueueStats(); stats.Dropped != 1 || stats.Saturated != 1 || stats.InFlight != 1 {
+		t.Errorf("drop-oldest stats = %+v", stats)
+	}
+
+	w = newFull(BackpressureReject)
+	var alerts int
+	w.SetAlertHandler(func(level, message string, details map[string]interface{}) { alerts++ })
+	w.markFileProcessing("second")
+	w.submit(fileJob{filePath: "second"})
+	if job := <-w.workChan; job.filePath != "first" {
+		t.Errorf("queued job = %s, want first", job.filePath)
+	}
+	if stats := w.QueueStats(); stats.Rejected != 1 || stats.InFlight != 1 || alerts != 1 {
+		t.Errorf("reject stats = %+v, alerts = %d", stats, alerts)
+	}
+	if w.isFileBeingProcessed("second") {
+		t.Error("rejected file should be picked up again by later events")
+	}
+
+	if err := w.SetQueueSettings(0, "newest-wins"); err == nil {
+		t.Error("unknown policy should be rejected")
+	}
+}
+
+func TestFileModeRewatchesReplacedFile(t *testing.T) {
+	w := NewWatcher(zerolog.Nop(), nil)
+	w.stopChan = make(chan struct{})
+	defer close(w.stopChan)
+	w.workChan = make(chan fileJob, 4)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "status.json")
+	if err := os.WriteFile(file, []byte(`{"v":1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.startWatchingFile(Rule{ID: "status", Name: "status", WatchMode: "file", DirRegEx: file}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectJob := func(what string) {
+		t.Helper()
+		select {
+		case job := <-w.workChan:
+			if job.filePath != file {
+				t.Errorf("%s: job for %s, want %s", what, job.filePath, file)
+			}
+			w.processingFiles.Delete(file) // skip the cooldown
+		case <-time.After(3 * time.Second):
+			t.Fatalf("%s: file was not enqueued", what)
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond) // let the initial watch be added
+	if err := os.WriteFile(file, []byte(`{"v":2}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectJob("write")
+
+	// Editor-style save: write a temp file and rename it over the original
+	tmp := filepath.Join(dir, "status.json.tmp")
+	if err := os.WriteFile(tmp, []byte(`{"v":3}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		t.Fatal(err)
+	}
+	expectJob("replace")
+
+	// The watch follows the new file
+	if err := os.WriteFile(file, []byte(`{"v":4}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectJob("write after replace")
+}
+
+func TestWatchLimit_PollsOverflowDirectories(t *testing.T) {
+	w := NewWatcher(zerolog.Nop(), nil)
+	w.stopChan = make(chan struct{})
+	defer close(w.stopChan)
+	w.workChan = make(chan fileJob, 4)
+
+	root := t.TempDir()
+	for _, sub := range []string{"a", "b", "c"} {
+		if err := os.MkdirAll(filepath.Join(root, "in", sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.SetGlobalSettings(root, true)
+	if err := w.SetWatchLimits(2, "rotate", 0); err == nil {
+		t.Error("unknown overflow policy should be rejected")
+	}
+	if err := w.SetWatchLimits(2, OverflowPoll, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	rule := Rule{ID: "deep", Name: "deep", WatchMode: "pattern", DirRegEx: `in`}
+	if err := w.startWatchingRule(rule); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := w.WatchStats()
+	polled := stats.Polled["deep"]
+	if stats.Watches != 2 || len(polled) != 2 || len(stats.Unwatched) != 0 {
+		t.Fatalf("stats = %+v, want in and in/a watched, in/b and in/c polled", stats)
+	}
+
+	file := filepath.Join(polled[len(polled)-1], "data.csv")
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case job := <-w.workChan:
+		if job.filePath != file {
+			t.Errorf("job for %s, want %s", job.filePath, file)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("file in polled directory was not enqueued")
+	}
+
+	w.mu.Lock()
+	w.stopRuleLocked(rule.key())
+	w.mu.Unlock()
+	if stats := w.WatchStats(); len(stats.Polled) != 0 {
+		t.Errorf("stopping the rule should stop polling, got %+v", stats.Polled)
+	}
+}
+
+func TestEventLogLevels(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWatcher(zerolog.New(&buf).Level(zerolog.InfoLevel), nil)
+	w.eventLog(Rule{Name: "important"}).Msg("matched")
+	w.eventLog(Rule{Name: "noisy", LogLevel: "debug"}).Msg("noisy matched")
+	if !bytes.Contains(buf.Bytes(), []byte(`"level":"info","component":"filewatcher","message":"matched"`)) || bytes.Contains(buf.Bytes(), []byte("noisy")) {
+		t.Errorf("matches should log at info, a debug rule's not at all at info level; got %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := w.SetFileEventLogLevel("debug"); err != nil {
+		t.Fatal(err)
+	}
+	w.eventLog(Rule{Name: "default"}).Msg("quiet")
+	w.eventLog(Rule{Name: "important", LogLevel: "warn"}).Msg("loud")
+	if bytes.Contains(buf.Bytes(), []byte("quiet")) || !bytes.Contains(buf.Bytes(), []byte(`"level":"warn"`)) {
+		t.Errorf("global level should apply to rules without their own; got %s", buf.String())
+	}
+	if err := w.SetFileEventLogLevel("chatty"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
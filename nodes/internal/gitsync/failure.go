@@ -0,0 +1,79 @@
+package gitsync
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrBackupNotFound is returned by RecoverBackup for an ID that names no backup
+var ErrBackupNotFound = errors.New("backup not found")
+
+// Failure kinds returned by FailureKind
+const (
+	FailureError       = "error"
+	FailureConflict    = "conflict"
+	FailureAuth        = "auth"
+	FailureUnavailable = "unavailable"
+	FailureNotFound    = "not-found"
+	FailureBusy        = "busy"
+)
+
+// authGitErrors mark failures where the manager rejected this agent's key
+var authGitErrors = []string{
+	"permission denied",
+	"authentication failed",
+	"host key verification failed",
+}
+
+// conflictGitErrors mark local and remote changes that cannot be combined
+// without a manual merge
+var conflictGitErrors = []string{
+	"conflict",
+	"non-fast-forward",
+	"[rejected]",
+	"[remote rejected]",
+	"would be overwritten",
+	"needs merge",
+}
+
+// notFoundGitErrors mark a repository or backup that does not exist
+var notFoundGitErrors = []string{
+	"repository not found",
+	"does not appear to be a git repository",
+	"is not a valid reference",
+	"no stash entries found",
+	"did not match any file(s) known to git",
+}
+
+// FailureKind classifies an error returned by a GitSync operation, so callers
+// such as the CLI can report why it failed. Errors from git carry its output,
+// which is matched like isTransientGitError does.
+func FailureKind(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrBusy):
+		return FailureBusy
+	case errors.Is(err, ErrBackupNotFound):
+		return FailureNotFound
+	}
+	text := strings.ToLower(err.Error())
+	for _, kind := range []struct {
+		name    string
+		markers []string
+	}{
+		{FailureAuth, authGitErrors},
+		{FailureConflict, conflictGitErrors},
+		{FailureNotFound, notFoundGitErrors},
+	} {
+		for _, marker := range kind.markers {
+			if strings.Contains(text, marker) {
+				return kind.name
+			}
+		}
+	}
+	if isTransientGitError(text) {
+		return FailureUnavailable
+	}
+	return FailureError
+}
@@ -1,12 +1,14 @@
 package gitsync
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -18,6 +20,22 @@ type GitSync struct {
 	agentID    string
 	logger     zerolog.Logger
 	sshKeyPath string
+
+	// TLS options for HTTPS remotes (see SetTLSOptions)
+	insecureSSL bool
+	caPath      string
+
+	// Retry policy for clone, fetch and push (see SetRetryPolicy)
+	retryAttempts     int
+	retryInitialDelay time.Duration
+	retryMaxDelay     time.Duration
+
+	// opLock serializes operations that change the repository (see lock)
+	opLock chan struct{}
+
+	// When Pull last succeeded (see LastSuccessfulPull)
+	pullMu   sync.Mutex
+	lastPull time.Time
 }
 
 func New(repoPath, remoteURL, agentID, sshKeyPath string, logger zerolog.Logger) *GitSync {
@@ -27,11 +45,26 @@ func New(repoPath, remoteURL, agentID, sshKeyPath string, logger zerolog.Logger)
 		agentID:    agentID,
 		sshKeyPath: sshKeyPath,
 		logger:     logger.With().Str("component", "gitsync").Logger(),
+
+		retryAttempts:     DefaultRetryAttempts,
+		retryInitialDelay: DefaultRetryInitialDelay,
+		retryMaxDelay:     DefaultRetryMaxDelay,
+
+		opLock: make(chan struct{}, 1),
 	}
 }
 
 // Initialize clones the repository if it doesn't exist
 func (g *GitSync) Initialize() error {
+	unlock, err := g.lock("initialize")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return g.initialize()
+}
+
+func (g *GitSync) initialize() error {
 	// Check if repo already exists
 	if _, err := os.Stat(filepath.Join(g.repoPath, ".git")); err == nil {
 		g.logger.Info().Msg("Git repository already exists")
@@ -47,15 +80,13 @@ func (g *GitSync) Initialize() error {
 		return fmt.Errorf("failed to create repo directory: %w", err)
 	}
 
-	// Clone using helper method that sets up SSH environment
-	cmd := g.setupGitCommand("clone", g.remoteURL, g.repoPath)
 	if g.sshKeyPath != "" {
 		g.logger.Info().Str("ssh_key", g.sshKeyPath).Msg("Using SSH key for clone")
 	}
 
-	// Clone the repository
+	// Clone the repository, retrying network failures
 	g.logger.Info().Str("url", g.remoteURL).Str("path", g.repoPath).Msg("Cloning repository")
-	output, err := cmd.CombinedOutput()
+	output, err := g.runRemoteCommand("clone", g.remoteURL, g.repoPath)
 	if err != nil {
 		// Don't fail on clone error - this might be first-time registration
 		// where the agent's public key isn't in the manager's database yet.
@@ -66,6 +97,7 @@ func (g *GitSync) Initialize() error {
 		return nil
 	}
 
+	g.recordPull()
 	g.logger.Info().Msg("Repository cloned successfully")
 
 	// Setup git config after cloning
@@ -78,20 +110,37 @@ func (g *GitSync) Initialize() error {
 
 // setupGitCommand creates a git command with SSH environment configured
 func (g *GitSync) setupGitCommand(args ...string) *exec.Cmd {
-	cmd := exec.Command("git", args...)
+	return g.setupGitCommandContext(context.Background(), args...)
+}
+
+// setupGitCommandContext is setupGitCommand with a context that kills the command
+func (g *GitSync) setupGitCommandContext(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	var env []string
 	if g.sshKeyPath != "" {
 		sshCmd := fmt.Sprintf("ssh -i \"%s\" -o StrictHostKeyChecking=no -o BatchMode=yes", g.sshKeyPath)
-		cmd.Env = append(os.Environ(), fmt.Sprintf("GIT_SSH_COMMAND=%s", sshCmd))
+		env = append(env, fmt.Sprintf("GIT_SSH_COMMAND=%s", sshCmd))
+	}
+	env = append(env, g.tlsEnv()...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
 	}
 	return cmd
 }
 
-// Pull fetches and merges latest changes from remote
+// Pull fetches and merges latest changes from remote. The fetch is retried
+// with backoff on network errors (see SetRetryPolicy).
 func (g *GitSync) Pull() error {
+	unlock, err := g.lock("pull")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// First, ensure we're in the repo directory
 	if _, err := os.Stat(filepath.Join(g.repoPath, ".git")); err != nil {
 		// Repository doesn't exist, initialize it
-		if err := g.Initialize(); err != nil {
+		if err := g.initialize(); err != nil {
 			return fmt.Errorf("failed to initialize repository: %w", err)
 		}
 	}
@@ -99,7 +148,7 @@ func (g *GitSync) Pull() error {
 	// Check for local changes and back them up if present
 	if hasChanges, _ := g.HasLocalChanges(); hasChanges {
 		g.logger.Warn().Msg("⚠️  LOCAL CHANGES DETECTED - Creating automatic backup before pulling from manager")
-		if err := g.BackupLocalChanges(); err != nil {
+		if err := g.backupLocalChanges(); err != nil {
 			g.logger.Error().Err(err).Msg("❌ FAILED to backup local changes - ABORTING pull to prevent data loss")
 			return fmt.Errorf("cannot pull with uncommitted changes and failed backup: %w", err)
 		}
@@ -110,14 +159,13 @@ func (g *GitSync) Pull() error {
 
 	// Fetch latest changes
 	g.logger.Info().Msg("Fetching latest changes")
-	cmd := g.setupGitCommand("-C", g.repoPath, "fetch", "origin")
-	if output, err := cmd.CombinedOutput(); err != nil {
+	if output, err := g.runRemoteCommand("-C", g.repoPath, "fetch", "origin"); err != nil {
 		return fmt.Errorf("git fetch failed: %w - output: %s", err, string(output))
 	}
 
 	// Determine the remote branch
 	branch := "main"
-	cmd = exec.Command("git", "-C", g.repoPath, "rev-parse", "--verify", fmt.Sprintf("origin/%s", branch))
+	cmd := exec.Command("git", "-C", g.repoPath, "rev-parse", "--verify", fmt.Sprintf("origin/%s", branch))
 	if err := cmd.Run(); err != nil {
 		branch = "master"
 	}
@@ -146,6 +194,7 @@ func (g *GitSync) Pull() error {
 		return fmt.Errorf("git reset failed: %w - output: %s", err, string(output))
 	}
 
+	g.recordPull()
 	g.logger.Info().Str("branch", branch).Msg("Repository updated successfully")
 	return nil
 }
@@ -240,6 +289,8 @@ func (g *GitSync) SetupGitConfig() error {
 		}
 	}
 
+	g.removeStaleSSLVerify()
+
 	// Ensure the remote URL is set correctly (in case it was changed from HTTP to SSH)
 	if err := g.UpdateRemoteURL(); err != nil {
 		g.logger.Warn().Err(err).Msg("Failed to update remote URL")
@@ -292,6 +343,12 @@ func (g *GitSync) HasLocalChanges() (bool, error) {
 
 // HasCommitsAhead checks if local branch has commits ahead of remote
 func (g *GitSync) HasCommitsAhead() (bool, error) {
+	unlock, err := g.lock("check commits ahead")
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
 	// First fetch to ensure we have latest remote info
 	cmd := g.setupGitCommand("-C", g.repoPath, "fetch", "origin")
 	if err := cmd.Run(); err != nil {
@@ -317,6 +374,12 @@ func (g *GitSync) HasCommitsAhead() (bool, error) {
 
 // HasDiverged checks if local and remote have diverged
 func (g *GitSync) HasDiverged() (bool, error) {
+	unlock, err := g.lock("check divergence")
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
 	// Fetch latest from remote without merging
 	cmd := g.setupGitCommand("-C", g.repoPath, "fetch", "origin")
 	if err := cmd.Run(); err != nil {
@@ -349,6 +412,12 @@ func (g *GitSync) GetDiff() (string, error) {
 
 // CommitLocalChanges commits all local changes
 func (g *GitSync) CommitLocalChanges(message string) error {
+	unlock, err := g.lock("commit")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// Add all changes
 	cmd := exec.Command("git", "-C", g.repoPath, "add", "-A")
 	if output, err := cmd.CombinedOutput(); err != nil {
@@ -371,10 +440,15 @@ func (g *GitSync) CommitLocalChanges(message string) error {
 	return nil
 }
 
-// Push pushes local commits to remote repository
+// Push pushes local commits to remote repository, retrying network errors
 func (g *GitSync) Push() error {
-	cmd := g.setupGitCommand("-C", g.repoPath, "push", "origin", "HEAD")
-	output, err := cmd.CombinedOutput()
+	unlock, err := g.lock("push")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	output, err := g.runRemoteCommand("-C", g.repoPath, "push", "origin", "HEAD")
 	if err != nil {
 		return fmt.Errorf("git push failed: %w - output: %s", err, string(output))
 	}
@@ -385,6 +459,15 @@ func (g *GitSync) Push() error {
 
 // BackupLocalChanges creates a backup of local changes using git stash or branch
 func (g *GitSync) BackupLocalChanges() error {
+	unlock, err := g.lock("backup")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return g.backupLocalChanges()
+}
+
+func (g *GitSync) backupLocalChanges() error {
 	// First try git stash with a descriptive message
 	timestamp := time.Now().Format("20060102-150405")
 	stashMsg := fmt.Sprintf("Agent-%s-backup-%s", g.agentID, timestamp)
@@ -478,6 +561,12 @@ func (g *GitSync) ListBackups() ([]string, error) {
 
 // RecoverBackup recovers changes from a specific backup
 func (g *GitSync) RecoverBackup(backupID string) error {
+	unlock, err := g.lock("restore")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// If backupID is empty or "latest", recover the most recent backup
 	if backupID == "" || backupID == "latest" {
 		// Try to recover the most recent stash for this agent
@@ -520,6 +609,8 @@ func (g *GitSync) RecoverBackup(backupID string) error {
 			return fmt.Errorf("failed to checkout backup branch: %w - output: %s", err, string(output))
 		}
 		g.logger.Info().Str("branch", backupID).Msg("Switched to backup branch")
+	} else {
+		return fmt.Errorf("%w: %q is neither a stash nor a backup/ branch", ErrBackupNotFound, backupID)
 	}
 
 	return nil
@@ -0,0 +1,37 @@
+package gitsync
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// lockTimeout is how long an operation waits for another to finish
+	lockTimeout = 2 * time.Minute
+
+	// remoteCommandTimeout kills a clone, fetch or push that hangs (e.g. a
+	// stalled SSH connection) so it cannot hold the lock indefinitely
+	remoteCommandTimeout = 5 * time.Minute
+)
+
+// ErrBusy is returned when another git operation held the repository lock
+// for longer than lockTimeout
+var ErrBusy = errors.New("another git operation is in progress")
+
+// lock serializes operations that change the repository (pull, push, commit,
+// backup, restore). It gives up after lockTimeout rather than queueing behind
+// a stuck operation forever. The returned function releases the lock.
+func (g *GitSync) lock(operation string) (func(), error) {
+	select {
+	case g.opLock <- struct{}{}:
+	default:
+		g.logger.Info().Str("operation", operation).Msg("⏳ Waiting for another git operation to finish")
+		select {
+		case g.opLock <- struct{}{}:
+		case <-time.After(lockTimeout):
+			return nil, fmt.Errorf("%s: %w (waited %s)", operation, ErrBusy, lockTimeout)
+		}
+	}
+	return func() { <-g.opLock }, nil
+}
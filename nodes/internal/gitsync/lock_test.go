@@ -0,0 +1,41 @@
+package gitsync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestLockSerializesOperations(t *testing.T) {
+	g := New(t.TempDir(), "", "agent-1", "", zerolog.Nop())
+
+	unlock, err := g.lock("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		release, err := g.lock("second")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		close(acquired)
+		release()
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second operation ran while the lock was held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(2 * time.Second):
+		t.Fatal("second operation did not get the lock after release")
+	}
+}
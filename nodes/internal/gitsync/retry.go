@@ -0,0 +1,144 @@
+package gitsync
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Defaults for retrying git operations that talk to the remote
+const (
+	DefaultRetryAttempts     = 4
+	DefaultRetryInitialDelay = 2 * time.Second
+	DefaultRetryMaxDelay     = 30 * time.Second
+)
+
+// permanentGitErrors mark failures retrying cannot fix. They are checked
+// before transientGitErrors because git prints "Could not read from remote
+// repository" for both auth and network failures.
+var permanentGitErrors = []string{
+	"permission denied",
+	"authentication failed",
+	"host key verification failed",
+	"repository not found",
+	"does not appear to be a git repository",
+	"conflict",
+	"non-fast-forward",
+	"[rejected]",
+	"[remote rejected]",
+	"already exists and is not an empty directory",
+}
+
+// transientGitErrors mark network failures worth retrying
+var transientGitErrors = []string{
+	"could not resolve host",
+	"connection refused",
+	"connection timed out",
+	"connection reset",
+	"operation timed out",
+	"network is unreachable",
+	"no route to host",
+	"temporary failure in name resolution",
+	"the remote end hung up unexpectedly",
+	"early eof",
+	"unexpected disconnect",
+	"rpc failed",
+	"connection closed by",
+	"kex_exchange_identification",
+	"broken pipe",
+	"could not read from remote repository",
+	"the requested url returned error: 5",
+}
+
+// isTransientGitError reports whether git output describes a network failure
+// that may succeed if the command is run again
+func isTransientGitError(output string) bool {
+	text := strings.ToLower(output)
+	for _, marker := range permanentGitErrors {
+		if strings.Contains(text, marker) {
+			return false
+		}
+	}
+	for _, marker := range transientGitErrors {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRetryPolicy configures how network git operations (clone, fetch, push)
+// are retried. Zero values keep the defaults; attempts of 1 disables retries.
+func (g *GitSync) SetRetryPolicy(attempts int, initialDelay, maxDelay time.Duration) {
+	if attempts > 0 {
+		g.retryAttempts = attempts
+	}
+	if initialDelay > 0 {
+		g.retryInitialDelay = initialDelay
+	}
+	if maxDelay > 0 {
+		g.retryMaxDelay = maxDelay
+	}
+	if g.retryMaxDelay < g.retryInitialDelay {
+		g.retryMaxDelay = g.retryInitialDelay
+	}
+}
+
+// runRemoteCommand runs a git command that talks to the remote, retrying with
+// exponential backoff while it fails with a transient network error. Each
+// attempt is killed after remoteCommandTimeout. It returns the combined
+// output of the last attempt.
+func (g *GitSync) runRemoteCommand(args ...string) ([]byte, error) {
+	delay := g.retryInitialDelay
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), remoteCommandTimeout)
+		output, err := g.setupGitCommandContext(ctx, args...).CombinedOutput()
+		if err != nil && ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s: %w", remoteCommandTimeout, err)
+			output = append(output, []byte("\noperation timed out")...)
+		}
+		cancel()
+		if err == nil {
+			if attempt > 1 {
+				g.logger.Info().
+					Str("command", gitCommandName(args)).
+					Int("attempt", attempt).
+					Msg("✅ Git operation succeeded after retry")
+			}
+			return output, nil
+		}
+		if attempt >= g.retryAttempts || !isTransientGitError(string(output)) {
+			if attempt > 1 {
+				err = fmt.Errorf("%w (after %d attempts)", err, attempt)
+			}
+			return output, err
+		}
+
+		g.logger.Warn().
+			Err(err).
+			Str("command", gitCommandName(args)).
+			Int("attempt", attempt).
+			Int("maxAttempts", g.retryAttempts).
+			Dur("retryIn", delay).
+			Str("output", strings.TrimSpace(string(output))).
+			Msg("🔁 Git operation failed with a network error, retrying")
+		time.Sleep(delay)
+		delay *= 2
+		if delay > g.retryMaxDelay {
+			delay = g.retryMaxDelay
+		}
+	}
+}
+
+// gitCommandName returns the git subcommand in args, skipping -C <path>
+func gitCommandName(args []string) string {
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-C" {
+			i++
+			continue
+		}
+		return args[i]
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package gitsync
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIsTransientGitError(t *testing.T) {
+	cases := []struct {
+		output    string
+		transient bool
+	}{
+		{"ssh: connect to host manager port 2223: Connection refused\nfatal: Could not read from remote repository.", true},
+		{"ssh: Could not resolve hostname manager: Temporary failure in name resolution", true},
+		{"fatal: the remote end hung up unexpectedly\nfatal: early EOF", true},
+		{"error: RPC failed; curl 56 GnuTLS recv error", true},
+		{"git@manager: Permission denied (publickey).\nfatal: Could not read from remote repository.", false},
+		{"Host key verification failed.\nfatal: Could not read from remote repository.", false},
+		{" ! [rejected]        HEAD -> master (non-fast-forward)", false},
+		{"CONFLICT (content): Merge conflict in agents/a.json", false},
+		{"fatal: not a git repository", false},
+	}
+	for _, c := range cases {
+		if got := isTransientGitError(c.output); got != c.transient {
+			t.Errorf("isTransientGitError(%q) = %v, want %v", c.output, got, c.transient)
+		}
+	}
+}
+
+func TestGitCommandName(t *testing.T) {
+	if got := gitCommandName([]string{"-C", "/repo", "fetch", "origin"}); got != "fetch" {
+		t.Errorf("got %q, want fetch", got)
+	}
+	if got := gitCommandName([]string{"clone", "url", "path"}); got != "clone" {
+		t.Errorf("got %q, want clone", got)
+	}
+}
+
+func TestFailureKind(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind string
+	}{
+		{nil, ""},
+		{fmt.Errorf("push: %w (waited 2m0s)", ErrBusy), FailureBusy},
+		{fmt.Errorf("%w: %q is neither a stash nor a backup/ branch", ErrBackupNotFound, "x"), FailureNotFound},
+		{errors.New("git push failed: exit status 128 - output: git@manager: Permission denied (publickey).\nfatal: Could not read from remote repository."), FailureAuth},
+		{errors.New("git push failed: exit status 1 - output:  ! [rejected]        HEAD -> master (non-fast-forward)"), FailureConflict},
+		{errors.New("failed to recover from stash: exit status 1 - output: CONFLICT (content): Merge conflict in agents/a.json"), FailureConflict},
+		{errors.New("failed to recover from stash: exit status 1 - output: error: stash@{7} is not a valid reference"), FailureNotFound},
+		{errors.New("git fetch failed: exit status 128 - output: ssh: connect to host manager port 2223: Connection refused"), FailureUnavailable},
+		{errors.New("failed to marshal config"), FailureError},
+	}
+	for _, c := range cases {
+		if got := FailureKind(c.err); got != c.kind {
+			t.Errorf("FailureKind(%v) = %q, want %q", c.err, got, c.kind)
+		}
+	}
+}
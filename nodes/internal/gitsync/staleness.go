@@ -0,0 +1,31 @@
+package gitsync
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// recordPull notes a successful pull
+func (g *GitSync) recordPull() {
+	g.pullMu.Lock()
+	g.lastPull = time.Now()
+	g.pullMu.Unlock()
+}
+
+// LastSuccessfulPull returns when config was last pulled from the manager.
+// Before the first pull of this run it falls back to the last fetch recorded
+// in the repository, so a restart doesn't make stale config look fresh. It is
+// zero when the repository was never fetched.
+func (g *GitSync) LastSuccessfulPull() time.Time {
+	g.pullMu.Lock()
+	last := g.lastPull
+	g.pullMu.Unlock()
+	if !last.IsZero() {
+		return last
+	}
+	if info, err := os.Stat(filepath.Join(g.repoPath, ".git", "FETCH_HEAD")); err == nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
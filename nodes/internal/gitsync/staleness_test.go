@@ -0,0 +1,33 @@
+package gitsync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestLastSuccessfulPull(t *testing.T) {
+	repo := t.TempDir()
+	g := New(repo, "ssh://git@manager:2223/config-repo", "agent-1", "", zerolog.Nop())
+	if !g.LastSuccessfulPull().IsZero() {
+		t.Error("a repository that was never fetched should have no last pull")
+	}
+
+	// After a restart the last fetch recorded in the repository counts
+	fetched := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	fetchHead := filepath.Join(repo, ".git", "FETCH_HEAD")
+	os.MkdirAll(filepath.Dir(fetchHead), 0755)
+	os.WriteFile(fetchHead, nil, 0644)
+	os.Chtimes(fetchHead, fetched, fetched)
+	if got := g.LastSuccessfulPull(); !got.Equal(fetched) {
+		t.Errorf("last pull %v, want the FETCH_HEAD time %v", got, fetched)
+	}
+
+	g.recordPull()
+	if time.Since(g.LastSuccessfulPull()) > time.Minute {
+		t.Errorf("a pull in this run should win, got %v", g.LastSuccessfulPull())
+	}
+}
@@ -0,0 +1,59 @@
+package gitsync
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestTLSEnv(t *testing.T) {
+	tests := []struct {
+		url      string
+		insecure bool
+		caPath   string
+		want     string
+	}{
+		{"ssh://git@manager:2223/config-repo", true, "/ca.pem", ""},
+		{"https://git.corp/config-repo", false, "", ""},
+		{"https://git.corp/config-repo", false, "/ca.pem", "GIT_SSL_CAINFO=/ca.pem"},
+		{"HTTPS://git.corp/config-repo", true, "/ca.pem", "GIT_SSL_NO_VERIFY=true"},
+	}
+	for _, tt := range tests {
+		g := New(t.TempDir(), tt.url, "agent-1", "", zerolog.Nop())
+		g.SetTLSOptions(tt.insecure, tt.caPath)
+		if got := strings.Join(g.tlsEnv(), " "); got != tt.want {
+			t.Errorf("%s (insecure=%v, ca=%q): env %q, want %q", tt.url, tt.insecure, tt.caPath, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveStaleSSLVerify(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{{"init", "-q", dir}, {"-C", dir, "config", "http.sslVerify", "false"}} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	sslVerify := func() string {
+		out, _ := exec.Command("git", "-C", dir, "config", "--get", "http.sslVerify").Output()
+		return strings.TrimSpace(string(out))
+	}
+
+	g := New(dir, "https://git.corp/config-repo", "agent-1", "", zerolog.Nop())
+	g.SetTLSOptions(true, "")
+	g.removeStaleSSLVerify()
+	if sslVerify() != "false" {
+		t.Error("gitInsecureSSL should keep sslVerify=false for an HTTPS remote")
+	}
+
+	g.SetTLSOptions(false, "")
+	g.removeStaleSSLVerify()
+	if got := sslVerify(); got != "" {
+		t.Errorf("http.sslVerify = %q after cleanup, want it unset", got)
+	}
+}
@@ -0,0 +1,72 @@
+package logrotation
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Log output formats
+const (
+	FormatJSON    = "json"    // One zerolog JSON object per line
+	FormatConsole = "console" // Human-readable zerolog console output
+)
+
+// ValidFormat reports whether format is empty or a known log format
+func ValidFormat(format string) bool {
+	return format == "" || format == FormatJSON || format == FormatConsole
+}
+
+// FormatWriter passes zerolog JSON events to out unchanged or rendered in
+// the console format. The format can be switched while loggers that share
+// the writer are in use.
+type FormatWriter struct {
+	mu      sync.RWMutex
+	format  string
+	out     io.Writer
+	console zerolog.ConsoleWriter
+}
+
+// NewFormatWriter creates a writer for out in the given format, falling back
+// to JSON for an unknown format. Colors are only used in the console format
+// and only when color is true.
+func NewFormatWriter(out io.Writer, format string, color bool) *FormatWriter {
+	fw := &FormatWriter{
+		out:     out,
+		console: zerolog.ConsoleWriter{Out: out, TimeFormat: time.RFC3339, NoColor: !color},
+	}
+	fw.SetFormat(format)
+	return fw
+}
+
+// SetFormat switches the output format. Unknown formats are rejected and the
+// current format is kept.
+func (fw *FormatWriter) SetFormat(format string) error {
+	if format != FormatJSON && format != FormatConsole {
+		return fmt.Errorf("unknown log format %q (use json or console)", format)
+	}
+	fw.mu.Lock()
+	fw.format = format
+	fw.mu.Unlock()
+	return nil
+}
+
+// Format returns the current output format
+func (fw *FormatWriter) Format() string {
+	fw.mu.RLock()
+	defer fw.mu.RUnlock()
+	return fw.format
+}
+
+// Write implements io.Writer
+func (fw *FormatWriter) Write(p []byte) (int, error) {
+	fw.mu.RLock()
+	defer fw.mu.RUnlock()
+	if fw.format == FormatConsole {
+		return fw.console.Write(p)
+	}
+	return fw.out.Write(p)
+}
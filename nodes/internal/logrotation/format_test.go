@@ -0,0 +1,35 @@
+package logrotation
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestFormatWriter_SwitchesFormat(t *testing.T) {
+	var buf bytes.Buffer
+	fw := NewFormatWriter(&buf, FormatJSON, false)
+	logger := zerolog.New(fw)
+
+	logger.Info().Str("rule", "inbox").Msg("first")
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil || entry["message"] != "first" {
+		t.Fatalf("json format wrote %q: %v", buf.String(), err)
+	}
+
+	buf.Reset()
+	if err := fw.SetFormat(FormatConsole); err != nil {
+		t.Fatal(err)
+	}
+	logger.Info().Str("rule", "inbox").Msg("second")
+	if out := buf.String(); strings.HasPrefix(out, "{") || !strings.Contains(out, "second") || !strings.Contains(out, "rule=inbox") {
+		t.Errorf("console format wrote %q", out)
+	}
+
+	if err := fw.SetFormat("xml"); err == nil || fw.Format() != FormatConsole {
+		t.Errorf("unknown format should be rejected and keep console, got %q", fw.Format())
+	}
+}
@@ -0,0 +1,202 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Settings selects the proxies for outbound connections to the manager, S3
+// and HTTP steps. Empty fields fall back to the standard HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY environment variables. Proxy URLs may use the
+// http or socks5 scheme (the only ones the websocket dialer supports); a bare
+// host:port means http.
+type Settings struct {
+	HTTPProxy  string // proxy for http:// and ws:// URLs
+	HTTPSProxy string // proxy for https:// and wss:// URLs (defaults to HTTPProxy)
+	NoProxy    string // comma-separated hosts, domains (.example.com), IPs or CIDRs to reach directly
+}
+
+// resolver is a parsed Settings
+type resolver struct {
+	httpProxy  *url.URL
+	httpsProxy *url.URL
+	noProxy    []string
+}
+
+var (
+	mu      sync.RWMutex
+	current = &resolver{}
+)
+
+// Configure sets the agent-wide proxy settings, merged with the environment
+func Configure(s Settings) error {
+	s = withEnvironment(s)
+	r := &resolver{noProxy: splitList(s.NoProxy)}
+	var err error
+	if r.httpProxy, err = parseProxyURL(s.HTTPProxy); err != nil {
+		return fmt.Errorf("invalid httpProxy: %w", err)
+	}
+	if r.httpsProxy, err = parseProxyURL(s.HTTPSProxy); err != nil {
+		return fmt.Errorf("invalid httpsProxy: %w", err)
+	}
+	if r.httpsProxy == nil {
+		r.httpsProxy = r.httpProxy
+	}
+
+	mu.Lock()
+	current = r
+	mu.Unlock()
+	return nil
+}
+
+// FromRequest returns the proxy for req, or nil to connect directly. It has
+// the signature of http.Transport.Proxy and websocket.Dialer.Proxy.
+func FromRequest(req *http.Request) (*url.URL, error) {
+	mu.RLock()
+	r := current
+	mu.RUnlock()
+	return r.proxyFor(req.URL), nil
+}
+
+// Describe returns the effective proxies for logging, with credentials removed
+func Describe() map[string]string {
+	mu.RLock()
+	r := current
+	mu.RUnlock()
+	out := map[string]string{}
+	if r.httpProxy != nil {
+		out["httpProxy"] = r.httpProxy.Redacted()
+	}
+	if r.httpsProxy != nil {
+		out["httpsProxy"] = r.httpsProxy.Redacted()
+	}
+	if len(r.noProxy) > 0 {
+		out["noProxy"] = strings.Join(r.noProxy, ",")
+	}
+	return out
+}
+
+// Transport returns a clone of http.DefaultTransport that uses the configured proxy
+func Transport() *http.Transport {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.Proxy = FromRequest
+	return tr
+}
+
+// Client returns an HTTP client with the given timeout that uses the configured proxy
+func Client(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: Transport()}
+}
+
+func (r *resolver) proxyFor(u *url.URL) *url.URL {
+	var p *url.URL
+	switch u.Scheme {
+	case "https", "wss":
+		p = r.httpsProxy
+	default:
+		p = r.httpProxy
+	}
+	if p == nil || r.bypass(u) {
+		return nil
+	}
+	return p
+}
+
+// bypass reports whether u should be reached directly. Loopback addresses
+// never go through the proxy.
+func (r *resolver) bypass(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return true
+	}
+
+	for _, entry := range r.noProxy {
+		if entry == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if h, port, err := net.SplitHostPort(entry); err == nil {
+			if port != u.Port() {
+				continue
+			}
+			entry = h
+		}
+		entry = strings.ToLower(entry)
+		domain := strings.TrimPrefix(entry, ".")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// withEnvironment fills empty settings from the standard proxy variables
+func withEnvironment(s Settings) Settings {
+	if s.HTTPProxy == "" {
+		s.HTTPProxy = getenv("HTTP_PROXY", "http_proxy")
+	}
+	if s.HTTPSProxy == "" {
+		s.HTTPSProxy = getenv("HTTPS_PROXY", "https_proxy")
+	}
+	if s.NoProxy == "" {
+		s.NoProxy = getenv("NO_PROXY", "no_proxy")
+	}
+	return s
+}
+
+func getenv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func parseProxyURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing proxy host in %q", raw)
+	}
+	return u, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
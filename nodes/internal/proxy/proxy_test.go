@@ -0,0 +1,49 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestFromRequest(t *testing.T) {
+	for _, name := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("HTTPS_PROXY", "socks5://env-proxy:1080")
+	if err := Configure(Settings{HTTPProxy: "corp-proxy:3128", NoProxy: ".internal, 10.0.0.0/8, s3.local:9000"}); err != nil {
+		t.Fatal(err)
+	}
+	defer Configure(Settings{})
+
+	cases := []struct {
+		url  string
+		want string
+	}{
+		{"http://example.com/file", "http://corp-proxy:3128"},
+		{"wss://manager.example.com/ws", "socks5://env-proxy:1080"},
+		{"https://api.internal/x", ""},
+		{"http://internal/x", ""},
+		{"http://10.1.2.3/x", ""},
+		{"http://s3.local:9000/bucket", ""},
+		{"http://s3.local:9001/bucket", "http://corp-proxy:3128"},
+		{"http://localhost:8088/healthz", ""},
+	}
+	for _, tc := range cases {
+		req, _ := http.NewRequest(http.MethodGet, tc.url, nil)
+		got, err := FromRequest(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotStr := ""
+		if got != nil {
+			gotStr = got.String()
+		}
+		if gotStr != tc.want {
+			t.Errorf("%s: proxy = %q, want %q", tc.url, gotStr, tc.want)
+		}
+	}
+
+	if err := Configure(Settings{HTTPProxy: "ftp://proxy:21"}); err == nil {
+		t.Error("unsupported proxy scheme should be rejected")
+	}
+}
@@ -0,0 +1,100 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Router is the agent's HTTP router. Every component registers its endpoints
+// on one shared Router instead of http.DefaultServeMux. Routes are scoped to
+// a method; a request for a known path with another method gets 405 with an
+// Allow header. Unlike http.ServeMux, registering a route twice returns an
+// error rather than panicking.
+type Router struct {
+	mu     sync.Mutex
+	mux    *http.ServeMux
+	routes map[string]bool // registered "METHOD /path" patterns
+}
+
+// New creates an empty router
+func New() *Router {
+	return &Router{
+		mux:    http.NewServeMux(),
+		routes: make(map[string]bool),
+	}
+}
+
+// Handle registers handler for method and path. An empty method matches any
+// method, leaving the handler to check it. Paths ending in "/" match the whole
+// subtree. GET routes also answer HEAD.
+func (rt *Router) Handle(method, path string, handler http.Handler) (err error) {
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("route path %q must start with /", path)
+	}
+	pattern := path
+	if method != "" {
+		pattern = strings.ToUpper(method) + " " + path
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if rt.routes[pattern] {
+		return fmt.Errorf("route %q is already registered", pattern)
+	}
+	// ServeMux also panics on patterns that conflict without being equal
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("route %q: %v", pattern, r)
+		}
+	}()
+	rt.mux.Handle(pattern, handler)
+	rt.routes[pattern] = true
+	return nil
+}
+
+// HandleFunc registers a handler function for method and path
+func (rt *Router) HandleFunc(method, path string, handler http.HandlerFunc) error {
+	return rt.Handle(method, path, handler)
+}
+
+// Registered reports whether method and path already have a route
+func (rt *Router) Registered(method, path string) bool {
+	pattern := path
+	if method != "" {
+		pattern = strings.ToUpper(method) + " " + path
+	}
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.routes[pattern]
+}
+
+// Routes returns the registered patterns, sorted by path
+func (rt *Router) Routes() []string {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	routes := make([]string, 0, len(rt.routes))
+	for pattern := range rt.routes {
+		routes = append(routes, pattern)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return patternPath(routes[i]) < patternPath(routes[j]) ||
+			patternPath(routes[i]) == patternPath(routes[j]) && routes[i] < routes[j]
+	})
+	return routes
+}
+
+// patternPath strips the method from a pattern
+func patternPath(pattern string) string {
+	if i := strings.IndexByte(pattern, ' '); i >= 0 {
+		return pattern[i+1:]
+	}
+	return pattern
+}
+
+// ServeHTTP implements http.Handler
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.mux.ServeHTTP(w, r)
+}
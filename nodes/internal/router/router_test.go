@@ -0,0 +1,70 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouter_MethodScopedRoutes(t *testing.T) {
+	rt := New()
+	ok := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(body)) }
+	}
+	if err := rt.HandleFunc(http.MethodGet, "/api/drain", ok("status")); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.HandleFunc(http.MethodPost, "/api/drain", ok("drained")); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.HandleFunc("", "/api/webhooks/a", ok("any")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{http.MethodGet, "/api/drain", http.StatusOK, "status"},
+		{http.MethodPost, "/api/drain", http.StatusOK, "drained"},
+		{http.MethodDelete, "/api/drain", http.StatusMethodNotAllowed, ""},
+		{http.MethodPut, "/api/webhooks/a", http.StatusOK, "any"},
+		{http.MethodGet, "/api/missing", http.StatusNotFound, ""},
+	} {
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != tc.code || (tc.body != "" && rec.Body.String() != tc.body) {
+			t.Errorf("%s %s = %d %q, want %d %q", tc.method, tc.path, rec.Code, rec.Body.String(), tc.code, tc.body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/drain", nil))
+	if allow := rec.Header().Get("Allow"); allow == "" {
+		t.Error("405 response should list the allowed methods")
+	}
+}
+
+func TestRouter_DuplicateRouteReturnsError(t *testing.T) {
+	rt := New()
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	if err := rt.HandleFunc(http.MethodPost, "/api/webhooks/orders", h); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.HandleFunc(http.MethodPost, "/api/webhooks/orders", h); err == nil {
+		t.Error("registering the same route twice should fail, not panic")
+	}
+	if err := rt.HandleFunc("", "/api/{id}/x", h); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.HandleFunc("", "/api/y/{name}", h); err == nil {
+		t.Error("conflicting patterns should fail, not panic")
+	}
+	if err := rt.HandleFunc(http.MethodGet, "api/no-slash", h); err == nil {
+		t.Error("paths must start with /")
+	}
+	if !rt.Registered(http.MethodPost, "/api/webhooks/orders") || rt.Registered(http.MethodGet, "/api/webhooks/orders") {
+		t.Error("Registered should match method and path")
+	}
+}
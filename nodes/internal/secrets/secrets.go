@@ -0,0 +1,79 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Store holds named secrets from a local JSON file ({"name": "value"}).
+// The file lives outside the config repository so secrets are never
+// committed or pushed to the manager; it should be readable only by the agent.
+type Store struct {
+	mu     sync.RWMutex
+	path   string
+	values map[string]string
+}
+
+// Load reads the secrets file at path. A missing file yields an empty store.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path, values: make(map[string]string)}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload re-reads the secrets file, keeping the current values on error
+func (s *Store) Reload() error {
+	if s == nil || s.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.mu.Lock()
+			s.values = make(map[string]string)
+			s.mu.Unlock()
+			return nil
+		}
+		return fmt.Errorf("failed to read secrets file: %w", err)
+	}
+	values := make(map[string]string)
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("failed to parse secrets file: %w", err)
+	}
+
+	s.mu.Lock()
+	s.values = values
+	s.mu.Unlock()
+	return nil
+}
+
+// Get returns the named secret
+func (s *Store) Get(name string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.values[name]
+	return value, ok
+}
+
+// Names returns the secret names in sorted order, never their values
+func (s *Store) Names() []string {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.values))
+	for name := range s.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
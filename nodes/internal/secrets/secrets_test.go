@@ -0,0 +1,42 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Get("awsKey"); ok {
+		t.Error("missing file should give an empty store")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"awsKey": "AKIA123", "smtpPassword": "hunter2"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := s.Get("awsKey"); !ok || v != "AKIA123" {
+		t.Errorf("Get(awsKey) = %q, %v", v, ok)
+	}
+	if names := s.Names(); !reflect.DeepEqual(names, []string{"awsKey", "smtpPassword"}) {
+		t.Errorf("Names() = %v", names)
+	}
+
+	if err := os.WriteFile(path, []byte(`not json`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reload(); err == nil {
+		t.Error("malformed secrets file should fail to reload")
+	}
+	if _, ok := s.Get("awsKey"); !ok {
+		t.Error("failed reload should keep the previous secrets")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"sync"
 
 	"github.com/rs/zerolog"
+	"github.com/your-org/controlcenter/nodes/internal/audit"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -21,6 +22,7 @@ const (
 
 type SSHServer struct {
 	port       int
+	listenAddr string // host:port to listen on; empty listens on all interfaces at port
 	privateKey ssh.Signer
 	keysMu     sync.RWMutex
 	authorizedKeys []ssh.PublicKey
@@ -28,6 +30,8 @@ type SSHServer struct {
 	allowedPaths   []string
 	logger     zerolog.Logger
 	listener   net.Listener
+	audit      *audit.Logger
+	maxCommands int // Exec and sftp requests allowed per connection (0 = unlimited)
 }
 
 func New(port int, privateKeyPath string, authorizedKeysList []string, logger zerolog.Logger) (*SSHServer, error) {
@@ -61,6 +65,7 @@ func New(port int, privateKeyPath string, authorizedKeysList []string, logger ze
 		privateKey:     privateKey,
 		authorizedKeys: authorizedKeys,
 		logger:         logger,
+		maxCommands:    DefaultMaxCommandsPerSession,
 	}, nil
 }
 
@@ -96,19 +101,28 @@ func (s *SSHServer) SetAllowedPaths(paths []string) {
 	s.allowedPaths = append([]string(nil), paths...)
 }
 
+// SetListenAddr restricts the server to a host:port, e.g. a management interface
+func (s *SSHServer) SetListenAddr(addr string) {
+	s.listenAddr = addr
+}
+
 func (s *SSHServer) Start() error {
 	config := &ssh.ServerConfig{
 		PublicKeyCallback: s.authCallback,
 	}
 	config.AddHostKey(s.privateKey)
 
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	addr := s.listenAddr
+	if addr == "" {
+		addr = fmt.Sprintf(":%d", s.port)
+	}
+	listener, err := net.Listen("tcp", addr)
 	if err != nil {
-		return fmt.Errorf("failed to listen on port %d: %w", s.port, err)
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
 	}
 	s.listener = listener
 
-	s.logger.Info().Int("port", s.port).Msg("SSH server started")
+	s.logger.Info().Str("addr", addr).Msg("SSH server started")
 
 	for {
 		conn, err := listener.Accept()
@@ -131,6 +145,24 @@ func (s *SSHServer) Stop() error {
 	return nil
 }
 
+// SetMaxCommandsPerSession limits the exec and sftp requests one connection
+// may make. 0 keeps the default, a negative value removes the limit.
+func (s *SSHServer) SetMaxCommandsPerSession(n int) {
+	switch {
+	case n == 0:
+		s.maxCommands = DefaultMaxCommandsPerSession
+	case n < 0:
+		s.maxCommands = 0
+	default:
+		s.maxCommands = n
+	}
+}
+
+// SetAuditLogger sets where authentication and command events are recorded
+func (s *SSHServer) SetAuditLogger(a *audit.Logger) {
+	s.audit = a
+}
+
 func (s *SSHServer) authCallback(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
 	s.keysMu.RLock()
 	keys := s.authorizedKeys
@@ -139,14 +171,23 @@ func (s *SSHServer) authCallback(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh
 	for _, authorizedKey := range keys {
 		if string(authorizedKey.Marshal()) == string(key.Marshal()) {
 			s.logger.Info().Str("user", conn.User()).Msg("SSH authentication successful")
+			s.audit.Record("ssh.auth", conn.RemoteAddr().String(), audit.OutcomeSuccess, map[string]interface{}{
+				"user":        conn.User(),
+				"fingerprint": ssh.FingerprintSHA256(key),
+			})
 			return &ssh.Permissions{
 				Extensions: map[string]string{
-					"user": conn.User(),
+					"user":        conn.User(),
+					"fingerprint": ssh.FingerprintSHA256(key),
 				},
 			}, nil
 		}
 	}
 	s.logger.Warn().Str("user", conn.User()).Msg("SSH authentication failed")
+	s.audit.Record("ssh.auth", conn.RemoteAddr().String(), audit.OutcomeFailure, map[string]interface{}{
+		"user":        conn.User(),
+		"fingerprint": ssh.FingerprintSHA256(key),
+	})
 	return nil, fmt.Errorf("unknown public key")
 }
 
@@ -161,66 +202,79 @@ func (s *SSHServer) handleConnection(conn net.Conn, config *ssh.ServerConfig) {
 	}
 	defer sshConn.Close()
 
-	s.logger.Info().Str("user", sshConn.User()).Str("remote", sshConn.RemoteAddr().String()).Msg("New SSH connection")
+	sess := newSession(sshConn, s.maxCommands, s.logger)
+	sess.logger.Info().Str("remote", sess.remote).Msg("New SSH connection")
+	defer func() {
+		sess.logger.Info().Int("commands", sess.commandCount()).Msg("SSH connection closed")
+	}()
 
 	// Handle out-of-band requests
 	go ssh.DiscardRequests(reqs)
 
 	// Handle channels
 	for newChannel := range chans {
-		s.handleChannel(newChannel)
+		s.handleChannel(newChannel, sess)
 	}
 }
 
-func (s *SSHServer) handleChannel(newChannel ssh.NewChannel) {
+func (s *SSHServer) handleChannel(newChannel ssh.NewChannel, sess *session) {
 	switch newChannel.ChannelType() {
 	case "session":
-		s.handleSession(newChannel)
+		s.handleSession(newChannel, sess)
 	case "direct-tcpip":
-		s.logger.Warn().Str("type", newChannel.ChannelType()).Msg("TCP forwarding not supported")
+		sess.logger.Warn().Str("type", newChannel.ChannelType()).Msg("TCP forwarding not supported")
 		newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
 	default:
-		s.logger.Warn().Str("type", newChannel.ChannelType()).Msg("Unknown channel type")
+		sess.logger.Warn().Str("type", newChannel.ChannelType()).Msg("Unknown channel type")
 		newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
 	}
 }
 
-func (s *SSHServer) handleSession(newChannel ssh.NewChannel) {
+func (s *SSHServer) handleSession(newChannel ssh.NewChannel, sess *session) {
 	channel, requests, err := newChannel.Accept()
 	if err != nil {
-		s.logger.Error().Err(err).Msg("Failed to accept channel")
+		sess.logger.Error().Err(err).Msg("Failed to accept channel")
 		return
 	}
 	defer channel.Close()
 
 	for req := range requests {
+		if (req.Type == "exec" || req.Type == "subsystem") && !sess.allow() {
+			sess.logger.Warn().Str("type", req.Type).Int("limit", sess.maxCommands).Msg("🚫 SSH session command limit reached, request refused")
+			details := sess.auditDetails()
+			details["request"] = req.Type
+			details["limit"] = sess.maxCommands
+			s.audit.Record("ssh.session-limit", sess.remote, audit.OutcomeDenied, details)
+			req.Reply(false, nil)
+			continue
+		}
 		switch req.Type {
 		case "exec":
 			if len(req.Payload) < 4 {
-				s.logger.Warn().Msg("exec request payload too short")
+				sess.logger.Warn().Msg("exec request payload too short")
 				req.Reply(false, nil)
 				continue
 			}
-			s.handleExec(channel, req)
+			s.handleExec(channel, req, sess)
 		case "subsystem":
 			if len(req.Payload) < 4 {
-				s.logger.Warn().Msg("subsystem request payload too short")
+				sess.logger.Warn().Msg("subsystem request payload too short")
 				req.Reply(false, nil)
 				continue
 			}
 			if string(req.Payload[4:]) == "sftp" {
-				s.handleSFTP(channel, req)
+				s.handleSFTP(channel, req, sess)
 			} else {
 				req.Reply(false, nil)
 			}
 		default:
-			s.logger.Debug().Str("type", req.Type).Msg("Unknown request type")
+			sess.logger.Debug().Str("type", req.Type).Msg("Unknown request type")
 			req.Reply(false, nil)
 		}
 	}
 }
 
-func (s *SSHServer) handleExec(channel ssh.Channel, req *ssh.Request) {
+func (s *SSHServer) handleExec(channel ssh.Channel, req *ssh.Request, sess *session) {
 	// Parse command from request
 	cmdLen := int(req.Payload[0])<<24 | int(req.Payload[1])<<16 | int(req.Payload[2])<<8 | int(req.Payload[3])
 	if cmdLen > len(req.Payload)-4 || cmdLen < 0 {
@@ -230,7 +284,7 @@ func (s *SSHServer) handleExec(channel ssh.Channel, req *ssh.Request) {
 	cmdStr := string(req.Payload[4 : 4+cmdLen])
 
 	// Security: Log all SSH command attempts for audit
-	s.logger.Info().
+	sess.logger.Info().
 		Str("command", cmdStr).
 		Msg("SSH command execution requested")
 
@@ -248,28 +302,33 @@ func (s *SSHServer) handleExec(channel ssh.Channel, req *ssh.Request) {
 	// Connect stdin/stdout/stderr
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
-		s.logger.Error().Err(err).Msg("Failed to get stdin pipe")
+		sess.logger.Error().Err(err).Msg("Failed to get stdin pipe")
 		req.Reply(false, nil)
 		return
 	}
 	
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		s.logger.Error().Err(err).Msg("Failed to get stdout pipe")
+		sess.logger.Error().Err(err).Msg("Failed to get stdout pipe")
 		req.Reply(false, nil)
 		return
 	}
 	
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		s.logger.Error().Err(err).Msg("Failed to get stderr pipe")
+		sess.logger.Error().Err(err).Msg("Failed to get stderr pipe")
 		req.Reply(false, nil)
 		return
 	}
 
+	auditDetails := sess.auditDetails()
+	auditDetails["command"] = cmdStr
+
 	// Start command
 	if err := cmd.Start(); err != nil {
-		s.logger.Error().Err(err).Msg("Failed to start command")
+		sess.logger.Error().Err(err).Msg("Failed to start command")
+		auditDetails["error"] = err.Error()
+		s.audit.Record("ssh.exec", sess.remote, audit.OutcomeFailure, auditDetails)
 		req.Reply(false, nil)
 		return
 	}
@@ -285,15 +344,18 @@ func (s *SSHServer) handleExec(channel ssh.Channel, req *ssh.Request) {
 
 	// Wait for command to complete
 	if err := cmd.Wait(); err != nil {
-		s.logger.Error().Err(err).Msg("Command execution failed")
+		sess.logger.Error().Err(err).Msg("Command execution failed")
+		auditDetails["error"] = err.Error()
+		s.audit.Record("ssh.exec", sess.remote, audit.OutcomeFailure, auditDetails)
 		channel.SendRequest("exit-status", false, []byte{0, 0, 0, 1})
 	} else {
+		s.audit.Record("ssh.exec", sess.remote, audit.OutcomeSuccess, auditDetails)
 		channel.SendRequest("exit-status", false, []byte{0, 0, 0, 0})
 	}
 }
 
-func (s *SSHServer) handleSFTP(channel ssh.Channel, req *ssh.Request) {
-	s.logger.Info().Msg("SFTP session requested")
+func (s *SSHServer) handleSFTP(channel ssh.Channel, req *ssh.Request, sess *session) {
+	sess.logger.Info().Msg("SFTP session requested")
 	req.Reply(true, nil)
 	
 	// For now, implement a simple file transfer protocol
@@ -303,17 +365,17 @@ func (s *SSHServer) handleSFTP(channel ssh.Channel, req *ssh.Request) {
 	opType := make([]byte, 1)
 	_, err := channel.Read(opType)
 	if err != nil {
-		s.logger.Error().Err(err).Msg("Failed to read SFTP operation")
+		sess.logger.Error().Err(err).Msg("Failed to read SFTP operation")
 		return
 	}
 
 	switch opType[0] {
 	case 'G': // Get file
-		s.handleSFTPGet(channel)
+		s.handleSFTPGet(channel, sess)
 	case 'P': // Put file
-		s.handleSFTPPut(channel)
+		s.handleSFTPPut(channel, sess)
 	default:
-		s.logger.Warn().Int("op", int(opType[0])).Msg("Unknown SFTP operation")
+		sess.logger.Warn().Int("op", int(opType[0])).Msg("Unknown SFTP operation")
 	}
 }
 
@@ -365,18 +427,18 @@ func (s *SSHServer) validatePath(rawPath string) (string, error) {
 	return "", fmt.Errorf("path %q is not under any allowed directory", rawPath)
 }
 
-func (s *SSHServer) handleSFTPGet(channel ssh.Channel) {
+func (s *SSHServer) handleSFTPGet(channel ssh.Channel, sess *session) {
 	// Read filename length
 	lenBytes := make([]byte, 4)
 	if _, err := io.ReadFull(channel, lenBytes); err != nil {
-		s.logger.Error().Err(err).Msg("Failed to read filename length")
+		sess.logger.Error().Err(err).Msg("Failed to read filename length")
 		return
 	}
 
 	fileLen := int(lenBytes[0])<<24 | int(lenBytes[1])<<16 | int(lenBytes[2])<<8 | int(lenBytes[3])
 
 	if fileLen < 0 || fileLen > maxFilenameLen {
-		s.logger.Warn().Int("fileLen", fileLen).Msg("SFTP GET: filename length out of range")
+		sess.logger.Warn().Int("fileLen", fileLen).Msg("SFTP GET: filename length out of range")
 		channel.Write([]byte{0})
 		return
 	}
@@ -384,25 +446,28 @@ func (s *SSHServer) handleSFTPGet(channel ssh.Channel) {
 	// Read filename
 	filename := make([]byte, fileLen)
 	if _, err := io.ReadFull(channel, filename); err != nil {
-		s.logger.Error().Err(err).Msg("Failed to read filename")
+		sess.logger.Error().Err(err).Msg("Failed to read filename")
 		return
 	}
 
 	filePath, err := s.validatePath(string(filename))
 	if err != nil {
-		s.logger.Warn().Err(err).Str("file", string(filename)).Msg("SFTP GET: path rejected")
+		sess.logger.Warn().Err(err).Str("file", string(filename)).Msg("SFTP GET: path rejected")
+		s.auditSFTP(sess, "get", string(filename), audit.OutcomeDenied, err)
 		channel.Write([]byte{0})
 		return
 	}
-	s.logger.Info().Str("file", filePath).Msg("SFTP GET request")
+	sess.logger.Info().Str("file", filePath).Msg("SFTP GET request")
 
 	// Read file
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		s.logger.Error().Err(err).Msg("Failed to read file")
+		sess.logger.Error().Err(err).Msg("Failed to read file")
+		s.auditSFTP(sess, "get", filePath, audit.OutcomeFailure, err)
 		channel.Write([]byte{0}) // Error
 		return
 	}
+	s.auditSFTP(sess, "get", filePath, audit.OutcomeSuccess, nil)
 
 	// Send success and file size
 	channel.Write([]byte{1}) // Success
@@ -417,18 +482,18 @@ func (s *SSHServer) handleSFTPGet(channel ssh.Channel) {
 	channel.Write(data)
 }
 
-func (s *SSHServer) handleSFTPPut(channel ssh.Channel) {
+func (s *SSHServer) handleSFTPPut(channel ssh.Channel, sess *session) {
 	// Read filename length
 	lenBytes := make([]byte, 4)
 	if _, err := io.ReadFull(channel, lenBytes); err != nil {
-		s.logger.Error().Err(err).Msg("Failed to read filename length")
+		sess.logger.Error().Err(err).Msg("Failed to read filename length")
 		return
 	}
 
 	fileLen := int(lenBytes[0])<<24 | int(lenBytes[1])<<16 | int(lenBytes[2])<<8 | int(lenBytes[3])
 
 	if fileLen < 0 || fileLen > maxFilenameLen {
-		s.logger.Warn().Int("fileLen", fileLen).Msg("SFTP PUT: filename length out of range")
+		sess.logger.Warn().Int("fileLen", fileLen).Msg("SFTP PUT: filename length out of range")
 		channel.Write([]byte{0})
 		return
 	}
@@ -436,22 +501,23 @@ func (s *SSHServer) handleSFTPPut(channel ssh.Channel) {
 	// Read filename
 	filename := make([]byte, fileLen)
 	if _, err := io.ReadFull(channel, filename); err != nil {
-		s.logger.Error().Err(err).Msg("Failed to read filename")
+		sess.logger.Error().Err(err).Msg("Failed to read filename")
 		return
 	}
 
 	filePath, err := s.validatePath(string(filename))
 	if err != nil {
-		s.logger.Warn().Err(err).Str("file", string(filename)).Msg("SFTP PUT: path rejected")
+		sess.logger.Warn().Err(err).Str("file", string(filename)).Msg("SFTP PUT: path rejected")
+		s.auditSFTP(sess, "put", string(filename), audit.OutcomeDenied, err)
 		channel.Write([]byte{0})
 		return
 	}
-	s.logger.Info().Str("file", filePath).Msg("SFTP PUT request")
+	sess.logger.Info().Str("file", filePath).Msg("SFTP PUT request")
 
 	// Read file size
 	sizeBytes := make([]byte, 8)
 	if _, err := io.ReadFull(channel, sizeBytes); err != nil {
-		s.logger.Error().Err(err).Msg("Failed to read file size")
+		sess.logger.Error().Err(err).Msg("Failed to read file size")
 		return
 	}
 
@@ -461,7 +527,7 @@ func (s *SSHServer) handleSFTPPut(channel ssh.Channel) {
 	}
 
 	if size > maxUploadSize {
-		s.logger.Warn().Uint64("size", size).Msg("SFTP PUT: file size exceeds limit")
+		sess.logger.Warn().Uint64("size", size).Msg("SFTP PUT: file size exceeds limit")
 		channel.Write([]byte{0})
 		return
 	}
@@ -469,7 +535,7 @@ func (s *SSHServer) handleSFTPPut(channel ssh.Channel) {
 	// Read file data
 	data := make([]byte, size)
 	if _, err := io.ReadFull(channel, data); err != nil {
-		s.logger.Error().Err(err).Msg("Failed to read file data")
+		sess.logger.Error().Err(err).Msg("Failed to read file data")
 		channel.Write([]byte{0}) // Error
 		return
 	}
@@ -477,17 +543,31 @@ func (s *SSHServer) handleSFTPPut(channel ssh.Channel) {
 	// Ensure directory exists
 	dir := filepath.Dir(filePath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		s.logger.Error().Err(err).Msg("Failed to create directory")
+		sess.logger.Error().Err(err).Msg("Failed to create directory")
+		s.auditSFTP(sess, "put", filePath, audit.OutcomeFailure, err)
 		channel.Write([]byte{0}) // Error
 		return
 	}
 
 	// Write file
 	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		s.logger.Error().Err(err).Msg("Failed to write file")
+		sess.logger.Error().Err(err).Msg("Failed to write file")
+		s.auditSFTP(sess, "put", filePath, audit.OutcomeFailure, err)
 		channel.Write([]byte{0}) // Error
 		return
 	}
 
+	s.auditSFTP(sess, "put", filePath, audit.OutcomeSuccess, nil)
 	channel.Write([]byte{1}) // Success
 }
+
+// auditSFTP records a file transfer with the session it belongs to
+func (s *SSHServer) auditSFTP(sess *session, op, file, outcome string, err error) {
+	details := sess.auditDetails()
+	details["op"] = op
+	details["file"] = file
+	if err != nil {
+		details["error"] = err.Error()
+	}
+	s.audit.Record("ssh.sftp", sess.remote, outcome, details)
+}
@@ -0,0 +1,74 @@
+package sshserver
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultMaxCommandsPerSession caps the exec and sftp requests one SSH
+// connection may make unless SetMaxCommandsPerSession says otherwise
+const DefaultMaxCommandsPerSession = 100
+
+// session is one authenticated SSH connection. Everything it does is logged
+// and audited with its id and the fingerprint of the key that authenticated
+// it, so actions can be traced back to an operator.
+type session struct {
+	id          string
+	user        string
+	fingerprint string
+	remote      string
+	logger      zerolog.Logger
+
+	mu          sync.Mutex
+	commands    int
+	maxCommands int // 0 = unlimited
+}
+
+func newSession(conn *ssh.ServerConn, maxCommands int, logger zerolog.Logger) *session {
+	sess := &session{
+		id:          uuid.New().String(),
+		user:        conn.User(),
+		remote:      conn.RemoteAddr().String(),
+		maxCommands: maxCommands,
+	}
+	if conn.Permissions != nil {
+		sess.fingerprint = conn.Permissions.Extensions["fingerprint"]
+	}
+	sess.logger = logger.With().
+		Str("session", sess.id).
+		Str("user", sess.user).
+		Str("fingerprint", sess.fingerprint).
+		Logger()
+	return sess
+}
+
+// allow counts an exec or sftp request and reports whether it is within the
+// session's limit
+func (sess *session) allow() bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.maxCommands > 0 && sess.commands >= sess.maxCommands {
+		return false
+	}
+	sess.commands++
+	return true
+}
+
+// commandCount returns the exec and sftp requests allowed so far
+func (sess *session) commandCount() int {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.commands
+}
+
+// auditDetails returns the fields every audit record of the session carries
+func (sess *session) auditDetails() map[string]interface{} {
+	return map[string]interface{}{
+		"session":     sess.id,
+		"user":        sess.user,
+		"fingerprint": sess.fingerprint,
+	}
+}
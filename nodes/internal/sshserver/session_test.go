@@ -0,0 +1,35 @@
+package sshserver
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestSessionCommandLimit(t *testing.T) {
+	s := &SSHServer{}
+	s.SetMaxCommandsPerSession(0)
+	if s.maxCommands != DefaultMaxCommandsPerSession {
+		t.Errorf("0 should keep the default, got %d", s.maxCommands)
+	}
+
+	s.SetMaxCommandsPerSession(2)
+	sess := &session{maxCommands: s.maxCommands, logger: zerolog.Nop()}
+	if !sess.allow() || !sess.allow() {
+		t.Fatal("commands within the limit were refused")
+	}
+	if sess.allow() {
+		t.Error("third command allowed past a limit of 2")
+	}
+	if sess.commandCount() != 2 {
+		t.Errorf("refused commands should not be counted, got %d", sess.commandCount())
+	}
+
+	s.SetMaxCommandsPerSession(-1)
+	unlimited := &session{maxCommands: s.maxCommands}
+	for i := 0; i < DefaultMaxCommandsPerSession+1; i++ {
+		if !unlimited.allow() {
+			t.Fatalf("command %d refused without a limit", i+1)
+		}
+	}
+}
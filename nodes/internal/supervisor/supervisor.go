@@ -0,0 +1,178 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Defaults for restarting failed components
+const (
+	DefaultInitialDelay = time.Second
+	DefaultMaxDelay     = 5 * time.Minute
+
+	// A run lasting this long counts as healthy and resets the backoff
+	DefaultStableAfter = time.Minute
+)
+
+// Restart describes a failed component that is about to be started again
+type Restart struct {
+	Component string        `json:"component"`
+	Attempt   int           `json:"attempt"` // Consecutive failures, 1 for the first
+	Error     string        `json:"error"`
+	Delay     time.Duration `json:"delay"`
+}
+
+// ComponentStatus is what Status reports for a supervised component
+type ComponentStatus struct {
+	Component   string     `json:"component"`
+	Running     bool       `json:"running"`
+	Restarts    int        `json:"restarts"`
+	LastError   string     `json:"lastError,omitempty"`
+	LastRestart *time.Time `json:"lastRestart,omitempty"`
+}
+
+// Supervisor keeps long-running components (listeners, connection loops)
+// alive by restarting them with exponential backoff when they fail
+type Supervisor struct {
+	logger       zerolog.Logger
+	initialDelay time.Duration
+	maxDelay     time.Duration
+	stableAfter  time.Duration
+	onRestart    func(Restart)
+
+	mu         sync.Mutex
+	components map[string]*ComponentStatus
+}
+
+// New creates a supervisor with the default backoff
+func New(logger zerolog.Logger) *Supervisor {
+	return &Supervisor{
+		logger:       logger,
+		initialDelay: DefaultInitialDelay,
+		maxDelay:     DefaultMaxDelay,
+		stableAfter:  DefaultStableAfter,
+		components:   make(map[string]*ComponentStatus),
+	}
+}
+
+// SetBackoff overrides the restart delays. Zero values keep the current ones.
+func (s *Supervisor) SetBackoff(initialDelay, maxDelay, stableAfter time.Duration) {
+	if initialDelay > 0 {
+		s.initialDelay = initialDelay
+	}
+	if maxDelay > 0 {
+		s.maxDelay = maxDelay
+	}
+	if stableAfter > 0 {
+		s.stableAfter = stableAfter
+	}
+	if s.maxDelay < s.initialDelay {
+		s.maxDelay = s.initialDelay
+	}
+}
+
+// OnRestart sets a callback invoked before each restart, e.g. to report it
+func (s *Supervisor) OnRestart(fn func(Restart)) {
+	s.onRestart = fn
+}
+
+// Run runs fn until ctx is cancelled. When fn returns an error or panics it
+// is started again after a backoff delay; when it returns nil the component
+// stopped on purpose and Run returns. Run blocks, so call it with go.
+func (s *Supervisor) Run(ctx context.Context, name string, fn func(ctx context.Context) error) {
+	status := s.component(name)
+	delay := s.initialDelay
+	attempt := 0
+	for {
+		s.setRunning(status, true)
+		started := time.Now()
+		err := s.runOnce(ctx, fn)
+		s.setRunning(status, false)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+
+		if time.Since(started) >= s.stableAfter {
+			delay = s.initialDelay
+			attempt = 0
+		}
+		attempt++
+
+		now := time.Now()
+		s.mu.Lock()
+		status.Restarts++
+		status.LastError = err.Error()
+		status.LastRestart = &now
+		s.mu.Unlock()
+
+		restart := Restart{Component: name, Attempt: attempt, Error: err.Error(), Delay: delay}
+		s.logger.Error().
+			Err(err).
+			Str("component", name).
+			Int("attempt", attempt).
+			Dur("retryIn", delay).
+			Msg("🔁 Component failed, restarting")
+		if s.onRestart != nil {
+			s.onRestart(restart)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		delay *= 2
+		if delay > s.maxDelay {
+			delay = s.maxDelay
+		}
+	}
+}
+
+// runOnce calls fn, turning a panic into an error. It only sees panics in
+// fn's own goroutine; goroutines fn starts must recover their own.
+func (s *Supervisor) runOnce(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error().Str("stack", string(debug.Stack())).Msgf("Recovered panic: %v", r)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+func (s *Supervisor) component(name string) *ComponentStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, ok := s.components[name]
+	if !ok {
+		status = &ComponentStatus{Component: name}
+		s.components[name] = status
+	}
+	return status
+}
+
+func (s *Supervisor) setRunning(status *ComponentStatus, running bool) {
+	s.mu.Lock()
+	status.Running = running
+	s.mu.Unlock()
+}
+
+// Status reports every supervised component, sorted by name
+func (s *Supervisor) Status() []ComponentStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]ComponentStatus, 0, len(s.components))
+	for _, status := range s.components {
+		result = append(result, *status)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Component < result[j].Component })
+	return result
+}
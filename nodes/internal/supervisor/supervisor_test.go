@@ -0,0 +1,66 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestRun_RestartsUntilStoppedOnPurpose(t *testing.T) {
+	s := New(zerolog.Nop())
+	s.SetBackoff(time.Millisecond, 4*time.Millisecond, time.Hour)
+	var restarts []Restart
+	s.OnRestart(func(r Restart) { restarts = append(restarts, r) })
+
+	calls := 0
+	s.Run(context.Background(), "listener", func(ctx context.Context) error {
+		calls++
+		switch calls {
+		case 1:
+			return errors.New("address already in use")
+		case 2:
+			panic("bad message")
+		case 3:
+			return errors.New("address already in use")
+		}
+		return nil
+	})
+
+	if calls != 4 {
+		t.Fatalf("fn ran %d times, want 4", calls)
+	}
+	if len(restarts) != 3 || restarts[1].Error != "panic: bad message" {
+		t.Fatalf("restarts = %+v", restarts)
+	}
+	if restarts[0].Delay != time.Millisecond || restarts[1].Delay != 2*time.Millisecond || restarts[2].Delay != 4*time.Millisecond {
+		t.Errorf("delays = %v, %v, %v; want exponential backoff", restarts[0].Delay, restarts[1].Delay, restarts[2].Delay)
+	}
+
+	status := s.Status()
+	if len(status) != 1 || status[0].Restarts != 3 || status[0].Running || status[0].LastError != "address already in use" {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestRun_StopsWhenContextCancelled(t *testing.T) {
+	s := New(zerolog.Nop())
+	s.SetBackoff(time.Hour, time.Hour, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, "client", func(ctx context.Context) error {
+			return errors.New("connection lost")
+		})
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
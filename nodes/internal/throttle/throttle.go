@@ -0,0 +1,123 @@
+package throttle
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// maxChunk caps a single read so throttled transfers progress smoothly
+const maxChunk = 32 * 1024
+
+// Limiter is a token bucket shared by every reader it throttles, so one
+// limiter caps the combined bandwidth of concurrent transfers.
+// A nil *Limiter imposes no limit.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a limiter allowing bytesPerSecond. It returns nil
+// (unlimited) when bytesPerSecond <= 0.
+func NewLimiter(bytesPerSecond int64) *Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &Limiter{
+		rate:   float64(bytesPerSecond),
+		tokens: float64(bytesPerSecond),
+		last:   time.Now(),
+	}
+}
+
+// Rate returns the configured limit in bytes per second (0 = unlimited)
+func (l *Limiter) Rate() int64 {
+	if l == nil {
+		return 0
+	}
+	return int64(l.rate)
+}
+
+// WaitN blocks until n bytes may be transferred
+func (l *Limiter) WaitN(n int) {
+	if l == nil || n <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate // burst of at most one second
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// reader throttles reads through one or more limiters
+type reader struct {
+	r        io.Reader
+	limiters []*Limiter
+	chunk    int
+}
+
+// readSeeker preserves io.Seeker so callers such as the S3 SDK can rewind
+type readSeeker struct {
+	*reader
+}
+
+// NewReader wraps r so reads respect every non-nil limiter. If no limiter is
+// set r is returned unchanged. When r implements io.Seeker, so does the result.
+func NewReader(r io.Reader, limiters ...*Limiter) io.Reader {
+	active := make([]*Limiter, 0, len(limiters))
+	chunk := maxChunk
+	for _, l := range limiters {
+		if l == nil {
+			continue
+		}
+		active = append(active, l)
+		if rate := int(l.rate); rate < chunk {
+			chunk = rate
+		}
+	}
+	if len(active) == 0 {
+		return r
+	}
+
+	tr := &reader{r: r, limiters: active, chunk: chunk}
+	if _, ok := r.(io.Seeker); ok {
+		return readSeeker{tr}
+	}
+	return tr
+}
+
+func (t *reader) Read(p []byte) (int, error) {
+	if len(p) > t.chunk {
+		p = p[:t.chunk]
+	}
+	n, err := t.r.Read(p)
+	for _, l := range t.limiters {
+		l.WaitN(n)
+	}
+	return n, err
+}
+
+func (t readSeeker) Seek(offset int64, whence int) (int64, error) {
+	seeker, ok := t.r.(io.Seeker)
+	if !ok {
+		return 0, errors.New("underlying reader does not support seeking")
+	}
+	return seeker.Seek(offset, whence)
+}
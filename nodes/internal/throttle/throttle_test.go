@@ -0,0 +1,33 @@
+package throttle
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestNewReader_Unlimited(t *testing.T) {
+	src := bytes.NewReader([]byte("data"))
+	if r := NewReader(src, nil, NewLimiter(0)); r != io.Reader(src) {
+		t.Error("expected reader to be returned unchanged without limits")
+	}
+}
+
+func TestNewReader_Throttles(t *testing.T) {
+	// 1000 B/s with a one-second burst: 2000 bytes need roughly one second
+	limiter := NewLimiter(1000)
+	r := NewReader(bytes.NewReader(make([]byte, 2000)), limiter)
+	if _, ok := r.(io.Seeker); !ok {
+		t.Error("throttled reader should preserve io.Seeker")
+	}
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, r)
+	if err != nil || n != 2000 {
+		t.Fatalf("copy = %d, %v", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("expected throttling to take about 1s, took %v", elapsed)
+	}
+}
@@ -7,11 +7,14 @@ import (
 	"net/url"
 	"os"
 	"runtime"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/rs/zerolog"
+	"github.com/your-org/controlcenter/nodes/internal/proxy"
 )
 
 type Client struct {
@@ -23,10 +26,29 @@ type Client struct {
 	logger     zerolog.Logger
 	reconnectInterval time.Duration
 	pingInterval     time.Duration
+	maxMissedAcks    int          // Reconnect after this many unacknowledged heartbeats (0 = never)
+	lastAck          atomic.Int64 // UnixNano of the last heartbeat_ack on the current connection (0 = none yet)
+
+	tags       func() map[string]string // labels included in registration and heartbeats
 
 	onMessage  func(MessageType, json.RawMessage)
 	onConnect  func()
 	onDisconnect func()
+
+	stateMu sync.RWMutex
+	state   ConnectionState
+}
+
+// ConnectionState is the client's view of its link to the manager
+type ConnectionState struct {
+	URL               string     `json:"url"`
+	Connected         bool       `json:"connected"`
+	LastConnect       *time.Time `json:"lastConnect,omitempty"`
+	LastDisconnect    *time.Time `json:"lastDisconnect,omitempty"`
+	LastError         string     `json:"lastError,omitempty"`
+	LastHeartbeatAck  *time.Time `json:"lastHeartbeatAck,omitempty"`
+	ReconnectAttempts int        `json:"reconnectAttempts"` // Failed dials since the last successful connect
+	TotalConnects     int        `json:"totalConnects"`
 }
 
 type MessageType string
@@ -38,8 +60,22 @@ const (
 	MessageTypeRegistration MessageType = "registration"
 	MessageTypeStatus      MessageType = "status"
 	MessageTypeAlert       MessageType = "alert"
+	MessageTypeHeartbeatAck MessageType = "heartbeat_ack"
+	MessageTypeCommandResult MessageType = "command_result"
 )
 
+// CommandResult is the agent's reply to a manager command. Every command
+// handler reports through it so the manager can treat replies uniformly.
+type CommandResult struct {
+	Command   string                 `json:"command"`
+	RequestID string                 `json:"requestId,omitempty"` // Echoed from the command for correlation
+	Success   bool                   `json:"success"`
+	Status    string                 `json:"status"` // Outcome, e.g. "git-pulled", "approval-required" or "error"
+	Message   string                 `json:"message,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Timestamp int64                  `json:"timestamp"`
+}
+
 type Message struct {
 	Type    MessageType     `json:"type"`
 	AgentID string          `json:"agentId,omitempty"`
@@ -65,9 +101,41 @@ func NewClient(managerURL, agentID string, logger zerolog.Logger) *Client {
 		logger:            logger,
 		reconnectInterval: 5 * time.Second,
 		pingInterval:      30 * time.Second,
+		maxMissedAcks:     3,
+		state:             ConnectionState{URL: u.String()},
+	}
+}
+
+// SetIntervals overrides the heartbeat and reconnect intervals. Zero keeps the current value.
+func (c *Client) SetIntervals(ping, reconnect time.Duration) {
+	if ping > 0 {
+		c.pingInterval = ping
+	}
+	if reconnect > 0 {
+		c.reconnectInterval = reconnect
 	}
 }
 
+// SetMaxMissedHeartbeats sets how many heartbeats may go unacknowledged before
+// the client drops the connection and reconnects. Zero or less disables the check.
+func (c *Client) SetMaxMissedHeartbeats(n int) {
+	c.maxMissedAcks = n
+}
+
+// State returns a snapshot of the connection state
+func (c *Client) State() ConnectionState {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return c.state
+}
+
+// updateState applies fn to the connection state under the lock
+func (c *Client) updateState(fn func(*ConnectionState)) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	fn(&c.state)
+}
+
 func (c *Client) OnMessage(handler func(MessageType, json.RawMessage)) {
 	c.onMessage = handler
 }
@@ -87,6 +155,7 @@ func (c *Client) Start(ctx context.Context) {
 			return
 		default:
 			if err := c.connect(ctx); err != nil {
+				c.updateState(func(s *ConnectionState) { s.LastError = err.Error() })
 				c.logger.Error().Err(err).Msg("WebSocket connection failed")
 				time.Sleep(c.reconnectInterval)
 				continue
@@ -98,15 +167,25 @@ func (c *Client) Start(ctx context.Context) {
 func (c *Client) connect(ctx context.Context) error {
 	dialer := websocket.Dialer{
 		HandshakeTimeout: 10 * time.Second,
+		Proxy:            proxy.FromRequest,
 	}
 
 	conn, _, err := dialer.Dial(c.url, nil)
 	if err != nil {
+		c.updateState(func(s *ConnectionState) { s.ReconnectAttempts++ })
 		return fmt.Errorf("failed to dial: %w", err)
 	}
 	c.connMu.Lock()
 	c.conn = conn
 	c.connMu.Unlock()
+	connectedAt := time.Now()
+	c.lastAck.Store(0)
+	c.updateState(func(s *ConnectionState) {
+		s.Connected = true
+		s.LastConnect = &connectedAt
+		s.ReconnectAttempts = 0
+		s.TotalConnects++
+	})
 	defer func() {
 		// Acquire writeMu first to ensure no concurrent SendMessage is mid-write,
 		// then connMu to nil out the pointer. Lock ordering: writeMu → connMu.
@@ -116,6 +195,11 @@ func (c *Client) connect(ctx context.Context) error {
 		c.conn = nil
 		c.connMu.Unlock()
 		c.writeMu.Unlock()
+		disconnectedAt := time.Now()
+		c.updateState(func(s *ConnectionState) {
+			s.Connected = false
+			s.LastDisconnect = &disconnectedAt
+		})
 		if c.onDisconnect != nil {
 			c.onDisconnect()
 		}
@@ -143,9 +227,13 @@ func (c *Client) connect(ctx context.Context) error {
 				return
 			}
 
-			if c.onMessage != nil {
-				c.onMessage(msg.Type, msg.Payload)
+			if msg.Type == MessageTypeHeartbeatAck {
+				ackedAt := time.Now()
+				c.lastAck.Store(ackedAt.UnixNano())
+				c.updateState(func(s *ConnectionState) { s.LastHeartbeatAck = &ackedAt })
 			}
+
+			c.dispatch(msg)
 		}
 	}(conn)
 
@@ -155,6 +243,9 @@ func (c *Client) connect(ctx context.Context) error {
 			return ctx.Err()
 			
 		case <-heartbeatTicker.C:
+			if err := c.checkHeartbeatAcks(connectedAt); err != nil {
+				return err
+			}
 			if err := c.SendHeartbeat(); err != nil {
 				return err
 			}
@@ -165,10 +256,67 @@ func (c *Client) connect(ctx context.Context) error {
 	}
 }
 
+// dispatch hands a message to the handler. A panicking handler is logged and
+// the message dropped, so one bad message does not kill the read loop.
+func (c *Client) dispatch(msg Message) {
+	if c.onMessage == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			c.logger.Error().
+				Str("type", string(msg.Type)).
+				Str("stack", string(debug.Stack())).
+				Msgf("💥 Message handler panicked: %v", r)
+		}
+	}()
+	c.onMessage(msg.Type, msg.Payload)
+}
+
+// checkHeartbeatAcks returns an error when the manager has not acknowledged
+// heartbeats for maxMissedAcks intervals, so the connection is re-established
+// instead of lingering half-open until TCP notices
+func (c *Client) checkHeartbeatAcks(connectedAt time.Time) error {
+	if c.maxMissedAcks <= 0 {
+		return nil
+	}
+	last := connectedAt
+	if ns := c.lastAck.Load(); ns != 0 {
+		last = time.Unix(0, ns)
+	}
+	limit := time.Duration(c.maxMissedAcks) * c.pingInterval
+	if silent := time.Since(last); silent > limit {
+		c.logger.Warn().
+			Dur("sinceLastAck", silent).
+			Int("maxMissedHeartbeats", c.maxMissedAcks).
+			Msg("💔 Heartbeats not acknowledged, reconnecting")
+		return fmt.Errorf("no heartbeat_ack for %s", silent.Round(time.Second))
+	}
+	return nil
+}
+
+// SetTagsProvider sets the source of the agent tags sent with registration,
+// reconnection and heartbeat messages
+func (c *Client) SetTagsProvider(tags func() map[string]string) {
+	c.tags = tags
+}
+
+// currentTags returns the agent tags, never nil so the manager sees removals
+func (c *Client) currentTags() map[string]string {
+	if c.tags == nil {
+		return map[string]string{}
+	}
+	if tags := c.tags(); tags != nil {
+		return tags
+	}
+	return map[string]string{}
+}
+
 func (c *Client) SendHeartbeat() error {
 	return c.SendMessage(MessageTypeHeartbeat, map[string]interface{}{
 		"timestamp": time.Now().Unix(),
 		"status":    "healthy",
+		"tags":      c.currentTags(),
 	})
 }
 
@@ -178,6 +326,7 @@ func (c *Client) SendRegistration(publicKey, token string) error {
 		"token":     token,
 		"hostname":  getHostname(),
 		"platform":  getPlatform(),
+		"tags":      c.currentTags(),
 	})
 }
 
@@ -186,6 +335,7 @@ func (c *Client) SendReconnection(publicKey string) error {
 		"publicKey": publicKey,
 		"hostname":  getHostname(),
 		"platform":  getPlatform(),
+		"tags":      c.currentTags(),
 	})
 }
 
@@ -200,6 +350,14 @@ func (c *Client) SendStatus(status string, details map[string]interface{}) error
 	return c.SendMessage(MessageTypeStatus, payload)
 }
 
+// SendCommandResult reports the outcome of a command to the manager
+func (c *Client) SendCommandResult(result CommandResult) error {
+	if result.Timestamp == 0 {
+		result.Timestamp = time.Now().Unix()
+	}
+	return c.SendMessage(MessageTypeCommandResult, result)
+}
+
 func (c *Client) SendMessage(msgType MessageType, payload interface{}) error {
 	c.connMu.RLock()
 	conn := c.conn
@@ -0,0 +1,52 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestCheckHeartbeatAcks(t *testing.T) {
+	c := NewClient("http://manager:3000", "agent-1", zerolog.Nop())
+	c.SetIntervals(time.Second, 0)
+
+	connectedAt := time.Now().Add(-2 * time.Second)
+	if err := c.checkHeartbeatAcks(connectedAt); err != nil {
+		t.Errorf("within the allowance: %v", err)
+	}
+
+	connectedAt = time.Now().Add(-5 * time.Second)
+	if err := c.checkHeartbeatAcks(connectedAt); err == nil {
+		t.Error("expected reconnect after three missed acks")
+	}
+
+	c.lastAck.Store(time.Now().UnixNano())
+	if err := c.checkHeartbeatAcks(connectedAt); err != nil {
+		t.Errorf("recent ack should keep the connection: %v", err)
+	}
+
+	c.SetMaxMissedHeartbeats(-1)
+	c.lastAck.Store(0)
+	if err := c.checkHeartbeatAcks(connectedAt); err != nil {
+		t.Errorf("check disabled: %v", err)
+	}
+}
+
+func TestDispatchRecoversHandlerPanic(t *testing.T) {
+	c := NewClient("http://manager:3000", "agent-1", zerolog.Nop())
+	var handled []MessageType
+	c.OnMessage(func(msgType MessageType, payload json.RawMessage) {
+		handled = append(handled, msgType)
+		if msgType == MessageTypeCommand {
+			panic("bad command")
+		}
+	})
+
+	c.dispatch(Message{Type: MessageTypeCommand})
+	c.dispatch(Message{Type: MessageTypeHeartbeatAck})
+	if len(handled) != 2 {
+		t.Errorf("handled %v, the read loop should survive a panicking handler", handled)
+	}
+}
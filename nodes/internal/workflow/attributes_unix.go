@@ -0,0 +1,32 @@
+//go:build !windows
+
+package workflow
+
+import "os"
+
+// applyFileAttributes maps read-only to the write permission bits. Hidden
+// and archive have no Unix equivalent and are returned as ignored.
+func applyFileAttributes(path string, attrs fileAttributes) ([]string, error) {
+	var ignored []string
+	if attrs.hidden != nil {
+		ignored = append(ignored, "hidden")
+	}
+	if attrs.archive != nil {
+		ignored = append(ignored, "archive")
+	}
+	if attrs.readOnly == nil {
+		return ignored, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return ignored, err
+	}
+	mode := info.Mode().Perm()
+	if *attrs.readOnly {
+		mode &^= 0222
+	} else {
+		mode |= 0200
+	}
+	return ignored, os.Chmod(path, mode)
+}
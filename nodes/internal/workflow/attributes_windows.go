@@ -0,0 +1,45 @@
+//go:build windows
+
+package workflow
+
+import "syscall"
+
+// applyFileAttributes sets or clears the FILE_ATTRIBUTE_READONLY, _HIDDEN and
+// _ARCHIVE bits, keeping the file's other attributes
+func applyFileAttributes(path string, attrs fileAttributes) ([]string, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	current, err := syscall.GetFileAttributes(name)
+	if err != nil {
+		return nil, err
+	}
+	updated := current
+	for _, change := range []struct {
+		value *bool
+		bit   uint32
+	}{
+		{attrs.readOnly, syscall.FILE_ATTRIBUTE_READONLY},
+		{attrs.hidden, syscall.FILE_ATTRIBUTE_HIDDEN},
+		{attrs.archive, syscall.FILE_ATTRIBUTE_ARCHIVE},
+	} {
+		if change.value == nil {
+			continue
+		}
+		if *change.value {
+			updated |= change.bit
+		} else {
+			updated &^= change.bit
+		}
+	}
+	if updated == current {
+		return nil, nil
+	}
+	// FILE_ATTRIBUTE_NORMAL is only valid on its own
+	updated &^= syscall.FILE_ATTRIBUTE_NORMAL
+	if updated == 0 {
+		updated = syscall.FILE_ATTRIBUTE_NORMAL
+	}
+	return nil, syscall.SetFileAttributes(name, updated)
+}
@@ -0,0 +1,299 @@
+package workflow
+
+import (
+	stdcontext "context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rs/zerolog"
+	"github.com/your-org/controlcenter/nodes/internal/proxy"
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultConnectionTestTimeout bounds a connection test unless timeoutSeconds is set
+const defaultConnectionTestTimeout = 15 * time.Second
+
+// ConnectionTestResult reports the outcome of a connection test
+type ConnectionTestResult struct {
+	Type       string                 `json:"type"`
+	Success    bool                   `json:"success"`
+	Message    string                 `json:"message"`
+	Category   ErrorCategory          `json:"category,omitempty"` // set on failure
+	Details    map[string]interface{} `json:"details,omitempty"`
+	DurationMs int64                  `json:"durationMs"`
+}
+
+// connectionTester checks one kind of integration and returns details to report
+type connectionTester func(b *BaseStep, config map[string]interface{}, timeout time.Duration) (map[string]interface{}, error)
+
+var connectionTesters = map[string]connectionTester{
+	"s3":   testS3Connection,
+	"http": testHTTPConnection,
+	"sftp": testSFTPConnection,
+	"smtp": testSMTPConnection,
+}
+
+// ConnectionTestTypes lists the integrations TestConnection supports
+func ConnectionTestTypes() []string {
+	return []string{"http", "s3", "sftp", "smtp"}
+}
+
+// TestConnection performs a minimal connectivity and authentication check for
+// an integration using step-style config (e.g. an s3-upload step's config).
+// No data is uploaded, downloaded or sent.
+func TestConnection(kind string, config map[string]interface{}, logger zerolog.Logger) ConnectionTestResult {
+	result := ConnectionTestResult{Type: kind}
+	tester, ok := connectionTesters[kind]
+	if !ok {
+		result.Message = fmt.Sprintf("unknown connection type %q", kind)
+		result.Category = ErrorValidation
+		return result
+	}
+
+	b := &BaseStep{Logger: logger}
+	timeout := defaultConnectionTestTimeout
+	seconds, err := b.getOptionalInt(config, "timeoutSeconds", 0)
+	if err != nil {
+		result.Message = err.Error()
+		result.Category = ErrorValidation
+		return result
+	}
+	if seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	start := time.Now()
+	details, err := tester(b, config, timeout)
+	result.DurationMs = time.Since(start).Milliseconds()
+	result.Details = details
+	if err != nil {
+		result.Message = err.Error()
+		result.Category = Categorize(err)
+		return result
+	}
+	result.Success = true
+	result.Message = "connection succeeded"
+	return result
+}
+
+// testS3Connection checks that the credentials can reach the bucket (HeadBucket)
+func testS3Connection(b *BaseStep, config map[string]interface{}, timeout time.Duration) (map[string]interface{}, error) {
+	bucket, err := b.getRequiredString(config, "bucket")
+	if err != nil {
+		return nil, err
+	}
+	accessKeyID, err := b.getRequiredString(config, "accessKeyId")
+	if err != nil {
+		return nil, err
+	}
+	secretAccessKey, err := b.getRequiredString(config, "secretAccessKey")
+	if err != nil {
+		return nil, err
+	}
+	region, err := b.getRequiredString(config, "region")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), timeout)
+	defer cancel()
+	client := newS3Client(region, accessKeyID, secretAccessKey)
+	if _, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)}); err != nil {
+		return nil, fmt.Errorf("cannot access bucket %s: %w", bucket, err)
+	}
+	return map[string]interface{}{"bucket": bucket, "region": region}, nil
+}
+
+// testHTTPConnection sends a HEAD request (or method) with the configured auth
+func testHTTPConnection(b *BaseStep, config map[string]interface{}, timeout time.Duration) (map[string]interface{}, error) {
+	url, err := b.getRequiredString(config, "url")
+	if err != nil {
+		return nil, err
+	}
+	headers, err := b.getOptionalStringMap(config, "headers")
+	if err != nil {
+		return nil, err
+	}
+	method := b.getOptionalString(config, "method", http.MethodHead)
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, validationErrorf("invalid request: %w", err)
+	}
+	b.setRequestHeaders(req, config, headers)
+
+	resp, err := proxy.Client(timeout).Do(req)
+	if err != nil {
+		return nil, transientErrorf("request failed: %w", err)
+	}
+	resp.Body.Close()
+
+	details := map[string]interface{}{"statusCode": resp.StatusCode}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return details, permanentErrorf("server rejected the credentials: %s", resp.Status)
+	case resp.StatusCode >= 500:
+		return details, transientErrorf("server error: %s", resp.Status)
+	case resp.StatusCode >= 400:
+		return details, permanentErrorf("unexpected response: %s", resp.Status)
+	}
+	return details, nil
+}
+
+// testSFTPConnection authenticates over SSH and opens the sftp subsystem.
+// Without hostKeyFingerprint the host key is not verified but is reported so
+// it can be pinned.
+func testSFTPConnection(b *BaseStep, config map[string]interface{}, timeout time.Duration) (map[string]interface{}, error) {
+	host, err := b.getRequiredString(config, "host")
+	if err != nil {
+		return nil, err
+	}
+	username, err := b.getRequiredString(config, "username")
+	if err != nil {
+		return nil, err
+	}
+	port, err := b.getOptionalInt(config, "port", 22)
+	if err != nil {
+		return nil, err
+	}
+
+	var auth []ssh.AuthMethod
+	keyData := []byte(b.getOptionalString(config, "privateKey", ""))
+	if keyPath := b.getOptionalString(config, "privateKeyPath", ""); keyPath != "" {
+		if keyData, err = os.ReadFile(keyPath); err != nil {
+			return nil, validationErrorf("failed to read private key: %w", err)
+		}
+	}
+	if len(keyData) > 0 {
+		var signer ssh.Signer
+		if passphrase := b.getOptionalString(config, "passphrase", ""); passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(keyData, []byte(passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(keyData)
+		}
+		if err != nil {
+			return nil, validationErrorf("invalid private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if password := b.getOptionalString(config, "password", ""); password != "" {
+		auth = append(auth, ssh.Password(password))
+	}
+	if len(auth) == 0 {
+		return nil, validationErrorf("password, privateKey or privateKeyPath is required")
+	}
+
+	details := map[string]interface{}{}
+	pinned := b.getOptionalString(config, "hostKeyFingerprint", "")
+	clientConfig := &ssh.ClientConfig{
+		User:    username,
+		Auth:    auth,
+		Timeout: timeout,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			fingerprint := ssh.FingerprintSHA256(key)
+			details["hostKeyFingerprint"] = fingerprint
+			if pinned != "" && pinned != fingerprint {
+				return permanentErrorf("host key mismatch: got %s, want %s", fingerprint, pinned)
+			}
+			return nil
+		},
+	}
+
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	client, err := ssh.Dial("tcp", addr, clientConfig)
+	if err != nil {
+		return details, sshDialError(err)
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return details, transientErrorf("failed to open session: %w", err)
+	}
+	defer session.Close()
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		return details, permanentErrorf("server does not offer sftp: %w", err)
+	}
+	details["verifiedHostKey"] = pinned != ""
+	return details, nil
+}
+
+// sshDialError classifies an ssh.Dial failure
+func sshDialError(err error) error {
+	var classified *PermanentError
+	if errors.As(err, &classified) {
+		return err
+	}
+	if _, ok := err.(net.Error); ok || IsTransient(err) {
+		return transientErrorf("cannot connect: %w", err)
+	}
+	return permanentErrorf("ssh handshake failed: %w", err)
+}
+
+// testSMTPConnection connects, upgrades with STARTTLS when offered (or uses
+// implicit TLS with tls: true) and authenticates if a username is set.
+// The session ends before any message is sent.
+func testSMTPConnection(b *BaseStep, config map[string]interface{}, timeout time.Duration) (map[string]interface{}, error) {
+	host, err := b.getRequiredString(config, "host")
+	if err != nil {
+		return nil, err
+	}
+	port, err := b.getOptionalInt(config, "port", 587)
+	if err != nil {
+		return nil, err
+	}
+	implicitTLS := b.getOptionalBool(config, "tls", port == 465)
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: host}
+
+	var conn net.Conn
+	dialer := &net.Dialer{Timeout: timeout}
+	if implicitTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, transientErrorf("cannot connect to %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return nil, transientErrorf("SMTP greeting failed: %w", err)
+	}
+	defer client.Close()
+
+	details := map[string]interface{}{"tls": implicitTLS}
+	if !implicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return details, permanentErrorf("STARTTLS failed: %w", err)
+			}
+			details["tls"] = true
+		}
+	}
+
+	if username := b.getOptionalString(config, "username", ""); username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return details, permanentErrorf("server does not support authentication")
+		}
+		auth := smtp.PlainAuth("", username, b.getOptionalString(config, "password", ""), host)
+		if err := client.Auth(auth); err != nil {
+			return details, permanentErrorf("authentication failed: %w", err)
+		}
+		details["authenticated"] = true
+	}
+	client.Quit()
+	return details, nil
+}
@@ -0,0 +1,41 @@
+package workflow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestTestConnection_HTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("method = %s, want HEAD", r.Method)
+		}
+		if r.Header.Get("Authorization") != "Bearer good" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	ok := TestConnection("http", map[string]interface{}{"url": srv.URL, "bearerToken": "good"}, zerolog.Nop())
+	if !ok.Success || ok.Details["statusCode"] != http.StatusOK {
+		t.Errorf("valid token: %+v", ok)
+	}
+
+	denied := TestConnection("http", map[string]interface{}{"url": srv.URL, "bearerToken": "bad"}, zerolog.Nop())
+	if denied.Success || denied.Category != ErrorPermanent {
+		t.Errorf("bad token: %+v", denied)
+	}
+
+	missing := TestConnection("http", map[string]interface{}{}, zerolog.Nop())
+	if missing.Success || missing.Category != ErrorValidation {
+		t.Errorf("missing url: %+v", missing)
+	}
+
+	unknown := TestConnection("ftp", map[string]interface{}{}, zerolog.Nop())
+	if unknown.Success || unknown.Category != ErrorValidation {
+		t.Errorf("unknown type: %+v", unknown)
+	}
+}
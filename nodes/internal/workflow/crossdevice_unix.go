@@ -0,0 +1,9 @@
+//go:build !windows
+
+package workflow
+
+import "syscall"
+
+// errCrossDevice is what rename fails with when source and destination are
+// on different filesystems
+const errCrossDevice = syscall.EXDEV
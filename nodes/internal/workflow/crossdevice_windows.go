@@ -0,0 +1,9 @@
+//go:build windows
+
+package workflow
+
+import "syscall"
+
+// errCrossDevice is ERROR_NOT_SAME_DEVICE, what MoveFileEx fails with when
+// source and destination are on different volumes
+const errCrossDevice = syscall.Errno(0x11)
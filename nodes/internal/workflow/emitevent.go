@@ -0,0 +1,85 @@
+package workflow
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EmitEventStep reports a domain event ("invoice batch processed: 412
+// records") to the manager for dashboards. Unlike alert steps, events are
+// informational and are not acknowledged.
+type EmitEventStep struct {
+	BaseStep
+	Emit func(eventType string, event map[string]interface{}) error
+}
+
+// Params describes the config keys accepted by emit-event steps
+func (s *EmitEventStep) Params() []StepParam {
+	return []StepParam{
+		{Name: "type", Type: "string", Required: true, Description: "Event type, e.g. invoice-batch-processed"},
+		{Name: "message", Type: "string", Description: "Human-readable summary"},
+		{Name: "data", Type: "object", Description: "Event fields (templated)"},
+		{Name: "required", Type: "boolean", Description: "Fail the step when the manager cannot be reached (default false)"},
+	}
+}
+
+func (s *EmitEventStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
+	eventType, err := s.getRequiredString(config, "type")
+	if err != nil {
+		return err
+	}
+	data, err := s.getOptionalObject(config, "data")
+	if err != nil {
+		return err
+	}
+	event := map[string]interface{}{
+		"type":      eventType,
+		"message":   s.getOptionalString(config, "message", ""),
+		"data":      data,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	}
+	for _, key := range []string{"workflowId", "executionId"} {
+		if v, ok := context[key]; ok {
+			event[key] = v
+		}
+	}
+
+	if s.Emit == nil {
+		s.Logger.Info().Str("event", eventType).Msg("📣 Event emitted (no manager, not delivered)")
+		return nil
+	}
+	if err := s.Emit(eventType, event); err != nil {
+		if s.getOptionalBool(config, "required", false) {
+			return transientErrorf("failed to send event %s: %w", eventType, err)
+		}
+		s.Logger.Warn().Err(err).Str("event", eventType).Msg("⚠️ Event not delivered to manager")
+		context["eventDelivered"] = false
+		return nil
+	}
+
+	s.Logger.Info().Str("event", eventType).Msg("📣 Event sent to manager")
+	context["eventDelivered"] = true
+	return nil
+}
+
+// getOptionalObject reads an object parameter keeping its value types, also
+// accepting a JSON string since the workflow editor saves textarea values as
+// strings
+func (s *EmitEventStep) getOptionalObject(config map[string]interface{}, key string) (map[string]interface{}, error) {
+	switch v := config[key].(type) {
+	case nil:
+		return map[string]interface{}{}, nil
+	case map[string]interface{}:
+		return v, nil
+	case string:
+		if v == "" {
+			return map[string]interface{}{}, nil
+		}
+		var parsed map[string]interface{}
+		if err := json.Unmarshal([]byte(v), &parsed); err != nil {
+			return nil, validationErrorf("%s step parameter %s must be a JSON object: %w", s.Type, key, err)
+		}
+		return parsed, nil
+	}
+	return nil, validationErrorf("%s step parameter %s must be an object", s.Type, key)
+}
@@ -0,0 +1,91 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// ErrorCategory classifies a step failure so retries and error handlers can
+// tell a flaky network from a broken workflow
+type ErrorCategory string
+
+const (
+	ErrorTransient  ErrorCategory = "transient"  // may succeed if retried
+	ErrorPermanent  ErrorCategory = "permanent"  // retrying will not help
+	ErrorValidation ErrorCategory = "validation" // step config is missing or malformed
+)
+
+// TransientError marks a failure that may succeed on retry (timeouts,
+// connection resets, 5xx responses, checksum mismatches after transfer)
+type TransientError struct{ Err error }
+
+func (e *TransientError) Error() string { return e.Err.Error() }
+func (e *TransientError) Unwrap() error { return e.Err }
+
+// PermanentError marks a failure that will recur however often it is retried
+type PermanentError struct{ Err error }
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// ValidationError marks a step whose config is missing or malformed
+type ValidationError struct{ Err error }
+
+func (e *ValidationError) Error() string { return e.Err.Error() }
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// transientErrorf formats a TransientError, wrapping any %w operand
+func transientErrorf(format string, args ...interface{}) error {
+	return &TransientError{Err: fmt.Errorf(format, args...)}
+}
+
+// permanentErrorf formats a PermanentError, wrapping any %w operand
+func permanentErrorf(format string, args ...interface{}) error {
+	return &PermanentError{Err: fmt.Errorf(format, args...)}
+}
+
+// validationErrorf formats a ValidationError, wrapping any %w operand
+func validationErrorf(format string, args ...interface{}) error {
+	return &ValidationError{Err: fmt.Errorf(format, args...)}
+}
+
+// Categorize returns the category of err. The outermost classified error in
+// the chain wins, so a step can reclassify an error returned by a helper.
+// Unclassified errors count as transient when they look like network
+// timeouts or resets and as permanent otherwise.
+func Categorize(err error) ErrorCategory {
+	if err == nil {
+		return ""
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		switch e.(type) {
+		case *TransientError:
+			return ErrorTransient
+		case *PermanentError:
+			return ErrorPermanent
+		case *ValidationError:
+			return ErrorValidation
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorTransient
+	}
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ETIMEDOUT) ||
+		errors.Is(err, syscall.EAGAIN) {
+		return ErrorTransient
+	}
+	return ErrorPermanent
+}
+
+// IsTransient reports whether retrying err may succeed
+func IsTransient(err error) bool {
+	return Categorize(err) == ErrorTransient
+}
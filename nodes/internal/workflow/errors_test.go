@@ -0,0 +1,121 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/controlcenter/nodes/internal/config"
+)
+
+func TestCategorize(t *testing.T) {
+	cases := []struct {
+		err  error
+		want ErrorCategory
+	}{
+		{nil, ""},
+		{errors.New("boom"), ErrorPermanent},
+		{fmt.Errorf("dial: %w", syscall.ECONNRESET), ErrorTransient},
+		{transientErrorf("upload failed"), ErrorTransient},
+		{fmt.Errorf("step: %w", validationErrorf("missing url")), ErrorValidation},
+		{&PermanentError{Err: transientErrorf("inner")}, ErrorPermanent},
+	}
+	for _, tc := range cases {
+		if got := Categorize(tc.err); got != tc.want {
+			t.Errorf("Categorize(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
+
+// scriptedStep returns the queued errors in order, then succeeds
+type scriptedStep struct {
+	BaseStep
+	errs  []error
+	calls int
+}
+
+func (s *scriptedStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
+	s.calls++
+	if len(s.errs) == 0 {
+		return nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return err
+}
+
+func TestExecuteStep_RetriesOnlyTransientErrors(t *testing.T) {
+	e, err := NewExecutor(filepath.Join(t.TempDir(), "state.json"), zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	flaky := &scriptedStep{errs: []error{transientErrorf("connection reset")}}
+	broken := &scriptedStep{errs: []error{validationErrorf("missing url"), nil}}
+	e.stepRegistry.Register("flaky", func() Step { return flaky })
+	e.stepRegistry.Register("broken", func() Step { return broken })
+
+	e.LoadWorkflows([]config.Workflow{
+		{
+			ID:      "retry",
+			Enabled: true,
+			Trigger: config.Trigger{Type: "manual", StartSteps: []string{"a"}},
+			Steps:   []config.Step{{ID: "a", Type: "flaky", Retries: 2, RetryDelaySeconds: 1}},
+		},
+		{
+			ID:      "invalid",
+			Enabled: true,
+			Trigger: config.Trigger{Type: "manual", StartSteps: []string{"a"}},
+			Steps:   []config.Step{{ID: "a", Type: "broken", Retries: 3, RetryDelaySeconds: 1}},
+		},
+	})
+
+	if err := e.ExecuteWorkflowSync("retry", TriggerEvent{Type: "manual"}); err != nil {
+		t.Fatal(err)
+	}
+	if flaky.calls != 2 || e.state.state["retry"].Status != "completed" {
+		t.Errorf("flaky step ran %d times, status %q; want 2 runs and completed", flaky.calls, e.state.state["retry"].Status)
+	}
+
+	if err := e.ExecuteWorkflowSync("invalid", TriggerEvent{Type: "manual"}); Categorize(err) != ErrorValidation {
+		t.Fatalf("expected the run to fail with a validation error, got %v", err)
+	}
+	if broken.calls != 1 {
+		t.Errorf("validation error was retried: %d calls", broken.calls)
+	}
+	if state := e.state.state["invalid"]; state.Status != "failed" || state.ErrorCategory != ErrorValidation {
+		t.Errorf("state = %q/%q, want failed/validation", state.Status, state.ErrorCategory)
+	}
+}
+
+func TestExecuteStep_RetryStopsWithExecutor(t *testing.T) {
+	e, err := NewExecutor(filepath.Join(t.TempDir(), "state.json"), zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	flaky := &scriptedStep{errs: []error{transientErrorf("connection reset"), nil}}
+	e.stepRegistry.Register("flaky", func() Step { return flaky })
+	e.LoadWorkflows([]config.Workflow{{
+		ID:      "retry",
+		Enabled: true,
+		Trigger: config.Trigger{Type: "manual", StartSteps: []string{"a"}},
+		Steps:   []config.Step{{ID: "a", Type: "flaky", Retries: 1, RetryDelaySeconds: 3600}},
+	}})
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		e.Stop()
+	}()
+	start := time.Now()
+	err = e.ExecuteWorkflowSync("retry", TriggerEvent{Type: "manual"})
+	if err == nil || !strings.Contains(err.Error(), "executor stopped") || Categorize(err) != ErrorTransient {
+		t.Errorf("expected the retry to be abandoned, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second || flaky.calls != 1 {
+		t.Errorf("retry waited %s and ran %d times", time.Since(start), flaky.calls)
+	}
+}
@@ -7,15 +7,20 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"runtime/debug"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"text/template"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/google/uuid"
 	"github.com/robfig/cron/v3"
 	"github.com/rs/zerolog"
 	"github.com/your-org/controlcenter/nodes/internal/config"
+	"github.com/your-org/controlcenter/nodes/internal/router"
+	"github.com/your-org/controlcenter/nodes/internal/throttle"
 )
 
 type Executor struct {
@@ -27,11 +32,26 @@ type Executor struct {
 	stopChan           chan struct{}
 	stopped            bool
 	alertHandler       func(level, message string, details map[string]interface{})
+	eventHandler       func(event string, details map[string]interface{})
+	eventEmitter       func(eventType string, event map[string]interface{}) error // where emit-event steps send events
 	stepRegistry       *StepRegistry
+	commandPolicy      atomic.Pointer[config.CommandPolicy]
+	transferLimiter    atomic.Pointer[throttle.Limiter]
+	sequences          *sequenceStore                     // rename-sequence counters, kept next to the state file
+	variables          map[string]string                  // global variables; workflow variables override them
+	secretLookup       func(name string) (string, bool)   // resolves "secret:" variable values
 	webhookMu          sync.Mutex
-	registeredWebhooks map[string]*webhookBinding // tracks registered HTTP paths to prevent duplicate panic
+	registeredWebhooks map[string]*webhookBinding // tracks registered HTTP paths so reloads rebind instead of re-registering
+	router             *router.Router             // where webhook handlers are registered
+	webhookSlots       chan struct{}              // caps concurrently running webhook-triggered workflows
+	draining           bool                       // triggers are ignored while set; running executions continue
+	activeRuns         int                        // executions currently in progress
 }
 
+// maxConcurrentWebhookRuns is the global cap on webhook-triggered workflows
+// executing at the same time. Requests beyond it are rejected with 429.
+const maxConcurrentWebhookRuns = 10
+
 // webhookBinding holds mutable state for a registered webhook handler.
 // The handler closure reads these fields under webhookMu so reloads take effect.
 type webhookBinding struct {
@@ -39,8 +59,18 @@ type webhookBinding struct {
 	instance   *WorkflowInstance
 	method     string
 	active     bool
+	limiter    *rateLimiter // nil when rateLimitPerMinute is not set
 }
 
+// defaultRetryDelay is the wait before the first retry of a transient step failure
+const defaultRetryDelay = 5 * time.Second
+
+// maxRetryDelay caps the doubling wait between step retries
+const maxRetryDelay = 5 * time.Minute
+
+// statusLoadFailed marks a workflow that was rejected by LoadWorkflows
+const statusLoadFailed = "load-failed"
+
 type WorkflowInstance struct {
 	Workflow *config.Workflow
 	Status   string
@@ -55,20 +85,158 @@ func NewExecutor(stateFile string, logger zerolog.Logger) (*Executor, error) {
 		return nil, err
 	}
 
-	return &Executor{
+	e := &Executor{
 		workflows:          make(map[string]*WorkflowInstance),
 		state:              state,
 		logger:             logger,
 		stopChan:           make(chan struct{}),
 		stepRegistry:       NewStepRegistry(logger, nil),
+		sequences:          newSequenceStore(filepath.Join(filepath.Dir(stateFile), "sequences.json")),
 		registeredWebhooks: make(map[string]*webhookBinding),
-	}, nil
+		webhookSlots:       make(chan struct{}, maxConcurrentWebhookRuns),
+	}
+	e.stepRegistry.SetSequenceStore(e.sequences)
+	return e, nil
 }
 
 func (e *Executor) SetAlertHandler(handler func(level, message string, details map[string]interface{})) {
 	e.alertHandler = handler
 	// Update registry with alert handler
 	e.stepRegistry = NewStepRegistry(e.logger, handler)
+	if policy := e.commandPolicy.Load(); policy != nil {
+		e.stepRegistry.SetCommandPolicy(*policy)
+	}
+	e.stepRegistry.SetTransferLimiter(e.transferLimiter.Load())
+	e.stepRegistry.SetEventHandler(e.eventHandler)
+	e.stepRegistry.SetEventEmitter(e.eventEmitter)
+	e.stepRegistry.SetSequenceStore(e.sequences)
+}
+
+// SetRouter sets the HTTP router webhook triggers register their paths on.
+// It must be called before workflows are loaded.
+func (e *Executor) SetRouter(rt *router.Router) {
+	e.webhookMu.Lock()
+	defer e.webhookMu.Unlock()
+	e.router = rt
+}
+
+// StepTypes describes the step types available to workflows on this agent
+func (e *Executor) StepTypes() []StepTypeInfo {
+	return e.stepRegistry.Describe()
+}
+
+// ValidateWorkflow checks a workflow definition against this agent's step types
+func (e *Executor) ValidateWorkflow(wf config.Workflow) []ValidationIssue {
+	return e.stepRegistry.ValidateWorkflow(wf)
+}
+
+// SetEventHandler sets the callback that receives workflow lifecycle events
+// (workflow-started, workflow-completed, workflow-failed) and step progress
+// (command-output)
+func (e *Executor) SetEventHandler(handler func(event string, details map[string]interface{})) {
+	e.eventHandler = handler
+	e.stepRegistry.SetEventHandler(handler)
+}
+
+// SetEventEmitter sets the callback emit-event steps use to send custom
+// events to the manager
+func (e *Executor) SetEventEmitter(emit func(eventType string, event map[string]interface{}) error) {
+	e.eventEmitter = emit
+	e.stepRegistry.SetEventEmitter(emit)
+}
+
+// emitEvent forwards a lifecycle event to the event handler, if one is set
+func (e *Executor) emitEvent(event string, details map[string]interface{}) {
+	if e.eventHandler != nil {
+		e.eventHandler(event, details)
+	}
+}
+
+// SetCommandPolicy sets the local policy that restricts run-command steps.
+// Steps created afterwards use it, so it can be changed on config reload.
+func (e *Executor) SetCommandPolicy(policy config.CommandPolicy) {
+	e.commandPolicy.Store(&policy)
+	e.stepRegistry.SetCommandPolicy(policy)
+}
+
+// SetTransferLimiter sets the agent-wide bandwidth limit for copy and upload steps
+func (e *Executor) SetTransferLimiter(l *throttle.Limiter) {
+	e.transferLimiter.Store(l)
+	e.stepRegistry.SetTransferLimiter(l)
+}
+
+// SetVariables sets the global variables exposed to templates as .vars
+func (e *Executor) SetVariables(vars map[string]string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.variables = vars
+}
+
+// SetSecretLookup sets the source for variables whose value is "secret:<name>"
+func (e *Executor) SetSecretLookup(lookup func(name string) (string, bool)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.secretLookup = lookup
+}
+
+// resolveVariables merges global and workflow variables and resolves
+// "secret:" and "env:" references. Resolved values are only handed to step
+// templates; they are never stored in the workflow context or state file.
+func (e *Executor) resolveVariables(workflowID string) (map[string]interface{}, error) {
+	e.mu.RLock()
+	merged := make(map[string]string, len(e.variables))
+	for k, v := range e.variables {
+		merged[k] = v
+	}
+	if instance, ok := e.workflows[workflowID]; ok && instance.Workflow != nil {
+		for k, v := range instance.Workflow.Variables {
+			merged[k] = v
+		}
+	}
+	lookup := e.secretLookup
+	e.mu.RUnlock()
+
+	vars := make(map[string]interface{}, len(merged))
+	for name, value := range merged {
+		switch {
+		case strings.HasPrefix(value, "secret:"):
+			secretName := strings.TrimPrefix(value, "secret:")
+			var secret string
+			var found bool
+			if lookup != nil {
+				secret, found = lookup(secretName)
+			}
+			if !found {
+				return nil, validationErrorf("variable %s references unknown secret %q", name, secretName)
+			}
+			vars[name] = secret
+		case strings.HasPrefix(value, "env:"):
+			envName := strings.TrimPrefix(value, "env:")
+			env, found := os.LookupEnv(envName)
+			if !found {
+				return nil, validationErrorf("variable %s references unset environment variable %s", name, envName)
+			}
+			vars[name] = env
+		default:
+			vars[name] = value
+		}
+	}
+	return vars, nil
+}
+
+// templateData returns the data steps render their config and when
+// conditions against: the workflow context plus resolved variables as .vars
+func (e *Executor) templateData(workflowID string, context map[string]interface{}) (map[string]interface{}, error) {
+	vars, err := e.resolveVariables(workflowID)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]interface{}, len(context)+1)
+	for k, v := range context {
+		data[k] = v
+	}
+	data["vars"] = vars
+	return data, nil
 }
 
 func (e *Executor) LoadWorkflows(workflows []config.Workflow) {
@@ -87,8 +255,45 @@ func (e *Executor) LoadWorkflows(workflows []config.Workflow) {
 	e.webhookMu.Unlock()
 
 	// Load new workflows
+	webhookOwners := make(map[string]string) // webhook path -> workflow ID
 	for _, wf := range workflows {
 		if wf.Enabled {
+			// A cyclic step graph is a misconfiguration; refuse to run it rather
+			// than silently pruning branches at execution time.
+			if cycle := findCycle(wf.Steps); cycle != nil {
+				loadErr := fmt.Sprintf("workflow %s has a cycle %s", wf.ID, strings.Join(cycle, "→"))
+				e.logger.Error().
+					Str("id", wf.ID).
+					Str("name", wf.Name).
+					Strs("cycle", cycle).
+					Msg("❌ " + loadErr + ", not loading")
+				e.workflows[wf.ID] = &WorkflowInstance{
+					Workflow: &wf,
+					Status:   statusLoadFailed,
+					Error:    loadErr,
+				}
+				continue
+			}
+			// Two workflows on one webhook path would take turns owning it
+			// depending on trigger start order; the first one listed keeps it.
+			if wf.Trigger.Type == "webhook" {
+				path := webhookPath(wf.ID, wf.Trigger.Config)
+				if owner, taken := webhookOwners[path]; taken {
+					loadErr := fmt.Sprintf("workflow %s webhook path %s is already used by workflow %s", wf.ID, path, owner)
+					e.logger.Error().
+						Str("id", wf.ID).
+						Str("name", wf.Name).
+						Str("path", path).
+						Msg("❌ " + loadErr + ", not loading")
+					e.workflows[wf.ID] = &WorkflowInstance{
+						Workflow: &wf,
+						Status:   statusLoadFailed,
+						Error:    loadErr,
+					}
+					continue
+				}
+				webhookOwners[path] = wf.ID
+			}
 			e.workflows[wf.ID] = &WorkflowInstance{
 				Workflow: &wf,
 				Status:   "idle",
@@ -101,6 +306,9 @@ func (e *Executor) LoadWorkflows(workflows []config.Workflow) {
 	}
 }
 
+// Start runs the trigger handlers of the loaded workflows until Stop. A
+// panicking trigger stops the executor and Start returns the panic as an
+// error, so the supervisor restarts every trigger.
 func (e *Executor) Start() error {
 	e.mu.Lock()
 	if e.stopped {
@@ -108,18 +316,48 @@ func (e *Executor) Start() error {
 		e.stopChan = make(chan struct{})
 		e.stopped = false
 	}
+	stop := e.stopChan
+	instances := make(map[string]*WorkflowInstance, len(e.workflows))
+	for id, instance := range e.workflows {
+		if instance.Status != statusLoadFailed {
+			instances[id] = instance
+		}
+	}
 	e.mu.Unlock()
 	
 	e.logger.Info().Msg("Starting workflow executor")
 
 	// Start trigger handlers
-	for id, instance := range e.workflows {
-		go e.handleTrigger(id, instance)
+	failed := make(chan error, 1)
+	for id, instance := range instances {
+		go e.runTrigger(id, instance, failed)
 	}
 
 	// Keep running until stopped
-	<-e.stopChan
-	return nil
+	select {
+	case <-stop:
+		return nil
+	case err := <-failed:
+		e.Stop()
+		return err
+	}
+}
+
+// runTrigger runs a workflow's trigger handler, reporting a panic on failed
+func (e *Executor) runTrigger(workflowID string, instance *WorkflowInstance, failed chan<- error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e.logger.Error().
+				Str("workflow", workflowID).
+				Str("stack", string(debug.Stack())).
+				Msgf("💥 Trigger panicked: %v", r)
+			select {
+			case failed <- fmt.Errorf("trigger of workflow %s panicked: %v", workflowID, r):
+			default:
+			}
+		}
+	}()
+	e.handleTrigger(workflowID, instance)
 }
 
 func (e *Executor) Stop() {
@@ -155,6 +393,8 @@ func (e *Executor) handleTrigger(workflowID string, instance *WorkflowInstance)
 		e.handleFileTrigger(workflowID, instance, trigger.Config)
 	case "schedule":
 		e.handleScheduleTrigger(workflowID, instance, trigger.Config)
+	case "fileage":
+		e.handleFileAgeTrigger(workflowID, instance, trigger.Config)
 	case "webhook":
 		e.handleWebhookTrigger(workflowID, instance, trigger.Config)
 	case "manual":
@@ -222,7 +462,10 @@ func (e *Executor) handleFileTrigger(workflowID string, instance *WorkflowInstan
 					Str("workflow", workflowID).
 					Str("file", event.Name).
 					Msg("File trigger activated")
-				
+
+				if e.skipWhileDraining(workflowID, "file") {
+					continue
+				}
 				e.executeWorkflow(workflowID, instance, map[string]interface{}{
 					"trigger":   "file",
 					"file":      event.Name,
@@ -250,8 +493,23 @@ func (e *Executor) handleScheduleTrigger(workflowID string, instance *WorkflowIn
 	cronExpr, hasCron := config["cron"].(string)
 	intervalStr, hasInterval := config["interval"].(string)
 
+	// Cron expressions are evaluated in the trigger's timezone (IANA name)
+	tzName, _ := config["timezone"].(string)
+	loc := time.Local
+	if tzName != "" {
+		var err error
+		if loc, err = time.LoadLocation(tzName); err != nil {
+			e.logger.Error().
+				Err(err).
+				Str("workflow", workflowID).
+				Str("timezone", tzName).
+				Msg("Invalid schedule timezone")
+			return
+		}
+	}
+
 	if hasCron && cronExpr != "" {
-		e.handleCronTrigger(workflowID, instance, cronExpr)
+		e.handleCronTrigger(workflowID, instance, cronExpr, loc)
 	} else if hasInterval && intervalStr != "" {
 		e.handleIntervalTrigger(workflowID, instance, intervalStr)
 	} else {
@@ -261,7 +519,7 @@ func (e *Executor) handleScheduleTrigger(workflowID string, instance *WorkflowIn
 	}
 }
 
-func (e *Executor) handleCronTrigger(workflowID string, instance *WorkflowInstance, cronExpr string) {
+func (e *Executor) handleCronTrigger(workflowID string, instance *WorkflowInstance, cronExpr string, loc *time.Location) {
 	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
 	schedule, err := parser.Parse(cronExpr)
 	if err != nil {
@@ -273,16 +531,17 @@ func (e *Executor) handleCronTrigger(workflowID string, instance *WorkflowInstan
 		return
 	}
 
-	now := time.Now()
+	now := time.Now().In(loc)
 	next := schedule.Next(now)
 	e.logger.Info().
 		Str("workflow", workflowID).
 		Str("cron", cronExpr).
+		Str("timezone", loc.String()).
 		Time("nextRun", next).
 		Msg("Cron trigger scheduled")
 
 	for {
-		now = time.Now()
+		now = time.Now().In(loc)
 		next = schedule.Next(now)
 		delay := next.Sub(now)
 
@@ -293,6 +552,9 @@ func (e *Executor) handleCronTrigger(workflowID string, instance *WorkflowInstan
 				Str("workflow", workflowID).
 				Str("cron", cronExpr).
 				Msg("Cron trigger fired")
+			if e.skipWhileDraining(workflowID, "schedule") {
+				continue
+			}
 			e.executeWorkflow(workflowID, instance, map[string]interface{}{
 				"trigger":      "schedule",
 				"time":         time.Now().Unix(),
@@ -327,6 +589,9 @@ func (e *Executor) handleIntervalTrigger(workflowID string, instance *WorkflowIn
 	for {
 		select {
 		case <-ticker.C:
+			if e.skipWhileDraining(workflowID, "schedule") {
+				continue
+			}
 			e.executeWorkflow(workflowID, instance, map[string]interface{}{
 				"trigger": "schedule",
 				"time":    time.Now().Unix(),
@@ -337,19 +602,37 @@ func (e *Executor) handleIntervalTrigger(workflowID string, instance *WorkflowIn
 	}
 }
 
+// webhookPath returns the HTTP path a webhook trigger listens on
+func webhookPath(workflowID string, config map[string]interface{}) string {
+	if path, _ := config["path"].(string); path != "" {
+		return path
+	}
+	return "/api/webhooks/" + workflowID
+}
+
 func (e *Executor) handleWebhookTrigger(workflowID string, instance *WorkflowInstance, config map[string]interface{}) {
 	// Register HTTP handler based on trigger config
-	path, _ := config["path"].(string)
-	if path == "" {
-		path = "/api/webhooks/" + workflowID
-	}
+	path := webhookPath(workflowID, config)
 
 	method, _ := config["method"].(string)
 	if method == "" {
 		method = http.MethodPost
 	}
 
+	ratePerMinute := 0
+	if v, ok := config["rateLimitPerMinute"].(float64); ok && v > 0 {
+		ratePerMinute = int(v)
+	}
+
 	e.webhookMu.Lock()
+	if e.router == nil {
+		e.webhookMu.Unlock()
+		e.logger.Warn().
+			Str("workflow", workflowID).
+			Str("path", path).
+			Msg("⚠️ No HTTP router configured, webhook trigger not registered")
+		return
+	}
 	if binding, exists := e.registeredWebhooks[path]; exists {
 		// Path already registered — update the binding so the existing handler
 		// picks up the new workflow config on the next request.
@@ -357,6 +640,9 @@ func (e *Executor) handleWebhookTrigger(workflowID string, instance *WorkflowIns
 		binding.instance = instance
 		binding.method = method
 		binding.active = true
+		if binding.limiter == nil || binding.limiter.perMinute != ratePerMinute {
+			binding.limiter = newRateLimiter(ratePerMinute)
+		}
 		e.webhookMu.Unlock()
 		e.logger.Info().
 			Str("workflow", workflowID).
@@ -369,11 +655,14 @@ func (e *Executor) handleWebhookTrigger(workflowID string, instance *WorkflowIns
 		instance:   instance,
 		method:     method,
 		active:     true,
+		limiter:    newRateLimiter(ratePerMinute),
 	}
-	e.registeredWebhooks[path] = binding
+	rt := e.router
 	e.webhookMu.Unlock()
 
-	http.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+	// The method is checked in the handler rather than by the router because
+	// a reload can change it while the path stays registered.
+	err := rt.HandleFunc("", path, func(w http.ResponseWriter, r *http.Request) {
 		// Read current binding under lock so reloads take effect
 		e.webhookMu.Lock()
 		b := *binding // snapshot
@@ -392,6 +681,25 @@ func (e *Executor) handleWebhookTrigger(workflowID string, instance *WorkflowIns
 			return
 		}
 
+		if e.skipWhileDraining(b.workflowID, "webhook") {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, "agent is draining")
+			return
+		}
+
+		if !b.limiter.Allow() {
+			e.logger.Warn().
+				Str("workflow", b.workflowID).
+				Str("path", path).
+				Str("remote", r.RemoteAddr).
+				Msg("🚦 Webhook rate limit exceeded")
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprintf(w, "rate limit exceeded")
+			return
+		}
+
 		// Parse payload (support JSON and form)
 		payload := make(map[string]interface{})
 		if strings.Contains(r.Header.Get("Content-Type"), "application/json") {
@@ -418,8 +726,24 @@ func (e *Executor) handleWebhookTrigger(workflowID string, instance *WorkflowIns
 			"timestamp":        time.Now().Unix(),
 		}
 
+		// Reserve a global execution slot so webhook storms can't pile up goroutines
+		select {
+		case e.webhookSlots <- struct{}{}:
+		default:
+			e.logger.Warn().
+				Str("workflow", b.workflowID).
+				Int("limit", maxConcurrentWebhookRuns).
+				Msg("🚦 Too many concurrent webhook executions, rejecting request")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprintf(w, "too many concurrent webhook executions")
+			return
+		}
+
 		// Execute workflow asynchronously
-		go e.executeWorkflow(b.workflowID, b.instance, ctx)
+		go func() {
+			defer func() { <-e.webhookSlots }()
+			e.executeWorkflow(b.workflowID, b.instance, ctx)
+		}()
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusAccepted)
@@ -428,6 +752,19 @@ func (e *Executor) handleWebhookTrigger(workflowID string, instance *WorkflowIns
 			"workflow": b.workflowID,
 		})
 	})
+	if err != nil {
+		// Another endpoint owns the path; ServeMux would have panicked here
+		e.logger.Error().
+			Err(err).
+			Str("workflow", workflowID).
+			Str("path", path).
+			Msg("❌ Failed to register webhook trigger")
+		return
+	}
+
+	e.webhookMu.Lock()
+	e.registeredWebhooks[path] = binding
+	e.webhookMu.Unlock()
 
 	e.logger.Info().
 		Str("workflow", workflowID).
@@ -436,21 +773,48 @@ func (e *Executor) handleWebhookTrigger(workflowID string, instance *WorkflowIns
 		Msg("Webhook trigger registered")
 }
 
-func (e *Executor) executeWorkflow(workflowID string, instance *WorkflowInstance, context map[string]interface{}) {
+// executeWorkflow runs a workflow to completion and returns the error that failed it, if any
+func (e *Executor) executeWorkflow(workflowID string, instance *WorkflowInstance, context map[string]interface{}) error {
+	e.mu.Lock()
+	e.activeRuns++
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		e.activeRuns--
+		e.mu.Unlock()
+	}()
+
+	startTime := time.Now()
+	executionID := uuid.New().String()
+	context["executionId"] = executionID
+	context["workflowId"] = workflowID
+
 	e.mu.Lock()
 	instance.Status = "running"
-	instance.LastRun = time.Now()
+	instance.LastRun = startTime
 	e.mu.Unlock()
 
 	e.logger.Info().
 		Str("workflow", workflowID).
 		Str("name", instance.Workflow.Name).
+		Str("executionId", executionID).
 		Interface("context", context).
 		Msg("🚀 Starting workflow execution")
 
 	// Save state
 	e.state.StartWorkflow(workflowID, context)
 
+	triggerType, _ := context["trigger"].(string)
+	if triggerType == "" {
+		triggerType, _ = context["triggerType"].(string)
+	}
+	e.emitEvent("workflow-started", map[string]interface{}{
+		"workflowId":   workflowID,
+		"workflowName": instance.Workflow.Name,
+		"executionId":  executionID,
+		"trigger":      triggerType,
+	})
+
 	// Build step map for quick lookup
 	stepMap := make(map[string]config.Step)
 	for _, step := range instance.Workflow.Steps {
@@ -464,8 +828,14 @@ func (e *Executor) executeWorkflow(workflowID string, instance *WorkflowInstance
 		e.logger.Warn().
 			Str("workflow", workflowID).
 			Msg("⚠️ No trigger.startSteps defined, falling back to sequential execution")
+		finally := make(map[string]bool, len(instance.Workflow.Finally))
+		for _, id := range instance.Workflow.Finally {
+			finally[id] = true
+		}
 		for _, step := range instance.Workflow.Steps {
-			startSteps = append(startSteps, step.ID)
+			if !finally[step.ID] {
+				startSteps = append(startSteps, step.ID)
+			}
 		}
 	}
 
@@ -476,7 +846,11 @@ func (e *Executor) executeWorkflow(workflowID string, instance *WorkflowInstance
 
 	// Execute step chains starting from trigger
 	visited := make(map[string]bool)
-	if err := e.executeStepChain(startSteps, stepMap, context, workflowID, visited); err != nil {
+	err := e.executeStepChain(startSteps, stepMap, context, workflowID, visited)
+	if len(instance.Workflow.Finally) > 0 {
+		err = e.executeFinally(instance.Workflow.Finally, stepMap, context, workflowID, err)
+	}
+	if err != nil {
 		e.logger.Error().
 			Err(err).
 			Str("workflow", workflowID).
@@ -487,8 +861,21 @@ func (e *Executor) executeWorkflow(workflowID string, instance *WorkflowInstance
 		instance.Error = err.Error()
 		e.mu.Unlock()
 
-		e.state.FailWorkflow(workflowID, err.Error())
-		return
+		category := Categorize(err)
+		e.state.FailWorkflow(workflowID, err.Error(), category)
+
+		e.emitEvent("workflow-failed", map[string]interface{}{
+			"workflowId":     workflowID,
+			"workflowName":   instance.Workflow.Name,
+			"executionId":    executionID,
+			"trigger":        triggerType,
+			"durationMs":     time.Since(startTime).Milliseconds(),
+			"stepsCompleted": e.state.CompletedStepCount(workflowID),
+			"totalSteps":     len(instance.Workflow.Steps),
+			"error":          err.Error(),
+			"errorCategory":  string(category),
+		})
+		return err
 	}
 
 	e.mu.Lock()
@@ -498,9 +885,59 @@ func (e *Executor) executeWorkflow(workflowID string, instance *WorkflowInstance
 
 	e.state.CompleteWorkflow(workflowID)
 
+	e.emitEvent("workflow-completed", map[string]interface{}{
+		"workflowId":     workflowID,
+		"workflowName":   instance.Workflow.Name,
+		"executionId":    executionID,
+		"trigger":        triggerType,
+		"durationMs":     time.Since(startTime).Milliseconds(),
+		"stepsCompleted": e.state.CompletedStepCount(workflowID),
+		"totalSteps":     len(instance.Workflow.Steps),
+	})
+
 	e.logger.Info().
 		Str("workflow", workflowID).
 		Msg("✅ Workflow completed successfully")
+	return nil
+}
+
+// executeFinally runs the workflow's finally steps after the main chain,
+// whatever its outcome. The steps see the outcome as {{.workflowStatus}}
+// (completed or failed) and, on failure, {{.error}} and {{.errorCategory}}.
+// It returns the error that decides the run: the main chain's error if it
+// failed, otherwise the finally chain's.
+func (e *Executor) executeFinally(stepIDs []string, stepMap map[string]config.Step, context map[string]interface{}, workflowID string, chainErr error) error {
+	finallyContext := make(map[string]interface{}, len(context)+3)
+	for k, v := range context {
+		finallyContext[k] = v
+	}
+	finallyContext["workflowStatus"] = "completed"
+	if chainErr != nil {
+		finallyContext["workflowStatus"] = "failed"
+		finallyContext["error"] = chainErr.Error()
+		finallyContext["errorCategory"] = string(Categorize(chainErr))
+	}
+
+	e.logger.Info().
+		Str("workflow", workflowID).
+		Strs("finally", stepIDs).
+		Str("workflowStatus", finallyContext["workflowStatus"].(string)).
+		Msg("🧹 Running finally steps")
+
+	err := e.executeStepChain(stepIDs, stepMap, finallyContext, workflowID, make(map[string]bool))
+	if err != nil {
+		e.logger.Error().
+			Err(err).
+			Str("workflow", workflowID).
+			Msg("❌ Finally steps failed")
+	}
+	if chainErr != nil {
+		return chainErr
+	}
+	if err != nil {
+		return fmt.Errorf("finally: %w", err)
+	}
+	return nil
 }
 
 func (e *Executor) executeStepChain(stepIDs []string, stepMap map[string]config.Step, context map[string]interface{}, workflowID string, visited map[string]bool) error {
@@ -522,14 +959,19 @@ func (e *Executor) executeStepChain(stepIDs []string, stepMap map[string]config.
 			return fmt.Errorf("step %s not found", stepID)
 		}
 
-		// Execute the step
-		if err := e.executeStep(step, context, workflowID); err != nil {
+		// Execute the step unless its when condition says to skip it
+		skip, err := e.skipByWhen(step, context, workflowID)
+		if err == nil && !skip {
+			err = e.executeStep(step, context, workflowID)
+		}
+		if err != nil {
 			// Step failed - check if there are error handlers
 			if len(step.OnError) > 0 {
 				e.logger.Info().
 					Str("step", stepID).
 					Strs("onError", step.OnError).
 					Str("error", err.Error()).
+					Str("category", string(Categorize(err))).
 					Msg("⚠️ Step failed, executing error handlers")
 
 				// Add error information to context for error handlers
@@ -538,6 +980,7 @@ func (e *Executor) executeStepChain(stepIDs []string, stepMap map[string]config.
 					errorContext[k] = v
 				}
 				errorContext["error"] = err.Error()
+				errorContext["errorCategory"] = string(Categorize(err))
 				errorContext["errorStep"] = step.ID
 				errorContext["errorStepName"] = step.Name
 
@@ -583,6 +1026,46 @@ func (e *Executor) executeStepChain(stepIDs []string, stepMap map[string]config.
 	return nil
 }
 
+// skipByWhen evaluates the step's when condition against the context
+func (e *Executor) skipByWhen(step config.Step, context map[string]interface{}, workflowID string) (bool, error) {
+	if strings.TrimSpace(step.When) == "" {
+		return false, nil
+	}
+	data, err := e.templateData(workflowID, context)
+	if err != nil {
+		return false, err
+	}
+	run, err := evaluateWhen(step.When, data)
+	if err != nil {
+		return false, validationErrorf("step %s when condition: %w", step.ID, err)
+	}
+	if !run {
+		e.logger.Info().
+			Str("step", step.ID).
+			Str("when", step.When).
+			Msg("⏭️ Step skipped: when condition is false")
+	}
+	return !run, nil
+}
+
+// evaluateWhen renders a when template and interprets the result as a boolean.
+// Empty output, "false", "0" and "no" are false; anything else is true.
+func evaluateWhen(when string, context map[string]interface{}) (bool, error) {
+	tmpl, err := template.New("when").Option("missingkey=zero").Parse(when)
+	if err != nil {
+		return false, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, context); err != nil {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(buf.String())) {
+	case "", "false", "0", "no", "<no value>":
+		return false, nil
+	}
+	return true, nil
+}
+
 func (e *Executor) executeStep(step config.Step, context map[string]interface{}, workflowID string) error {
 	e.logger.Info().
 		Str("step", step.ID).
@@ -591,7 +1074,11 @@ func (e *Executor) executeStep(step config.Step, context map[string]interface{},
 		Msg("▶️ Executing step")
 
 	// Process config values with recursive template substitution
-	processedConfig := e.processConfigWithTemplate(step.Config, context)
+	data, err := e.templateData(workflowID, context)
+	if err != nil {
+		return fmt.Errorf("step %s: %w", step.ID, err)
+	}
+	processedConfig := e.processConfigWithTemplate(step.Config, data)
 
 	e.logger.Debug().
 		Str("step", step.ID).
@@ -603,15 +1090,52 @@ func (e *Executor) executeStep(step config.Step, context map[string]interface{},
 	if err != nil {
 		return fmt.Errorf("failed to create step %s: %w", step.Type, err)
 	}
+	if rc, ok := stepImpl.(rawConfigReceiver); ok {
+		rc.SetRawConfig(step.Config)
+	}
 
-	// Execute the step
-	if err := stepImpl.Execute(processedConfig, context); err != nil {
-		e.logger.Error().
+	// Execute the step, retrying transient failures if the step allows it
+	delay := time.Duration(step.RetryDelaySeconds) * time.Second
+	if delay <= 0 {
+		delay = defaultRetryDelay
+	}
+	for attempt := 0; ; attempt++ {
+		err := e.runStep(step, stepImpl, processedConfig, context)
+		if err == nil {
+			break
+		}
+		category := Categorize(err)
+		if category != ErrorTransient || attempt >= step.Retries {
+			e.logger.Error().
+				Err(err).
+				Str("step", step.ID).
+				Str("type", step.Type).
+				Str("category", string(category)).
+				Int("attempts", attempt+1).
+				Msg("❌ Step execution failed")
+			return err
+		}
+
+		e.logger.Warn().
 			Err(err).
 			Str("step", step.ID).
 			Str("type", step.Type).
-			Msg("❌ Step execution failed")
-		return err
+			Int("attempt", attempt+1).
+			Int("retries", step.Retries).
+			Dur("delay", delay).
+			Msg("🔁 Transient step failure, retrying")
+		if reason := e.waitRetry(delay); reason != "" {
+			e.logger.Warn().
+				Str("step", step.ID).
+				Str("type", step.Type).
+				Str("reason", reason).
+				Msg("⏹️ Step retry abandoned")
+			return fmt.Errorf("%w (retry abandoned: %s)", err, reason)
+		}
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
 	}
 
 	e.logger.Info().
@@ -625,6 +1149,47 @@ func (e *Executor) executeStep(step config.Step, context map[string]interface{},
 	return nil
 }
 
+// waitRetry waits delay before a step retry. It returns early with the reason
+// when the executor stops or starts draining, so a retry cannot hold those up.
+func (e *Executor) waitRetry(delay time.Duration) string {
+	e.mu.RLock()
+	stop := e.stopChan
+	e.mu.RUnlock()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-timer.C:
+			return ""
+		case <-stop:
+			return "executor stopped"
+		case <-ticker.C:
+			if e.IsDraining() {
+				return "agent is draining"
+			}
+		}
+	}
+}
+
+// runStep executes a step, turning a panic into a permanent error so a
+// broken step fails its run instead of crashing the agent
+func (e *Executor) runStep(step config.Step, stepImpl Step, cfg map[string]interface{}, context map[string]interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e.logger.Error().
+				Str("step", step.ID).
+				Str("type", step.Type).
+				Str("stack", string(debug.Stack())).
+				Msgf("💥 Step panicked: %v", r)
+			err = permanentErrorf("step %s panicked: %v", step.ID, r)
+		}
+	}()
+	return stepImpl.Execute(cfg, context)
+}
+
 // processConfigWithTemplate recursively processes config values with template substitution
 func (e *Executor) processConfigWithTemplate(config map[string]interface{}, context map[string]interface{}) map[string]interface{} {
 	result := make(map[string]interface{})
@@ -695,6 +1260,11 @@ type StateManager struct {
 	mu       sync.RWMutex
 	filepath string
 	state    map[string]*WorkflowState
+
+	// Finished runs per workflow and the cached summary (see metrics.go)
+	history      map[string][]runRecord
+	metricsCache []WorkflowMetrics
+	metricsAt    time.Time
 }
 
 type WorkflowState struct {
@@ -705,18 +1275,23 @@ type WorkflowState struct {
 	Context      map[string]interface{} `json:"context"`
 	CompletedSteps []string             `json:"completedSteps"`
 	Error        string                 `json:"error,omitempty"`
+	ErrorCategory ErrorCategory         `json:"errorCategory,omitempty"`
 }
 
 func NewStateManager(filepath string) (*StateManager, error) {
 	sm := &StateManager{
 		filepath: filepath,
 		state:    make(map[string]*WorkflowState),
+		history:  make(map[string][]runRecord),
 	}
 	
 	// Load existing state
 	if err := sm.load(); err != nil && !os.IsNotExist(err) {
 		return nil, err
 	}
+	for _, state := range sm.state {
+		sm.recordRun(state)
+	}
 	
 	return sm, nil
 }
@@ -736,7 +1311,7 @@ func (sm *StateManager) save() error {
 		return err
 	}
 	
-	return os.WriteFile(sm.filepath, data, 0644)
+	return config.WriteFileAtomic(sm.filepath, data, 0644)
 }
 
 func (sm *StateManager) StartWorkflow(workflowID string, context map[string]interface{}) {
@@ -755,6 +1330,7 @@ func (sm *StateManager) StartWorkflow(workflowID string, context map[string]inte
 		Context:        ctxCopy,
 		CompletedSteps: []string{},
 	}
+	sm.metricsCache = nil
 
 	sm.save()
 }
@@ -798,6 +1374,17 @@ func (sm *StateManager) CompleteStep(workflowID, stepID string) {
 	}
 }
 
+// CompletedStepCount returns how many steps the current run of a workflow has completed
+func (sm *StateManager) CompletedStepCount(workflowID string) int {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	if state, ok := sm.state[workflowID]; ok {
+		return len(state.CompletedSteps)
+	}
+	return 0
+}
+
 func (sm *StateManager) CompleteWorkflow(workflowID string) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
@@ -805,11 +1392,12 @@ func (sm *StateManager) CompleteWorkflow(workflowID string) {
 	if state, ok := sm.state[workflowID]; ok {
 		state.Status = "completed"
 		state.EndTime = time.Now()
+		sm.recordRun(state)
 		sm.save()
 	}
 }
 
-func (sm *StateManager) FailWorkflow(workflowID, error string) {
+func (sm *StateManager) FailWorkflow(workflowID, error string, category ErrorCategory) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 	
@@ -817,6 +1405,8 @@ func (sm *StateManager) FailWorkflow(workflowID, error string) {
 		state.Status = "failed"
 		state.EndTime = time.Now()
 		state.Error = error
+		state.ErrorCategory = category
+		sm.recordRun(state)
 		sm.save()
 	}
 }
@@ -827,6 +1417,42 @@ type TriggerEvent struct {
 	Data map[string]interface{} `json:"data"`
 }
 
+// SetDraining stops (or resumes) starting workflows from file, schedule and
+// webhook triggers. Executions already running are left to finish. Direct
+// ExecuteWorkflow calls are not gated: they come from file watcher jobs that
+// were already in flight when draining began.
+func (e *Executor) SetDraining(draining bool) {
+	e.mu.Lock()
+	e.draining = draining
+	e.mu.Unlock()
+}
+
+// IsDraining reports whether triggers are currently being ignored
+func (e *Executor) IsDraining() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.draining
+}
+
+// ActiveExecutions returns the number of workflow executions in progress
+func (e *Executor) ActiveExecutions() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.activeRuns
+}
+
+// skipWhileDraining logs and returns true if a trigger should be ignored
+func (e *Executor) skipWhileDraining(workflowID, trigger string) bool {
+	if !e.IsDraining() {
+		return false
+	}
+	e.logger.Info().
+		Str("workflow", workflowID).
+		Str("trigger", trigger).
+		Msg("⏸️ Agent is draining, ignoring trigger")
+	return true
+}
+
 // GetWorkflows returns all loaded workflows
 func (e *Executor) GetWorkflows() []config.Workflow {
 	e.mu.RLock()
@@ -841,15 +1467,34 @@ func (e *Executor) GetWorkflows() []config.Workflow {
 	return workflows
 }
 
+// LoadFailures returns the workflows LoadWorkflows rejected, keyed by ID,
+// with the reason
+func (e *Executor) LoadFailures() map[string]string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	failures := make(map[string]string)
+	for id, instance := range e.workflows {
+		if instance.Status == statusLoadFailed {
+			failures[id] = instance.Error
+		}
+	}
+	return failures
+}
+
 // ExecuteWorkflow executes a workflow by ID with an external trigger (async)
 func (e *Executor) ExecuteWorkflow(workflowID string, trigger TriggerEvent) error {
 	e.mu.RLock()
 	instance, exists := e.workflows[workflowID]
+	loadFailed := exists && instance.Status == statusLoadFailed
 	e.mu.RUnlock()
 
 	if !exists {
 		return fmt.Errorf("workflow %s not found", workflowID)
 	}
+	if loadFailed {
+		return fmt.Errorf("workflow %s failed to load: %s", workflowID, instance.Error)
+	}
 
 	// Create context with trigger data
 	context := make(map[string]interface{})
@@ -863,24 +1508,29 @@ func (e *Executor) ExecuteWorkflow(workflowID string, trigger TriggerEvent) erro
 	return nil
 }
 
-// ExecuteWorkflowSync executes a workflow by ID with an external trigger and waits for completion
+// ExecuteWorkflowSync executes a workflow by ID with an external trigger, waits
+// for completion and returns the error that failed the run, if any
 func (e *Executor) ExecuteWorkflowSync(workflowID string, trigger TriggerEvent) error {
 	e.mu.RLock()
 	instance, exists := e.workflows[workflowID]
+	loadFailed := exists && instance.Status == statusLoadFailed
 	e.mu.RUnlock()
 
 	if !exists {
 		return fmt.Errorf("workflow %s not found", workflowID)
 	}
+	if loadFailed {
+		return fmt.Errorf("workflow %s failed to load: %s", workflowID, instance.Error)
+	}
 
-	// Create context with trigger data
 	context := make(map[string]interface{})
 	for k, v := range trigger.Data {
 		context[k] = v
 	}
 	context["triggerType"] = trigger.Type
 
-	// Execute the workflow synchronously (wait for completion)
-	e.executeWorkflow(workflowID, instance, context)
+	if err := e.executeWorkflow(workflowID, instance, context); err != nil {
+		return fmt.Errorf("workflow %s failed: %w", workflowID, err)
+	}
 	return nil
 }
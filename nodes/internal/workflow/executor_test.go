@@ -0,0 +1,294 @@
+package workflow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/controlcenter/nodes/internal/config"
+	"github.com/your-org/controlcenter/nodes/internal/router"
+)
+
+// configCapture records the templated config each time it runs
+type configCapture struct {
+	BaseStep
+	configs []map[string]interface{}
+}
+
+func (s *configCapture) Execute(config map[string]interface{}, context map[string]interface{}) error {
+	s.configs = append(s.configs, config)
+	return nil
+}
+
+func TestVariables(t *testing.T) {
+	e, err := NewExecutor(filepath.Join(t.TempDir(), "state.json"), zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	capture := &configCapture{}
+	e.stepRegistry.Register("capture", func() Step { return capture })
+	t.Setenv("CC_TEST_REGION", "eu-west-1")
+	e.SetVariables(map[string]string{"bucket": "global-bucket", "awsKey": "secret:aws", "region": "env:CC_TEST_REGION"})
+	e.SetSecretLookup(func(name string) (string, bool) {
+		if name == "aws" {
+			return "AKIA123", true
+		}
+		return "", false
+	})
+
+	e.LoadWorkflows([]config.Workflow{{
+		ID:        "upload",
+		Enabled:   true,
+		Trigger:   config.Trigger{Type: "manual", StartSteps: []string{"a"}},
+		Variables: map[string]string{"bucket": "workflow-bucket"},
+		Steps: []config.Step{{ID: "a", Type: "capture", Config: map[string]interface{}{
+			"bucket": "{{ .vars.bucket }}",
+			"key":    "{{ .vars.awsKey }}",
+			"region": "{{ .vars.region }}",
+		}}},
+	}})
+	if err := e.ExecuteWorkflowSync("upload", TriggerEvent{Type: "manual"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(capture.configs) != 1 {
+		t.Fatalf("step ran %d times", len(capture.configs))
+	}
+	got := capture.configs[0]
+	if got["bucket"] != "workflow-bucket" || got["key"] != "AKIA123" || got["region"] != "eu-west-1" {
+		t.Errorf("templated config = %v", got)
+	}
+	if _, leaked := e.state.state["upload"].Context["vars"]; leaked {
+		t.Error("resolved variables must not be persisted in the state file")
+	}
+
+	e.SetVariables(map[string]string{"awsKey": "secret:missing"})
+	if err := e.ExecuteWorkflowSync("upload", TriggerEvent{Type: "manual"}); err == nil {
+		t.Fatal("expected the run to fail")
+	}
+	state := e.state.state["upload"]
+	if state.Status != "failed" || state.ErrorCategory != ErrorValidation || !strings.Contains(state.Error, "missing") {
+		t.Errorf("unknown secret should fail the workflow with a validation error, got %q/%q: %s", state.Status, state.ErrorCategory, state.Error)
+	}
+}
+
+func TestFinallyRunsAfterEveryOutcome(t *testing.T) {
+	e, err := NewExecutor(filepath.Join(t.TempDir(), "state.json"), zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	work := &scriptedStep{errs: []error{nil, permanentErrorf("disk full"), nil}}
+	cleanup := &configCapture{}
+	notify := &scriptedStep{errs: []error{nil, nil, permanentErrorf("smtp down")}}
+	e.stepRegistry.Register("work", func() Step { return work })
+	e.stepRegistry.Register("cleanup", func() Step { return cleanup })
+	e.stepRegistry.Register("notify", func() Step { return notify })
+
+	e.LoadWorkflows([]config.Workflow{{
+		ID:      "batch",
+		Enabled: true,
+		Trigger: config.Trigger{Type: "manual", StartSteps: []string{"work"}},
+		Steps: []config.Step{
+			{ID: "work", Type: "work"},
+			{ID: "cleanup", Type: "cleanup", Config: map[string]interface{}{"status": "{{ .workflowStatus }}"}, Next: []string{"notify"}},
+			{ID: "notify", Type: "notify"},
+		},
+		Finally: []string{"cleanup"},
+	}})
+
+	for i, want := range []struct {
+		status      string
+		finalStatus string
+		errContains string
+	}{
+		{"completed", "completed", ""},
+		{"failed", "failed", "disk full"},
+		{"completed", "failed", "finally: smtp down"},
+	} {
+		if err := e.ExecuteWorkflowSync("batch", TriggerEvent{Type: "manual"}); (err != nil) != (want.finalStatus == "failed") {
+			t.Fatalf("run %d: err = %v, want final status %s", i, err, want.finalStatus)
+		}
+		if got := cleanup.configs[i]["status"]; got != want.status {
+			t.Errorf("run %d: finally saw workflowStatus %v, want %s", i, got, want.status)
+		}
+		state := e.state.state["batch"]
+		if state.Status != want.finalStatus || !strings.Contains(state.Error, want.errContains) {
+			t.Errorf("run %d: state = %q (%s), want %q containing %q", i, state.Status, state.Error, want.finalStatus, want.errContains)
+		}
+	}
+	if work.calls != 3 || notify.calls != 3 {
+		t.Errorf("work ran %d times, notify %d times; want 3 each", work.calls, notify.calls)
+	}
+}
+
+func TestWebhookRegistration_UsesRouterWithoutPanicking(t *testing.T) {
+	e, err := NewExecutor(filepath.Join(t.TempDir(), "state.json"), zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := router.New()
+	if err := rt.HandleFunc("", "/api/webhooks/taken", func(w http.ResponseWriter, r *http.Request) {}); err != nil {
+		t.Fatal(err)
+	}
+	e.SetRouter(rt)
+
+	wf := &config.Workflow{ID: "orders", Enabled: true, Steps: []config.Step{{ID: "a", Type: "capture"}}}
+	instance := &WorkflowInstance{Workflow: wf, Status: "idle"}
+	e.handleWebhookTrigger("orders", instance, map[string]interface{}{"path": "/api/webhooks/taken"})
+	if _, ok := e.registeredWebhooks["/api/webhooks/taken"]; ok {
+		t.Error("a path owned by another handler should not be bound")
+	}
+
+	// A reload registers the same path again; it must rebind, not re-register
+	e.handleWebhookTrigger("orders", instance, map[string]interface{}{})
+	e.handleWebhookTrigger("orders", instance, map[string]interface{}{"method": "PUT"})
+
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/webhooks/orders", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST after reload to PUT = %d, want 405", rec.Code)
+	}
+}
+
+func TestWebhookReload_RebindsPath(t *testing.T) {
+	e, err := NewExecutor(filepath.Join(t.TempDir(), "state.json"), zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := router.New()
+	e.SetRouter(rt)
+
+	hook := func(id, path string) config.Workflow {
+		return config.Workflow{ID: id, Enabled: true,
+			Trigger: config.Trigger{Type: "webhook", Config: map[string]interface{}{"path": path}},
+			Steps:   []config.Step{{ID: "a", Type: "log"}}}
+	}
+	// Editing and reloading a webhook workflow used to panic on re-registration
+	for i := 0; i < 3; i++ {
+		e.LoadWorkflows([]config.Workflow{hook("orders", "/hooks/orders"), hook("copy", "/hooks/orders")})
+		e.handleWebhookTrigger("orders", e.workflows["orders"], e.workflows["orders"].Workflow.Trigger.Config)
+	}
+	if got := e.workflows["copy"]; got.Status != statusLoadFailed || !strings.Contains(got.Error, "already used by workflow orders") {
+		t.Errorf("second workflow on the same path should fail to load, got %q: %s", got.Status, got.Error)
+	}
+	if failures := e.LoadFailures(); len(failures) != 1 || failures["copy"] == "" {
+		t.Errorf("LoadFailures() = %v, want only copy", failures)
+	}
+	if b := e.registeredWebhooks["/hooks/orders"]; b == nil || b.instance != e.workflows["orders"] || !b.active {
+		t.Fatal("reload should rebind the path to the newly loaded instance")
+	}
+
+	// Dropping the workflow leaves the route registered but inactive
+	e.LoadWorkflows(nil)
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/hooks/orders", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("POST to a removed webhook = %d, want 404", rec.Code)
+	}
+}
+
+func TestScanFileAge(t *testing.T) {
+	e, err := NewExecutor(filepath.Join(t.TempDir(), "state.json"), zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	capture := &configCapture{}
+	e.stepRegistry.Register("capture", func() Step { return capture })
+	e.LoadWorkflows([]config.Workflow{{
+		ID:      "sla",
+		Enabled: true,
+		Trigger: config.Trigger{Type: "fileage", StartSteps: []string{"a"}},
+		Steps: []config.Step{{ID: "a", Type: "capture", Config: map[string]interface{}{
+			"count": "{{ .count }}",
+			"file":  "{{ .fileName }}",
+		}}},
+	}})
+	instance := e.workflows["sla"]
+
+	dir := t.TempDir()
+	now := time.Now()
+	touch := func(name string, age time.Duration) {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	touch("old.csv", 3*time.Hour)
+	touch("fresh.csv", time.Minute)
+	touch("old.tmp", 5*time.Hour)
+
+	trigger, err := parseFileAgeTrigger(map[string]interface{}{"path": dir, "pattern": "*.csv", "olderThan": "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reported := make(map[string]time.Time)
+	e.scanFileAge("sla", instance, trigger, reported, now)
+	e.scanFileAge("sla", instance, trigger, reported, now)
+	if len(capture.configs) != 1 || capture.configs[0]["count"] != "1" || capture.configs[0]["file"] != "old.csv" {
+		t.Fatalf("runs = %v, want one run for old.csv", capture.configs)
+	}
+
+	// Only the newly aged file is reported; the one already reported is not
+	touch("late.csv", 2*time.Hour)
+	e.scanFileAge("sla", instance, trigger, reported, now)
+	if len(capture.configs) != 2 || capture.configs[1]["count"] != "1" || capture.configs[1]["file"] != "late.csv" {
+		t.Fatalf("second run = %v, want only late.csv", capture.configs)
+	}
+
+	trigger.repeat = true
+	e.scanFileAge("sla", instance, trigger, reported, now)
+	if len(capture.configs) != 3 || capture.configs[2]["count"] != "2" || capture.configs[2]["file"] != "old.csv" {
+		t.Fatalf("repeat run = %v, want both files, oldest first", capture.configs)
+	}
+
+	if _, err := parseFileAgeTrigger(map[string]interface{}{"path": dir}); err == nil {
+		t.Error("olderThan should be required")
+	}
+}
+
+// panicStep panics when executed
+type panicStep struct{ BaseStep }
+
+func (s *panicStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
+	panic("boom")
+}
+
+func TestPanicsAreContained(t *testing.T) {
+	e, err := NewExecutor(filepath.Join(t.TempDir(), "state.json"), zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.stepRegistry.Register("panic", func() Step { return &panicStep{} })
+	e.LoadWorkflows([]config.Workflow{{
+		ID:      "broken",
+		Enabled: true,
+		Trigger: config.Trigger{Type: "manual", StartSteps: []string{"a"}},
+		Steps:   []config.Step{{ID: "a", Type: "panic"}},
+	}})
+	err = e.ExecuteWorkflowSync("broken", TriggerEvent{Type: "manual"})
+	if err == nil || !strings.Contains(err.Error(), "panicked: boom") || Categorize(err) != ErrorPermanent {
+		t.Errorf("a panicking step should fail the run, got %v", err)
+	}
+
+	// A trigger without a workflow panics in handleTrigger
+	e.mu.Lock()
+	e.workflows["no-workflow"] = &WorkflowInstance{Status: "idle"}
+	e.mu.Unlock()
+	done := make(chan error, 1)
+	go func() { done <- e.Start() }()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "no-workflow") {
+			t.Errorf("Start should report the trigger panic, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after a trigger panicked")
+	}
+}
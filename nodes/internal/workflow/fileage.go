@@ -0,0 +1,185 @@
+package workflow
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// defaultFileAgeInterval is how often a fileage trigger rescans its directory
+const defaultFileAgeInterval = time.Minute
+
+// fileAgeTrigger is the config of a fileage trigger, which fires for files
+// that have sat in a directory longer than olderThan (e.g. an inbound feed
+// nobody picked up)
+type fileAgeTrigger struct {
+	path      string
+	pattern   string
+	olderThan time.Duration
+	interval  time.Duration
+	recursive bool
+	repeat    bool // fire again on every scan while a file stays aged
+	maxFiles  int
+}
+
+func parseFileAgeTrigger(config map[string]interface{}) (fileAgeTrigger, error) {
+	t := fileAgeTrigger{pattern: "*", interval: defaultFileAgeInterval, maxFiles: defaultMaxListed}
+	t.path, _ = config["path"].(string)
+	if t.path == "" {
+		return t, fmt.Errorf("fileage trigger requires path")
+	}
+	if pattern, _ := config["pattern"].(string); pattern != "" {
+		t.pattern = pattern
+	}
+	if _, err := filepath.Match(t.pattern, ""); err != nil {
+		return t, fmt.Errorf("invalid pattern %q: %w", t.pattern, err)
+	}
+	olderThan, _ := config["olderThan"].(string)
+	d, err := time.ParseDuration(olderThan)
+	if err != nil || d <= 0 {
+		return t, fmt.Errorf("fileage trigger requires olderThan as a positive duration (e.g. 1h), got %q", olderThan)
+	}
+	t.olderThan = d
+	if interval, _ := config["interval"].(string); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			return t, fmt.Errorf("invalid interval %q", interval)
+		}
+		t.interval = d
+	}
+	t.recursive = triggerBool(config["recursive"])
+	t.repeat = triggerBool(config["repeat"])
+	if v, ok := config["maxFiles"].(float64); ok && v > 0 {
+		t.maxFiles = int(v)
+	}
+	return t, nil
+}
+
+// triggerBool reads a boolean trigger option, which the editor may save as text
+func triggerBool(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true" || v == "yes" || v == "1"
+	}
+	return false
+}
+
+func (e *Executor) handleFileAgeTrigger(workflowID string, instance *WorkflowInstance, config map[string]interface{}) {
+	t, err := parseFileAgeTrigger(config)
+	if err != nil {
+		e.logger.Error().Err(err).Str("workflow", workflowID).Msg("Invalid fileage trigger")
+		return
+	}
+
+	e.logger.Info().
+		Str("workflow", workflowID).
+		Str("path", t.path).
+		Str("pattern", t.pattern).
+		Dur("olderThan", t.olderThan).
+		Dur("interval", t.interval).
+		Msg("File age trigger scheduled")
+
+	reported := make(map[string]time.Time)
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		e.scanFileAge(workflowID, instance, t, reported, time.Now())
+		select {
+		case <-ticker.C:
+		case <-e.stopChan:
+			return
+		}
+	}
+}
+
+// scanFileAge runs the workflow once for the files older than t.olderThan at
+// now. reported holds the files (and their mtimes) already handed to a
+// successful run; they are not reported again unless t.repeat is set or the
+// file is modified. Files that are gone are forgotten.
+func (e *Executor) scanFileAge(workflowID string, instance *WorkflowInstance, t fileAgeTrigger, reported map[string]time.Time, now time.Time) {
+	cutoff := now.Add(-t.olderThan)
+	aged, _, err := scanFiles(t.path, t.pattern, t.recursive, func(info os.FileInfo) bool {
+		return info.ModTime().Before(cutoff)
+	})
+	if err != nil {
+		e.logger.Warn().Err(err).Str("workflow", workflowID).Str("path", t.path).Msg("File age scan failed")
+		return
+	}
+
+	current := make(map[string]bool, len(aged))
+	var pending []scannedFile
+	for _, f := range aged {
+		current[f.path] = true
+		if modTime, seen := reported[f.path]; seen && modTime.Equal(f.info.ModTime()) && !t.repeat {
+			continue
+		}
+		pending = append(pending, f)
+	}
+	for path := range reported {
+		if !current[path] {
+			delete(reported, path)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	// Oldest first, so the most overdue files survive truncation
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].info.ModTime().Before(pending[j].info.ModTime())
+	})
+	truncated := len(pending) > t.maxFiles
+	listed := pending
+	if truncated {
+		listed = pending[:t.maxFiles]
+	}
+	files := make([]interface{}, 0, len(listed))
+	paths := make([]string, 0, len(listed))
+	for _, f := range listed {
+		files = append(files, map[string]interface{}{
+			"path":       f.path,
+			"name":       f.info.Name(),
+			"size":       f.info.Size(),
+			"modTime":    f.info.ModTime().UTC().Format(time.RFC3339),
+			"ageSeconds": int64(now.Sub(f.info.ModTime()).Seconds()),
+		})
+		paths = append(paths, f.path)
+	}
+
+	e.logger.Warn().
+		Str("workflow", workflowID).
+		Str("path", t.path).
+		Int("count", len(pending)).
+		Dur("olderThan", t.olderThan).
+		Msg("⏳ File age trigger activated")
+
+	if e.skipWhileDraining(workflowID, "fileage") {
+		return
+	}
+	oldest := listed[0]
+	err = e.executeWorkflow(workflowID, instance, map[string]interface{}{
+		"trigger":          "fileage",
+		"directory":        t.path,
+		"olderThan":        t.olderThan.String(),
+		"file":             oldest.path,
+		"fileName":         oldest.info.Name(),
+		"files":            files,
+		"paths":            paths,
+		"count":            len(pending),
+		"truncated":        truncated,
+		"oldestAgeSeconds": int64(now.Sub(oldest.info.ModTime()).Seconds()),
+		"timestamp":        now.Unix(),
+	})
+	// A failed run (e.g. the escalation could not be sent) retries on the next scan
+	if err != nil {
+		return
+	}
+	for _, f := range listed {
+		reported[f.path] = f.info.ModTime()
+	}
+}
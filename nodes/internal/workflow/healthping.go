@@ -0,0 +1,174 @@
+package workflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/your-org/controlcenter/nodes/internal/proxy"
+)
+
+// Health ping signals
+const (
+	pingStart   = "start"
+	pingSuccess = "success"
+	pingFailure = "failure"
+)
+
+// HealthPingStep notifies an external monitor (healthchecks.io, Cronitor,
+// Uptime Kuma push URLs and the like) that a workflow started, succeeded or
+// failed, so a run that never happens or never finishes can page someone
+type HealthPingStep struct {
+	BaseStep
+}
+
+// Params describes the config keys accepted by health-ping steps
+func (s *HealthPingStep) Params() []StepParam {
+	return []StepParam{
+		{Name: "signal", Type: "string", Description: "start, success or failure (default: failure if the workflow failed, else success; use in finally or onError)"},
+		{Name: "url", Type: "string", Description: "healthchecks.io-style base URL; start and failure ping url/start and url/fail"},
+		{Name: "startUrl", Type: "string", Description: "URL pinged for the start signal"},
+		{Name: "successUrl", Type: "string", Description: "URL pinged for the success signal"},
+		{Name: "failureUrl", Type: "string", Description: "URL pinged for the failure signal"},
+		{Name: "method", Type: "string", Description: "GET or POST (default GET); POST sends the workflow and error as JSON"},
+		{Name: "headers", Type: "object", Description: "Extra request headers"},
+		{Name: "timeoutSeconds", Type: "number", Description: "Request timeout (default 10)"},
+		{Name: "failOnError", Type: "boolean", Description: "Fail the step when the monitor cannot be reached (default false: log and continue)"},
+	}
+}
+
+// pingSignal works out which signal to send. Without an explicit signal it
+// follows the outcome the executor exposes to finally and onError steps.
+func (s *HealthPingStep) pingSignal(config, context map[string]interface{}) (string, error) {
+	signal := strings.ToLower(s.getOptionalString(config, "signal", ""))
+	switch signal {
+	case pingStart, pingSuccess, pingFailure:
+		return signal, nil
+	case "":
+	default:
+		return "", validationErrorf("%s step signal must be start, success or failure, got %q", s.Type, signal)
+	}
+	if status, _ := context["workflowStatus"].(string); status == "failed" {
+		return pingFailure, nil
+	}
+	if _, failed := context["errorCategory"]; failed && context["workflowStatus"] == nil {
+		return pingFailure, nil
+	}
+	return pingSuccess, nil
+}
+
+// pingURL returns the URL configured for signal, deriving it from url when
+// no specific one is set
+func (s *HealthPingStep) pingURL(config map[string]interface{}, signal string) string {
+	base := strings.TrimRight(s.getOptionalString(config, "url", ""), "/")
+	switch signal {
+	case pingStart:
+		if u := s.getOptionalString(config, "startUrl", ""); u != "" || base == "" {
+			return u
+		}
+		return base + "/start"
+	case pingFailure:
+		if u := s.getOptionalString(config, "failureUrl", ""); u != "" || base == "" {
+			return u
+		}
+		return base + "/fail"
+	default:
+		if u := s.getOptionalString(config, "successUrl", ""); u != "" {
+			return u
+		}
+		return base
+	}
+}
+
+func (s *HealthPingStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
+	signal, err := s.pingSignal(config, context)
+	if err != nil {
+		return err
+	}
+	configured := false
+	for _, key := range []string{"url", "startUrl", "successUrl", "failureUrl"} {
+		configured = configured || s.getOptionalString(config, key, "") != ""
+	}
+	if !configured {
+		return validationErrorf("%s step requires url, startUrl, successUrl or failureUrl", s.Type)
+	}
+	url := s.pingURL(config, signal)
+	if url == "" {
+		s.Logger.Debug().Str("signal", signal).Msg("No health ping URL for this signal, skipping")
+		context["healthPingSignal"] = signal
+		return nil
+	}
+
+	method := strings.ToUpper(s.getOptionalString(config, "method", http.MethodGet))
+	if method != http.MethodGet && method != http.MethodPost {
+		return validationErrorf("%s step method must be GET or POST, got %q", s.Type, method)
+	}
+	headers, err := s.getOptionalStringMap(config, "headers")
+	if err != nil {
+		return err
+	}
+	timeoutSeconds, err := s.getOptionalInt(config, "timeoutSeconds", 10)
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader
+	if method == http.MethodPost {
+		payload := map[string]interface{}{
+			"signal":      signal,
+			"workflowId":  context["workflowId"],
+			"executionId": context["executionId"],
+			"time":        time.Now().UTC().Format(time.RFC3339),
+		}
+		if signal == pingFailure {
+			payload["error"] = context["error"]
+			payload["errorCategory"] = context["errorCategory"]
+		}
+		data, _ := json.Marshal(payload)
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return validationErrorf("invalid health ping URL: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	s.setRequestHeaders(req, config, headers)
+
+	context["healthPingSignal"] = signal
+	err = s.ping(req, time.Duration(timeoutSeconds)*time.Second)
+	if err == nil {
+		s.Logger.Info().Str("signal", signal).Str("url", url).Msg("💓 Health ping sent")
+		return nil
+	}
+	context["healthPingError"] = err.Error()
+	if s.getOptionalBool(config, "failOnError", false) {
+		return err
+	}
+	// A monitoring outage must not fail the work it monitors
+	s.Logger.Warn().Err(err).Str("signal", signal).Str("url", url).Msg("⚠️ Health ping failed, continuing")
+	return nil
+}
+
+// ping sends the request and treats any non-2xx response as an error
+func (s *HealthPingStep) ping(req *http.Request, timeout time.Duration) error {
+	resp, err := proxy.Client(timeout).Do(req)
+	if err != nil {
+		return transientErrorf("health ping failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := fmt.Errorf("health ping returned status %d", resp.StatusCode)
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return &TransientError{Err: err}
+		}
+		return &PermanentError{Err: err}
+	}
+	return nil
+}
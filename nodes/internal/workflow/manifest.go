@@ -0,0 +1,173 @@
+package workflow
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// manifestColumns are the fixed leading columns of CSV manifests
+var manifestColumns = []string{"timestamp", "workflowId", "executionId", "sourceFile", "destination", "sha256", "size"}
+
+// manifestLocks serializes appends to the same manifest from concurrent runs
+var manifestLocks sync.Map // cleaned path -> *sync.Mutex
+
+// ManifestStep appends a record of a processed file to a JSON-lines or CSV manifest
+type ManifestStep struct {
+	BaseStep
+}
+
+// Params describes the config keys accepted by write-manifest steps
+func (s *ManifestStep) Params() []StepParam {
+	return []StepParam{
+		{Name: "path", Type: "string", Required: true, Description: "Manifest file, created if absent"},
+		{Name: "format", Type: "string", Description: "jsonl or csv (default jsonl)"},
+		{Name: "sourceFile", Type: "string", Description: "File the record is about (default: the triggering file)"},
+		{Name: "destination", Type: "string", Description: "Where the file was delivered"},
+		{Name: "hash", Type: "boolean", Description: "Record the SHA-256 and size of the file (default true)"},
+		{Name: "fields", Type: "object", Description: "Extra string fields added to the record"},
+		{Name: "filePerm", Type: "string", Description: "Octal mode for a new manifest (default 0644)"},
+	}
+}
+
+func (s *ManifestStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
+	path, err := s.getRequiredString(config, "path")
+	if err != nil {
+		return err
+	}
+	format := s.getOptionalString(config, "format", "jsonl")
+	if format != "jsonl" && format != "csv" {
+		return validationErrorf("%s step parameter format must be jsonl or csv", s.Type)
+	}
+	fields, err := s.getOptionalStringMap(config, "fields")
+	if err != nil {
+		return err
+	}
+	fileMode, err := s.getOptionalFileMode(config, "filePerm", 0644)
+	if err != nil {
+		return err
+	}
+	triggerFile, _ := context["file"].(string)
+	sourceFile := s.getOptionalString(config, "sourceFile", triggerFile)
+	destination := s.getOptionalString(config, "destination", "")
+	workflowID, _ := context["workflowId"].(string)
+	executionID, _ := context["executionId"].(string)
+
+	record := map[string]interface{}{
+		"timestamp":   time.Now().UTC().Format(time.RFC3339),
+		"workflowId":  workflowID,
+		"executionId": executionID,
+		"sourceFile":  sourceFile,
+		"destination": destination,
+	}
+	if s.getOptionalBool(config, "hash", true) {
+		// The source may have been moved; fall back to the delivered copy
+		target := sourceFile
+		if _, err := os.Stat(target); err != nil && destination != "" {
+			target = destination
+		}
+		if target == "" {
+			return validationErrorf("%s step needs sourceFile or destination to hash (or hash: false)", s.Type)
+		}
+		info, err := os.Stat(target)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s for manifest: %w", target, err)
+		}
+		sum, err := sha256File(target)
+		if err != nil {
+			return fmt.Errorf("failed to hash %s for manifest: %w", target, err)
+		}
+		record["sha256"] = hex.EncodeToString(sum[:])
+		record["size"] = info.Size()
+	}
+	for k, v := range fields {
+		if _, reserved := record[k]; !reserved {
+			record[k] = v
+		}
+	}
+
+	if err := appendManifest(path, format, record, fields, fileMode); err != nil {
+		return err
+	}
+
+	s.Logger.Info().
+		Str("manifest", path).
+		Str("format", format).
+		Str("sourceFile", sourceFile).
+		Msg("🧾 Manifest record written")
+
+	context["manifestPath"] = path
+	context["manifestRecord"] = record
+	return nil
+}
+
+// appendManifest writes one record to the manifest in a single append while
+// holding the per-path lock. A new or empty CSV manifest gets a header row.
+func appendManifest(path, format string, record map[string]interface{}, fields map[string]string, mode os.FileMode) error {
+	lockValue, _ := manifestLocks.LoadOrStore(filepath.Clean(path), &sync.Mutex{})
+	lock := lockValue.(*sync.Mutex)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, mode)
+	if err != nil {
+		return fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	switch format {
+	case "csv":
+		info, err := f.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to stat manifest: %w", err)
+		}
+		extra := make([]string, 0, len(fields))
+		for k := range fields {
+			if !slices.Contains(manifestColumns, k) {
+				extra = append(extra, k)
+			}
+		}
+		sort.Strings(extra)
+		columns := append(append([]string{}, manifestColumns...), extra...)
+
+		w := csv.NewWriter(&buf)
+		if info.Size() == 0 {
+			w.Write(columns)
+		}
+		row := make([]string, len(columns))
+		for i, col := range columns {
+			if v, ok := record[col]; ok {
+				row[i] = fmt.Sprint(v)
+			}
+		}
+		w.Write(row)
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return fmt.Errorf("failed to encode manifest record: %w", err)
+		}
+	default:
+		line, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode manifest record: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to append to manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package workflow
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// MergeFilesStep concatenates the files matching a pattern into one
+// destination, e.g. to consolidate a day's small arrival files before
+// archiving or uploading. Files are streamed, never read into memory whole.
+type MergeFilesStep struct {
+	BaseStep
+}
+
+// Params describes the config keys accepted by merge-files steps
+func (s *MergeFilesStep) Params() []StepParam {
+	return []StepParam{
+		{Name: "directory", Type: "string", Required: true, Description: "Directory holding the files to merge"},
+		{Name: "pattern", Type: "string", Description: "Glob matched against file names (default *)"},
+		{Name: "recursive", Type: "boolean", Description: "Include subdirectories"},
+		{Name: "destination", Type: "string", Required: true, Description: "Merged file to write"},
+		{Name: "sortBy", Type: "string", Description: "Merge order: name, modTime or size (default name)"},
+		{Name: "headerLines", Type: "number", Description: "Header lines at the top of each file, e.g. 1 for CSV; only the first file's are kept (default 0)"},
+		{Name: "keepHeader", Type: "boolean", Description: "Write the first file's header lines (default true)"},
+		{Name: "overwrite", Type: "boolean", Description: "Replace an existing destination (default false)"},
+		{Name: "deleteSources", Type: "boolean", Description: "Delete the merged files once the destination is written"},
+		{Name: "minCount", Type: "number", Description: "Fail if fewer files match (default 1)"},
+	}
+}
+
+func (s *MergeFilesStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
+	directory, err := s.getRequiredString(config, "directory")
+	if err != nil {
+		return err
+	}
+	destination, err := s.getRequiredString(config, "destination")
+	if err != nil {
+		return err
+	}
+	pattern := s.getOptionalString(config, "pattern", "*")
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return validationErrorf("invalid pattern %q: %w", pattern, err)
+	}
+	sortBy := s.getOptionalString(config, "sortBy", "name")
+	if sortBy != "name" && sortBy != "modTime" && sortBy != "size" {
+		return validationErrorf("%s step parameter sortBy must be name, modTime or size", s.Type)
+	}
+	headerLines, err := s.getOptionalInt(config, "headerLines", 0)
+	if err != nil {
+		return err
+	}
+	minCount, err := s.getOptionalInt(config, "minCount", 1)
+	if err != nil {
+		return err
+	}
+	if headerLines < 0 {
+		return validationErrorf("%s step parameter headerLines must not be negative", s.Type)
+	}
+	keepHeader := s.getOptionalBool(config, "keepHeader", true)
+
+	if !s.getOptionalBool(config, "overwrite", false) {
+		if _, err := os.Stat(destination); err == nil {
+			return permanentErrorf("destination %s already exists; set overwrite to replace it", destination)
+		}
+	}
+
+	// Leave out the destination and its temp file when they match the pattern
+	tmp := destination + ".tmp"
+	skip := map[string]bool{}
+	for _, path := range []string{destination, tmp} {
+		if abs, err := filepath.Abs(path); err == nil {
+			skip[abs] = true
+		}
+	}
+	recursive := s.getOptionalBool(config, "recursive", false)
+	matches, _, err := scanFiles(directory, pattern, recursive, func(os.FileInfo) bool { return true })
+	if err != nil {
+		return fmt.Errorf("failed to scan directory: %w", err)
+	}
+	sources := matches[:0]
+	for _, m := range matches {
+		if abs, err := filepath.Abs(m.path); err == nil && skip[abs] {
+			continue
+		}
+		sources = append(sources, m)
+	}
+	if len(sources) < minCount {
+		return permanentErrorf("found %d files matching %s in %s, need at least %d", len(sources), pattern, directory, minCount)
+	}
+	sort.SliceStable(sources, func(i, j int) bool {
+		switch sortBy {
+		case "modTime":
+			return sources[i].info.ModTime().Before(sources[j].info.ModTime())
+		case "size":
+			return sources[i].info.Size() < sources[j].info.Size()
+		}
+		return sources[i].path < sources[j].path
+	})
+
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+	out, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+	buf := bufio.NewWriter(out)
+	w := &lastByteWriter{w: buf}
+	paths := make([]string, 0, len(sources))
+	for i, src := range sources {
+		skipLines := headerLines
+		if i == 0 && keepHeader {
+			skipLines = 0
+		}
+		if err := mergeOne(w, src.path, skipLines); err != nil {
+			out.Close()
+			os.Remove(tmp)
+			return err
+		}
+		paths = append(paths, src.path)
+	}
+	err = buf.Flush()
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, destination)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", destination, err)
+	}
+
+	if s.getOptionalBool(config, "deleteSources", false) {
+		for _, path := range paths {
+			if err := os.Remove(path); err != nil {
+				s.Logger.Warn().Err(err).Str("path", path).Msg("Failed to delete merged file")
+			}
+		}
+	}
+
+	context["mergedFile"] = destination
+	context["mergedCount"] = len(paths)
+	context["mergedBytes"] = w.n
+	context["mergedPaths"] = paths
+
+	s.Logger.Info().
+		Str("destination", destination).
+		Int("files", len(paths)).
+		Int64("bytes", w.n).
+		Msg("✅ Files merged successfully")
+	return nil
+}
+
+// mergeOne appends the file at path to w, leaving out its first skipLines
+// lines. A newline is added between files when the previous one lacks it.
+func mergeOne(w *lastByteWriter, path string, skipLines int) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for skipped := 0; skipped < skipLines; {
+		switch _, err := r.ReadSlice('\n'); err {
+		case nil:
+			skipped++
+		case bufio.ErrBufferFull:
+			// Overlong line, keep skipping it
+		case io.EOF:
+			return nil
+		default:
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+
+	if _, err := r.Peek(1); err == io.EOF {
+		return nil
+	}
+	if w.n > 0 && w.last != '\n' {
+		if _, err := w.Write([]byte{'\n'}); err != nil {
+			return err
+		}
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return fmt.Errorf("failed to merge %s: %w", path, err)
+	}
+	return nil
+}
+
+// lastByteWriter counts what is written and remembers its last byte
+type lastByteWriter struct {
+	w    io.Writer
+	n    int64
+	last byte
+}
+
+func (l *lastByteWriter) Write(p []byte) (int, error) {
+	n, err := l.w.Write(p)
+	if n > 0 {
+		l.n += int64(n)
+		l.last = p[n-1]
+	}
+	return n, err
+}
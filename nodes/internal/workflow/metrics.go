@@ -0,0 +1,142 @@
+package workflow
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// maxRunHistory caps how many finished runs per workflow feed the metrics
+	maxRunHistory = 200
+
+	// metricsCacheTTL keeps repeated metrics requests from recomputing percentiles
+	metricsCacheTTL = 5 * time.Second
+)
+
+// runRecord is the outcome of one finished workflow execution
+type runRecord struct {
+	status   string
+	start    time.Time
+	duration time.Duration
+	err      string
+}
+
+// WorkflowMetrics summarizes recent executions of one workflow. Counts and
+// durations cover the runs finished since the agent started (up to
+// maxRunHistory per workflow), seeded with the last run from the state file.
+type WorkflowMetrics struct {
+	WorkflowID    string    `json:"workflowId"`
+	Executions    int       `json:"executions"`
+	Succeeded     int       `json:"succeeded"`
+	Failed        int       `json:"failed"`
+	SuccessRate   float64   `json:"successRate"` // Fraction of executions that completed, 0-1
+	AvgDurationMs int64     `json:"avgDurationMs"`
+	P95DurationMs int64     `json:"p95DurationMs"`
+	LastStatus    string    `json:"lastStatus"` // running, completed or failed
+	LastRunAt     time.Time `json:"lastRunAt"`
+	LastError     string    `json:"lastError,omitempty"`
+}
+
+// recordRun adds a finished run to the workflow's history. Callers must hold sm.mu.
+func (sm *StateManager) recordRun(state *WorkflowState) {
+	if state.EndTime.IsZero() {
+		return
+	}
+	runs := append(sm.history[state.WorkflowID], runRecord{
+		status:   state.Status,
+		start:    state.StartTime,
+		duration: state.EndTime.Sub(state.StartTime),
+		err:      state.Error,
+	})
+	if len(runs) > maxRunHistory {
+		runs = runs[len(runs)-maxRunHistory:]
+	}
+	sm.history[state.WorkflowID] = runs
+	sm.metricsCache = nil
+}
+
+// Metrics returns per-workflow execution metrics sorted by workflow ID.
+// Results are cached for metricsCacheTTL.
+func (sm *StateManager) Metrics() []WorkflowMetrics {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sm.metricsCache != nil && time.Since(sm.metricsAt) < metricsCacheTTL {
+		return sm.metricsCache
+	}
+
+	ids := make(map[string]bool, len(sm.state))
+	for id := range sm.state {
+		ids[id] = true
+	}
+	for id := range sm.history {
+		ids[id] = true
+	}
+
+	metrics := make([]WorkflowMetrics, 0, len(ids))
+	for id := range ids {
+		m := summarizeRuns(id, sm.history[id])
+		// The current state is the most recent run, including one still running
+		if state, ok := sm.state[id]; ok && !state.StartTime.Before(m.LastRunAt) {
+			m.LastStatus = state.Status
+			m.LastRunAt = state.StartTime
+			m.LastError = state.Error
+		}
+		metrics = append(metrics, m)
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].WorkflowID < metrics[j].WorkflowID })
+
+	sm.metricsCache = metrics
+	sm.metricsAt = time.Now()
+	return metrics
+}
+
+// summarizeRuns computes counts, success rate and duration statistics
+func summarizeRuns(workflowID string, runs []runRecord) WorkflowMetrics {
+	m := WorkflowMetrics{WorkflowID: workflowID, Executions: len(runs)}
+	if len(runs) == 0 {
+		return m
+	}
+
+	durations := make([]time.Duration, 0, len(runs))
+	var total time.Duration
+	for _, run := range runs {
+		switch run.status {
+		case "completed":
+			m.Succeeded++
+		case "failed":
+			m.Failed++
+		}
+		durations = append(durations, run.duration)
+		total += run.duration
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	m.SuccessRate = float64(m.Succeeded) / float64(len(runs))
+	m.AvgDurationMs = (total / time.Duration(len(runs))).Milliseconds()
+	m.P95DurationMs = percentile(durations, 0.95).Milliseconds()
+
+	last := runs[len(runs)-1]
+	m.LastStatus = last.status
+	m.LastRunAt = last.start
+	m.LastError = last.err
+	return m
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// WorkflowMetrics returns execution metrics for every workflow that has run
+func (e *Executor) WorkflowMetrics() []WorkflowMetrics {
+	return e.state.Metrics()
+}
@@ -0,0 +1,51 @@
+package workflow
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStateManagerMetrics(t *testing.T) {
+	sm, err := NewStateManager(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 20 runs of 1..20 seconds; every fifth one fails
+	base := time.Now().Add(-time.Hour)
+	for i := 1; i <= 20; i++ {
+		state := &WorkflowState{WorkflowID: "wf", Status: "completed", StartTime: base, EndTime: base.Add(time.Duration(i) * time.Second)}
+		if i%5 == 0 {
+			state.Status, state.Error = "failed", "boom"
+		}
+		sm.mu.Lock()
+		sm.recordRun(state)
+		sm.mu.Unlock()
+	}
+
+	metrics := sm.Metrics()
+	if len(metrics) != 1 {
+		t.Fatalf("expected metrics for 1 workflow, got %d", len(metrics))
+	}
+	m := metrics[0]
+	if m.Executions != 20 || m.Succeeded != 16 || m.Failed != 4 || m.SuccessRate != 0.8 {
+		t.Errorf("counts = %+v", m)
+	}
+	if m.AvgDurationMs != 10500 || m.P95DurationMs != 19000 {
+		t.Errorf("avg = %dms, p95 = %dms, want 10500ms and 19000ms", m.AvgDurationMs, m.P95DurationMs)
+	}
+	if m.LastStatus != "failed" || m.LastError != "boom" {
+		t.Errorf("last run = %s %q", m.LastStatus, m.LastError)
+	}
+
+	// A new run shows up as the last status right away
+	sm.StartWorkflow("wf", map[string]interface{}{})
+	if m := sm.Metrics()[0]; m.LastStatus != "running" || m.Executions != 20 {
+		t.Errorf("after start: %+v", m)
+	}
+	sm.CompleteWorkflow("wf")
+	if m := sm.Metrics()[0]; m.LastStatus != "completed" || m.Executions != 21 {
+		t.Errorf("after completion: %+v", m)
+	}
+}
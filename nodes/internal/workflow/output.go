@@ -0,0 +1,164 @@
+package workflow
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultMaxOutputBytes caps the command output kept in the workflow context
+// when neither the step nor the command policy sets maxOutputBytes
+const defaultMaxOutputBytes = 1024 * 1024
+
+// outputCapture is an io.Writer that keeps the first max bytes written to it
+// and only counts the rest, so a chatty command cannot exhaust memory or
+// bloat the state file. Writes never fail, so the command is not killed by
+// a broken pipe when the cap is reached.
+type outputCapture struct {
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	max   int64
+	total int64
+}
+
+func newOutputCapture(max int64) *outputCapture {
+	return &outputCapture{max: max}
+}
+
+func (c *outputCapture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if room := c.max - int64(c.buf.Len()); room > 0 {
+		if int64(len(p)) > room {
+			c.buf.Write(p[:room])
+		} else {
+			c.buf.Write(p)
+		}
+	}
+	c.total += int64(len(p))
+	return len(p), nil
+}
+
+// Truncated reports whether output was dropped
+func (c *outputCapture) Truncated() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total > int64(c.buf.Len())
+}
+
+// Total returns the number of bytes written, kept or not
+func (c *outputCapture) Total() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total
+}
+
+// String returns the kept output, ending with a marker if any was dropped
+func (c *outputCapture) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.total <= int64(c.buf.Len()) {
+		return c.buf.String()
+	}
+	return fmt.Sprintf("%s\n... [output truncated: kept %d of %d bytes]", c.buf.String(), c.buf.Len(), c.total)
+}
+
+const (
+	// maxLineBytes splits overlong lines so a command without newlines
+	// cannot grow the line buffer without bound
+	maxLineBytes = 64 * 1024
+
+	// progressInterval is the minimum time between progress events of one command
+	progressInterval = time.Second
+
+	// maxProgressLines caps the lines batched into one progress event; the
+	// rest are counted as dropped
+	maxProgressLines = 50
+)
+
+// lineWriter is an io.Writer that hands each complete line to onLine as it
+// arrives. A trailing partial line is held until Flush.
+type lineWriter struct {
+	mu     sync.Mutex
+	buf    []byte
+	onLine func(line string)
+}
+
+func newLineWriter(onLine func(line string)) *lineWriter {
+	return &lineWriter{onLine: onLine}
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			if len(l.buf) >= maxLineBytes {
+				l.onLine(string(l.buf))
+				l.buf = l.buf[:0]
+			}
+			return len(p), nil
+		}
+		l.onLine(strings.TrimSuffix(string(l.buf[:i]), "\r"))
+		l.buf = l.buf[i+1:]
+	}
+}
+
+// Flush emits a trailing line that did not end in a newline
+func (l *lineWriter) Flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.buf) > 0 {
+		l.onLine(string(l.buf))
+		l.buf = nil
+	}
+}
+
+// progressBatcher groups output lines into progress events sent at most once
+// per progressInterval, so a chatty command cannot flood the manager
+type progressBatcher struct {
+	mu      sync.Mutex
+	send    func(lines []string, dropped int)
+	pending []string
+	dropped int
+	last    time.Time
+}
+
+func newProgressBatcher(send func(lines []string, dropped int)) *progressBatcher {
+	return &progressBatcher{send: send}
+}
+
+// Add queues a line and sends the batch if the interval has passed
+func (b *progressBatcher) Add(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.pending) < maxProgressLines {
+		b.pending = append(b.pending, line)
+	} else {
+		b.dropped++
+	}
+	if time.Since(b.last) >= progressInterval {
+		b.flushLocked()
+	}
+}
+
+// Flush sends any queued lines
+func (b *progressBatcher) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushLocked()
+}
+
+func (b *progressBatcher) flushLocked() {
+	if len(b.pending) == 0 && b.dropped == 0 {
+		return
+	}
+	b.send(b.pending, b.dropped)
+	b.pending = nil
+	b.dropped = 0
+	b.last = time.Now()
+}
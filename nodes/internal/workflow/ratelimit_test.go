@@ -0,0 +1,32 @@
+package workflow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	var unlimited *rateLimiter
+	if !unlimited.Allow() {
+		t.Error("nil limiter should allow requests")
+	}
+
+	l := newRateLimiter(3)
+	for i := 0; i < 3; i++ {
+		if !l.Allow() {
+			t.Fatalf("request %d should be allowed within burst", i+1)
+		}
+	}
+	if l.Allow() {
+		t.Error("fourth request should be rate limited")
+	}
+
+	// Simulate 20 seconds passing: one token (3/min) should be refilled
+	l.last = l.last.Add(-20 * time.Second)
+	if !l.Allow() {
+		t.Error("request should be allowed after refill")
+	}
+	if l.Allow() {
+		t.Error("only one token should have been refilled")
+	}
+}
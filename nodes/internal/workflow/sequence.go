@@ -0,0 +1,194 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/your-org/controlcenter/nodes/internal/throttle"
+)
+
+// sequenceStore persists the last number handed out per sequence key, so
+// numbering continues across restarts. It is loaded on first use.
+type sequenceStore struct {
+	mu       sync.Mutex
+	path     string
+	loaded   bool
+	counters map[string]int64
+}
+
+func newSequenceStore(path string) *sequenceStore {
+	return &sequenceStore{path: path}
+}
+
+// load reads the counters file. A missing file starts every sequence afresh;
+// an unreadable one is an error, since guessing could reuse numbers.
+func (s *sequenceStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	s.counters = make(map[string]int64)
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read sequence counters: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &s.counters); err != nil {
+			return fmt.Errorf("failed to parse sequence counters %s: %w", s.path, err)
+		}
+	}
+	s.loaded = true
+	return nil
+}
+
+func (s *sequenceStore) save() error {
+	data, err := json.MarshalIndent(s.counters, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sequence counters: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create sequence counters directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write sequence counters: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace sequence counters: %w", err)
+	}
+	return nil
+}
+
+// next hands the next number for key (at least start) to use. The number is
+// saved before use so a crash can leave a gap but never a duplicate, and it
+// is given back if use fails. Calls for all keys are serialized.
+func (s *sequenceStore) next(key string, start int64, use func(n int64) error) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return 0, err
+	}
+
+	prev, had := s.counters[key]
+	n := prev + 1
+	if n < start {
+		n = start
+	}
+	s.counters[key] = n
+	if err := s.save(); err != nil {
+		s.restore(key, prev, had)
+		return 0, err
+	}
+	if err := use(n); err != nil {
+		s.restore(key, prev, had)
+		if saveErr := s.save(); saveErr != nil {
+			return 0, fmt.Errorf("%w (and failed to release sequence number %d: %v)", err, n, saveErr)
+		}
+		return 0, err
+	}
+	return n, nil
+}
+
+func (s *sequenceStore) restore(key string, prev int64, had bool) {
+	if had {
+		s.counters[key] = prev
+	} else {
+		delete(s.counters, key)
+	}
+}
+
+// RenameSequenceStep moves a file to a name carrying the next number of a
+// persistent per-directory sequence, e.g. feed_000001.csv, feed_000002.csv,
+// for downstream systems that require strictly sequential filenames
+type RenameSequenceStep struct {
+	BaseStep
+	Limiter   *throttle.Limiter
+	Sequences *sequenceStore
+}
+
+// Params describes the config keys accepted by rename-sequence steps
+func (s *RenameSequenceStep) Params() []StepParam {
+	return []StepParam{
+		{Name: "source", Type: "string", Required: true, Description: "File to rename"},
+		{Name: "destination", Type: "string", Description: "Directory to move the file into (default: the source's directory)"},
+		{Name: "pattern", Type: "string", Description: "New name; {seq} is the padded number, also {name}, {ext} and {filename} (default {name}_{seq}{ext})"},
+		{Name: "padding", Type: "number", Description: "Digits {seq} is zero-padded to (default 6)"},
+		{Name: "start", Type: "number", Description: "First number of a new sequence (default 1)"},
+		{Name: "sequenceKey", Type: "string", Description: "Counter to use (default: the destination directory); steps sharing a key share numbering"},
+		{Name: "dirPerm", Type: "string", Description: "Octal mode for created directories (default 0755)"},
+	}
+}
+
+func (s *RenameSequenceStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
+	source, err := s.getRequiredString(config, "source")
+	if err != nil {
+		return err
+	}
+	destDir := s.getOptionalString(config, "destination", filepath.Dir(source))
+	if abs, err := filepath.Abs(destDir); err == nil {
+		destDir = abs
+	}
+	pattern := s.getOptionalString(config, "pattern", "{name}_{seq}{ext}")
+	if !strings.Contains(pattern, "{seq}") {
+		return validationErrorf("%s step pattern must contain {seq}", s.Type)
+	}
+	if strings.ContainsAny(pattern, `/\`) {
+		return validationErrorf("%s step pattern must be a file name, not a path", s.Type)
+	}
+	padding, err := s.getOptionalInt(config, "padding", 6)
+	if err != nil {
+		return err
+	}
+	start, err := s.getOptionalInt(config, "start", 1)
+	if err != nil {
+		return err
+	}
+	if padding < 0 || padding > 20 || start < 0 {
+		return validationErrorf("%s step padding must be 0-20 and start must not be negative", s.Type)
+	}
+	key := s.getOptionalString(config, "sequenceKey", destDir)
+	if _, err := os.Stat(source); err != nil {
+		return permanentErrorf("failed to rename with sequence: %w", err)
+	}
+	if s.Sequences == nil {
+		return permanentErrorf("%s step has no sequence store", s.Type)
+	}
+
+	fileName := filepath.Base(source)
+	ext := filepath.Ext(fileName)
+	var destination string
+	seq, err := s.Sequences.next(key, int64(start), func(n int64) error {
+		name := strings.ReplaceAll(pattern, "{seq}", fmt.Sprintf("%0*d", padding, n))
+		name = strings.ReplaceAll(name, "{filename}", fileName)
+		name = strings.ReplaceAll(name, "{name}", strings.TrimSuffix(fileName, ext))
+		name = strings.ReplaceAll(name, "{ext}", ext)
+		destination = filepath.Join(destDir, name)
+
+		// An existing file means the counter was reset or another writer
+		// uses this directory; never overwrite a numbered file
+		if _, err := os.Lstat(destination); err == nil {
+			return permanentErrorf("sequence file %s already exists (counter %q may have been reset)", destination, key)
+		}
+		mover := &MoveFileStep{BaseStep: s.BaseStep, Limiter: s.Limiter}
+		moveConfig := map[string]interface{}{"source": source, "destination": destination}
+		if perm, ok := config["dirPerm"]; ok {
+			moveConfig["dirPerm"] = perm
+		}
+		return mover.Execute(moveConfig, context)
+	})
+	if err != nil {
+		return err
+	}
+
+	context["sequenceNumber"] = seq
+	context["renamedTo"] = destination
+	s.Logger.Info().
+		Str("source", source).
+		Str("destination", destination).
+		Int64("sequence", seq).
+		Msg("🔢 File renamed with sequence number")
+	return nil
+}
@@ -2,16 +2,31 @@ package workflow
 
 import (
 	stdcontext "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/rs/zerolog"
+	"github.com/your-org/controlcenter/nodes/internal/config"
+	"github.com/your-org/controlcenter/nodes/internal/proxy"
+	"github.com/your-org/controlcenter/nodes/internal/throttle"
 )
 
 // Step represents a workflow step that can be executed
@@ -35,7 +50,7 @@ func (b *BaseStep) GetType() string {
 func (b *BaseStep) getRequiredString(config map[string]interface{}, key string) (string, error) {
 	value, ok := config[key].(string)
 	if !ok || value == "" {
-		return "", fmt.Errorf("%s step requires %s parameter", b.Type, key)
+		return "", validationErrorf("%s step requires %s parameter", b.Type, key)
 	}
 	return value, nil
 }
@@ -48,9 +63,120 @@ func (b *BaseStep) getOptionalString(config map[string]interface{}, key string,
 	return defaultValue
 }
 
+// getOptionalFileMode parses an optional octal permission string such as "0660"
+func (b *BaseStep) getOptionalFileMode(cfg map[string]interface{}, key string, defaultValue os.FileMode) (os.FileMode, error) {
+	s, _ := cfg[key].(string)
+	mode, err := config.ParseFileMode(s, defaultValue)
+	if err != nil {
+		return 0, &ValidationError{Err: err}
+	}
+	return mode, nil
+}
+
+// getOptionalInt extracts an optional integer parameter from config. JSON
+// numbers and numeric strings (as saved by the workflow editor) are accepted.
+func (b *BaseStep) getOptionalInt(config map[string]interface{}, key string, defaultValue int) (int, error) {
+	switch v := config[key].(type) {
+	case nil:
+		return defaultValue, nil
+	case float64:
+		return int(v), nil
+	case int:
+		return v, nil
+	case string:
+		if v == "" {
+			return defaultValue, nil
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return 0, validationErrorf("%s step parameter %s must be a number", b.Type, key)
+		}
+		return n, nil
+	default:
+		return 0, validationErrorf("%s step parameter %s must be a number", b.Type, key)
+	}
+}
+
+// getOptionalBool extracts an optional boolean parameter from config,
+// accepting true/false as JSON booleans or strings
+func (b *BaseStep) getOptionalBool(config map[string]interface{}, key string, defaultValue bool) bool {
+	switch v := config[key].(type) {
+	case bool:
+		return v
+	case string:
+		if parsed, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getOptionalLimiter builds a per-step bandwidth limiter from maxBytesPerSecond
+func getOptionalLimiter(config map[string]interface{}) *throttle.Limiter {
+	n, _ := config["maxBytesPerSecond"].(float64)
+	return throttle.NewLimiter(int64(n))
+}
+
+// getOptionalStringSlice extracts an optional list of strings from config
+func (b *BaseStep) getOptionalStringSlice(config map[string]interface{}, key string) ([]string, error) {
+	raw, exists := config[key]
+	if !exists || raw == nil {
+		return nil, nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, validationErrorf("%s step parameter %s must be an array", b.Type, key)
+	}
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		result = append(result, fmt.Sprint(item))
+	}
+	return result, nil
+}
+
+// getOptionalStringMap reads an object parameter, also accepting a JSON string
+// since the workflow editor saves textarea values as strings
+func (b *BaseStep) getOptionalStringMap(config map[string]interface{}, key string) (map[string]string, error) {
+	raw, exists := config[key]
+	if !exists || raw == nil || raw == "" {
+		return nil, nil
+	}
+	if str, ok := raw.(string); ok {
+		var parsed map[string]interface{}
+		if err := json.Unmarshal([]byte(str), &parsed); err != nil {
+			return nil, validationErrorf("%s step parameter %s must be a JSON object: %w", b.Type, key, err)
+		}
+		raw = parsed
+	}
+	obj, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, validationErrorf("%s step parameter %s must be an object", b.Type, key)
+	}
+	result := make(map[string]string, len(obj))
+	for k, v := range obj {
+		result[k] = fmt.Sprint(v)
+	}
+	return result, nil
+}
+
 // MoveFileStep implements file moving
 type MoveFileStep struct {
 	BaseStep
+	Limiter *throttle.Limiter // agent-wide bandwidth limit for cross-device moves (nil = unlimited)
+
+	rename func(oldpath, newpath string) error // os.Rename unless overridden in tests
+}
+
+// Params describes the config keys accepted by move-file steps
+func (s *MoveFileStep) Params() []StepParam {
+	return []StepParam{
+		{Name: "source", Type: "string", Required: true, Description: "File or directory to move"},
+		{Name: "destination", Type: "string", Required: true, Description: "Destination path"},
+		{Name: "dirPerm", Type: "string", Description: "Octal mode for created directories (default 0755)"},
+		{Name: "preserveTimestamps", Type: "boolean", Description: "Keep modification times when moving across filesystems (default true)"},
+		{Name: "verifyChecksum", Type: "boolean", Description: "Compare SHA-256 of each file when moving across filesystems"},
+		{Name: "maxBytesPerSecond", Type: "number", Description: "Bandwidth limit when moving across filesystems"},
+	}
 }
 
 func (s *MoveFileStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
@@ -64,13 +190,30 @@ func (s *MoveFileStep) Execute(config map[string]interface{}, context map[string
 		return err
 	}
 
+	dirMode, err := s.getOptionalFileMode(config, "dirPerm", 0755)
+	if err != nil {
+		return err
+	}
+
 	// Ensure destination directory exists
 	destDir := filepath.Dir(destination)
-	if err := os.MkdirAll(destDir, 0755); err != nil {
+	if err := makeDirs(destDir, dirMode, config["dirPerm"] != nil); err != nil {
 		return fmt.Errorf("failed to create destination directory: %w", err)
 	}
 
-	if err := os.Rename(source, destination); err != nil {
+	rename := s.rename
+	if rename == nil {
+		rename = os.Rename
+	}
+	err = rename(source, destination)
+	if err != nil && errors.Is(err, errCrossDevice) {
+		s.Logger.Info().
+			Str("source", source).
+			Str("destination", destination).
+			Msg("📦 Source and destination are on different filesystems, copying then removing source")
+		err = s.copyAndRemove(source, destination, dirMode, config)
+	}
+	if err != nil {
 		return fmt.Errorf("failed to move file: %w", err)
 	}
 
@@ -82,9 +225,71 @@ func (s *MoveFileStep) Execute(config map[string]interface{}, context map[string
 	return nil
 }
 
+// makeDirs creates path and its missing parents. With chmod set (dirPerm was
+// given explicitly) the created directories get mode despite the umask.
+func makeDirs(path string, mode os.FileMode, chmod bool) error {
+	if chmod {
+		return config.MkdirAllPerm(path, mode)
+	}
+	return os.MkdirAll(path, mode)
+}
+
+// copyAndRemove moves source across filesystems by copying it (recursively for
+// directories) and removing the source only once the copy has fully succeeded
+func (s *MoveFileStep) copyAndRemove(source, destination string, dirMode os.FileMode, config map[string]interface{}) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+
+	copier := &CopyFileStep{BaseStep: s.BaseStep, Limiter: s.Limiter}
+	opts := copyOptions{
+		fileMode:   info.Mode().Perm(),
+		dirChmod:   config["dirPerm"] != nil,
+		preserve:   s.getOptionalBool(config, "preserveTimestamps", true),
+		verify:     s.getOptionalBool(config, "verifyChecksum", false),
+		maxRetries: 1,
+		limiters:   []*throttle.Limiter{s.Limiter, getOptionalLimiter(config)},
+	}
+
+	if info.IsDir() {
+		_, statErr := os.Stat(destination)
+		createdDest := os.IsNotExist(statErr)
+		if _, _, err := copier.copyDir(source, destination, dirMode, opts); err != nil {
+			if createdDest {
+				os.RemoveAll(destination)
+			}
+			return err
+		}
+		return os.RemoveAll(source)
+	}
+
+	if err := copier.copyOne(source, destination, info, opts); err != nil {
+		os.Remove(destination)
+		return err
+	}
+	return os.Remove(source)
+}
+
 // CopyFileStep implements file copying
 type CopyFileStep struct {
 	BaseStep
+	Limiter *throttle.Limiter // agent-wide bandwidth limit (nil = unlimited)
+}
+
+// Params describes the config keys accepted by copy-file steps
+func (s *CopyFileStep) Params() []StepParam {
+	return []StepParam{
+		{Name: "source", Type: "string", Required: true, Description: "File or directory to copy"},
+		{Name: "destination", Type: "string", Required: true, Description: "Destination path"},
+		{Name: "recursive", Type: "boolean", Description: "Copy a source directory and everything under it"},
+		{Name: "filePerm", Type: "string", Description: "Octal mode for the copied file"},
+		{Name: "dirPerm", Type: "string", Description: "Octal mode for created directories (default 0755)"},
+		{Name: "preserveTimestamps", Type: "boolean", Description: "Keep the source modification time (default true)"},
+		{Name: "verifyChecksum", Type: "boolean", Description: "Compare SHA-256 of source and destination after copying"},
+		{Name: "maxRetries", Type: "number", Description: "Copy attempts when checksum verification fails (default 3)"},
+		{Name: "maxBytesPerSecond", Type: "number", Description: "Bandwidth limit for this copy"},
+	}
 }
 
 func (s *CopyFileStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
@@ -98,22 +303,66 @@ func (s *CopyFileStep) Execute(config map[string]interface{}, context map[string
 		return err
 	}
 
-	// Ensure destination directory exists
-	destDir := filepath.Dir(destination)
-	if err := os.MkdirAll(destDir, 0755); err != nil {
-		return fmt.Errorf("failed to create destination directory: %w", err)
+	fileMode, err := s.getOptionalFileMode(config, "filePerm", 0644)
+	if err != nil {
+		return err
+	}
+	dirMode, err := s.getOptionalFileMode(config, "dirPerm", 0755)
+	if err != nil {
+		return err
+	}
+	maxRetries, err := s.getOptionalInt(config, "maxRetries", 3)
+	if err != nil {
+		return err
+	}
+	verify := s.getOptionalBool(config, "verifyChecksum", false)
+	if !verify || maxRetries < 1 {
+		maxRetries = 1
 	}
 
-	// Read source file
-	data, err := os.ReadFile(source)
+	opts := copyOptions{
+		fileMode:   fileMode,
+		chmod:      config["filePerm"] != nil,
+		dirChmod:   config["dirPerm"] != nil,
+		preserve:   s.getOptionalBool(config, "preserveTimestamps", true),
+		verify:     verify,
+		maxRetries: maxRetries,
+		limiters:   []*throttle.Limiter{s.Limiter, getOptionalLimiter(config)},
+	}
+
+	info, err := os.Stat(source)
 	if err != nil {
 		return fmt.Errorf("failed to read source file: %w", err)
 	}
 
-	// Write to destination
-	if err := os.WriteFile(destination, data, 0644); err != nil {
-		return fmt.Errorf("failed to write destination file: %w", err)
+	if info.IsDir() {
+		if !s.getOptionalBool(config, "recursive", false) {
+			return fmt.Errorf("source %s is a directory; set recursive to copy it", source)
+		}
+		files, bytes, err := s.copyDir(source, destination, dirMode, opts)
+		if err != nil {
+			return err
+		}
+		context["copiedFiles"] = files
+		context["copiedBytes"] = bytes
+		s.Logger.Info().
+			Str("source", source).
+			Str("destination", destination).
+			Int("files", files).
+			Int64("bytes", bytes).
+			Msg("✅ Directory copied successfully")
+		return nil
+	}
+
+	// Ensure destination directory exists
+	if err := makeDirs(filepath.Dir(destination), dirMode, opts.dirChmod); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+	if err := s.copyOne(source, destination, info, opts); err != nil {
+		return err
 	}
+	context["copiedFiles"] = 1
+	context["copiedBytes"] = info.Size()
 
 	s.Logger.Info().
 		Str("source", source).
@@ -123,11 +372,186 @@ func (s *CopyFileStep) Execute(config map[string]interface{}, context map[string
 	return nil
 }
 
+// copyOptions carries per-step settings shared by every file in a copy
+type copyOptions struct {
+	fileMode   os.FileMode
+	chmod      bool // filePerm was set explicitly, so apply it despite umask
+	dirChmod   bool // likewise for dirPerm and created directories
+	preserve   bool
+	verify     bool
+	maxRetries int
+	limiters   []*throttle.Limiter
+}
+
+// copyDir copies the tree under source into destination, returning the number
+// of files and bytes copied. Symlinks and other special files are skipped.
+func (s *CopyFileStep) copyDir(source, destination string, dirMode os.FileMode, opts copyOptions) (int, int64, error) {
+	absSource, err := filepath.Abs(source)
+	if err != nil {
+		return 0, 0, err
+	}
+	absDest, err := filepath.Abs(destination)
+	if err != nil {
+		return 0, 0, err
+	}
+	if rel, err := filepath.Rel(absSource, absDest); err == nil && !strings.HasPrefix(rel, "..") {
+		return 0, 0, fmt.Errorf("destination %s is inside source %s", destination, source)
+	}
+
+	files := 0
+	var bytes int64
+	err = filepath.WalkDir(source, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destination, rel)
+
+		switch {
+		case d.IsDir():
+			if err := makeDirs(target, dirMode, opts.dirChmod); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", target, err)
+			}
+			return nil
+		case !d.Type().IsRegular():
+			s.Logger.Warn().Str("path", path).Msg("Skipping non-regular file during recursive copy")
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if err := s.copyOne(path, target, info, opts); err != nil {
+			return err
+		}
+		files++
+		bytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		return files, bytes, err
+	}
+
+	if opts.preserve {
+		// Directory times change as their contents are written, so set them last
+		filepath.WalkDir(source, func(path string, d os.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				rel, _ := filepath.Rel(source, path)
+				os.Chtimes(filepath.Join(destination, rel), time.Time{}, info.ModTime())
+			}
+			return nil
+		})
+	}
+	return files, bytes, nil
+}
+
+// copyOne streams a single file to destination, hashing while it copies so
+// verification only needs to re-read the destination
+func (s *CopyFileStep) copyOne(source, destination string, info os.FileInfo, opts copyOptions) error {
+	for attempt := 1; ; attempt++ {
+		sourceSum, err := streamCopy(source, destination, opts.fileMode, opts.limiters)
+		if err != nil {
+			return err
+		}
+		if !opts.verify {
+			break
+		}
+
+		destSum, err := sha256File(destination)
+		if err == nil && destSum == sourceSum {
+			break
+		}
+
+		os.Remove(destination)
+		s.Logger.Error().
+			Str("destination", destination).
+			Int("attempt", attempt).
+			Int("maxRetries", opts.maxRetries).
+			Msg("❌ Checksum mismatch after copy, removed destination")
+		if attempt >= opts.maxRetries {
+			return transientErrorf("copy verification failed after %d attempts", opts.maxRetries)
+		}
+	}
+
+	// OpenFile only applies the mode on create and is subject to umask
+	if opts.chmod {
+		if err := os.Chmod(destination, opts.fileMode); err != nil {
+			s.Logger.Warn().Err(err).Str("destination", destination).Msg("Failed to set file permissions")
+		}
+	}
+
+	// Keep the source modification time unless explicitly disabled
+	if opts.preserve {
+		if err := os.Chtimes(destination, time.Time{}, info.ModTime()); err != nil {
+			s.Logger.Warn().Err(err).Str("destination", destination).Msg("Failed to preserve file timestamps")
+		}
+	}
+	return nil
+}
+
+// streamCopy copies source to destination without buffering the whole file,
+// returning the SHA-256 of the bytes read
+func streamCopy(source, destination string, mode os.FileMode, limiters []*throttle.Limiter) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+
+	in, err := os.Open(source)
+	if err != nil {
+		return sum, fmt.Errorf("failed to read source file: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return sum, fmt.Errorf("failed to write destination file: %w", err)
+	}
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, hash), throttle.NewReader(in, limiters...))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return sum, fmt.Errorf("failed to write destination file: %w", err)
+	}
+	copy(sum[:], hash.Sum(nil))
+	return sum, nil
+}
+
+// sha256File hashes the file at path
+func sha256File(path string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	f, err := os.Open(path)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return sum, err
+	}
+	copy(sum[:], hash.Sum(nil))
+	return sum, nil
+}
+
 // DeleteFileStep implements file deletion
 type DeleteFileStep struct {
 	BaseStep
 }
 
+// Params describes the config keys accepted by delete-file steps
+func (s *DeleteFileStep) Params() []StepParam {
+	return []StepParam{
+		{Name: "path", Type: "string", Required: true, Description: "File to delete"},
+	}
+}
+
 func (s *DeleteFileStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
 	path, err := s.getRequiredString(config, "path")
 	if err != nil {
@@ -145,9 +569,401 @@ func (s *DeleteFileStep) Execute(config map[string]interface{}, context map[stri
 	return nil
 }
 
+// ChownFileStep changes the owner and/or group of a file (Unix only)
+type ChownFileStep struct {
+	BaseStep
+}
+
+// Params describes the config keys accepted by chown-file steps
+func (s *ChownFileStep) Params() []StepParam {
+	return []StepParam{
+		{Name: "path", Type: "string", Required: true, Description: "File or directory to change"},
+		{Name: "owner", Type: "string", Description: "User name or uid"},
+		{Name: "group", Type: "string", Description: "Group name or gid"},
+	}
+}
+
+func (s *ChownFileStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
+	path, err := s.getRequiredString(config, "path")
+	if err != nil {
+		return err
+	}
+
+	owner := s.getOptionalString(config, "owner", "")
+	group := s.getOptionalString(config, "group", "")
+	if owner == "" && group == "" {
+		return fmt.Errorf("at least one of owner or group is required")
+	}
+
+	if runtime.GOOS == "windows" {
+		s.Logger.Warn().Str("path", path).Msg("⚠️ chown-file is not supported on Windows, skipping")
+		return nil
+	}
+
+	if err := chownPath(path, owner, group); err != nil {
+		return err
+	}
+
+	s.Logger.Info().
+		Str("path", path).
+		Str("owner", owner).
+		Str("group", group).
+		Msg("✅ File ownership changed successfully")
+
+	return nil
+}
+
+// chownPath resolves owner/group names or ids and applies them to path
+func chownPath(path, owner, group string) error {
+	uid, gid, err := config.ResolveOwnership(owner, group)
+	if err != nil {
+		return err
+	}
+	if err := os.Chown(path, uid, gid); err != nil {
+		return fmt.Errorf("failed to change ownership: %w", err)
+	}
+	return nil
+}
+
+// rawConfigReceiver is implemented by steps that need the step config as it
+// was before template substitution (e.g. to tell literal values from injected ones).
+type rawConfigReceiver interface {
+	SetRawConfig(raw map[string]interface{})
+}
+
+// shellMetacharacters are the characters that let a shell line chain or
+// substitute commands.
+const shellMetacharacters = ";|&$`\n"
+
+// defaultMaxDeletes caps how many files one cleanup-files step may delete
+const defaultMaxDeletes = 1000
+
+// scannedFile is a regular file found by scanFiles
+type scannedFile struct {
+	path string
+	info os.FileInfo
+}
+
+// scanFiles walks directory (and subdirectories when recursive) for regular
+// files whose name matches the glob pattern and for which keep returns true.
+// It also returns their total size.
+func scanFiles(directory, pattern string, recursive bool, keep func(info os.FileInfo) bool) ([]scannedFile, int64, error) {
+	var matches []scannedFile
+	var totalBytes int64
+	err := filepath.WalkDir(directory, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != directory && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ok, _ := filepath.Match(pattern, d.Name()); !ok {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() || !keep(info) {
+			return nil
+		}
+		matches = append(matches, scannedFile{path: path, info: info})
+		totalBytes += info.Size()
+		return nil
+	})
+	return matches, totalBytes, err
+}
+
+// CleanupFilesStep deletes files older than a given age, for retention of
+// archive and processed directories
+type CleanupFilesStep struct {
+	BaseStep
+}
+
+// Params describes the config keys accepted by cleanup-files steps
+func (s *CleanupFilesStep) Params() []StepParam {
+	return []StepParam{
+		{Name: "directory", Type: "string", Required: true, Description: "Directory to clean up"},
+		{Name: "olderThanHours", Type: "number", Required: true, Description: "Delete files last modified more than this many hours ago"},
+		{Name: "pattern", Type: "string", Description: "Glob matched against file names (default *)"},
+		{Name: "recursive", Type: "boolean", Description: "Include subdirectories"},
+		{Name: "maxDeletes", Type: "number", Description: "Abort without deleting if more files match (default 1000)"},
+		{Name: "dryRun", Type: "boolean", Description: "Only report what would be deleted"},
+	}
+}
+
+func (s *CleanupFilesStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
+	directory, err := s.getRequiredString(config, "directory")
+	if err != nil {
+		return err
+	}
+	olderThanHours, err := s.getOptionalInt(config, "olderThanHours", 0)
+	if err != nil {
+		return err
+	}
+	if olderThanHours <= 0 {
+		return fmt.Errorf("%s step requires olderThanHours greater than 0", s.Type)
+	}
+	maxDeletes, err := s.getOptionalInt(config, "maxDeletes", defaultMaxDeletes)
+	if err != nil {
+		return err
+	}
+	pattern := s.getOptionalString(config, "pattern", "*")
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	recursive := s.getOptionalBool(config, "recursive", false)
+
+	// A dryRun flag in the workflow context (e.g. from a manual trigger) applies too
+	dryRun := s.getOptionalBool(config, "dryRun", false) || s.getOptionalBool(context, "dryRun", false)
+
+	cutoff := time.Now().Add(-time.Duration(olderThanHours) * time.Hour)
+	found, totalBytes, err := scanFiles(directory, pattern, recursive, func(info os.FileInfo) bool {
+		return info.ModTime().Before(cutoff)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan directory: %w", err)
+	}
+	matches := make([]string, 0, len(found))
+	for _, f := range found {
+		matches = append(matches, f.path)
+	}
+
+	if maxDeletes > 0 && len(matches) > maxDeletes {
+		return fmt.Errorf("%d files match, exceeding maxDeletes %d; nothing deleted", len(matches), maxDeletes)
+	}
+
+	deleted := make([]string, 0, len(matches))
+	if dryRun {
+		deleted = matches
+	} else {
+		for _, path := range matches {
+			if err := os.Remove(path); err != nil {
+				s.Logger.Warn().Err(err).Str("path", path).Msg("Failed to delete old file")
+				continue
+			}
+			deleted = append(deleted, path)
+		}
+	}
+
+	s.Logger.Info().
+		Str("directory", directory).
+		Str("pattern", pattern).
+		Int("olderThanHours", olderThanHours).
+		Int("deleted", len(deleted)).
+		Int64("bytes", totalBytes).
+		Bool("dryRun", dryRun).
+		Msg("🧹 Cleanup completed")
+
+	context["cleanupDeleted"] = len(deleted)
+	context["cleanupFiles"] = deleted
+	context["cleanupDryRun"] = dryRun
+	return nil
+}
+
+// defaultMaxListed caps how many entries list-files stores in the context
+const defaultMaxListed = 1000
+
+// ListFilesStep scans a directory and stores the matching files in context
+type ListFilesStep struct {
+	BaseStep
+}
+
+// Params describes the config keys accepted by list-files steps
+func (s *ListFilesStep) Params() []StepParam {
+	return []StepParam{
+		{Name: "directory", Type: "string", Required: true, Description: "Directory to scan"},
+		{Name: "pattern", Type: "string", Description: "Glob matched against file names (default *)"},
+		{Name: "recursive", Type: "boolean", Description: "Include subdirectories"},
+		{Name: "modifiedWithinHours", Type: "number", Description: "Only list files modified within this many hours"},
+		{Name: "sortBy", Type: "string", Description: "name, modTime or size (default name)"},
+		{Name: "minCount", Type: "number", Description: "Fail if fewer files match"},
+		{Name: "maxResults", Type: "number", Description: "Maximum files stored in context (default 1000); counts cover all matches"},
+	}
+}
+
+func (s *ListFilesStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
+	directory, err := s.getRequiredString(config, "directory")
+	if err != nil {
+		return err
+	}
+	pattern := s.getOptionalString(config, "pattern", "*")
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return validationErrorf("invalid pattern %q: %w", pattern, err)
+	}
+	recursive := s.getOptionalBool(config, "recursive", false)
+	withinHours, err := s.getOptionalInt(config, "modifiedWithinHours", 0)
+	if err != nil {
+		return err
+	}
+	minCount, err := s.getOptionalInt(config, "minCount", 0)
+	if err != nil {
+		return err
+	}
+	maxResults, err := s.getOptionalInt(config, "maxResults", defaultMaxListed)
+	if err != nil {
+		return err
+	}
+	sortBy := s.getOptionalString(config, "sortBy", "name")
+	if sortBy != "name" && sortBy != "modTime" && sortBy != "size" {
+		return validationErrorf("%s step parameter sortBy must be name, modTime or size", s.Type)
+	}
+
+	var cutoff time.Time
+	if withinHours > 0 {
+		cutoff = time.Now().Add(-time.Duration(withinHours) * time.Hour)
+	}
+
+	matches, totalBytes, err := scanFiles(directory, pattern, recursive, func(info os.FileInfo) bool {
+		return !info.ModTime().Before(cutoff)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan directory: %w", err)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		switch sortBy {
+		case "modTime":
+			return matches[i].info.ModTime().Before(matches[j].info.ModTime())
+		case "size":
+			return matches[i].info.Size() < matches[j].info.Size()
+		}
+		return matches[i].path < matches[j].path
+	})
+
+	truncated := maxResults > 0 && len(matches) > maxResults
+	listed := matches
+	if truncated {
+		listed = matches[:maxResults]
+	}
+	files := make([]interface{}, 0, len(listed))
+	paths := make([]string, 0, len(listed))
+	for _, m := range listed {
+		files = append(files, map[string]interface{}{
+			"path":    m.path,
+			"name":    m.info.Name(),
+			"size":    m.info.Size(),
+			"modTime": m.info.ModTime().UTC().Format(time.RFC3339),
+		})
+		paths = append(paths, m.path)
+	}
+
+	s.Logger.Info().
+		Str("directory", directory).
+		Str("pattern", pattern).
+		Int("count", len(matches)).
+		Int64("bytes", totalBytes).
+		Bool("truncated", truncated).
+		Msg("📂 Directory listed")
+
+	context["listFiles"] = files
+	context["listPaths"] = paths
+	context["listCount"] = len(matches)
+	context["listTotalBytes"] = totalBytes
+	context["listTruncated"] = truncated
+
+	// Files may still be arriving, so a shortfall is worth retrying
+	if len(matches) < minCount {
+		return transientErrorf("only %d files match %s in %s, expected at least %d", len(matches), pattern, directory, minCount)
+	}
+	return nil
+}
+
 // CommandStep implements command execution
 type CommandStep struct {
 	BaseStep
+	Policy    config.CommandPolicy
+	Events    func(event string, details map[string]interface{}) // receives command-output progress events
+	rawConfig map[string]interface{}
+}
+
+// SetRawConfig stores the untemplated step config for policy checks
+func (s *CommandStep) SetRawConfig(raw map[string]interface{}) {
+	s.rawConfig = raw
+}
+
+// checkPolicy enforces the local command policy against the final command line
+func (s *CommandStep) checkPolicy(command, arguments string) error {
+	fullCommand := strings.TrimSpace(command + " " + arguments)
+
+	if s.Policy.DenyShellMetacharacters && s.rawConfig != nil {
+		rawCommand, _ := s.rawConfig["command"].(string)
+		rawArguments, _ := s.rawConfig["arguments"].(string)
+		if rawArguments == "" {
+			rawArguments, _ = s.rawConfig["args"].(string)
+		}
+		rawFull := rawCommand + " " + rawArguments
+
+		// Metacharacters written literally in the workflow are allowed; ones
+		// that only appear after template substitution came from context data.
+		for _, ch := range shellMetacharacters {
+			if strings.Count(fullCommand, string(ch)) > strings.Count(rawFull, string(ch)) {
+				return permanentErrorf("command rejected by policy: template substitution introduced shell metacharacter %q", ch)
+			}
+		}
+	}
+
+	if len(s.Policy.AllowedCommands) > 0 {
+		// With an allowlist, chaining operators would let any binary run after an allowed one
+		if strings.ContainsAny(fullCommand, shellMetacharacters) {
+			return permanentErrorf("command rejected by policy: shell metacharacters are not allowed when allowedCommands is set")
+		}
+
+		fields := strings.Fields(fullCommand)
+		if len(fields) == 0 {
+			return permanentErrorf("command rejected by policy: empty command")
+		}
+		return s.checkAllowedBinary(strings.Trim(fields[0], "\"'"))
+	}
+
+	return nil
+}
+
+// checkAllowedBinary verifies a binary against the allowedCommands list
+func (s *CommandStep) checkAllowedBinary(binary string) error {
+	if len(s.Policy.AllowedCommands) == 0 {
+		return nil
+	}
+	for _, entry := range s.Policy.AllowedCommands {
+		if entry == binary || (!filepath.IsAbs(entry) && entry == filepath.Base(binary)) {
+			return nil
+		}
+	}
+	return permanentErrorf("command rejected by policy: %s is not in allowedCommands", binary)
+}
+
+// Params describes the config keys accepted by run-command steps
+func (s *CommandStep) Params() []StepParam {
+	return []StepParam{
+		{Name: "command", Type: "string", Description: "Executable to run (required unless argv is set)"},
+		{Name: "arguments", Type: "string", Description: "Space-separated arguments (alias: args)"},
+		{Name: "argv", Type: "array", Description: "Command and arguments as a list, bypassing argument splitting"},
+		{Name: "workingDir", Type: "string", Description: "Working directory"},
+		{Name: "env", Type: "object", Description: "Extra environment variables"},
+		{Name: "maxOutputBytes", Type: "number", Description: "Output kept in the workflow context, truncated beyond this (default 1MB, never above commandPolicy.maxOutputBytes)"},
+		{Name: "outputFile", Type: "string", Description: "Also write the full, untruncated output to this file"},
+		{Name: "progressEvents", Type: "boolean", Description: "Forward output lines to the manager as command-output events while the command runs"},
+	}
+}
+
+// outputLimit returns the effective output cap: the step's maxOutputBytes,
+// bounded by the command policy's, or the default
+func (s *CommandStep) outputLimit(config map[string]interface{}) (int64, error) {
+	limit, err := s.getOptionalInt(config, "maxOutputBytes", 0)
+	if err != nil {
+		return 0, err
+	}
+	if limit < 0 {
+		return 0, validationErrorf("%s step parameter maxOutputBytes must not be negative", s.Type)
+	}
+	max := int64(limit)
+	if policy := s.Policy.MaxOutputBytes; policy > 0 && (max == 0 || max > policy) {
+		max = policy
+	}
+	if max == 0 {
+		max = defaultMaxOutputBytes
+	}
+	return max, nil
 }
 
 func (s *CommandStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
@@ -157,51 +973,151 @@ func (s *CommandStep) Execute(config map[string]interface{}, context map[string]
 		Interface("context", context).
 		Msg("📋 Command step configuration")
 
-	command, err := s.getRequiredString(config, "command")
+	workDir := s.getOptionalString(config, "workingDir", "")
+
+	// argv runs the binary directly without a shell, so context values passed
+	// as arguments can never be interpreted as shell syntax.
+	argv, err := s.getOptionalStringSlice(config, "argv")
 	if err != nil {
 		return err
 	}
 
-	// Get arguments if provided (try both "arguments" and "args" for compatibility)
-	arguments := s.getOptionalString(config, "arguments", "")
-	if arguments == "" {
-		arguments = s.getOptionalString(config, "args", "")
-	}
+	var cmd *exec.Cmd
+	var fullCommand string
+	if len(argv) > 0 {
+		fullCommand = strings.Join(argv, " ")
+
+		if err := s.checkAllowedBinary(argv[0]); err != nil {
+			s.Logger.Error().
+				Err(err).
+				Strs("argv", argv).
+				Msg("🚫 Command blocked by command policy")
+			context["commandError"] = err.Error()
+			return err
+		}
 
-	// Combine command and arguments
-	fullCommand := command
-	if arguments != "" {
-		fullCommand = command + " " + arguments
-	}
+		s.Logger.Info().
+			Strs("argv", argv).
+			Str("workDir", workDir).
+			Msg("🔧 Executing command (no shell)")
 
-	workDir := s.getOptionalString(config, "workingDir", "")
+		cmd = exec.Command(argv[0], argv[1:]...)
+	} else {
+		command, err := s.getRequiredString(config, "command")
+		if err != nil {
+			return validationErrorf("%s step requires command or argv parameter", s.Type)
+		}
 
-	s.Logger.Info().
-		Str("command", command).
-		Str("arguments", arguments).
-		Str("fullCommand", fullCommand).
-		Str("workDir", workDir).
-		Msg("🔧 Executing command")
+		// Get arguments if provided (try both "arguments" and "args" for compatibility)
+		arguments := s.getOptionalString(config, "arguments", "")
+		if arguments == "" {
+			arguments = s.getOptionalString(config, "args", "")
+		}
 
-	// Use shell to execute for proper handling
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.Command("cmd", "/C", fullCommand)
-	} else {
-		cmd = exec.Command("sh", "-c", fullCommand)
+		// Combine command and arguments
+		fullCommand = command
+		if arguments != "" {
+			fullCommand = command + " " + arguments
+		}
+
+		if err := s.checkPolicy(command, arguments); err != nil {
+			s.Logger.Error().
+				Err(err).
+				Str("fullCommand", fullCommand).
+				Msg("🚫 Command blocked by command policy")
+			context["commandError"] = err.Error()
+			return err
+		}
+
+		s.Logger.Info().
+			Str("command", command).
+			Str("arguments", arguments).
+			Str("fullCommand", fullCommand).
+			Str("workDir", workDir).
+			Msg("🔧 Executing command")
+
+		// Use shell to execute for proper handling
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", fullCommand)
+		} else {
+			cmd = exec.Command("sh", "-c", fullCommand)
+		}
+	}
+
+	// Merge step-level environment variables (already template-substituted)
+	if envMap, ok := config["env"].(map[string]interface{}); ok && len(envMap) > 0 {
+		cmd.Env = os.Environ()
+		for key, value := range envMap {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%v", key, value))
+		}
 	}
 
 	if workDir != "" {
 		cmd.Dir = workDir
 	}
 
-	output, err := cmd.CombinedOutput()
-	outputStr := string(output)
+	maxOutput, err := s.outputLimit(config)
+	if err != nil {
+		return err
+	}
+	capture := newOutputCapture(maxOutput)
+	var output io.Writer = capture
+	outputFile := s.getOptionalString(config, "outputFile", "")
+	if outputFile != "" {
+		file, err := os.Create(outputFile)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		output = io.MultiWriter(capture, file)
+		context["outputFile"] = outputFile
+	}
+
+	// Log output lines as they arrive so long commands show progress
+	var progress *progressBatcher
+	if s.Events != nil && s.getOptionalBool(config, "progressEvents", false) {
+		progress = newProgressBatcher(func(lines []string, dropped int) {
+			s.Events("command-output", map[string]interface{}{
+				"workflowId":   context["workflowId"],
+				"executionId":  context["executionId"],
+				"command":      fullCommand,
+				"lines":        lines,
+				"droppedLines": dropped,
+			})
+		})
+	}
+	lines := newLineWriter(func(line string) {
+		s.Logger.Debug().Str("fullCommand", fullCommand).Str("line", line).Msg("📤 Command output")
+		if progress != nil {
+			progress.Add(line)
+		}
+	})
+	output = io.MultiWriter(output, lines)
+
+	// One writer for both streams keeps them interleaved as CombinedOutput did
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	err = cmd.Run()
+	lines.Flush()
+	if progress != nil {
+		progress.Flush()
+	}
+	outputStr := capture.String()
 
 	// Always store command info in context for downstream steps
 	context["command"] = fullCommand
 	context["commandOutput"] = outputStr
 	context["output"] = outputStr  // Short alias for convenience
+	context["commandOutputBytes"] = capture.Total()
+	context["commandOutputTruncated"] = capture.Truncated()
+	if capture.Truncated() {
+		s.Logger.Warn().
+			Str("fullCommand", fullCommand).
+			Int64("outputBytes", capture.Total()).
+			Int64("maxOutputBytes", maxOutput).
+			Msg("✂️ Command output truncated in workflow context")
+	}
 
 	if err != nil {
 		// Extract actual exit code from error
@@ -223,7 +1139,7 @@ func (s *CommandStep) Execute(config map[string]interface{}, context map[string]
 		context["commandExitCode"] = exitCode
 		context["exitCode"] = exitCode  // Short alias for convenience
 
-		return fmt.Errorf("command failed: %w, output: %s", err, output)
+		return fmt.Errorf("command failed: %w, output: %s", err, outputStr)
 	}
 
 	s.Logger.Info().
@@ -240,6 +1156,36 @@ func (s *CommandStep) Execute(config map[string]interface{}, context map[string]
 // S3UploadStep implements S3 file upload
 type S3UploadStep struct {
 	BaseStep
+	Limiter *throttle.Limiter // agent-wide bandwidth limit (nil = unlimited)
+}
+
+// Params describes the config keys accepted by s3-upload steps
+func (s *S3UploadStep) Params() []StepParam {
+	return []StepParam{
+		{Name: "filePath", Type: "string", Required: true, Description: "File to upload"},
+		{Name: "bucket", Type: "string", Required: true, Description: "Target bucket"},
+		{Name: "accessKeyId", Type: "string", Required: true, Description: "AWS access key ID"},
+		{Name: "secretAccessKey", Type: "string", Required: true, Description: "AWS secret access key"},
+		{Name: "region", Type: "string", Required: true, Description: "AWS region"},
+		{Name: "s3Key", Type: "string", Description: "Object key (default: file name)"},
+		{Name: "s3Prefix", Type: "string", Description: "Prefix prepended to the object key"},
+		{Name: "maxBytesPerSecond", Type: "number", Description: "Bandwidth limit for this upload"},
+	}
+}
+
+// newS3Client creates an S3 client with static credentials that honors the agent proxy
+func newS3Client(region, accessKeyID, secretAccessKey string) *s3.Client {
+	return s3.NewFromConfig(aws.Config{
+		Region: region,
+		Credentials: credentials.NewStaticCredentialsProvider(
+			accessKeyID,
+			secretAccessKey,
+			"", // session token (empty for IAM user credentials)
+		),
+		HTTPClient: awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+			tr.Proxy = proxy.FromRequest
+		}),
+	})
 }
 
 func (s *S3UploadStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
@@ -303,25 +1249,15 @@ func (s *S3UploadStep) Execute(config map[string]interface{}, context map[string
 	}
 	defer file.Close()
 
-	// Create AWS config with static credentials
-	awsCfg := aws.Config{
-		Region: region,
-		Credentials: credentials.NewStaticCredentialsProvider(
-			accessKeyID,
-			secretAccessKey,
-			"", // session token (empty for IAM user credentials)
-		),
-	}
-
-	// Create S3 client
-	s3Client := s3.NewFromConfig(awsCfg)
+	s3Client := newS3Client(region, accessKeyID, secretAccessKey)
 
 	// Upload file to S3
 	awsCtx := stdcontext.Background()
 	_, err = s3Client.PutObject(awsCtx, &s3.PutObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(s3Key),
-		Body:   file,
+		Bucket:        aws.String(bucket),
+		Key:           aws.String(s3Key),
+		Body:          throttle.NewReader(file, s.Limiter, getOptionalLimiter(config)),
+		ContentLength: aws.Int64(fileInfo.Size()),
 	})
 
 	if err != nil {
@@ -331,7 +1267,7 @@ func (s *S3UploadStep) Execute(config map[string]interface{}, context map[string
 			Str("bucket", bucket).
 			Str("s3Key", s3Key).
 			Msg("❌ S3 upload failed")
-		return fmt.Errorf("failed to upload to S3: %w", err)
+		return transientErrorf("failed to upload to S3: %w", err)
 	}
 
 	s.Logger.Info().
@@ -350,12 +1286,160 @@ func (s *S3UploadStep) Execute(config map[string]interface{}, context map[string
 	return nil
 }
 
+// HTTPDownloadStep streams the body of an HTTP GET to a file
+type HTTPDownloadStep struct {
+	BaseStep
+	Limiter *throttle.Limiter // agent-wide bandwidth limit (nil = unlimited)
+}
+
+// Params describes the config keys accepted by http-download steps
+func (s *HTTPDownloadStep) Params() []StepParam {
+	return []StepParam{
+		{Name: "url", Type: "string", Required: true, Description: "URL to download"},
+		{Name: "destination", Type: "string", Required: true, Description: "File to write the response body to"},
+		{Name: "headers", Type: "object", Description: "Extra request headers"},
+		{Name: "bearerToken", Type: "string", Description: "Sent as Authorization: Bearer <token>"},
+		{Name: "username", Type: "string", Description: "Basic auth user name"},
+		{Name: "password", Type: "string", Description: "Basic auth password"},
+		{Name: "sha256", Type: "string", Description: "Expected SHA-256 of the body; the file is discarded on mismatch"},
+		{Name: "timeoutSeconds", Type: "number", Description: "Overall request timeout (default 300)"},
+		{Name: "filePerm", Type: "string", Description: "Octal mode for the downloaded file"},
+		{Name: "dirPerm", Type: "string", Description: "Octal mode for created directories (default 0755)"},
+		{Name: "maxBytesPerSecond", Type: "number", Description: "Bandwidth limit for this download"},
+	}
+}
+
+// setRequestHeaders applies headers and bearerToken or username/password auth
+func (b *BaseStep) setRequestHeaders(req *http.Request, config map[string]interface{}, headers map[string]string) {
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if token := b.getOptionalString(config, "bearerToken", ""); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if user := b.getOptionalString(config, "username", ""); user != "" {
+		req.SetBasicAuth(user, b.getOptionalString(config, "password", ""))
+	}
+}
+
+func (s *HTTPDownloadStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
+	url, err := s.getRequiredString(config, "url")
+	if err != nil {
+		return err
+	}
+	destination, err := s.getRequiredString(config, "destination")
+	if err != nil {
+		return err
+	}
+	headers, err := s.getOptionalStringMap(config, "headers")
+	if err != nil {
+		return err
+	}
+	timeoutSeconds, err := s.getOptionalInt(config, "timeoutSeconds", 300)
+	if err != nil {
+		return err
+	}
+	fileMode, err := s.getOptionalFileMode(config, "filePerm", 0644)
+	if err != nil {
+		return err
+	}
+	dirMode, err := s.getOptionalFileMode(config, "dirPerm", 0755)
+	if err != nil {
+		return err
+	}
+	expectedSum := strings.ToLower(strings.TrimSpace(s.getOptionalString(config, "sha256", "")))
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return validationErrorf("invalid download request: %w", err)
+	}
+	s.setRequestHeaders(req, config, headers)
+
+	s.Logger.Info().
+		Str("url", url).
+		Str("destination", destination).
+		Msg("🌐 Starting HTTP download")
+
+	client := proxy.Client(time.Duration(timeoutSeconds) * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return transientErrorf("download request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("download failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+		// Server errors, throttling and timeouts may clear up; other 4xx will not
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout {
+			return &TransientError{Err: err}
+		}
+		return &PermanentError{Err: err}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destination), dirMode); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	// Write to a temporary name so a partial download never looks complete
+	partPath := destination + ".part"
+	out, err := os.OpenFile(partPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileMode)
+	if err != nil {
+		return fmt.Errorf("failed to create destination file: %w", err)
+	}
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(out, hash), throttle.NewReader(resp.Body, s.Limiter, getOptionalLimiter(config)))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(partPath)
+		return transientErrorf("failed to download body: %w", err)
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if expectedSum != "" && sum != expectedSum {
+		os.Remove(partPath)
+		s.Logger.Error().
+			Str("url", url).
+			Str("expected", expectedSum).
+			Str("actual", sum).
+			Msg("❌ Downloaded file checksum mismatch")
+		return transientErrorf("checksum mismatch: expected %s, got %s", expectedSum, sum)
+	}
+
+	if err := os.Rename(partPath, destination); err != nil {
+		os.Remove(partPath)
+		return fmt.Errorf("failed to finalize download: %w", err)
+	}
+
+	s.Logger.Info().
+		Str("url", url).
+		Str("destination", destination).
+		Int64("bytes", written).
+		Msg("✅ File downloaded successfully")
+
+	context["downloadedFile"] = destination
+	context["downloadSize"] = written
+	context["downloadSha256"] = sum
+	context["downloadStatusCode"] = resp.StatusCode
+
+	return nil
+}
+
 // AlertStep implements alert sending
 type AlertStep struct {
 	BaseStep
 	AlertHandler func(level, message string, details map[string]interface{})
 }
 
+// Params describes the config keys accepted by alert steps
+func (s *AlertStep) Params() []StepParam {
+	return []StepParam{
+		{Name: "message", Type: "string", Required: true, Description: "Alert message"},
+		{Name: "level", Type: "string", Description: "info, warning or error (default info)"},
+	}
+}
+
 func (s *AlertStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
 	message, err := s.getRequiredString(config, "message")
 	if err != nil {
@@ -382,6 +1466,11 @@ type UnimplementedStep struct {
 	BaseStep
 }
 
+// Implemented reports false so the UI can avoid offering this step type
+func (s *UnimplementedStep) Implemented() bool {
+	return false
+}
+
 func (s *UnimplementedStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
 	// Log some details about what was attempted
 	details := ""
@@ -396,14 +1485,44 @@ func (s *UnimplementedStep) Execute(config map[string]interface{}, context map[s
 		Str("details", details).
 		Msg("⚠️ Step type not yet implemented")
 
-	return fmt.Errorf("%s step not yet implemented", s.Type)
+	return permanentErrorf("%s step not yet implemented", s.Type)
+}
+
+// StepParam describes a config key accepted by a step type
+type StepParam struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"` // JSON type: string, number, boolean, array or object
+	Required    bool   `json:"required"`
+	Description string `json:"description,omitempty"`
+}
+
+// ParamDescriber is implemented by steps that document their config keys
+type ParamDescriber interface {
+	Params() []StepParam
+}
+
+// StepTypeInfo describes a registered step type
+type StepTypeInfo struct {
+	Type        string      `json:"type"`
+	Implemented bool        `json:"implemented"`
+	Params      []StepParam `json:"params"`
+}
+
+// implementationReporter is implemented by placeholder steps that always fail
+type implementationReporter interface {
+	Implemented() bool
 }
 
 // StepRegistry manages available step types
 type StepRegistry struct {
-	steps        map[string]func() Step
-	logger       zerolog.Logger
-	alertHandler func(level, message string, details map[string]interface{})
+	steps         map[string]func() Step
+	logger        zerolog.Logger
+	alertHandler  func(level, message string, details map[string]interface{})
+	eventHandler  func(event string, details map[string]interface{})
+	eventEmitter  func(eventType string, event map[string]interface{}) error
+	commandPolicy atomic.Pointer[config.CommandPolicy]
+	limiter       atomic.Pointer[throttle.Limiter] // swapped on config reload while steps are being created
+	sequences     *sequenceStore
 }
 
 // NewStepRegistry creates a new step registry
@@ -416,16 +1535,29 @@ func NewStepRegistry(logger zerolog.Logger, alertHandler func(level, message str
 
 	// Register implemented steps
 	registry.Register("move-file", func() Step {
-		return &MoveFileStep{BaseStep: BaseStep{Type: "move-file", Logger: logger}}
+		return &MoveFileStep{BaseStep: BaseStep{Type: "move-file", Logger: logger}, Limiter: registry.limiter.Load()}
 	})
 	registry.Register("copy-file", func() Step {
-		return &CopyFileStep{BaseStep: BaseStep{Type: "copy-file", Logger: logger}}
+		return &CopyFileStep{BaseStep: BaseStep{Type: "copy-file", Logger: logger}, Limiter: registry.limiter.Load()}
 	})
 	registry.Register("delete-file", func() Step {
 		return &DeleteFileStep{BaseStep: BaseStep{Type: "delete-file", Logger: logger}}
 	})
+	registry.Register("chown-file", func() Step {
+		return &ChownFileStep{BaseStep: BaseStep{Type: "chown-file", Logger: logger}}
+	})
+	registry.Register("set-attributes", func() Step {
+		return &SetAttributesStep{BaseStep: BaseStep{Type: "set-attributes", Logger: logger}}
+	})
+	registry.Register("cleanup-files", func() Step {
+		return &CleanupFilesStep{BaseStep: BaseStep{Type: "cleanup-files", Logger: logger}}
+	})
 	registry.Register("run-command", func() Step {
-		return &CommandStep{BaseStep: BaseStep{Type: "run-command", Logger: logger}}
+		return &CommandStep{
+			BaseStep: BaseStep{Type: "run-command", Logger: logger},
+			Policy:   registry.policy(),
+			Events:   registry.eventHandler,
+		}
 	})
 	registry.Register("alert", func() Step {
 		return &AlertStep{
@@ -433,8 +1565,42 @@ func NewStepRegistry(logger zerolog.Logger, alertHandler func(level, message str
 			AlertHandler: alertHandler,
 		}
 	})
+	registry.Register("emit-event", func() Step {
+		return &EmitEventStep{
+			BaseStep: BaseStep{Type: "emit-event", Logger: logger},
+			Emit:     registry.eventEmitter,
+		}
+	})
+	registry.Register("list-files", func() Step {
+		return &ListFilesStep{BaseStep: BaseStep{Type: "list-files", Logger: logger}}
+	})
+	registry.Register("merge-files", func() Step {
+		return &MergeFilesStep{BaseStep: BaseStep{Type: "merge-files", Logger: logger}}
+	})
+	registry.Register("wait-for-file", func() Step {
+		return &WaitForFileStep{BaseStep: BaseStep{Type: "wait-for-file", Logger: logger}}
+	})
+	registry.Register("write-manifest", func() Step {
+		return &ManifestStep{BaseStep: BaseStep{Type: "write-manifest", Logger: logger}}
+	})
+	registry.Register("http-download", func() Step {
+		return &HTTPDownloadStep{BaseStep: BaseStep{Type: "http-download", Logger: logger}, Limiter: registry.limiter.Load()}
+	})
 	registry.Register("s3-upload", func() Step {
-		return &S3UploadStep{BaseStep: BaseStep{Type: "s3-upload", Logger: logger}}
+		return &S3UploadStep{BaseStep: BaseStep{Type: "s3-upload", Logger: logger}, Limiter: registry.limiter.Load()}
+	})
+	registry.Register("health-ping", func() Step {
+		return &HealthPingStep{BaseStep: BaseStep{Type: "health-ping", Logger: logger}}
+	})
+	registry.Register("validate-file", func() Step {
+		return &ValidateStep{BaseStep: BaseStep{Type: "validate-file", Logger: logger}}
+	})
+	registry.Register("rename-sequence", func() Step {
+		return &RenameSequenceStep{
+			BaseStep:  BaseStep{Type: "rename-sequence", Logger: logger},
+			Limiter:   registry.limiter.Load(),
+			Sequences: registry.sequences,
+		}
 	})
 
 	// Register unimplemented steps with proper names
@@ -455,16 +1621,72 @@ func NewStepRegistry(logger zerolog.Logger, alertHandler func(level, message str
 	return registry
 }
 
+// SetCommandPolicy sets the policy applied to run-command steps created by this registry
+func (r *StepRegistry) SetCommandPolicy(policy config.CommandPolicy) {
+	r.commandPolicy.Store(&policy)
+}
+
+// policy returns the current run-command policy
+func (r *StepRegistry) policy() config.CommandPolicy {
+	if p := r.commandPolicy.Load(); p != nil {
+		return *p
+	}
+	return config.CommandPolicy{}
+}
+
+// SetEventHandler sets where steps send progress events
+func (r *StepRegistry) SetEventHandler(handler func(event string, details map[string]interface{})) {
+	r.eventHandler = handler
+}
+
+// SetEventEmitter sets where emit-event steps send custom events
+func (r *StepRegistry) SetEventEmitter(emit func(eventType string, event map[string]interface{}) error) {
+	r.eventEmitter = emit
+}
+
+// SetTransferLimiter sets the bandwidth limiter shared by copy and upload steps
+func (r *StepRegistry) SetTransferLimiter(l *throttle.Limiter) {
+	r.limiter.Store(l)
+}
+
+// SetSequenceStore sets where rename-sequence steps keep their counters
+func (r *StepRegistry) SetSequenceStore(store *sequenceStore) {
+	r.sequences = store
+}
+
 // Register adds a new step type to the registry
 func (r *StepRegistry) Register(stepType string, factory func() Step) {
 	r.steps[stepType] = factory
 }
 
+// Describe returns every registered step type with its config keys, sorted by type
+func (r *StepRegistry) Describe() []StepTypeInfo {
+	types := make([]string, 0, len(r.steps))
+	for stepType := range r.steps {
+		types = append(types, stepType)
+	}
+	sort.Strings(types)
+
+	infos := make([]StepTypeInfo, 0, len(types))
+	for _, stepType := range types {
+		step := r.steps[stepType]()
+		info := StepTypeInfo{Type: stepType, Implemented: true, Params: []StepParam{}}
+		if ir, ok := step.(implementationReporter); ok {
+			info.Implemented = ir.Implemented()
+		}
+		if d, ok := step.(ParamDescriber); ok {
+			info.Params = d.Params()
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
 // Create creates a step instance by type
 func (r *StepRegistry) Create(stepType string) (Step, error) {
 	factory, exists := r.steps[stepType]
 	if !exists {
-		return nil, fmt.Errorf("unknown step type: %s", stepType)
+		return nil, validationErrorf("unknown step type: %s", stepType)
 	}
 	return factory(), nil
 }
@@ -0,0 +1,882 @@
+package workflow
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/controlcenter/nodes/internal/config"
+	"github.com/your-org/controlcenter/nodes/internal/throttle"
+)
+
+func TestCommandPolicy_NoPolicy(t *testing.T) {
+	s := &CommandStep{}
+	if err := s.checkPolicy("echo hi; rm -rf /tmp/x", ""); err != nil {
+		t.Errorf("empty policy should allow everything, got %v", err)
+	}
+}
+
+func TestCommandPolicy_DenyInjectedMetacharacters(t *testing.T) {
+	s := &CommandStep{Policy: config.CommandPolicy{DenyShellMetacharacters: true}}
+	s.SetRawConfig(map[string]interface{}{
+		"command":   "process.sh",
+		"arguments": "{{.fileName}}",
+	})
+
+	if err := s.checkPolicy("process.sh", "report.csv"); err != nil {
+		t.Errorf("clean substitution should be allowed, got %v", err)
+	}
+	if err := s.checkPolicy("process.sh", "x.csv; curl evil"); err == nil {
+		t.Error("expected injected ';' to be rejected")
+	}
+}
+
+func TestCommandPolicy_LiteralMetacharactersAllowed(t *testing.T) {
+	s := &CommandStep{Policy: config.CommandPolicy{DenyShellMetacharacters: true}}
+	s.SetRawConfig(map[string]interface{}{
+		"command": "cat {{.file}} | wc -l",
+	})
+
+	if err := s.checkPolicy("cat /data/in.txt | wc -l", ""); err != nil {
+		t.Errorf("pipe written in the workflow should be allowed, got %v", err)
+	}
+}
+
+func TestCommandPolicy_AllowedCommands(t *testing.T) {
+	s := &CommandStep{Policy: config.CommandPolicy{
+		AllowedCommands: []string{"gzip", "/usr/local/bin/convert"},
+	}}
+
+	tests := []struct {
+		command string
+		allowed bool
+	}{
+		{"gzip -9 file.txt", true},
+		{"/bin/gzip file.txt", true},
+		{"/usr/local/bin/convert a b", true},
+		{"convert a b", false},
+		{"rm -rf /", false},
+		{"gzip file.txt && rm -rf /", false},
+	}
+
+	for _, tt := range tests {
+		err := s.checkPolicy(tt.command, "")
+		if tt.allowed && err != nil {
+			t.Errorf("%q: expected allowed, got %v", tt.command, err)
+		}
+		if !tt.allowed && err == nil {
+			t.Errorf("%q: expected rejection", tt.command)
+		}
+	}
+}
+
+func TestCommandStep_ArgvAndEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	s := &CommandStep{BaseStep: BaseStep{Type: "run-command"}}
+	context := map[string]interface{}{}
+
+	// The argument contains shell syntax; with argv it must be passed literally.
+	err := s.Execute(map[string]interface{}{
+		"argv": []interface{}{"sh", "-c", "printf '%s %s' \"$1\" \"$GREETING\"", "sh", "a; echo pwned"},
+		"env":  map[string]interface{}{"GREETING": "hello"},
+	}, context)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if context["output"] != "a; echo pwned hello" {
+		t.Errorf("unexpected output: %q", context["output"])
+	}
+}
+
+func TestCommandStep_OutputLimit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	s := &CommandStep{
+		BaseStep: BaseStep{Type: "run-command", Logger: zerolog.Nop()},
+		Policy:   config.CommandPolicy{MaxOutputBytes: 64},
+	}
+	outputFile := filepath.Join(t.TempDir(), "full.log")
+	context := map[string]interface{}{}
+
+	// The step asks for more than the policy allows; the policy wins
+	err := s.Execute(map[string]interface{}{
+		"argv":           []interface{}{"sh", "-c", "head -c 1000 /dev/zero | tr '\\0' x; echo err >&2"},
+		"maxOutputBytes": 500,
+		"outputFile":     outputFile,
+	}, context)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	output := context["output"].(string)
+	if !strings.HasPrefix(output, strings.Repeat("x", 64)+"\n... [output truncated: kept 64 of 1004 bytes]") {
+		t.Errorf("unexpected output: %q", output)
+	}
+	if context["commandOutputTruncated"] != true || context["commandOutputBytes"] != int64(1004) {
+		t.Errorf("truncation not reported: %v / %v", context["commandOutputTruncated"], context["commandOutputBytes"])
+	}
+	full, err := os.ReadFile(outputFile)
+	if err != nil || string(full) != strings.Repeat("x", 1000)+"err\n" {
+		t.Errorf("outputFile should hold the full output, got %d bytes: %v", len(full), err)
+	}
+
+	if err := s.Execute(map[string]interface{}{"argv": []interface{}{"true"}, "maxOutputBytes": -1}, context); Categorize(err) != ErrorValidation {
+		t.Errorf("negative maxOutputBytes should be a validation error, got %v", err)
+	}
+}
+
+func TestCommandStep_ProgressEvents(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	var streamed []string
+	s := &CommandStep{
+		BaseStep: BaseStep{Type: "run-command", Logger: zerolog.Nop()},
+		Events: func(event string, details map[string]interface{}) {
+			if event != "command-output" || details["workflowId"] != "convert" {
+				t.Errorf("unexpected event %s: %v", event, details)
+			}
+			streamed = append(streamed, details["lines"].([]string)...)
+		},
+	}
+	context := map[string]interface{}{"workflowId": "convert"}
+	err := s.Execute(map[string]interface{}{
+		"argv":           []interface{}{"sh", "-c", "echo one; echo two >&2; printf three"},
+		"progressEvents": true,
+	}, context)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(streamed, ",") != "one,two,three" {
+		t.Errorf("streamed lines = %q", streamed)
+	}
+	if context["output"] != "one\ntwo\nthree" {
+		t.Errorf("full output should still be captured, got %q", context["output"])
+	}
+}
+
+func TestHealthPingStep(t *testing.T) {
+	var pings []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pings = append(pings, r.Method+" "+r.URL.Path)
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	step := &HealthPingStep{BaseStep: BaseStep{Type: "health-ping", Logger: zerolog.Nop()}}
+
+	run := func(config, context map[string]interface{}) error {
+		return step.Execute(config, context)
+	}
+	base := map[string]interface{}{"url": srv.URL + "/ping/abc"}
+	if err := run(map[string]interface{}{"url": srv.URL + "/ping/abc", "signal": "start"}, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(base, map[string]interface{}{"workflowStatus": "completed"}); err != nil {
+		t.Fatal(err)
+	}
+	failed := map[string]interface{}{"workflowStatus": "failed", "error": "boom", "errorCategory": "permanent"}
+	if err := run(map[string]interface{}{"url": srv.URL + "/ping/abc", "method": "POST"}, failed); err != nil {
+		t.Fatal(err)
+	}
+	want := "GET /ping/abc/start,GET /ping/abc,POST /ping/abc/fail"
+	if got := strings.Join(pings, ","); got != want {
+		t.Errorf("pings = %s, want %s", got, want)
+	}
+
+	// A monitoring outage is logged, not fatal, unless failOnError is set
+	down := map[string]interface{}{"successUrl": srv.URL + "/down"}
+	if err := run(down, map[string]interface{}{}); err != nil {
+		t.Errorf("failed ping should not fail the step by default: %v", err)
+	}
+	down["failOnError"] = true
+	if err := run(down, map[string]interface{}{}); Categorize(err) != ErrorTransient {
+		t.Errorf("503 with failOnError should be a transient error, got %v", err)
+	}
+	if err := run(map[string]interface{}{}, map[string]interface{}{}); Categorize(err) != ErrorValidation {
+		t.Errorf("missing URLs should be a validation error, got %v", err)
+	}
+}
+
+func TestStepRegistry_Describe(t *testing.T) {
+	registry := NewStepRegistry(zerolog.Nop(), nil)
+
+	infos := make(map[string]StepTypeInfo)
+	for _, info := range registry.Describe() {
+		infos[info.Type] = info
+	}
+
+	if info := infos["copy-file"]; !info.Implemented || len(info.Params) == 0 {
+		t.Errorf("copy-file should be implemented with params, got %+v", info)
+	}
+	if info, ok := infos["database-query"]; !ok || info.Implemented {
+		t.Errorf("database-query should be registered as unimplemented, got %+v", info)
+	}
+}
+
+func TestStepRegistry_SetTransferLimiterWhileCreating(t *testing.T) {
+	registry := NewStepRegistry(zerolog.Nop(), nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			registry.SetTransferLimiter(throttle.NewLimiter(int64(1024 * (i + 1))))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if _, err := registry.Create("copy-file"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+
+	step, _ := registry.Create("copy-file")
+	if l := step.(*CopyFileStep).Limiter; l == nil || l.Rate() != 100*1024 {
+		t.Errorf("copy-file should use the last limiter set, got %v", l)
+	}
+}
+
+func TestStepRegistry_SetCommandPolicy(t *testing.T) {
+	registry := NewStepRegistry(zerolog.Nop(), nil)
+	if step, _ := registry.Create("run-command"); len(step.(*CommandStep).Policy.AllowedCommands) != 0 {
+		t.Error("run-command should be unrestricted without a policy")
+	}
+
+	registry.SetCommandPolicy(config.CommandPolicy{AllowedCommands: []string{"rsync"}})
+	step, _ := registry.Create("run-command")
+	if got := step.(*CommandStep).Policy.AllowedCommands; len(got) != 1 || got[0] != "rsync" {
+		t.Errorf("run-command should use the updated policy, got %v", got)
+	}
+}
+
+func TestCleanupFilesStep(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	files := map[string]bool{ // path -> old
+		"a.log":     true,
+		"b.log":     false,
+		"c.txt":     true,
+		"sub/d.log": true,
+	}
+	for name, isOld := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if isOld {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	step := &CleanupFilesStep{BaseStep: BaseStep{Type: "cleanup-files", Logger: zerolog.Nop()}}
+	cfg := map[string]interface{}{"directory": dir, "pattern": "*.log", "olderThanHours": "24"}
+
+	// Safety cap aborts without deleting anything
+	capped := map[string]interface{}{"directory": dir, "pattern": "*.log", "olderThanHours": "24", "recursive": "true", "maxDeletes": "1"}
+	if err := step.Execute(capped, map[string]interface{}{}); err == nil {
+		t.Error("expected maxDeletes to abort the cleanup")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.log")); err != nil {
+		t.Error("nothing should be deleted when maxDeletes is exceeded")
+	}
+
+	// Dry run from the workflow context reports but keeps files
+	ctx := map[string]interface{}{"dryRun": true}
+	if err := step.Execute(cfg, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ctx["cleanupDeleted"] != 1 {
+		t.Errorf("dry run deleted = %v, want 1", ctx["cleanupDeleted"])
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.log")); err != nil {
+		t.Error("dry run must not delete files")
+	}
+
+	// Non-recursive run removes only old matching files in the top directory
+	if err := step.Execute(cfg, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	for name, wantExists := range map[string]bool{"a.log": false, "b.log": true, "c.txt": true, "sub/d.log": true} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists := err == nil; exists != wantExists {
+			t.Errorf("%s exists = %v, want %v", name, exists, wantExists)
+		}
+	}
+}
+
+func TestCopyFileStep_RecursiveDirectory(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "out")
+	files := map[string]string{
+		"report.csv":           "a,b",
+		"logs/run.log":         "ok",
+		"logs/archive/old.log": "older",
+		"empty/.keep":          "",
+	}
+	for name, content := range files {
+		path := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	step := &CopyFileStep{BaseStep: BaseStep{Type: "copy-file", Logger: zerolog.Nop()}}
+	dest := filepath.Join(dir, "backup")
+	cfg := map[string]interface{}{"source": src, "destination": dest, "verifyChecksum": true}
+	if err := step.Execute(cfg, map[string]interface{}{}); err == nil {
+		t.Fatal("copying a directory without recursive should fail")
+	}
+
+	cfg["recursive"] = "true"
+	ctx := map[string]interface{}{}
+	if err := step.Execute(cfg, ctx); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join(dest, name))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if ctx["copiedFiles"] != len(files) {
+		t.Errorf("copiedFiles = %v, want %d", ctx["copiedFiles"], len(files))
+	}
+
+	cfg["destination"] = filepath.Join(src, "nested")
+	if err := step.Execute(cfg, map[string]interface{}{}); err == nil {
+		t.Error("copying a directory into itself should fail")
+	}
+}
+
+func TestCopyFileStep_DirPermIgnoresUmask(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permission bits")
+	}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "report.csv")
+	if err := os.WriteFile(src, []byte("a,b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 0777 is never left intact by the usual 022 umask
+	step := &CopyFileStep{BaseStep: BaseStep{Type: "copy-file", Logger: zerolog.Nop()}}
+	dest := filepath.Join(dir, "shared", "inbox", "report.csv")
+	if err := step.Execute(map[string]interface{}{"source": src, "destination": dest, "dirPerm": "0777"}, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []string{filepath.Join(dir, "shared"), filepath.Dir(dest)} {
+		info, err := os.Stat(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0777 {
+			t.Errorf("%s mode = %o, want 0777", d, info.Mode().Perm())
+		}
+	}
+}
+
+func TestMoveFileStep_CrossDeviceFallback(t *testing.T) {
+	dir := t.TempDir()
+	spool := filepath.Join(dir, "spool")
+	if err := os.MkdirAll(filepath.Join(spool, "batch", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(spool, "batch", "sub", "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(spool, "single.txt"), []byte("single"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(filepath.Join(spool, "single.txt"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	step := &MoveFileStep{BaseStep: BaseStep{Type: "move-file", Logger: zerolog.Nop()}}
+	step.rename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errCrossDevice}
+	}
+	archive := filepath.Join(dir, "archive")
+
+	cfg := map[string]interface{}{"source": filepath.Join(spool, "single.txt"), "destination": filepath.Join(archive, "single.txt")}
+	if err := step.Execute(cfg, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(archive, "single.txt"))
+	if err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("moved file stat = %v, %v; want mtime %v", info, err, old)
+	}
+	if _, err := os.Stat(filepath.Join(spool, "single.txt")); !os.IsNotExist(err) {
+		t.Error("source file should be removed after cross-device move")
+	}
+
+	cfg = map[string]interface{}{"source": filepath.Join(spool, "batch"), "destination": filepath.Join(archive, "batch")}
+	if err := step.Execute(cfg, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(filepath.Join(archive, "batch", "sub", "a.txt")); err != nil || string(got) != "a" {
+		t.Errorf("moved tree content = %q, %v", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(spool, "batch")); !os.IsNotExist(err) {
+		t.Error("source directory should be removed after cross-device move")
+	}
+}
+
+func TestHTTPDownloadStep(t *testing.T) {
+	body := "payload"
+	sum := sha256.Sum256([]byte(body))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/file" {
+			http.Error(w, "nope", http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("X-Tenant") != "acme" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "in", "file.bin")
+	step := &HTTPDownloadStep{BaseStep: BaseStep{Type: "http-download", Logger: zerolog.Nop()}}
+	cfg := map[string]interface{}{
+		"url":         srv.URL + "/file",
+		"destination": dest,
+		"headers":     `{"X-Tenant": "acme"}`,
+		"bearerToken": "secret",
+		"sha256":      hex.EncodeToString(sum[:]),
+	}
+	ctx := map[string]interface{}{}
+	if err := step.Execute(cfg, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(dest); err != nil || string(got) != body {
+		t.Errorf("downloaded content = %q, %v", got, err)
+	}
+	if ctx["downloadedFile"] != dest || ctx["downloadSize"] != int64(len(body)) {
+		t.Errorf("context = %v", ctx)
+	}
+
+	mismatch := filepath.Join(dir, "mismatch.bin")
+	cfg["destination"] = mismatch
+	cfg["sha256"] = strings.Repeat("0", 64)
+	if err := step.Execute(cfg, map[string]interface{}{}); err == nil {
+		t.Error("checksum mismatch should fail")
+	}
+	if _, err := os.Stat(mismatch); !os.IsNotExist(err) {
+		t.Error("file with a bad checksum should not be kept")
+	}
+	if _, err := os.Stat(mismatch + ".part"); !os.IsNotExist(err) {
+		t.Error("partial file should be removed")
+	}
+
+	cfg["sha256"] = ""
+	cfg["url"] = srv.URL + "/missing"
+	if err := step.Execute(cfg, map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("non-2xx status should fail with the code, got %v", err)
+	}
+}
+
+func TestListFilesStep(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-72 * time.Hour)
+	for name, content := range map[string]string{"a.csv": "1", "b.csv": "22", "old.csv": "333", "notes.txt": "x", "sub/c.csv": "4444"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chtimes(filepath.Join(dir, "old.csv"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	step := &ListFilesStep{BaseStep: BaseStep{Type: "list-files", Logger: zerolog.Nop()}}
+	ctx := map[string]interface{}{}
+	cfg := map[string]interface{}{"directory": dir, "pattern": "*.csv", "modifiedWithinHours": "24", "sortBy": "size"}
+	if err := step.Execute(cfg, ctx); err != nil {
+		t.Fatal(err)
+	}
+	paths := ctx["listPaths"].([]string)
+	if len(paths) != 2 || filepath.Base(paths[0]) != "a.csv" || filepath.Base(paths[1]) != "b.csv" {
+		t.Errorf("listPaths = %v, want a.csv, b.csv", paths)
+	}
+	if ctx["listCount"] != 2 || ctx["listTotalBytes"] != int64(3) {
+		t.Errorf("listCount = %v, listTotalBytes = %v", ctx["listCount"], ctx["listTotalBytes"])
+	}
+
+	cfg = map[string]interface{}{"directory": dir, "pattern": "*.csv", "recursive": "true", "maxResults": "2", "minCount": "5"}
+	ctx = map[string]interface{}{}
+	err := step.Execute(cfg, ctx)
+	if !IsTransient(err) {
+		t.Errorf("fewer than minCount files should be a transient error, got %v", err)
+	}
+	if ctx["listCount"] != 4 || ctx["listTruncated"] != true || len(ctx["listFiles"].([]interface{})) != 2 {
+		t.Errorf("recursive listing context = %v", ctx)
+	}
+}
+
+func TestManifestStep(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "in.csv")
+	if err := os.WriteFile(source, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	const helloSHA = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+	step := &ManifestStep{BaseStep: BaseStep{Type: "write-manifest", Logger: zerolog.Nop()}}
+	ctx := map[string]interface{}{"file": source, "workflowId": "wf-1", "executionId": "exec-1"}
+
+	jsonl := filepath.Join(dir, "logs", "manifest.jsonl")
+	cfg := map[string]interface{}{"path": jsonl, "destination": "s3://bucket/in.csv", "fields": map[string]interface{}{"customer": "acme"}}
+	for i := 0; i < 2; i++ {
+		if err := step.Execute(cfg, ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(jsonl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 JSON lines, got %d: %s", len(lines), data)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["sha256"] != helloSHA || record["workflowId"] != "wf-1" || record["sourceFile"] != source || record["customer"] != "acme" {
+		t.Errorf("unexpected record: %v", record)
+	}
+
+	csvPath := filepath.Join(dir, "manifest.csv")
+	cfg = map[string]interface{}{"path": csvPath, "format": "csv", "fields": map[string]interface{}{"customer": "acme"}}
+	for i := 0; i < 2; i++ {
+		if err := step.Execute(cfg, ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err = os.ReadFile(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0][0] != "timestamp" || rows[0][len(rows[0])-1] != "customer" {
+		t.Fatalf("expected a header and 2 rows, got %v", rows)
+	}
+	if rows[2][5] != helloSHA || rows[2][6] != "5" {
+		t.Errorf("unexpected CSV row: %v", rows[2])
+	}
+
+	if err := step.Execute(map[string]interface{}{"path": csvPath, "format": "xml"}, ctx); Categorize(err) != ErrorValidation {
+		t.Errorf("unknown format should be a validation error, got %v", err)
+	}
+}
+
+func TestRenameSequenceStep(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	store := newSequenceStore(filepath.Join(dir, "state", "sequences.json"))
+	step := &RenameSequenceStep{BaseStep: BaseStep{Type: "rename-sequence", Logger: zerolog.Nop()}, Sequences: store}
+
+	rename := func(name string) (map[string]interface{}, error) {
+		source := filepath.Join(dir, name)
+		if err := os.WriteFile(source, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		ctx := map[string]interface{}{}
+		err := step.Execute(map[string]interface{}{"source": source, "destination": out, "pattern": "feed_{seq}{ext}"}, ctx)
+		return ctx, err
+	}
+	for i, want := range []string{"feed_000001.csv", "feed_000002.csv"} {
+		ctx, err := rename("in.csv")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ctx["renamedTo"] != filepath.Join(out, want) || ctx["sequenceNumber"] != int64(i+1) {
+			t.Errorf("rename %d: %v", i, ctx)
+		}
+	}
+
+	// The counter survives a restart, and a name that is already taken fails
+	// without consuming the number
+	step.Sequences = newSequenceStore(store.path)
+	os.WriteFile(filepath.Join(out, "feed_000003.csv"), []byte("x"), 0644)
+	if _, err := rename("in.csv"); Categorize(err) != ErrorPermanent {
+		t.Fatalf("existing sequence file should be a permanent error, got %v", err)
+	}
+	os.Remove(filepath.Join(out, "feed_000003.csv"))
+	if ctx, err := rename("in.csv"); err != nil || ctx["sequenceNumber"] != int64(3) {
+		t.Errorf("after restart: %v %v", ctx, err)
+	}
+
+	if err := step.Execute(map[string]interface{}{"source": filepath.Join(dir, "x"), "pattern": "feed.csv"}, map[string]interface{}{}); Categorize(err) != ErrorValidation {
+		t.Errorf("pattern without {seq} should be a validation error, got %v", err)
+	}
+}
+
+func TestValidateStep(t *testing.T) {
+	dir := t.TempDir()
+	step := &ValidateStep{BaseStep: BaseStep{Type: "validate-file", Logger: zerolog.Nop()}}
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	validate := func(path string, schema interface{}) (map[string]interface{}, error) {
+		ctx := map[string]interface{}{"file": path}
+		return ctx, step.Execute(map[string]interface{}{"schema": schema}, ctx)
+	}
+
+	csvSchema := map[string]interface{}{"headers": []interface{}{"id", "name"}}
+	if _, err := validate(write("good.csv", "id,name\n1,a\n2,b\n"), csvSchema); err != nil {
+		t.Errorf("valid CSV: %v", err)
+	}
+	ctx, err := validate(write("bad.csv", "id,title\n1,a\n2\n"), csvSchema)
+	if Categorize(err) != ErrorPermanent || ctx["validationValid"] != false {
+		t.Fatalf("invalid CSV should be a permanent error, got %v", err)
+	}
+	if problems := ctx["validationErrors"].([]string); len(problems) != 2 || !strings.Contains(problems[1], "row 3 has 1 columns") {
+		t.Errorf("unexpected CSV problems: %v", problems)
+	}
+
+	// Schemas typed into the editor arrive as JSON text
+	jsonSchema := `{"type":"object","required":["id","items"],"properties":{
+		"id":{"type":"integer","minimum":1},
+		"items":{"type":"array","minItems":1,"items":{"type":"string","pattern":"^[A-Z]+$"}}}}`
+	if _, err := validate(write("good.json", `{"id":3,"items":["AB"]}`), jsonSchema); err != nil {
+		t.Errorf("valid JSON: %v", err)
+	}
+	ctx, err = validate(write("bad.json", `{"id":0.5,"items":["ab"]}`), jsonSchema)
+	if err == nil {
+		t.Fatal("JSON violating the schema should fail")
+	}
+	if got := strings.Join(ctx["validationErrors"].([]string), "; "); !strings.Contains(got, "$.id: expected integer") || !strings.Contains(got, "$.items[0]") {
+		t.Errorf("unexpected JSON problems: %s", got)
+	}
+	if _, err := validate(write("trunc.json", `{"id":`), nil); err == nil {
+		t.Error("truncated JSON should fail")
+	}
+
+	if _, err := validate(write("good.xml", `<Invoice><Line/></Invoice>`), map[string]interface{}{"rootElement": "Invoice"}); err != nil {
+		t.Errorf("valid XML: %v", err)
+	}
+	if _, err := validate(write("bad.xml", `<Invoice><Line></Invoice>`), nil); err == nil {
+		t.Error("malformed XML should fail")
+	}
+	if _, err := validate(write("data.txt", "x"), nil); Categorize(err) != ErrorValidation {
+		t.Errorf("unknown format should be a validation error, got %v", err)
+	}
+}
+
+func TestSetAttributesStep(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("checks Unix permission bits")
+	}
+	path := filepath.Join(t.TempDir(), "report.csv")
+	if err := os.WriteFile(path, []byte("a,b\n"), 0664); err != nil {
+		t.Fatal(err)
+	}
+	step := &SetAttributesStep{BaseStep: BaseStep{Type: "set-attributes", Logger: zerolog.Nop()}}
+
+	ctx := map[string]interface{}{}
+	if err := step.Execute(map[string]interface{}{"path": path, "readOnly": true, "hidden": "true"}, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0444 {
+		t.Errorf("read-only mode = %v, want 0444", info.Mode().Perm())
+	}
+	if applied := ctx["attributes"].(map[string]interface{}); applied["readOnly"] != true || applied["hidden"] != nil {
+		t.Errorf("attributes = %v, want only readOnly (hidden is Windows only)", applied)
+	}
+
+	if err := step.Execute(map[string]interface{}{"path": path, "readOnly": "false"}, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0644 {
+		t.Errorf("writable mode = %v, want 0644", info.Mode().Perm())
+	}
+
+	if err := step.Execute(map[string]interface{}{"path": path, "readOnly": ""}, ctx); Categorize(err) != ErrorValidation {
+		t.Errorf("no attributes should be a validation error, got %v", err)
+	}
+	if err := step.Execute(map[string]interface{}{"path": path, "readOnly": "maybe"}, ctx); Categorize(err) != ErrorValidation {
+		t.Errorf("invalid value should be a validation error, got %v", err)
+	}
+}
+
+func TestEmitEventStep(t *testing.T) {
+	var gotType string
+	var got map[string]interface{}
+	var sendErr error
+	step := &EmitEventStep{
+		BaseStep: BaseStep{Type: "emit-event", Logger: zerolog.Nop()},
+		Emit: func(eventType string, event map[string]interface{}) error {
+			gotType, got = eventType, event
+			return sendErr
+		},
+	}
+	ctx := map[string]interface{}{"workflowId": "invoices", "executionId": "run-1"}
+
+	// The editor saves data as a (templated) JSON string
+	err := step.Execute(map[string]interface{}{
+		"type":    "invoice-batch-processed",
+		"message": "invoice batch processed: 412 records",
+		"data":    `{"records": 412, "batch": "B-7"}`,
+	}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := got["data"].(map[string]interface{})
+	if gotType != "invoice-batch-processed" || data["records"] != float64(412) || got["workflowId"] != "invoices" || got["executionId"] != "run-1" {
+		t.Errorf("sent %s %+v", gotType, got)
+	}
+	if ctx["eventDelivered"] != true {
+		t.Errorf("eventDelivered = %v", ctx["eventDelivered"])
+	}
+
+	sendErr = os.ErrDeadlineExceeded
+	if err := step.Execute(map[string]interface{}{"type": "x"}, ctx); err != nil || ctx["eventDelivered"] != false {
+		t.Errorf("undelivered optional event: err %v, eventDelivered %v", err, ctx["eventDelivered"])
+	}
+	if err := step.Execute(map[string]interface{}{"type": "x", "required": true}, ctx); Categorize(err) != ErrorTransient {
+		t.Errorf("undelivered required event: got %v, want a transient error", err)
+	}
+	if err := step.Execute(map[string]interface{}{"type": "x", "data": "[1, 2]"}, ctx); Categorize(err) != ErrorValidation {
+		t.Errorf("data that is not an object: got %v, want a validation error", err)
+	}
+}
+
+func TestWaitForFileStep(t *testing.T) {
+	dir := t.TempDir()
+	step := &WaitForFileStep{BaseStep: BaseStep{Type: "wait-for-file", Logger: zerolog.Nop()}}
+
+	// The producer writes its report while the step is waiting
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		os.WriteFile(filepath.Join(dir, "batch7_report.csv"), []byte("a,b\n"), 0644)
+	}()
+	ctx := map[string]interface{}{}
+	err := step.Execute(map[string]interface{}{
+		"path":           filepath.Join(dir, "batch7_*.csv"),
+		"timeoutSeconds": 5,
+		"stableSeconds":  0,
+		"pollIntervalMs": "20",
+	}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx["foundPath"] != filepath.Join(dir, "batch7_report.csv") || ctx["foundSize"] != int64(4) {
+		t.Errorf("unexpected context: %+v", ctx)
+	}
+
+	// A file that keeps growing is not ready
+	growing := filepath.Join(dir, "growing.dat")
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(50 * time.Millisecond):
+				f, _ := os.OpenFile(growing, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+				f.WriteString("x")
+				f.Close()
+			}
+		}
+	}()
+	err = step.Execute(map[string]interface{}{"path": growing, "timeoutSeconds": 1, "stableSeconds": 1, "pollIntervalMs": 20}, ctx)
+	close(done)
+	if Categorize(err) != ErrorTransient || !strings.Contains(err.Error(), "stop changing") {
+		t.Errorf("growing file: got %v, want a transient timeout", err)
+	}
+
+	if err := step.Execute(map[string]interface{}{"path": filepath.Join(dir, "[")}, ctx); Categorize(err) != ErrorValidation {
+		t.Errorf("bad pattern: got %v, want a validation error", err)
+	}
+}
+
+func TestMergeFilesStep(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "feed_2.csv"), []byte("id,amount\n3,30\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "feed_1.csv"), []byte("id,amount\n1,10\n2,20"), 0644) // No final newline
+	os.WriteFile(filepath.Join(dir, "feed_3.csv"), []byte("id,amount\n"), 0644)           // Header only
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored\n"), 0644)
+
+	step := &MergeFilesStep{BaseStep: BaseStep{Type: "merge-files", Logger: zerolog.Nop()}}
+	destination := filepath.Join(dir, "daily.csv")
+	cfg := map[string]interface{}{
+		"directory":   dir,
+		"pattern":     "*.csv",
+		"destination": destination,
+		"headerLines": "1",
+	}
+	ctx := map[string]interface{}{}
+	if err := step.Execute(cfg, ctx); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(destination)
+	if want := "id,amount\n1,10\n2,20\n3,30\n"; string(got) != want {
+		t.Errorf("merged file:\n%q\nwant\n%q", got, want)
+	}
+	if ctx["mergedCount"] != 3 || ctx["mergedBytes"] != int64(len(got)) {
+		t.Errorf("unexpected context: %+v", ctx)
+	}
+
+	// The destination matches the pattern but is never merged into itself
+	if err := step.Execute(cfg, ctx); Categorize(err) != ErrorPermanent {
+		t.Errorf("existing destination: got %v, want a permanent error", err)
+	}
+	cfg["overwrite"] = true
+	cfg["keepHeader"] = false
+	cfg["deleteSources"] = true
+	if err := step.Execute(cfg, ctx); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = os.ReadFile(destination)
+	if want := "1,10\n2,20\n3,30\n"; string(got) != want {
+		t.Errorf("merged file without header: %q, want %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "feed_1.csv")); !os.IsNotExist(err) {
+		t.Error("deleteSources should remove the merged files")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Error("files not matching the pattern must be kept")
+	}
+
+	// Nothing left to merge
+	if err := step.Execute(map[string]interface{}{"directory": dir, "pattern": "feed_*", "destination": filepath.Join(dir, "x.csv")}, ctx); Categorize(err) != ErrorPermanent {
+		t.Errorf("no matches: got %v, want a permanent error", err)
+	}
+}
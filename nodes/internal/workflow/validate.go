@@ -0,0 +1,223 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/your-org/controlcenter/nodes/internal/config"
+)
+
+// Validation issue severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ValidationIssue describes a problem found in a workflow definition
+type ValidationIssue struct {
+	Severity string `json:"severity"`
+	Step     string `json:"step,omitempty"`
+	Message  string `json:"message"`
+}
+
+// knownTriggerTypes lists the trigger types handled by handleTrigger
+var knownTriggerTypes = map[string]bool{
+	"file": true, "fileage": true, "schedule": true, "webhook": true, "manual": true, "filewatcher": true,
+}
+
+// ValidateWorkflow checks a workflow definition against the registered step
+// types: step references (including finally), required config keys,
+// reachability and cycles.
+func (r *StepRegistry) ValidateWorkflow(wf config.Workflow) []ValidationIssue {
+	issues := []ValidationIssue{}
+	addError := func(step, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Severity: SeverityError, Step: step, Message: fmt.Sprintf(format, args...)})
+	}
+	addWarning := func(step, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Severity: SeverityWarning, Step: step, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if wf.ID == "" {
+		addError("", "workflow id is required")
+	}
+	if wf.Trigger.Type == "" {
+		addWarning("", "trigger type is empty; workflow cannot be triggered automatically")
+	} else if !knownTriggerTypes[wf.Trigger.Type] {
+		addError("", "unknown trigger type %q", wf.Trigger.Type)
+	}
+	if len(wf.Steps) == 0 {
+		addWarning("", "workflow has no steps")
+	}
+
+	stepTypes := make(map[string]StepTypeInfo)
+	for _, info := range r.Describe() {
+		stepTypes[info.Type] = info
+	}
+
+	stepIDs := make(map[string]bool, len(wf.Steps))
+	for _, step := range wf.Steps {
+		if step.ID == "" {
+			addError("", "step %q has no id", step.Name)
+			continue
+		}
+		if stepIDs[step.ID] {
+			addError(step.ID, "duplicate step id %q", step.ID)
+		}
+		stepIDs[step.ID] = true
+	}
+
+	for _, step := range wf.Steps {
+		info, ok := stepTypes[step.Type]
+		switch {
+		case !ok:
+			addError(step.ID, "unknown step type %q", step.Type)
+		case !info.Implemented:
+			addError(step.ID, "step type %q is not implemented on this agent", step.Type)
+		default:
+			for _, param := range info.Params {
+				if !param.Required {
+					continue
+				}
+				if v, present := step.Config[param.Name]; !present || v == nil || v == "" {
+					addError(step.ID, "%s step requires %s parameter", step.Type, param.Name)
+				}
+			}
+		}
+
+		if step.When != "" {
+			if _, err := template.New("when").Parse(step.When); err != nil {
+				addError(step.ID, "invalid when condition: %v", err)
+			}
+		}
+
+		for _, next := range step.Next {
+			if !stepIDs[next] {
+				addError(step.ID, "next references unknown step %q", next)
+			}
+		}
+		for _, handler := range step.OnError {
+			if !stepIDs[handler] {
+				addError(step.ID, "onError references unknown step %q", handler)
+			}
+		}
+	}
+
+	for _, id := range wf.Finally {
+		if !stepIDs[id] {
+			addError("", "finally references unknown step %q", id)
+		}
+	}
+
+	if len(wf.Trigger.StartSteps) == 0 {
+		if len(wf.Steps) > 0 {
+			addWarning("", "trigger.startSteps is empty; all steps will run sequentially")
+		}
+	} else {
+		for _, start := range wf.Trigger.StartSteps {
+			if !stepIDs[start] {
+				addError("", "trigger.startSteps references unknown step %q", start)
+			}
+		}
+		reachable := reachableSteps(append(append([]string(nil), wf.Trigger.StartSteps...), wf.Finally...), wf.Steps)
+		for _, step := range wf.Steps {
+			if step.ID != "" && !reachable[step.ID] {
+				addWarning(step.ID, "step is not reachable from trigger.startSteps")
+			}
+		}
+	}
+
+	if cycle := findCycle(wf.Steps); cycle != nil {
+		addError(cycle[0], "step graph has a cycle %s", strings.Join(cycle, "→"))
+	}
+
+	return issues
+}
+
+// HasErrors reports whether any issue has error severity
+func HasErrors(issues []ValidationIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// reachableSteps returns the step IDs reachable from start via Next and OnError
+func reachableSteps(start []string, steps []config.Step) map[string]bool {
+	stepMap := make(map[string]config.Step, len(steps))
+	for _, step := range steps {
+		stepMap[step.ID] = step
+	}
+
+	reachable := make(map[string]bool)
+	queue := append([]string(nil), start...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if reachable[id] {
+			continue
+		}
+		step, ok := stepMap[id]
+		if !ok {
+			continue
+		}
+		reachable[id] = true
+		queue = append(queue, step.Next...)
+		queue = append(queue, step.OnError...)
+	}
+	return reachable
+}
+
+// findCycle returns the first cycle in the Next/OnError graph as a path
+// that starts and ends with the same step ID, or nil if the graph is acyclic.
+func findCycle(steps []config.Step) []string {
+	edges := make(map[string][]string, len(steps))
+	for _, step := range steps {
+		edges[step.ID] = append(append([]string(nil), step.Next...), step.OnError...)
+	}
+
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := make(map[string]int, len(steps))
+	var path []string
+
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		state[id] = inProgress
+		path = append(path, id)
+		for _, next := range edges[id] {
+			if _, exists := edges[next]; !exists {
+				continue
+			}
+			switch state[next] {
+			case inProgress:
+				for i, p := range path {
+					if p == next {
+						return append(append([]string(nil), path[i:]...), next)
+					}
+				}
+			case unvisited:
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = done
+		return nil
+	}
+
+	for _, step := range steps {
+		if state[step.ID] == unvisited {
+			if cycle := visit(step.ID); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
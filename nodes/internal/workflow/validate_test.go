@@ -0,0 +1,145 @@
+package workflow
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/controlcenter/nodes/internal/config"
+)
+
+func TestValidateWorkflow(t *testing.T) {
+	registry := NewStepRegistry(zerolog.Nop(), nil)
+
+	valid := config.Workflow{
+		ID:      "wf-1",
+		Trigger: config.Trigger{Type: "manual", StartSteps: []string{"copy"}},
+		Steps: []config.Step{
+			{ID: "copy", Type: "copy-file", Config: map[string]interface{}{"source": "a", "destination": "b"}, OnError: []string{"alert"}},
+			{ID: "alert", Type: "alert", Config: map[string]interface{}{"message": "copy failed"}},
+		},
+	}
+	if issues := registry.ValidateWorkflow(valid); len(issues) != 0 {
+		t.Errorf("expected no issues, got %+v", issues)
+	}
+
+	broken := config.Workflow{
+		ID:      "wf-2",
+		Trigger: config.Trigger{Type: "manual", StartSteps: []string{"a", "missing"}},
+		Steps: []config.Step{
+			{ID: "a", Type: "copy-file", Config: map[string]interface{}{"source": "x"}, Next: []string{"b"}},
+			{ID: "b", Type: "database-query", Next: []string{"a"}},
+			{ID: "orphan", Type: "no-such-step"},
+		},
+		Finally: []string{"gone"},
+	}
+	issues := registry.ValidateWorkflow(broken)
+	if !HasErrors(issues) {
+		t.Fatal("expected errors for broken workflow")
+	}
+
+	var messages []string
+	for _, issue := range issues {
+		messages = append(messages, issue.Message)
+	}
+	joined := strings.Join(messages, "\n")
+	for _, want := range []string{
+		"requires destination",
+		"not implemented",
+		"unknown step type",
+		`unknown step "missing"`,
+		`finally references unknown step "gone"`,
+		"not reachable",
+		"cycle a→b→a",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected issue containing %q, got:\n%s", want, joined)
+		}
+	}
+}
+
+func TestFindCycle_DiamondIsAcyclic(t *testing.T) {
+	steps := []config.Step{
+		{ID: "a", Next: []string{"b", "c"}},
+		{ID: "b", Next: []string{"d"}},
+		{ID: "c", Next: []string{"d"}},
+		{ID: "d"},
+	}
+	if cycle := findCycle(steps); cycle != nil {
+		t.Errorf("diamond graph reported as cycle %v", cycle)
+	}
+}
+
+func TestLoadWorkflows_RejectsCycles(t *testing.T) {
+	e, err := NewExecutor(filepath.Join(t.TempDir(), "state.json"), zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.LoadWorkflows([]config.Workflow{{
+		ID:      "loop",
+		Enabled: true,
+		Trigger: config.Trigger{Type: "manual", StartSteps: []string{"a"}},
+		Steps: []config.Step{
+			{ID: "a", Type: "alert", Config: map[string]interface{}{"message": "a"}, Next: []string{"b"}},
+			{ID: "b", Type: "alert", Config: map[string]interface{}{"message": "b"}, OnError: []string{"a"}},
+		},
+	}})
+
+	err = e.ExecuteWorkflowSync("loop", TriggerEvent{Type: "manual"})
+	if err == nil || !strings.Contains(err.Error(), "cycle a→b→a") {
+		t.Errorf("expected cycle load error, got %v", err)
+	}
+}
+
+func TestWhenCondition(t *testing.T) {
+	e, err := NewExecutor(filepath.Join(t.TempDir(), "state.json"), zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload := &scriptedStep{}
+	notify := &scriptedStep{}
+	e.stepRegistry.Register("upload", func() Step { return upload })
+	e.stepRegistry.Register("notify", func() Step { return notify })
+
+	e.LoadWorkflows([]config.Workflow{{
+		ID:      "convert",
+		Enabled: true,
+		Trigger: config.Trigger{Type: "manual", StartSteps: []string{"upload"}},
+		Steps: []config.Step{
+			{ID: "upload", Type: "upload", When: "{{ eq .exitCode 0 }}", Next: []string{"notify"}},
+			{ID: "notify", Type: "notify"},
+		},
+	}})
+
+	for _, tc := range []struct {
+		exitCode   int
+		wantUpload int
+	}{{0, 1}, {2, 1}} {
+		if err := e.ExecuteWorkflowSync("convert", TriggerEvent{Type: "manual", Data: map[string]interface{}{"exitCode": tc.exitCode}}); err != nil {
+			t.Fatal(err)
+		}
+		if upload.calls != tc.wantUpload {
+			t.Errorf("exitCode %d: upload ran %d times, want %d", tc.exitCode, upload.calls, tc.wantUpload)
+		}
+	}
+	if notify.calls != 2 {
+		t.Errorf("next steps of a skipped step should still run, notify ran %d times", notify.calls)
+	}
+
+	for when, want := range map[string]bool{"": false, "false": false, "0": false, "yes": true, "{{ .missing }}": false} {
+		if got, err := evaluateWhen(when, map[string]interface{}{}); err != nil || got != want {
+			t.Errorf("evaluateWhen(%q) = %v, %v; want %v", when, got, err, want)
+		}
+	}
+
+	issues := e.stepRegistry.ValidateWorkflow(config.Workflow{
+		ID:      "bad",
+		Trigger: config.Trigger{Type: "manual"},
+		Steps:   []config.Step{{ID: "a", Type: "alert", Config: map[string]interface{}{"message": "x"}, When: "{{ eq .exitCode"}},
+	})
+	if !HasErrors(issues) {
+		t.Error("unparseable when condition should be a validation error")
+	}
+}
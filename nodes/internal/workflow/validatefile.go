@@ -0,0 +1,469 @@
+package workflow
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultMaxValidationErrors caps how many problems validate-file reports
+const defaultMaxValidationErrors = 10
+
+// ValidateStep checks an arriving file against a declared schema (CSV
+// columns/headers, JSON Schema or XML well-formedness) so malformed feeds
+// are rejected up front, before later steps fail on them
+type ValidateStep struct {
+	BaseStep
+}
+
+// Params describes the config keys accepted by validate-file steps
+func (s *ValidateStep) Params() []StepParam {
+	return []StepParam{
+		{Name: "path", Type: "string", Description: "File to validate (default: the triggering file)"},
+		{Name: "format", Type: "string", Description: "csv, json or xml (default: from the file extension)"},
+		{Name: "schema", Type: "object", Description: "csv: {columns, headers, delimiter, hasHeader}; json: a JSON Schema object or path to a schema file; xml: {rootElement}"},
+		{Name: "maxErrors", Type: "number", Description: "Problems to report before stopping (default 10)"},
+	}
+}
+
+func (s *ValidateStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
+	triggerFile, _ := context["file"].(string)
+	path := s.getOptionalString(config, "path", triggerFile)
+	if path == "" {
+		return validationErrorf("%s step requires path (or a triggering file)", s.Type)
+	}
+	format := strings.ToLower(s.getOptionalString(config, "format", strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")))
+	maxErrors, err := s.getOptionalInt(config, "maxErrors", defaultMaxValidationErrors)
+	if err != nil {
+		return err
+	}
+	if maxErrors <= 0 {
+		maxErrors = defaultMaxValidationErrors
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return permanentErrorf("failed to open file to validate: %w", err)
+	}
+	defer f.Close()
+
+	schemaValue, err := s.schemaValue(config["schema"])
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	switch format {
+	case "csv":
+		schema, err := s.csvSchema(schemaValue)
+		if err != nil {
+			return err
+		}
+		problems, err = validateCSV(f, schema, maxErrors)
+		if err != nil {
+			return err
+		}
+	case "json":
+		schema, err := s.jsonSchema(schemaValue)
+		if err != nil {
+			return err
+		}
+		var doc interface{}
+		dec := json.NewDecoder(bufio.NewReader(f))
+		if err := dec.Decode(&doc); err != nil {
+			problems = []string{fmt.Sprintf("invalid JSON: %v", err)}
+		} else if _, err := dec.Token(); err != io.EOF {
+			problems = []string{"invalid JSON: unexpected data after the top-level value"}
+		} else if schema != nil {
+			problems = validateJSONSchema(doc, schema, "$", maxErrors)
+		}
+	case "xml":
+		rootElement := ""
+		if schema, ok := schemaValue.(map[string]interface{}); ok {
+			rootElement, _ = schema["rootElement"].(string)
+		}
+		problems = validateXML(f, rootElement)
+	default:
+		return validationErrorf("%s step format must be csv, json or xml, got %q", s.Type, format)
+	}
+
+	context["validationFormat"] = format
+	context["validationErrors"] = problems
+	context["validationValid"] = len(problems) == 0
+	if len(problems) > 0 {
+		s.Logger.Warn().Str("file", path).Str("format", format).Strs("problems", problems).Msg("❌ File failed validation")
+		return permanentErrorf("%s failed %s validation: %s", filepath.Base(path), format, strings.Join(problems, "; "))
+	}
+	s.Logger.Info().Str("file", path).Str("format", format).Msg("✅ File passed validation")
+	return nil
+}
+
+// schemaValue decodes a schema given as JSON text, as the workflow editor
+// stores it; objects and file paths are returned unchanged
+func (s *ValidateStep) schemaValue(raw interface{}) (interface{}, error) {
+	text, ok := raw.(string)
+	if !ok || !strings.HasPrefix(strings.TrimSpace(text), "{") {
+		return raw, nil
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &schema); err != nil {
+		return nil, validationErrorf("%s step schema is not valid JSON: %w", s.Type, err)
+	}
+	return schema, nil
+}
+
+// csvRules is the schema accepted for csv validation
+type csvRules struct {
+	columns   int
+	headers   []string
+	delimiter rune
+	hasHeader bool
+}
+
+func (s *ValidateStep) csvSchema(raw interface{}) (csvRules, error) {
+	rules := csvRules{delimiter: ','}
+	if raw == nil {
+		return rules, nil
+	}
+	schema, ok := raw.(map[string]interface{})
+	if !ok {
+		return rules, validationErrorf("%s step csv schema must be an object", s.Type)
+	}
+	columns, err := s.getOptionalInt(schema, "columns", 0)
+	if err != nil {
+		return rules, err
+	}
+	rules.columns = columns
+	if rules.headers, err = s.getOptionalStringSlice(schema, "headers"); err != nil {
+		return rules, err
+	}
+	if d := s.getOptionalString(schema, "delimiter", ","); d != "" {
+		r, size := utf8.DecodeRuneInString(d)
+		if d == `\t` {
+			r, size = '\t', len(d)
+		}
+		if size != len(d) {
+			return rules, validationErrorf("%s step csv delimiter must be a single character", s.Type)
+		}
+		rules.delimiter = r
+	}
+	rules.hasHeader = s.getOptionalBool(schema, "hasHeader", len(rules.headers) > 0)
+	if rules.columns == 0 && len(rules.headers) > 0 {
+		rules.columns = len(rules.headers)
+	}
+	return rules, nil
+}
+
+// jsonSchema loads the schema from an inline object or a file path
+func (s *ValidateStep) jsonSchema(raw interface{}) (map[string]interface{}, error) {
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return v, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		data, err := os.ReadFile(v)
+		if err != nil {
+			return nil, validationErrorf("failed to read JSON schema: %w", err)
+		}
+		var schema map[string]interface{}
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return nil, validationErrorf("invalid JSON schema %s: %w", v, err)
+		}
+		return schema, nil
+	default:
+		return nil, validationErrorf("%s step json schema must be an object or a file path", s.Type)
+	}
+}
+
+// validateCSV checks every row's column count, and the header row against
+// the expected headers. Without columns or headers rows must match the first.
+func validateCSV(r io.Reader, rules csvRules, maxErrors int) ([]string, error) {
+	reader := csv.NewReader(bufio.NewReader(r))
+	reader.Comma = rules.delimiter
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	var problems []string
+	want := rules.columns
+	for row := 1; len(problems) < maxErrors; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			if row == 1 {
+				problems = append(problems, "file is empty")
+			}
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				problems = append(problems, parseErr.Error())
+				continue
+			}
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		if row == 1 && rules.hasHeader && len(rules.headers) > 0 {
+			for i, header := range rules.headers {
+				got := ""
+				if i < len(record) {
+					got = strings.TrimSpace(strings.TrimPrefix(record[i], "\ufeff"))
+				}
+				if !strings.EqualFold(got, header) {
+					problems = append(problems, fmt.Sprintf("header %d is %q, expected %q", i+1, got, header))
+				}
+			}
+		}
+		if want == 0 {
+			want = len(record)
+			continue
+		}
+		if len(record) != want {
+			problems = append(problems, fmt.Sprintf("row %d has %d columns, expected %d", row, len(record), want))
+		}
+	}
+	if len(problems) > maxErrors {
+		problems = problems[:maxErrors]
+	}
+	return problems, nil
+}
+
+// validateXML checks the document is well-formed and, when rootElement is
+// set, that it is the document element
+func validateXML(r io.Reader, rootElement string) []string {
+	dec := xml.NewDecoder(bufio.NewReader(r))
+	root := ""
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return []string{fmt.Sprintf("XML is not well-formed: %v", err)}
+		}
+		if start, ok := tok.(xml.StartElement); ok && root == "" {
+			root = start.Name.Local
+		}
+	}
+	if root == "" {
+		return []string{"XML has no root element"}
+	}
+	if rootElement != "" && root != rootElement {
+		return []string{fmt.Sprintf("root element is <%s>, expected <%s>", root, rootElement)}
+	}
+	return nil
+}
+
+// validateJSONSchema checks v against the commonly used JSON Schema keywords:
+// type, enum, const, required, properties, additionalProperties, items,
+// min/maxItems, min/maxLength, pattern, minimum/maximum, exclusiveMinimum/
+// exclusiveMaximum, allOf, anyOf and oneOf. Other keywords are ignored.
+func validateJSONSchema(v interface{}, schema map[string]interface{}, at string, maxErrors int) []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		if len(problems) < maxErrors {
+			problems = append(problems, at+": "+fmt.Sprintf(format, args...))
+		}
+	}
+
+	if t, ok := schema["type"]; ok && !jsonTypeMatches(v, t) {
+		add("expected %s, got %s", jsonTypeNames(t), jsonTypeOf(v))
+		return problems
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || jsonEqual(v, e)
+		}
+		if !found {
+			add("value %v is not one of %v", v, enum)
+		}
+	}
+	if c, ok := schema["const"]; ok && !jsonEqual(v, c) {
+		add("value %v must be %v", v, c)
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, r := range required {
+				if name, ok := r.(string); ok {
+					if _, present := val[name]; !present {
+						add("missing required property %q", name)
+					}
+				}
+			}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if propSchema, ok := props[k].(map[string]interface{}); ok {
+				problems = appendProblems(problems, validateJSONSchema(val[k], propSchema, at+"."+k, maxErrors), maxErrors)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					add("unexpected property %q", k)
+				}
+			case map[string]interface{}:
+				problems = appendProblems(problems, validateJSONSchema(val[k], extra, at+"."+k, maxErrors), maxErrors)
+			}
+		}
+	case []interface{}:
+		if n, ok := schema["minItems"].(float64); ok && float64(len(val)) < n {
+			add("expected at least %v items, got %d", n, len(val))
+		}
+		if n, ok := schema["maxItems"].(float64); ok && float64(len(val)) > n {
+			add("expected at most %v items, got %d", n, len(val))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range val {
+				if len(problems) >= maxErrors {
+					break
+				}
+				problems = appendProblems(problems, validateJSONSchema(item, items, fmt.Sprintf("%s[%d]", at, i), maxErrors), maxErrors)
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(val))
+		if n, ok := schema["minLength"].(float64); ok && length < n {
+			add("expected at least %v characters", n)
+		}
+		if n, ok := schema["maxLength"].(float64); ok && length > n {
+			add("expected at most %v characters", n)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				add("invalid pattern %q in schema: %v", pattern, err)
+			} else if !re.MatchString(val) {
+				add("%q does not match pattern %q", val, pattern)
+			}
+		}
+	case float64:
+		if n, ok := schema["minimum"].(float64); ok && val < n {
+			add("%v is less than minimum %v", val, n)
+		}
+		if n, ok := schema["maximum"].(float64); ok && val > n {
+			add("%v is greater than maximum %v", val, n)
+		}
+		if n, ok := schema["exclusiveMinimum"].(float64); ok && val <= n {
+			add("%v must be greater than %v", val, n)
+		}
+		if n, ok := schema["exclusiveMaximum"].(float64); ok && val >= n {
+			add("%v must be less than %v", val, n)
+		}
+	}
+
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			if subSchema, ok := sub.(map[string]interface{}); ok {
+				problems = appendProblems(problems, validateJSONSchema(v, subSchema, at, maxErrors), maxErrors)
+			}
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok && countMatching(v, anyOf, at) == 0 {
+		add("does not match any of the anyOf schemas")
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		if n := countMatching(v, oneOf, at); n != 1 {
+			add("matches %d of the oneOf schemas, expected exactly 1", n)
+		}
+	}
+	return problems
+}
+
+// countMatching returns how many of schemas v satisfies
+func countMatching(v interface{}, schemas []interface{}, at string) int {
+	n := 0
+	for _, sub := range schemas {
+		if subSchema, ok := sub.(map[string]interface{}); ok && len(validateJSONSchema(v, subSchema, at, 1)) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func appendProblems(problems, more []string, maxErrors int) []string {
+	for _, p := range more {
+		if len(problems) >= maxErrors {
+			break
+		}
+		problems = append(problems, p)
+	}
+	return problems
+}
+
+// jsonTypeMatches reports whether v has the schema type t (a name or list of names)
+func jsonTypeMatches(v interface{}, t interface{}) bool {
+	switch tt := t.(type) {
+	case string:
+		actual := jsonTypeOf(v)
+		return actual == tt || (tt == "number" && actual == "integer")
+	case []interface{}:
+		for _, name := range tt {
+			if jsonTypeMatches(v, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jsonTypeNames(t interface{}) string {
+	if list, ok := t.([]interface{}); ok {
+		names := make([]string, 0, len(list))
+		for _, name := range list {
+			names = append(names, fmt.Sprint(name))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+// jsonTypeOf names the JSON Schema type of a decoded JSON value
+func jsonTypeOf(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if val == math.Trunc(val) && !math.IsInf(val, 0) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// jsonEqual compares decoded JSON values
+func jsonEqual(a, b interface{}) bool {
+	aj, err1 := json.Marshal(a)
+	bj, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && string(aj) == string(bj)
+}
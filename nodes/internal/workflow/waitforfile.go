@@ -0,0 +1,114 @@
+package workflow
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// WaitForFileStep waits for a companion file from another process, e.g. the
+// .done marker or report a producer writes after the file that triggered the
+// workflow. The file must exist and keep its size and modification time for
+// stableSeconds before the step continues.
+type WaitForFileStep struct {
+	BaseStep
+}
+
+// Params describes the config keys accepted by wait-for-file steps
+func (s *WaitForFileStep) Params() []StepParam {
+	return []StepParam{
+		{Name: "path", Type: "string", Required: true, Description: "File to wait for, or a glob such as /out/{{.fileName}}.*"},
+		{Name: "timeoutSeconds", Type: "number", Description: "Fail when no file is ready by then (default 300)"},
+		{Name: "stableSeconds", Type: "number", Description: "How long size and modification time must stay unchanged (default 2, 0 = as soon as it exists)"},
+		{Name: "pollIntervalMs", Type: "number", Description: "How often to look for the file (default 1000)"},
+	}
+}
+
+func (s *WaitForFileStep) Execute(config map[string]interface{}, context map[string]interface{}) error {
+	pattern, err := s.getRequiredString(config, "path")
+	if err != nil {
+		return err
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return validationErrorf("invalid path pattern %q: %w", pattern, err)
+	}
+	timeoutSecs, err := s.getOptionalInt(config, "timeoutSeconds", 300)
+	if err != nil {
+		return err
+	}
+	stableSecs, err := s.getOptionalInt(config, "stableSeconds", 2)
+	if err != nil {
+		return err
+	}
+	pollMs, err := s.getOptionalInt(config, "pollIntervalMs", 1000)
+	if err != nil {
+		return err
+	}
+	if timeoutSecs <= 0 || stableSecs < 0 || pollMs <= 0 {
+		return validationErrorf("%s step needs a positive timeoutSeconds and pollIntervalMs and a stableSeconds of 0 or more", s.Type)
+	}
+	stableFor := time.Duration(stableSecs) * time.Second
+	poll := time.Duration(pollMs) * time.Millisecond
+
+	s.Logger.Info().Str("path", pattern).Int("timeoutSeconds", timeoutSecs).Msg("⏳ Waiting for file")
+
+	start := time.Now()
+	deadline := start.Add(time.Duration(timeoutSecs) * time.Second)
+	seen := map[string]fileSnapshot{}
+	for {
+		if path, info, ok := stableFile(pattern, seen, stableFor); ok {
+			waited := time.Since(start)
+			s.Logger.Info().Str("path", path).Dur("waited", waited).Msg("✅ File is ready")
+			context["foundPath"] = path
+			context["foundName"] = info.Name()
+			context["foundSize"] = info.Size()
+			context["waitedSeconds"] = int(waited.Seconds())
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			if len(seen) > 0 {
+				return transientErrorf("timed out after %ds waiting for %s to stop changing", timeoutSecs, pattern)
+			}
+			return transientErrorf("timed out after %ds waiting for %s", timeoutSecs, pattern)
+		}
+		if poll < remaining {
+			remaining = poll
+		}
+		time.Sleep(remaining)
+	}
+}
+
+// fileSnapshot is what a file looked like when first seen with its current
+// size and modification time
+type fileSnapshot struct {
+	size    int64
+	modTime time.Time
+	since   time.Time
+}
+
+// stableFile returns the first regular file (by name) matching pattern whose
+// size and modification time have not changed for stableFor. seen carries the
+// snapshots between polls.
+func stableFile(pattern string, seen map[string]fileSnapshot, stableFor time.Duration) (string, os.FileInfo, bool) {
+	matches, _ := filepath.Glob(pattern)
+	sort.Strings(matches)
+	now := time.Now()
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		snap, ok := seen[path]
+		if !ok || snap.size != info.Size() || !snap.modTime.Equal(info.ModTime()) {
+			snap = fileSnapshot{size: info.Size(), modTime: info.ModTime(), since: now}
+			seen[path] = snap
+		}
+		if now.Sub(snap.since) >= stableFor {
+			return path, info, true
+		}
+	}
+	return "", nil, false
+}
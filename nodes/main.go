@@ -21,6 +21,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
@@ -28,20 +29,29 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
+	_ "time/tzdata" // embedded zone database so schedule/rule timezones work on hosts without one (e.g. Windows)
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"github.com/your-org/controlcenter/nodes/internal/api"
+	"github.com/your-org/controlcenter/nodes/internal/audit"
 	"github.com/your-org/controlcenter/nodes/internal/config"
 	"github.com/your-org/controlcenter/nodes/internal/filebrowser"
 	"github.com/your-org/controlcenter/nodes/internal/filewatcher"
 	"github.com/your-org/controlcenter/nodes/internal/gitsync"
 	"github.com/your-org/controlcenter/nodes/internal/identity"
 	"github.com/your-org/controlcenter/nodes/internal/logrotation"
+	"github.com/your-org/controlcenter/nodes/internal/proxy"
+	"github.com/your-org/controlcenter/nodes/internal/router"
+	"github.com/your-org/controlcenter/nodes/internal/secrets"
 	"github.com/your-org/controlcenter/nodes/internal/sshserver"
+	"github.com/your-org/controlcenter/nodes/internal/supervisor"
+	"github.com/your-org/controlcenter/nodes/internal/throttle"
 	"github.com/your-org/controlcenter/nodes/internal/websocket"
 	"github.com/your-org/controlcenter/nodes/internal/workflow"
 )
@@ -57,11 +67,34 @@ type Agent struct {
 	wsConnected  bool  // Track WebSocket connection state
 	gitSync      *gitsync.GitSync
 	executor     *workflow.Executor
+	router       *router.Router // serves the agent API, file browser and webhooks on :8088
 	sshServer    *sshserver.SSHServer
 	fileWatcher  *filewatcher.Watcher
 	logger       zerolog.Logger
 	logLevel     *zerolog.Level
+	consoleLog   *logrotation.FormatWriter // stdout sink, format from logSettings.consoleFormat
+	fileLog      *logrotation.FormatWriter // agent.log sink, format from logSettings.fileFormat
+	audit        *audit.Logger
+	secrets      *secrets.Store
+	commandMu     sync.Mutex // serializes commands that change agent state
+	pendingMu     sync.Mutex
+	pendingConfig map[string]interface{} // git config held for approval by configChangePolicy
+	pendingDiff   config.ConfigDiff
 	configPath   string
+	fileWatcherRulesSource string // "git" or "local", for /api/config provenance
+	supervisor   *supervisor.Supervisor // restarts the SSH server, websocket client and executor when they fail
+	executorOnce sync.Once
+	executorWake chan struct{} // restarts the supervised executor after a reload stopped it
+	ctx          context.Context        // cancelled on shutdown, stopping supervised components
+}
+
+// stringMap converts a decoded JSON object to map[string]string
+func stringMap(m map[string]interface{}) map[string]string {
+	result := make(map[string]string, len(m))
+	for k, v := range m {
+		result[k] = fmt.Sprint(v)
+	}
+	return result
 }
 
 func fileExists(path string) bool {
@@ -92,8 +125,10 @@ func main() {
 		listBackups    = flag.Bool("list-backups", false, "List available configuration backups")
 		recoverBackup  = flag.String("recover-backup", "", "Recover from a specific backup (stash or branch ID, or 'latest')")
 		mergeConfig    = flag.Bool("merge-config", false, "Interactive merge of local and remote configurations")
+		jsonOutput     = flag.Bool("json", false, "Print the result of -push-config, -merge-config, -recover-backup, -list-backups or -check-changes as JSON on stdout (logs go to stderr)")
 	)
 	flag.Parse()
+	cliOperation := cliOperationName(*pushConfig, *checkChanges, *listBackups, *recoverBackup, *mergeConfig)
 
 	// Handle version flag
 	if *versionFlag {
@@ -129,12 +164,37 @@ func main() {
 	}
 	defer rotatingWriter.Close()
 
-	// Create multi-writer for both console and rotating file
-	consoleWriter := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
-	multiWriter := zerolog.MultiLevelWriter(consoleWriter, rotatingWriter)
+	// Create multi-writer for both console and rotating file. Each sink's
+	// format is switched to the configured one once the config is loaded.
+	consoleOut := os.Stdout
+	if *jsonOutput {
+		// Keep stdout for the JSON result
+		consoleOut = os.Stderr
+	}
+	consoleLog := logrotation.NewFormatWriter(consoleOut, logrotation.FormatConsole, true)
+	fileLog := logrotation.NewFormatWriter(rotatingWriter, logrotation.FormatJSON, false)
+	multiWriter := zerolog.MultiLevelWriter(consoleLog, fileLog)
 
 	logger := zerolog.New(multiWriter).With().Timestamp().Logger().Level(currentLevel)
 
+	// Audit log is kept separate from operational logs so it can be shipped and
+	// retained independently. Entries are hash-chained across restarts.
+	auditFilePath := filepath.Join(getDefaultConfigDir(), "audit.log")
+	lastAuditHash := audit.LastHash(auditFilePath)
+	auditWriter, err := logrotation.NewRotatingWriter(
+		auditFilePath,
+		100,  // 100MB max size
+		365,  // 1 year retention
+		20,   // 20 backup files
+		true, // compress old logs
+	)
+	if err != nil {
+		fmt.Printf("Failed to create audit log writer: %v\n", err)
+		os.Exit(1)
+	}
+	defer auditWriter.Close()
+	auditLogger := audit.New(auditWriter, lastAuditHash)
+
 	// Log version prominently at startup
 	logger.Info().
 		Str("version", AgentVersion).
@@ -165,10 +225,24 @@ func main() {
 	
 	// Load or create configuration
 	logger.Debug().Str("actualConfigPath", actualConfigPath).Msg("Loading config from path")
-	cfg, err := config.Load(actualConfigPath)
+	cfg, recovery, err := config.LoadOrRecover(actualConfigPath)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("Failed to load configuration")
 	}
+	if recovery != nil {
+		event := logger.Error().
+			Err(recovery.Err).
+			Str("path", actualConfigPath).
+			Str("corruptCopy", recovery.CorruptPath).
+			Str("recoveredFrom", recovery.Source)
+		if recovery.BackupErr != nil {
+			event = event.AnErr("backupError", recovery.BackupErr)
+		}
+		event.Msg("🚨 Config file was corrupt and has been moved aside - recovered, check the agent's settings")
+		if recovery.Source == "defaults" {
+			logger.Error().Str("agentId", cfg.AgentID).Msg("🚨 No usable config backup - started from defaults with a new agent ID, the agent must re-register")
+		}
+	}
 	
 	// Update the configPath pointer to the actual path used
 	*configPath = actualConfigPath
@@ -217,7 +291,22 @@ func main() {
 		identity:   identity,
 		logger:     logger,
 		logLevel:   &currentLevel,
+		consoleLog: consoleLog,
+		fileLog:    fileLog,
 		configPath: *configPath,
+		audit:      auditLogger,
+	}
+	agent.applyLogFormats()
+	auditLogger.Record("agent.start", "local", audit.OutcomeSuccess, map[string]interface{}{
+		"agentId": cfg.AgentID,
+		"version": AgentVersion,
+	})
+
+	// Outbound proxy for the manager connection, S3 and HTTP steps
+	agent.applyProxySettings()
+
+	if cliOperation != "" && *standalone {
+		finishCLI(logger, *jsonOutput, cliFailure(cliOperation, "Configuration sync operations are not available in standalone mode", nil))
 	}
 
 	// Initialize Git sync only if not in standalone mode
@@ -241,9 +330,19 @@ func main() {
 		}
 
 		agent.gitSync = gitsync.New(cfg.ConfigRepoPath, gitURL, cfg.AgentID, cfg.SSHPrivateKeyPath, logger)
+		connSettings := cfg.GetConnectionSettings()
+		agent.gitSync.SetRetryPolicy(
+			connSettings.GitRetryAttempts,
+			time.Duration(connSettings.GitRetryDelaySeconds)*time.Second,
+			time.Duration(connSettings.GitRetryMaxDelaySeconds)*time.Second,
+		)
+		agent.gitSync.SetTLSOptions(connSettings.GitInsecureSSL, connSettings.GitCAPath)
 
 		// Initialize the git repository
 		if err := agent.gitSync.Initialize(); err != nil {
+			if cliOperation != "" {
+				finishCLI(logger, *jsonOutput, cliFailure(cliOperation, "Failed to initialize git repository", err))
+			}
 			logger.Error().Err(err).Msg("Failed to initialize git repository")
 			// Continue without git sync
 			agent.gitSync = nil
@@ -260,63 +359,63 @@ func main() {
 				hasDiverged, _ := agent.gitSync.HasDiverged()
 
 				if !hasLocal && !hasDiverged {
-					logger.Info().Msg("No changes to merge - configurations are in sync")
-					return
+					finishCLI(logger, *jsonOutput, cliSuccess("merge-config", "no-changes", "No changes to merge - configurations are in sync"))
 				}
 
 				logger.Info().Msg("STEP 1: Backing up local changes...")
 				if hasLocal {
 					if err := agent.gitSync.BackupLocalChanges(); err != nil {
-						logger.Error().Err(err).Msg("Failed to backup local changes")
-						return
+						finishCLI(logger, *jsonOutput, cliFailure("merge-config", "Failed to backup local changes", err))
 					}
 				}
 
 				logger.Info().Msg("STEP 2: Pulling remote changes...")
 				if err := agent.gitSync.Pull(); err != nil {
-					logger.Error().Err(err).Msg("Failed to pull remote changes")
 					logger.Info().Msg("Your changes are safe in backup. Use -list-backups to see them")
-					return
+					finishCLI(logger, *jsonOutput, cliFailure("merge-config", "Failed to pull remote changes", err).with("backedUp", hasLocal))
 				}
 
-				if hasLocal {
-					logger.Info().Msg("STEP 3: Applying your local changes on top...")
-					if err := agent.gitSync.RecoverBackup("latest"); err != nil {
-						logger.Warn().Err(err).Msg("Automatic merge failed - manual resolution needed")
-						logger.Info().Msg("Your changes are in stash. Manual steps:")
-						logger.Info().Msg("  1. cd " + agent.config.ConfigRepoPath)
-						logger.Info().Msg("  2. git stash pop")
-						logger.Info().Msg("  3. Resolve any conflicts")
-						logger.Info().Msg("  4. git add -A && git commit -m 'Merged configs'")
-						logger.Info().Msg("  5. Use -push-config to save to manager")
-					} else {
-						logger.Info().Msg("✅ Changes merged successfully!")
-						logger.Info().Msg("Review the merged configuration and use -push-config to save to manager")
-					}
-				} else {
-					logger.Info().Msg("✅ Remote changes pulled successfully")
+				if !hasLocal {
+					finishCLI(logger, *jsonOutput, cliSuccess("merge-config", "pulled", "Remote changes pulled successfully"))
 				}
 
-				return
+				logger.Info().Msg("STEP 3: Applying your local changes on top...")
+				if err := agent.gitSync.RecoverBackup("latest"); err != nil {
+					logger.Info().Msg("Your changes are in stash. Manual steps:")
+					logger.Info().Msg("  1. cd " + agent.config.ConfigRepoPath)
+					logger.Info().Msg("  2. git stash pop")
+					logger.Info().Msg("  3. Resolve any conflicts")
+					logger.Info().Msg("  4. git add -A && git commit -m 'Merged configs'")
+					logger.Info().Msg("  5. Use -push-config to save to manager")
+					result := cliFailure("merge-config", "Automatic merge failed - manual resolution needed", err)
+					// Whatever stopped the stash from applying, the next step is a manual merge
+					result.Status, result.ExitCode = gitsync.FailureConflict, exitConflict
+					finishCLI(logger, *jsonOutput, result.with("repoPath", agent.config.ConfigRepoPath))
+				}
+				logger.Info().Msg("Review the merged configuration and use -push-config to save to manager")
+				finishCLI(logger, *jsonOutput, cliSuccess("merge-config", "merged", "Changes merged successfully"))
 			}
 
 			// Handle backup operations
 			if *listBackups {
 				logger.Info().Msg("Listing available configuration backups...")
-				if backups, err := agent.gitSync.ListBackups(); err == nil {
-					if len(backups) == 0 {
-						logger.Info().Msg("No backups found")
-					} else {
-						logger.Info().Msg("Available backups:")
-						for _, backup := range backups {
-							fmt.Println("  " + backup)
-						}
-						logger.Info().Msg("Use -recover-backup <ID> to restore a backup")
+				backups, err := agent.gitSync.ListBackups()
+				if err != nil {
+					finishCLI(logger, *jsonOutput, cliFailure("list-backups", "Failed to list backups", err))
+				}
+				if len(backups) == 0 {
+					logger.Info().Msg("No backups found")
+				} else if !*jsonOutput {
+					logger.Info().Msg("Available backups:")
+					for _, backup := range backups {
+						fmt.Println("  " + backup)
 					}
-				} else {
-					logger.Error().Err(err).Msg("Failed to list backups")
+					logger.Info().Msg("Use -recover-backup <ID> to restore a backup")
 				}
-				return
+				if backups == nil {
+					backups = []string{}
+				}
+				finishCLI(logger, *jsonOutput, cliSuccess("list-backups", "listed", fmt.Sprintf("%d backups found", len(backups))).with("backups", backups))
 			}
 
 			if *recoverBackup != "" {
@@ -327,28 +426,34 @@ func main() {
 				}
 				logger.Info().Str("backup", backupToRecover).Msg("Recovering from backup...")
 				if err := agent.gitSync.RecoverBackup(backupToRecover); err != nil {
-					logger.Error().Err(err).Msg("Failed to recover backup")
 					logger.Info().Msg("Try -list-backups to see available backups")
-				} else {
-					logger.Info().Msg("✅ Backup recovered successfully")
-					logger.Info().Msg("Review changes and use -push-config to save to manager if desired")
+					finishCLI(logger, *jsonOutput, cliFailure("recover-backup", "Failed to recover backup", err).with("backup", backupToRecover))
 				}
-				return
+				logger.Info().Msg("Review changes and use -push-config to save to manager if desired")
+				finishCLI(logger, *jsonOutput, cliSuccess("recover-backup", "recovered", "Backup recovered successfully").with("backup", backupToRecover))
 			}
 
 			// Check for local changes if requested
 			if *checkChanges || *pushConfig {
+				operation := "check-changes"
+				if *pushConfig {
+					operation = "push-config"
+				}
 				hasUncommittedChanges, _ := agent.gitSync.HasLocalChanges()
 				hasCommitsAhead, _ := agent.gitSync.HasCommitsAhead()
+				var diff string
 
 				if hasUncommittedChanges {
 					logger.Warn().Msg("⚠️  UNCOMMITTED CONFIGURATION CHANGES DETECTED")
 					logger.Warn().Msg("Changes will be automatically backed up before sync")
 					logger.Warn().Msg("Use -push-config to save to manager, or -list-backups to see backups")
 
-					if diff, err := agent.gitSync.GetDiff(); err == nil && diff != "" {
-						logger.Info().Msg("Local changes:")
-						fmt.Println(diff)
+					if d, err := agent.gitSync.GetDiff(); err == nil && d != "" {
+						diff = d
+						if !*jsonOutput {
+							logger.Info().Msg("Local changes:")
+							fmt.Println(diff)
+						}
 					}
 				}
 
@@ -357,60 +462,49 @@ func main() {
 					logger.Warn().Msg("Use -push-config to push to manager")
 				}
 
-				if *pushConfig {
-					if hasUncommittedChanges || hasCommitsAhead {
-						logger.Info().Msg("Pushing local changes to manager...")
-
-						// Track if push was successful
-						pushSuccessful := false
-
-						// Save current config to git repo if there are uncommitted changes
-						if hasUncommittedChanges {
-							configData := make(map[string]interface{})
-							if configJSON, err := json.Marshal(cfg); err == nil {
-								json.Unmarshal(configJSON, &configData)
-								if err := agent.gitSync.SaveAgentConfig(configData); err != nil {
-									logger.Error().Err(err).Msg("Failed to save config to repository")
-								}
-							}
-
-							// Commit changes
-							commitMsg := fmt.Sprintf("Agent %s: Push local configuration changes", cfg.AgentID)
-							if err := agent.gitSync.CommitLocalChanges(commitMsg); err != nil {
-								logger.Error().Err(err).Msg("❌ Failed to commit changes")
-								logger.Error().Msg("Push failed. Please review the errors above and try again.")
-								os.Exit(1)
-							}
-						}
+				withChanges := func(r cliResult) cliResult {
+					r = r.with("uncommittedChanges", hasUncommittedChanges).with("commitsAhead", hasCommitsAhead)
+					if diff != "" {
+						r = r.with("diff", diff)
+					}
+					return r
+				}
 
-						// Push to remote
-						if err := agent.gitSync.Push(); err != nil {
-							logger.Error().Err(err).Msg("❌ Failed to push changes to manager")
-							logger.Error().Msg("Push failed. Please review the errors above and try again.")
-							os.Exit(1)
-						} else {
-							logger.Info().Msg("✅ Configuration successfully pushed to manager")
-							pushSuccessful = true
-						}
+				if !hasUncommittedChanges && !hasCommitsAhead {
+					message := "No local configuration changes detected"
+					if *pushConfig {
+						message = "No local changes to push"
+					}
+					finishCLI(logger, *jsonOutput, withChanges(cliSuccess(operation, "no-changes", message)))
+				}
+				if !*pushConfig {
+					finishCLI(logger, *jsonOutput, withChanges(cliSuccess(operation, "changes", "Local configuration changes detected")))
+				}
 
-						// Exit with appropriate status
-						if pushSuccessful {
-							return
+				logger.Info().Msg("Pushing local changes to manager...")
+
+				// Save current config to git repo if there are uncommitted changes
+				if hasUncommittedChanges {
+					configData := make(map[string]interface{})
+					if configJSON, err := json.Marshal(cfg); err == nil {
+						json.Unmarshal(configJSON, &configData)
+						if err := agent.gitSync.SaveAgentConfig(configData); err != nil {
+							logger.Error().Err(err).Msg("Failed to save config to repository")
 						}
-					} else {
-						logger.Info().Msg("No local changes to push.")
-						return
 					}
-				} else if *checkChanges {
-					if !hasUncommittedChanges && !hasCommitsAhead {
-						logger.Info().Msg("No local configuration changes detected")
+
+					// Commit changes
+					commitMsg := fmt.Sprintf("Agent %s: Push local configuration changes", cfg.AgentID)
+					if err := agent.gitSync.CommitLocalChanges(commitMsg); err != nil {
+						finishCLI(logger, *jsonOutput, withChanges(cliFailure(operation, "Failed to commit changes", err)))
 					}
 				}
 
-				// Exit if we were just checking
-				if *checkChanges && !*pushConfig {
-					return
+				// Push to remote
+				if err := agent.gitSync.Push(); err != nil {
+					finishCLI(logger, *jsonOutput, withChanges(cliFailure(operation, "Failed to push changes to manager", err)))
 				}
+				finishCLI(logger, *jsonOutput, withChanges(cliSuccess(operation, "pushed", "Configuration successfully pushed to manager")))
 			}
 
 			// Check for divergence before pulling
@@ -421,26 +515,53 @@ func main() {
 			}
 
 			// Load configuration from git repository (including workflows)
-			// This uses the same logic as reloadConfig() to ensure consistency
-			if err := agent.reloadConfig(); err != nil {
-				logger.Error().Err(err).Msg("Failed to load configuration from git")
-			}
+			// This uses the same logic as reloadConfig() to ensure consistency,
+			// including the config change policy
+			agent.reloadConfigUnattended("startup")
 		}
 	} else {
 		logger.Info().Msg("Running in standalone mode - Git sync disabled")
 	}
 	
+	// Long-running components are restarted with backoff if they fail
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agent.ctx = ctx
+	agent.supervisor = supervisor.New(logger)
+	agent.supervisor.OnRestart(agent.reportComponentRestart)
+
 	// Initialize workflow executor
 	executor, err := workflow.NewExecutor(cfg.StateFilePath, logger)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("Failed to create workflow executor")
 	}
 	agent.executor = executor
+	agent.router = router.New()
+	executor.SetRouter(agent.router)
 	
 	// Set alert handler to forward alerts to manager
 	executor.SetAlertHandler(func(level, message string, details map[string]interface{}) {
 		agent.sendAlert(level, message, details)
 	})
+
+	// Forward workflow lifecycle events to the manager (gated by logSettings.workflowEvents)
+	executor.SetEventHandler(agent.sendWorkflowEvent)
+
+	// Custom events from emit-event steps
+	executor.SetEventEmitter(agent.sendCustomEvent)
+
+	// Apply local command policy (never sourced from git)
+	agent.applyCommandPolicy()
+
+	// Workflow variables; "secret:" values come from the local secrets file
+	secretStore, err := secrets.Load(cfg.SecretsFilePath)
+	if err != nil {
+		logger.Error().Err(err).Str("path", cfg.SecretsFilePath).Msg("Failed to load secrets file, secret variables will not resolve")
+		secretStore, _ = secrets.Load("")
+	}
+	agent.secrets = secretStore
+	executor.SetSecretLookup(secretStore.Get)
+	executor.SetVariables(cfg.GetVariables())
 	
 	// Initialize file watcher with workflow executor adapter
 	workflowAdapter := &workflowExecutorAdapter{
@@ -448,7 +569,16 @@ func main() {
 		logger:   logger,
 	}
 	agent.fileWatcher = filewatcher.NewWatcher(logger, workflowAdapter)
+	agent.fileWatcher.SetAlertHandler(agent.sendAlert)
+	ledgerPath := filepath.Join(filepath.Dir(cfg.StateFilePath), "filewatcher-ledger.json")
+	ledgerTTL := time.Duration(cfg.FileWatcherSettings.LedgerTTLHours) * time.Hour
+	if err := agent.fileWatcher.SetLedger(ledgerPath, ledgerTTL); err != nil {
+		logger.Warn().Err(err).Msg("⚠️ Failed to load processed file ledger, files may be reprocessed after restart")
+	}
 	
+	// Share one bandwidth limiter between file watcher copies and workflow steps
+	agent.applyTransferSettings()
+
 	// Load file watcher rules from config if any exist
 	agent.loadFileWatcherRules()
 
@@ -458,6 +588,8 @@ func main() {
 		logger.Error().Err(err).Msg("Failed to create SSH server")
 	} else {
 		agent.sshServer = sshServer
+		sshServer.SetAuditLogger(auditLogger)
+		sshServer.SetMaxCommandsPerSession(cfg.SSHMaxCommandsPerSession)
 		// Wire up allowed paths for SFTP from FileBrowserSettings
 		fbSettings := cfg.GetFileBrowserSettings()
 		if fbSettings.Enabled && len(fbSettings.AllowedPaths) > 0 {
@@ -465,31 +597,49 @@ func main() {
 		} else {
 			sshServer.SetAllowedPaths(nil)
 		}
-		go func() {
-			if err := sshServer.Start(); err != nil {
-				logger.Error().Err(err).Msg("SSH server stopped")
-			}
-		}()
-		logger.Info().Int("port", cfg.SSHServerPort).Msg("SSH server started")
+		// Never fall back to all interfaces when a bind address was asked for
+		if addr, err := cfg.SSHListenAddr(); err != nil {
+			logger.Error().Err(err).Str("sshBindAddr", cfg.SSHBindAddr).Msg("❌ Invalid SSH bind address, SSH server not started")
+		} else {
+			sshServer.SetListenAddr(addr)
+			// Start returns nil only when the listener is closed on purpose
+			agent.supervise("ssh-server", func(ctx context.Context) error {
+				return sshServer.Start()
+			})
+			logger.Info().Str("addr", addr).Msg("SSH server started")
+		}
 	}
 
 	// Start health endpoint
 	go agent.startHealthEndpoint()
 
 	// Start WebSocket client only if not in standalone mode
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	if !*standalone {
 		agent.wsClient = websocket.NewClient(cfg.ManagerURL, cfg.AgentID, logger)
+		connSettings := cfg.GetConnectionSettings()
+		agent.wsClient.SetIntervals(
+			time.Duration(connSettings.PingIntervalSeconds)*time.Second,
+			time.Duration(connSettings.ReconnectIntervalSeconds)*time.Second,
+		)
+		if connSettings.MaxMissedHeartbeats != 0 {
+			agent.wsClient.SetMaxMissedHeartbeats(connSettings.MaxMissedHeartbeats)
+		}
+		agent.wsClient.SetTagsProvider(cfg.GetTags)
 
 		// Set up message handlers
 		agent.wsClient.OnMessage(agent.handleMessage)
 		agent.wsClient.OnConnect(agent.handleConnect)
 		agent.wsClient.OnDisconnect(agent.handleDisconnect)
 
-		// Start WebSocket connection in background
-		go agent.wsClient.Start(ctx)
+		// Start WebSocket connection in background. The client reconnects by
+		// itself, so returning before shutdown means it failed.
+		agent.supervise("websocket", func(ctx context.Context) error {
+			agent.wsClient.Start(ctx)
+			if ctx.Err() == nil {
+				return errors.New("websocket client stopped unexpectedly")
+			}
+			return nil
+		})
 
 		logger.Info().
 			Str("agentId", cfg.AgentID).
@@ -499,10 +649,19 @@ func main() {
 		logger.Info().Msg("Running in standalone mode - Manager connection disabled")
 	}
 
+	// Pull config that has gone stale, e.g. after losing git connectivity
+	if staleness := cfg.GetConnectionSettings().MaxConfigStalenessSeconds; agent.gitSync != nil && staleness > 0 {
+		maxAge := time.Duration(staleness) * time.Second
+		agent.supervise("config-staleness", func(ctx context.Context) error {
+			return agent.watchConfigStaleness(ctx, maxAge)
+		})
+		logger.Info().Dur("maxAge", maxAge).Msg("Config staleness watchdog started")
+	}
+
 	// Load workflows from config if any exist
 	if len(cfg.Workflows) > 0 {
 		agent.executor.LoadWorkflows(cfg.Workflows)
-		go agent.executor.Start()
+		agent.startExecutor()
 		logger.Info().Int("count", len(cfg.Workflows)).Msg("Loaded workflows from configuration")
 	}
 
@@ -537,17 +696,75 @@ func main() {
 	cancel()
 }
 
+// supervise runs a long-running component in the background, restarting it
+// with backoff when it fails (returns an error or panics) until shutdown
+func (a *Agent) supervise(name string, run func(ctx context.Context) error) {
+	go a.supervisor.Run(a.ctx, name, run)
+}
+
+// startExecutor starts the workflow executor. It runs under supervision in
+// one loop for the agent's lifetime: a reload stops it (Start returns nil) and
+// calls startExecutor again, which wakes the loop instead of adding another.
+func (a *Agent) startExecutor() {
+	a.executorOnce.Do(func() {
+		a.executorWake = make(chan struct{}, 1)
+		a.supervise("executor", func(ctx context.Context) error {
+			for {
+				if err := a.executor.Start(); err != nil {
+					return err
+				}
+				select {
+				case <-a.executorWake:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		})
+	})
+	select {
+	case a.executorWake <- struct{}{}:
+	default:
+	}
+}
+
+// reportComponentRestart tells the manager a failed component is being restarted
+func (a *Agent) reportComponentRestart(r supervisor.Restart) {
+	if a.wsClient == nil || !a.wsConnected {
+		return
+	}
+	if err := a.wsClient.SendStatus("component-restarted", map[string]interface{}{
+		"component":    r.Component,
+		"attempt":      r.Attempt,
+		"error":        r.Error,
+		"retryInMs":    r.Delay.Milliseconds(),
+		"restartCount": a.restartCount(r.Component),
+	}); err != nil {
+		a.logger.Debug().Err(err).Str("component", r.Component).Msg("Failed to report component restart")
+	}
+}
+
+// restartCount returns how often a supervised component has been restarted
+func (a *Agent) restartCount(name string) int {
+	for _, status := range a.supervisor.Status() {
+		if status.Component == name {
+			return status.Restarts
+		}
+	}
+	return 0
+}
+
 func (a *Agent) startHealthEndpoint() {
-	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+	var routeErrs []error
+	routeErrs = append(routeErrs, a.router.HandleFunc(http.MethodGet, "/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"status":  "ok",
 			"agentId": a.config.AgentID,
 			"time":    time.Now().Unix(),
 		})
-	})
+	}))
 
-	http.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) {
+	routeErrs = append(routeErrs, a.router.HandleFunc(http.MethodGet, "/info", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
 		// Get hostname
@@ -564,33 +781,78 @@ func (a *Agent) startHealthEndpoint() {
 			"version":   AgentVersion,
 			"platform":  runtime.GOOS + "/" + runtime.GOARCH,
 			"hostname":  hostname,
+			"tags":      a.config.GetTags(),
 		})
-	})
+	}))
 
 	// Register API endpoints for logs, metrics, and workflow data
 	apiServer := api.NewServer(a.config, a.executor, a.logger, a.logLevel)
-	apiServer.RegisterHandlers()
+	apiServer.SetAuditLogger(a.audit)
+	apiServer.SetProvenanceProvider(a.configProvenance)
+	apiServer.SetFileWatcher(a.fileWatcher)
+	apiServer.SetConnectionStateProvider(a.connectionState)
+	if a.gitSync != nil {
+		apiServer.SetConfigBackups(a.gitSync)
+	}
+	routeErrs = append(routeErrs, apiServer.RegisterHandlers(a.router))
 
 	// Register file browser endpoints (if enabled)
 	fileBrowser := filebrowser.New(a.config, a.logger)
-	fileBrowser.RegisterHandlers()
+	fileBrowser.SetAuditLogger(a.audit)
+	routeErrs = append(routeErrs, fileBrowser.RegisterHandlers(a.router))
 
-	a.logger.Info().Msg("Agent API listening on :8088")
+	// A clash (usually a webhook path shadowing a built-in endpoint) leaves the
+	// first registration in place; report it rather than failing the whole API
+	if err := errors.Join(routeErrs...); err != nil {
+		a.logger.Error().Err(err).Msg("❌ Some agent API routes could not be registered")
+	}
+
+	listenAddr, err := a.config.APIListenAddr(8088)
+	if err != nil {
+		a.logger.Error().Err(err).Str("apiBindAddr", a.config.APIBindAddr).Msg("❌ Invalid API bind address, agent API not started")
+		return
+	}
+
+	a.logger.Info().Str("addr", listenAddr).Msg("Agent API listening")
 	a.logger.Info().Msg("  GET /healthz - Health check")
 	a.logger.Info().Msg("  GET /info - Agent information")
 	a.logger.Info().Msg("  GET /api/logs?page=1&pageSize=100&level=error&search=query - Paginated logs")
 	a.logger.Info().Msg("  GET /api/logs/download?level=error&limit=5000 - Download logs")
 	a.logger.Info().Msg("  GET /api/workflows/executions - Workflow execution history")
 	a.logger.Info().Msg("  GET /api/workflows/state - Current workflow state")
+	a.logger.Info().Msg("  GET /api/workflows/metrics - Per-workflow success rate and durations")
+	a.logger.Info().Msg("  GET /api/workflows/step-types - Available step types and their config keys")
+	a.logger.Info().Msg("  POST /api/workflows/validate - Pre-flight check of a workflow definition")
 	a.logger.Info().Msg("  GET /api/metrics - Agent metrics")
 	a.logger.Info().Msg("  GET /api/loglevel - Get current log level")
 	a.logger.Info().Msg("  POST /api/loglevel {\"level\":\"debug\"} - Change log level")
+	a.logger.Info().Msg("  GET /api/config - Effective configuration (secrets redacted)")
+	a.logger.Info().Msg("  GET /api/connection - Manager connection state as seen by this agent")
+	a.logger.Info().Msg("  GET|POST /api/drain - Drain status / stop taking new work")
+	a.logger.Info().Msg("  POST /api/undrain - Resume taking new work")
+	a.logger.Info().Msg("  GET /api/schema - JSON Schema for workflows, steps and filewatcher rules")
+	a.logger.Info().Msg("  POST /api/filewatcher/import-ini[?apply=true] - Convert legacy INI rules")
+	a.logger.Info().Msg("  GET /api/filewatcher/next-allowed[?ruleId=x] - Next time rules may process files")
+	a.logger.Info().Msg("  POST /api/filewatcher/reprocess {\"path\":\"...\",\"ruleId\":\"...\"} - Force a file through a rule again")
+	if a.gitSync != nil && a.config.GetAPISettings().AdminToken != "" {
+		a.logger.Info().Msg("  GET /api/config/backups - List config backups (admin token)")
+		a.logger.Info().Msg("  POST /api/config/backup - Back up local config changes (admin token)")
+		a.logger.Info().Msg("  POST /api/config/restore {\"id\":\"latest\"} - Restore a config backup (admin token)")
+	}
+	if a.config.GetAPISettings().AdminToken != "" {
+		a.logger.Info().Msg("  POST /api/test/{s3,http,sftp,smtp} - Check integration connectivity and credentials (admin token)")
+	}
+	if a.config.GetAPISettings().EnableDebugEndpoints {
+		a.logger.Info().Msg("  GET /api/debug/stats - Goroutine, heap and component counts (bearer token)")
+		a.logger.Info().Msg("  GET /api/debug/pprof/<profile> - Runtime profiles (bearer token)")
+	}
 
 	// Log file browser status
 	if a.config.FileBrowserSettings.Enabled {
 		a.logger.Info().Msg("  📁 File Browser: ENABLED")
 		a.logger.Info().Msg("    GET /api/files/browse?path=/path - Browse directory")
 		a.logger.Info().Msg("    GET /api/files/download?path=/file - Download file")
+		a.logger.Info().Msg("    GET /api/files/checksum?path=/file&algo=sha256 - File checksum")
 		a.logger.Info().Msg("    POST /api/files/upload - Upload file")
 		a.logger.Info().Msg("    POST /api/files/mkdir?path=/path - Create directory")
 		a.logger.Info().Msg("    DELETE /api/files/delete?path=/path - Delete file/folder")
@@ -598,11 +860,21 @@ func (a *Agent) startHealthEndpoint() {
 		a.logger.Info().Msg("  📁 File Browser: DISABLED (set fileBrowserSettings.enabled=true to enable)")
 	}
 
-	if err := http.ListenAndServe(":8088", nil); err != nil {
+	a.logger.Info().Strs("allowedOrigins", a.config.GetAllowedOrigins()).Msg("  🌐 CORS policy")
+
+	if err := http.ListenAndServe(listenAddr, api.CORS(a.config, api.Gzip(a.router))); err != nil {
 		a.logger.Error().Err(err).Msg("Agent API server failed")
 	}
 }
 
+// connectionState reports the manager connection state, or false in standalone mode
+func (a *Agent) connectionState() (websocket.ConnectionState, bool) {
+	if a.wsClient == nil {
+		return websocket.ConnectionState{}, false
+	}
+	return a.wsClient.State(), true
+}
+
 func (a *Agent) handleConnect() {
 	a.wsConnected = true
 	a.logger.Info().Msg("Connected to manager")
@@ -685,8 +957,7 @@ func (a *Agent) handleMessage(msgType websocket.MessageType, payload json.RawMes
 				a.logger.Error().Str("error", resp.Error).Msg("Reconnection failed")
 				// If reconnection fails, we might need to re-register
 				if resp.Error == "Agent not found - registration required" {
-					a.config.Registered = false
-					a.logger.Warn().Msg("Agent needs to re-register - please provide a new token")
+					a.handleAgentNotFound()
 				}
 			}
 		}
@@ -698,68 +969,158 @@ func (a *Agent) handleMessage(msgType websocket.MessageType, payload json.RawMes
 	}
 }
 
-func (a *Agent) handleCommand(payload json.RawMessage) {
-	var cmd struct {
-		Command string                 `json:"command"`
-		Args    map[string]interface{} `json:"args"`
+// handleAgentNotFound drops the registered state after the manager has lost
+// this agent. With a reRegistrationToken configured the agent re-enrolls with
+// it on the next connection (the manager closes this one); otherwise it waits
+// for a new registration token.
+func (a *Agent) handleAgentNotFound() {
+	a.config.Registered = false
+	if a.config.ReRegistrationToken == "" {
+		a.logger.Warn().Msg("Agent needs to re-register - please provide a new token")
+		return
 	}
-	
+	a.config.RegistrationToken = a.config.ReRegistrationToken
+	a.logger.Warn().Msg("🔁 Manager no longer knows this agent - re-registering with reRegistrationToken on reconnect")
+}
+
+// commandMessage is a command sent by the manager
+type commandMessage struct {
+	Command   string                 `json:"command"`
+	Args      map[string]interface{} `json:"args"`
+	RequestID string                 `json:"requestId,omitempty"` // Optional correlation ID echoed in replies
+}
+
+func (a *Agent) handleCommand(payload json.RawMessage) {
+	var cmd commandMessage
 	if err := json.Unmarshal(payload, &cmd); err != nil {
 		a.logger.Error().Err(err).Msg("Failed to parse command")
 		return
 	}
 
-	a.logger.Info().Str("command", cmd.Command).Msg("Executing command")
+	// Batches and waited workflows can run for minutes. They run off the
+	// websocket read loop, which must keep processing heartbeat acks.
+	ref := commandRef{Command: cmd.Command, RequestID: cmd.RequestID}
+	switch {
+	case cmd.Command == "batch":
+		a.goCommand(ref, func() { a.handleBatch(ref, payload) })
+	case cmd.Command == "run-workflow" && waitRequested(cmd):
+		a.goCommand(ref, func() { a.executeCommand(ref, cmd, payload) })
+	default:
+		a.executeCommand(ref, cmd, payload)
+	}
+}
+
+// goCommand runs a command in its own goroutine. A panic fails the command
+// instead of crashing the agent.
+func (a *Agent) goCommand(ref commandRef, run func()) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				a.logger.Error().
+					Str("command", ref.Command).
+					Str("requestId", ref.RequestID).
+					Str("stack", string(debug.Stack())).
+					Msgf("💥 Command panicked: %v", r)
+				a.commandFailed(ref, fmt.Sprintf("command panicked: %v", r), nil)
+			}
+		}()
+		run()
+	}()
+}
+
+// waitRequested reports whether the command asks to reply only when done
+func waitRequested(cmd commandMessage) bool {
+	wait, _ := cmd.Args["wait"].(bool)
+	return wait
+}
+
+// executeCommand runs one command, replying through ref. payload is the raw
+// command message, for commands that read fields outside args.
+func (a *Agent) executeCommand(ref commandRef, cmd commandMessage, payload json.RawMessage) {
+	// Parallel batches and batches running beside the websocket read loop
+	// execute commands concurrently. All but running a workflow and reading
+	// metrics change workflows, config or the logger, so they run one at a time.
+	if cmd.Command != "run-workflow" && cmd.Command != "fetch-metrics" {
+		a.commandMu.Lock()
+		defer a.commandMu.Unlock()
+	}
+	a.logger.Info().Str("command", cmd.Command).Str("requestId", cmd.RequestID).Msg("Executing command")
 
 	switch cmd.Command {
 	case "reload-config":
-		if err := a.reloadConfig(); err != nil {
-			a.logger.Error().Err(err).Msg("Failed to reload config")
-			a.wsClient.SendStatus("error", map[string]interface{}{
-				"error": err.Error(),
-			})
-		} else {
-			// Reload workflows after config reload
-			a.reloadWorkflows()
-			a.wsClient.SendStatus("config-reloaded", nil)
-		}
+		a.reloadConfig(ref, cmd.Args)
 	case "remove-workflow":
 		// Handle workflow removal
 		workflowId, ok := cmd.Args["workflowId"].(string)
 		if !ok {
 			a.logger.Error().Msg("Invalid workflowId in remove-workflow command")
+			a.commandFailed(ref, "workflowId is required", nil)
 			return
 		}
 		
 		a.logger.Info().Str("workflowId", workflowId).Msg("Removing workflow")
 		
 		// Remove workflow from config
-		newWorkflows := []config.Workflow{}
-		removed := false
-		for _, w := range a.config.Workflows {
-			if w.ID != workflowId {
-				newWorkflows = append(newWorkflows, w)
-			} else {
-				removed = true
-			}
-		}
-		
-		if removed {
-			a.config.Workflows = newWorkflows
-
+		if a.config.RemoveWorkflow(workflowId) {
 			// Reload workflows
 			// Note: Workflows are Git-managed, not saved to local config
 			a.reloadWorkflows()
-			a.wsClient.SendStatus("workflow-removed", map[string]interface{}{
+			a.audit.Record("workflow.remove", "manager", audit.OutcomeSuccess, map[string]interface{}{"workflowId": workflowId})
+			a.commandSucceeded(ref, "workflow-removed", "Workflow removed", map[string]interface{}{
 				"workflowId": workflowId,
 			})
 		} else {
 			a.logger.Warn().Str("workflowId", workflowId).Msg("Workflow not found for removal")
+			a.commandFailed(ref, "workflow not found", map[string]interface{}{
+				"workflowId": workflowId,
+			})
+		}
+	case "enable-workflow", "disable-workflow":
+		// Runtime override for incident response - flips the in-memory workflow
+		// without a git round-trip. The next git pull restores the git state.
+		workflowId, ok := cmd.Args["workflowId"].(string)
+		if !ok || workflowId == "" {
+			a.logger.Error().Str("command", cmd.Command).Msg("Invalid workflowId in command")
+			a.commandFailed(ref, "workflowId is required", nil)
+			return
+		}
+
+		enabled := cmd.Command == "enable-workflow"
+		if !a.config.SetWorkflowEnabled(workflowId, enabled) {
+			a.logger.Warn().Str("workflowId", workflowId).Msg("Workflow not found for enable/disable")
+			a.commandFailed(ref, "workflow not found", map[string]interface{}{
+				"workflowId": workflowId,
+			})
+			return
 		}
+
+		a.logger.Warn().
+			Str("workflowId", workflowId).
+			Bool("enabled", enabled).
+			Msg("Workflow state overridden at runtime (will be reset on next git pull)")
+
+		// Note: Workflows are Git-managed, not saved to local config
+		a.reloadWorkflows()
+		a.audit.Record("workflow."+strings.TrimSuffix(cmd.Command, "-workflow"), "manager", audit.OutcomeSuccess, map[string]interface{}{
+			"workflowId":      workflowId,
+			"runtimeOverride": true,
+		})
+
+		status := "workflow-disabled"
+		if enabled {
+			status = "workflow-enabled"
+		}
+		a.commandSucceeded(ref, status,
+			"Temporary runtime override - reset on next git pull. Update the workflow in git to persist this change.",
+			map[string]interface{}{
+				"workflowId":      workflowId,
+				"enabled":         enabled,
+				"runtimeOverride": true,
+			})
 	case "reload-filewatcher":
 		a.logger.Info().Msg("Reloading file watcher rules")
 		a.loadFileWatcherRules()
-		a.wsClient.SendStatus("filewatcher-reloaded", nil)
+		a.commandSucceeded(ref, "filewatcher-reloaded", "File watcher rules reloaded", nil)
 	case "git-pull":
 		a.logger.Info().Msg("Pulling configuration from Git")
 		if a.gitSync != nil {
@@ -772,97 +1133,56 @@ func (a *Agent) handleCommand(payload json.RawMessage) {
 
 			if err := a.gitSync.Pull(); err != nil {
 				a.logger.Error().Err(err).Msg("Git pull failed")
-				a.wsClient.SendStatus("error", map[string]interface{}{
-					"command": "git-pull",
-					"error": err.Error(),
-				})
+				a.audit.Record("config.git-pull", "manager", audit.OutcomeFailure, map[string]interface{}{"error": err.Error()})
+				a.commandFailed(ref, err.Error(), nil)
 			} else {
 				a.logger.Info().Msg("Git pull successful, reloading configuration")
+				a.audit.Record("config.git-pull", "manager", audit.OutcomeSuccess, nil)
 				
 				// Load config from git repository
 				gitConfig, err := a.gitSync.LoadAgentConfig()
 				if err != nil {
 					a.logger.Error().Err(err).Msg("Failed to load config from git")
-					a.wsClient.SendStatus("error", map[string]interface{}{
-						"command": "git-pull",
-						"error": "Failed to load config from git",
+					a.commandFailed(ref, "Failed to load config from git", map[string]interface{}{
+						"cause": err.Error(),
 					})
 				} else if gitConfig != nil {
-					updated := false
-
-					// Update workflows from git config
-					if workflows, ok := gitConfig["workflows"].([]interface{}); ok {
-						a.config.Workflows = []config.Workflow{}
-						for _, w := range workflows {
-							if workflowData, err := json.Marshal(w); err == nil {
-								var workflow config.Workflow
-								if err := json.Unmarshal(workflowData, &workflow); err == nil {
-									a.config.Workflows = append(a.config.Workflows, workflow)
-								}
-							}
-						}
-						updated = true
-					}
-
-					// Update fileWatcherSettings from git config
-					if fwSettings, ok := gitConfig["fileWatcherSettings"].(map[string]interface{}); ok {
-						if settingsData, err := json.Marshal(fwSettings); err == nil {
-							var settings config.FileWatcherSettings
-							if err := json.Unmarshal(settingsData, &settings); err == nil {
-								a.config.FileWatcherSettings = settings
-								a.logger.Info().
-									Str("scanDir", settings.ScanDir).
-									Bool("scanSubDir", settings.ScanSubDir).
-									Msg("Updated file watcher settings from git")
-								updated = true
-
-								// Update file watcher with new settings
-								if a.fileWatcher != nil {
-									a.fileWatcher.SetGlobalSettings(settings.ScanDir, settings.ScanSubDir)
-								}
-							}
-						}
-					}
-
-					// Update fileWatcherRules from git config
-					if fwRules, ok := gitConfig["fileWatcherRules"].([]interface{}); ok {
-						a.loadFileWatcherRulesFromGit(fwRules)
-						updated = true
-					}
-
-					if updated {
-						// Reload workflows
-						// Note: Managed settings are not saved to local config
-						a.reloadWorkflows()
-
-						a.logger.Info().
-							Int("workflows", len(a.config.Workflows)).
-							Msg("Loaded configuration from git")
-						a.wsClient.SendStatus("git-pulled", map[string]interface{}{
-							"workflows": len(a.config.Workflows),
-							"fileWatcherSettings": a.config.FileWatcherSettings,
-						})
-					} else {
-						a.logger.Info().Msg("No updates found in git config")
-						a.wsClient.SendStatus("git-pulled", map[string]interface{}{
-							"message": "No updates",
-						})
-					}
+					a.reviewPulledConfig(ref, cmd.Args, gitConfig, a.applyPulledConfig)
 				} else {
 					a.logger.Warn().Msg("No agent config found in git repository")
-					a.wsClient.SendStatus("git-pulled", map[string]interface{}{
+					a.commandSucceeded(ref, "git-pulled", "No config found in repository", map[string]interface{}{
 						"workflows": 0,
-						"message": "No config found in repository",
 					})
 				}
 			}
 		} else {
 			a.logger.Warn().Msg("Git sync not initialized")
-			a.wsClient.SendStatus("error", map[string]interface{}{
-				"command": "git-pull",
-				"error": "Git sync not initialized",
-			})
+			a.commandFailed(ref, "Git sync not initialized", nil)
+		}
+	case "approve-config":
+		a.pendingMu.Lock()
+		pending, diff := a.pendingConfig, a.pendingDiff
+		a.pendingConfig = nil
+		a.pendingMu.Unlock()
+		if pending == nil {
+			a.commandFailed(ref, "No config changes awaiting approval", nil)
+			break
+		}
+		a.logger.Info().Str("changes", diff.String()).Msg("✅ Held config changes approved")
+		a.audit.Record("config.approve", "manager", audit.OutcomeSuccess, map[string]interface{}{"changes": diff.String()})
+		a.applyPulledConfig(ref, pending)
+	case "reject-config":
+		a.pendingMu.Lock()
+		pending, diff := a.pendingConfig, a.pendingDiff
+		a.pendingConfig = nil
+		a.pendingMu.Unlock()
+		if pending == nil {
+			a.commandFailed(ref, "No config changes awaiting approval", nil)
+			break
 		}
+		a.logger.Warn().Str("changes", diff.String()).Msg("🚫 Held config changes rejected")
+		a.audit.Record("config.reject", "manager", audit.OutcomeSuccess, map[string]interface{}{"changes": diff.String()})
+		a.commandSucceeded(ref, "config-rejected", "Held config changes discarded", map[string]interface{}{"diff": diff})
 	case "set-log-level":
 		// Get level from command payload (could be in Args or directly in command)
 		var level string
@@ -885,10 +1205,7 @@ func (a *Agent) handleCommand(payload json.RawMessage) {
 
 		if level == "" {
 			a.logger.Error().Msg("No log level specified in set-log-level command")
-			a.wsClient.SendStatus("error", map[string]interface{}{
-				"command": "set-log-level",
-				"error": "No log level specified",
-			})
+			a.commandFailed(ref, "No log level specified", nil)
 			return
 		}
 
@@ -905,23 +1222,173 @@ func (a *Agent) handleCommand(payload json.RawMessage) {
 			newLevel = zerolog.ErrorLevel
 		default:
 			a.logger.Error().Str("level", level).Msg("Invalid log level")
-			a.wsClient.SendStatus("error", map[string]interface{}{
-				"command": "set-log-level",
-				"error": fmt.Sprintf("Invalid log level: %s", level),
-			})
+			a.commandFailed(ref, fmt.Sprintf("Invalid log level: %s", level), nil)
 			return
 		}
 
 		// Update the log level
+		oldLevel := a.logLevel.String()
 		*a.logLevel = newLevel
 		a.logger = a.logger.Level(newLevel)
+		a.audit.Record("loglevel.change", "manager", audit.OutcomeSuccess, map[string]interface{}{
+			"oldLevel": oldLevel,
+			"newLevel": newLevel.String(),
+			"via":      "websocket",
+		})
 
 		a.logger.Info().Str("level", level).Msg("🔧 Log level changed")
-		a.wsClient.SendStatus("log-level-set", map[string]interface{}{
-			"level": level,
+		a.commandSucceeded(ref, "log-level-set", "Log level changed to "+newLevel.String(), map[string]interface{}{
+			"level":         level,
+			"previousLevel": oldLevel,
 		})
+	case "drain":
+		api.SetDraining(a.executor, a.fileWatcher, true)
+		a.logger.Warn().Msg("⏸️ Agent draining: new work paused, running jobs will finish")
+		a.audit.Record("agent.drain", "manager", audit.OutcomeSuccess, nil)
+		a.commandSucceeded(ref, "draining", "New work paused; running jobs will finish", drainDetails(api.GetDrainStatus(a.executor, a.fileWatcher)))
+		go a.reportWhenDrained(ref)
+	case "undrain":
+		api.SetDraining(a.executor, a.fileWatcher, false)
+		a.logger.Info().Msg("▶️ Agent undrained: accepting new work")
+		a.audit.Record("agent.undrain", "manager", audit.OutcomeSuccess, nil)
+		a.commandSucceeded(ref, "undrained", "Accepting new work", drainDetails(api.GetDrainStatus(a.executor, a.fileWatcher)))
+	case "run-workflow":
+		workflowId, ok := cmd.Args["workflowId"].(string)
+		if !ok || workflowId == "" {
+			a.commandFailed(ref, "workflowId is required", nil)
+			return
+		}
+		data, _ := cmd.Args["data"].(map[string]interface{})
+		trigger := workflow.TriggerEvent{Type: "manager", Data: data}
+		wait := waitRequested(cmd)
+		details := map[string]interface{}{"workflowId": workflowId, "wait": wait}
+
+		a.logger.Info().Str("workflowId", workflowId).Bool("wait", wait).Msg("▶️ Running workflow on manager request")
+		if !wait {
+			if err := a.executor.ExecuteWorkflow(workflowId, trigger); err != nil {
+				a.commandFailed(ref, err.Error(), details)
+				return
+			}
+			a.commandSucceeded(ref, "workflow-started", "Workflow started", details)
+			return
+		}
+		if err := a.executor.ExecuteWorkflowSync(workflowId, trigger); err != nil {
+			a.audit.Record("workflow.run", "manager", audit.OutcomeFailure, map[string]interface{}{"workflowId": workflowId, "error": err.Error()})
+			a.commandFailed(ref, err.Error(), details)
+			return
+		}
+		a.audit.Record("workflow.run", "manager", audit.OutcomeSuccess, map[string]interface{}{"workflowId": workflowId})
+		a.commandSucceeded(ref, "workflow-completed", "Workflow completed", details)
+	case "fetch-metrics":
+		a.commandSucceeded(ref, "metrics", "Current agent metrics", a.metricsSnapshot())
 	default:
 		a.logger.Warn().Str("command", cmd.Command).Msg("Unknown command")
+		a.commandFailed(ref, fmt.Sprintf("Unknown command: %s", cmd.Command), nil)
+	}
+}
+
+// reportWhenDrained waits for in-flight work to finish after a drain command
+// and tells the manager. It gives up silently if the agent is undrained first.
+func (a *Agent) reportWhenDrained(ref commandRef) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		status := api.GetDrainStatus(a.executor, a.fileWatcher)
+		if !status.Draining {
+			return
+		}
+		if status.Drained {
+			a.logger.Info().Msg("✅ Agent fully drained, safe to stop")
+			a.commandSucceeded(ref, "drained", "Agent fully drained, safe to stop", drainDetails(status))
+			return
+		}
+	}
+}
+
+// drainDetails converts a drain status into command status details
+func drainDetails(status api.DrainStatus) map[string]interface{} {
+	return map[string]interface{}{
+		"draining":         status.Draining,
+		"drained":          status.Drained,
+		"activeExecutions": status.ActiveExecutions,
+		"activeFiles":      status.ActiveFiles,
+	}
+}
+
+// metricsSnapshot gathers what /api/metrics reports plus workflow, drain,
+// connection and runtime stats, for the fetch-metrics command
+func (a *Agent) metricsSnapshot() map[string]interface{} {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	data := map[string]interface{}{
+		"metrics":   api.CollectMetrics(a.config, a.executor, a.fileWatcher),
+		"workflows": a.executor.WorkflowMetrics(),
+		"drain":     drainDetails(api.GetDrainStatus(a.executor, a.fileWatcher)),
+		"runtime": map[string]interface{}{
+			"goroutines":     runtime.NumGoroutine(),
+			"heapAllocBytes": mem.HeapAlloc,
+			"sysBytes":       mem.Sys,
+			"numGC":          mem.NumGC,
+		},
+		"components":  a.supervisor.Status(),
+		"version":     AgentVersion,
+		"collectedAt": time.Now().UTC().Format(time.RFC3339),
+	}
+	if state, ok := a.connectionState(); ok {
+		data["connection"] = state
+	}
+	return data
+}
+
+// commandRef identifies the manager command a reply belongs to
+type commandRef struct {
+	Command   string
+	RequestID string
+	reply     *commandReply // Set for commands run inside a batch
+}
+
+// commandSucceeded reports a successful command with its outcome status
+func (a *Agent) commandSucceeded(ref commandRef, status, message string, data map[string]interface{}) {
+	a.deliverCommandResult(ref, websocket.CommandResult{
+		Command:   ref.Command,
+		RequestID: ref.RequestID,
+		Success:   true,
+		Status:    status,
+		Message:   message,
+		Data:      data,
+	})
+}
+
+// commandFailed reports a failed command with the reason in message
+func (a *Agent) commandFailed(ref commandRef, message string, data map[string]interface{}) {
+	a.deliverCommandResult(ref, websocket.CommandResult{
+		Command:   ref.Command,
+		RequestID: ref.RequestID,
+		Status:    "error",
+		Message:   message,
+		Data:      data,
+	})
+}
+
+// deliverCommandResult hands a reply to the batch the command runs in, or
+// sends it when there is none (or the batch has already replied)
+func (a *Agent) deliverCommandResult(ref commandRef, result websocket.CommandResult) {
+	if ref.reply != nil && ref.reply.capture(result) {
+		return
+	}
+	a.sendCommandResult(result)
+}
+
+// sendCommandResult sends a command reply, echoing the command's requestId
+// (if any) so the manager can correlate responses with requests.
+func (a *Agent) sendCommandResult(result websocket.CommandResult) {
+	if err := a.wsClient.SendCommandResult(result); err != nil {
+		a.logger.Warn().
+			Err(err).
+			Str("command", result.Command).
+			Str("status", result.Status).
+			Str("requestId", result.RequestID).
+			Msg("Failed to send command result")
 	}
 }
 
@@ -956,13 +1423,313 @@ func (a *Agent) handleConfigUpdate(payload json.RawMessage) {
 	} else if update.ConfigPath != "" {
 		// Legacy path-based update
 		a.logger.Info().Str("path", update.ConfigPath).Msg("Config path update")
-		if err := a.reloadConfig(); err != nil {
-			a.logger.Error().Err(err).Msg("Failed to reload config")
+		a.reloadConfigUnattended("config-path-update")
+	}
+}
+
+// managedConfigKeys are the config sections git manages, applied by applyGitConfig
+var managedConfigKeys = []string{"workflows", "fileBrowserSettings", "logSettings", "fileWatcherSettings",
+	"transferSettings", "variables", "sshServerPort", "authorizedSSHKeys", "fileWatcherRules"}
+
+// reviewPulledConfig diffs a pulled config against what is running, then
+// reports it (args.dryRun), holds it for approval (configChangePolicy) or
+// applies it with apply
+func (a *Agent) reviewPulledConfig(ref commandRef, args map[string]interface{}, gitConfig map[string]interface{}, apply func(ref commandRef, gitConfig map[string]interface{})) {
+	diff := a.diffManagedConfig(gitConfig, managedConfigKeys)
+	a.logger.Info().
+		Str("changes", diff.String()).
+		Bool("destructive", diff.Destructive()).
+		Msg("🔍 Config changes from git")
+
+	if dryRun, _ := args["dryRun"].(bool); dryRun {
+		a.commandSucceeded(ref, "git-pull-dry-run", "Dry run: "+diff.String(), map[string]interface{}{"diff": diff})
+		return
+	}
+
+	if policy := a.config.GetConfigChangePolicy(); diff.NeedsApproval(policy) {
+		a.pendingMu.Lock()
+		a.pendingConfig = gitConfig
+		a.pendingDiff = diff
+		a.pendingMu.Unlock()
+		a.logger.Warn().
+			Str("policy", policy).
+			Str("changes", diff.String()).
+			Msg("⏸️ Config changes held for approval (send approve-config or reject-config)")
+		a.audit.Record("config.held", "manager", audit.OutcomeSuccess, map[string]interface{}{"changes": diff.String()})
+		a.commandSucceeded(ref, "approval-required", "Config changes held for approval (send approve-config or reject-config)", map[string]interface{}{
+			"diff":   diff,
+			"policy": policy,
+		})
+		return
+	}
+
+	// A newer pull supersedes anything still waiting for approval
+	a.pendingMu.Lock()
+	a.pendingConfig = nil
+	a.pendingMu.Unlock()
+	apply(ref, gitConfig)
+}
+
+// diffManagedConfig compares the running config with the given sections of a
+// git config. Both sides are decoded through the agent's types first so
+// defaulted or reordered fields do not show up as changes.
+func (a *Agent) diffManagedConfig(gitConfig map[string]interface{}, keys []string) config.ConfigDiff {
+	current := a.managedSections()
+
+	before := make(map[string]interface{}, len(keys))
+	after := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		raw, ok := gitConfig[key]
+		if !ok {
+			continue
+		}
+		after[key] = normalizeManaged(key, raw)
+		before[key] = normalizeManaged(key, current[key])
+	}
+	return config.DiffManaged(before, after)
+}
+
+// managedSnapshot returns the running values of the managed sections in keys,
+// normalized like diffManagedConfig does, to diff against a later snapshot
+func (a *Agent) managedSnapshot(keys []string) map[string]interface{} {
+	current := a.managedSections()
+	snapshot := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		snapshot[key] = normalizeManaged(key, current[key])
+	}
+	return snapshot
+}
+
+// managedSections returns the running config sections that git can manage
+func (a *Agent) managedSections() map[string]interface{} {
+	current := map[string]interface{}{
+		"workflows":           a.config.Workflows,
+		"fileBrowserSettings": a.config.FileBrowserSettings,
+		"logSettings":         a.config.LogSettings,
+		"fileWatcherSettings": a.config.FileWatcherSettings,
+		"transferSettings":    a.config.TransferSettings,
+		"variables":           a.config.Variables,
+		"sshServerPort":       a.config.SSHServerPort,
+		"authorizedSSHKeys":   a.config.AuthorizedSSHKeys,
+	}
+	if a.fileWatcher != nil {
+		current["fileWatcherRules"] = a.fileWatcher.GetRules()
+	}
+	return current
+}
+
+// normalizeManaged round-trips a managed config section through its Go type
+// and back to generic JSON
+func normalizeManaged(key string, v interface{}) interface{} {
+	var typed interface{}
+	switch key {
+	case "workflows":
+		typed = &[]config.Workflow{}
+	case "fileWatcherRules":
+		typed = &[]filewatcher.Rule{}
+	case "fileBrowserSettings":
+		typed = &config.FileBrowserSettings{}
+	case "logSettings":
+		typed = &config.LogSettings{}
+	case "fileWatcherSettings":
+		typed = &config.FileWatcherSettings{}
+	case "transferSettings":
+		typed = &config.TransferSettings{}
+	case "variables":
+		typed = &map[string]string{}
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	if typed != nil && json.Unmarshal(data, typed) == nil {
+		if data, err = json.Marshal(typed); err != nil {
+			return v
 		}
 	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return v
+	}
+	return generic
 }
 
-func (a *Agent) reloadConfig() error {
+// gitConfigApplied reports what applyGitConfig took from a git config
+type gitConfigApplied struct {
+	Updated          bool // At least one managed section was present
+	SkippedWorkflows []config.EntryError
+	SkippedRules     []config.EntryError
+}
+
+// decodeSection decodes a generic JSON config section into out
+func decodeSection(raw interface{}, out interface{}) bool {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, out) == nil
+}
+
+// applyGitConfig copies the managed sections present in a git config into
+// the running config. The caller reloads workflows afterwards.
+func (a *Agent) applyGitConfig(gitConfig map[string]interface{}) gitConfigApplied {
+	var applied gitConfigApplied
+
+	if workflows, ok := gitConfig["workflows"].([]interface{}); ok {
+		a.config.Workflows, applied.SkippedWorkflows = config.DecodeEntries[config.Workflow](workflows)
+		a.reportConfigEntryErrors("workflows", applied.SkippedWorkflows)
+		applied.Updated = true
+		a.logger.Info().
+			Int("count", len(a.config.Workflows)).
+			Int("skipped", len(applied.SkippedWorkflows)).
+			Msg("Loaded workflows from git")
+	}
+
+	if fbs, ok := gitConfig["fileBrowserSettings"].(map[string]interface{}); ok {
+		var settings config.FileBrowserSettings
+		if decodeSection(fbs, &settings) {
+			a.config.FileBrowserSettings = settings
+			applied.Updated = true
+			a.logger.Info().Int("allowedPaths", len(settings.AllowedPaths)).Msg("Loaded fileBrowserSettings from git")
+		}
+	}
+
+	if ls, ok := gitConfig["logSettings"].(map[string]interface{}); ok {
+		var settings config.LogSettings
+		if decodeSection(ls, &settings) {
+			a.config.LogSettings = settings
+			applied.Updated = true
+			a.logger.Info().Msg("Loaded logSettings from git")
+		}
+	}
+
+	if fws, ok := gitConfig["fileWatcherSettings"].(map[string]interface{}); ok {
+		var settings config.FileWatcherSettings
+		if decodeSection(fws, &settings) {
+			a.config.FileWatcherSettings = settings
+			applied.Updated = true
+			a.logger.Info().
+				Str("scanDir", settings.ScanDir).
+				Bool("scanSubDir", settings.ScanSubDir).
+				Msg("Loaded fileWatcherSettings from git")
+			if a.fileWatcher != nil {
+				a.fileWatcher.SetGlobalSettings(settings.ScanDir, settings.ScanSubDir)
+			}
+		}
+	}
+
+	if ts, ok := gitConfig["transferSettings"].(map[string]interface{}); ok {
+		var settings config.TransferSettings
+		if decodeSection(ts, &settings) {
+			a.config.TransferSettings = settings
+			applied.Updated = true
+			a.logger.Info().Msg("Loaded transferSettings from git")
+		}
+	}
+
+	if vars, ok := gitConfig["variables"].(map[string]interface{}); ok {
+		a.config.Variables = stringMap(vars)
+		applied.Updated = true
+		a.logger.Info().Int("count", len(a.config.Variables)).Msg("Loaded variables from git")
+	}
+
+	if port, ok := gitConfig["sshServerPort"].(float64); ok {
+		a.config.SSHServerPort = int(port)
+		applied.Updated = true
+		a.logger.Info().Int("port", int(port)).Msg("Loaded sshServerPort from git")
+	}
+
+	if keys, ok := gitConfig["authorizedSSHKeys"].([]interface{}); ok {
+		a.config.AuthorizedSSHKeys = []string{}
+		for _, k := range keys {
+			if key, ok := k.(string); ok {
+				a.config.AuthorizedSSHKeys = append(a.config.AuthorizedSSHKeys, key)
+			}
+		}
+		applied.Updated = true
+		a.logger.Info().Int("count", len(a.config.AuthorizedSSHKeys)).Msg("Loaded authorizedSSHKeys from git")
+	}
+
+	if fwRules, ok := gitConfig["fileWatcherRules"].([]interface{}); ok {
+		applied.SkippedRules = a.loadFileWatcherRulesFromGit(fwRules)
+		applied.Updated = true
+	}
+
+	return applied
+}
+
+// applyPulledConfig applies the managed sections of a config pulled from git
+func (a *Agent) applyPulledConfig(ref commandRef, gitConfig map[string]interface{}) {
+	applied := a.applyGitConfig(gitConfig)
+	if !applied.Updated {
+		a.logger.Info().Msg("No updates found in git config")
+		a.commandSucceeded(ref, "git-pulled", "No updates", nil)
+		return
+	}
+
+	// Note: Managed settings are not saved to local config
+	a.reloadWorkflows()
+
+	a.logger.Info().
+		Int("workflows", len(a.config.Workflows)).
+		Int("skippedWorkflows", len(applied.SkippedWorkflows)).
+		Int("skippedRules", len(applied.SkippedRules)).
+		Msg("Loaded configuration from git")
+	details := map[string]interface{}{
+		"workflows": len(a.config.Workflows),
+		"fileWatcherSettings": a.config.FileWatcherSettings,
+	}
+	if len(applied.SkippedWorkflows) > 0 {
+		details["skippedWorkflows"] = applied.SkippedWorkflows
+	}
+	if len(applied.SkippedRules) > 0 {
+		details["skippedRules"] = applied.SkippedRules
+	}
+	a.commandSucceeded(ref, "git-pulled", "Configuration loaded from git", details)
+}
+
+// reloadSummary reports what a reload-config applied
+type reloadSummary struct {
+	Source          string            // "git", "local" or "none"
+	Commit          string            // config repo HEAD after the pull, when from git
+	CommitMessage   string
+	Changes         config.ConfigDiff // managed sections that differ from before the reload
+	WorkflowsLoaded int
+	WorkflowsFailed map[string]string // workflow ID -> reason it was not loaded
+}
+
+// data renders the summary for the command result
+func (s reloadSummary) data() map[string]interface{} {
+	data := map[string]interface{}{
+		"source":          s.Source,
+		"changes":         s.Changes,
+		"workflowsLoaded": s.WorkflowsLoaded,
+	}
+	if s.Commit != "" {
+		data["commit"] = s.Commit
+		data["commitMessage"] = s.CommitMessage
+	}
+	if len(s.WorkflowsFailed) > 0 {
+		data["workflowsFailed"] = s.WorkflowsFailed
+	}
+	return data
+}
+
+// loadedWorkflows counts the enabled workflows the executor accepted and
+// returns the ones it rejected
+func (a *Agent) loadedWorkflows() (int, map[string]string) {
+	if a.executor == nil {
+		return 0, nil
+	}
+	failed := a.executor.LoadFailures()
+	return len(a.executor.GetWorkflows()) - len(failed), failed
+}
+
+// reloadConfig re-reads the managed config from git or, failing that, the
+// local config file, and replies through ref. Config from git goes through
+// reviewPulledConfig, so dryRun and configChangePolicy apply as for git-pull.
+func (a *Agent) reloadConfig(ref commandRef, args map[string]interface{}) {
 	// First pull from git if available
 	if a.gitSync != nil {
 		a.logger.Info().Msg("Pulling latest config from git")
@@ -975,118 +1742,112 @@ func (a *Agent) reloadConfig() error {
 		gitConfig, err := a.gitSync.LoadAgentConfig()
 		if err != nil {
 			a.logger.Error().Err(err).Msg("Failed to load config from git")
-		} else if gitConfig != nil {
-			updated := false
-
-			// Update workflows from git config
-			if workflows, ok := gitConfig["workflows"].([]interface{}); ok {
-				a.config.Workflows = []config.Workflow{}
-				for _, w := range workflows {
-					if workflowData, err := json.Marshal(w); err == nil {
-						var workflow config.Workflow
-						if err := json.Unmarshal(workflowData, &workflow); err == nil {
-							a.config.Workflows = append(a.config.Workflows, workflow)
-						}
-					}
-				}
-				updated = true
-				a.logger.Info().Int("count", len(a.config.Workflows)).Msg("Loaded workflows from git")
-			}
-
-			// Update fileBrowserSettings from git config
-			if fbs, ok := gitConfig["fileBrowserSettings"].(map[string]interface{}); ok {
-				if fbsData, err := json.Marshal(fbs); err == nil {
-					var fileBrowserSettings config.FileBrowserSettings
-					if err := json.Unmarshal(fbsData, &fileBrowserSettings); err == nil {
-						a.config.FileBrowserSettings = fileBrowserSettings
-						updated = true
-						a.logger.Info().Int("allowedPaths", len(fileBrowserSettings.AllowedPaths)).Msg("Loaded fileBrowserSettings from git")
-					}
-				}
-			}
-
-			// Update logSettings from git config
-			if ls, ok := gitConfig["logSettings"].(map[string]interface{}); ok {
-				if lsData, err := json.Marshal(ls); err == nil {
-					var logSettings config.LogSettings
-					if err := json.Unmarshal(lsData, &logSettings); err == nil {
-						a.config.LogSettings = logSettings
-						updated = true
-						a.logger.Info().Msg("Loaded logSettings from git")
-					}
-				}
-			}
-
-			// Update fileWatcherSettings from git config
-			if fws, ok := gitConfig["fileWatcherSettings"].(map[string]interface{}); ok {
-				if fwsData, err := json.Marshal(fws); err == nil {
-					var fileWatcherSettings config.FileWatcherSettings
-					if err := json.Unmarshal(fwsData, &fileWatcherSettings); err == nil {
-						a.config.FileWatcherSettings = fileWatcherSettings
-						updated = true
-						a.logger.Info().Msg("Loaded fileWatcherSettings from git")
-					}
-				}
-			}
-
-			// Update sshServerPort from git config
-			if port, ok := gitConfig["sshServerPort"].(float64); ok {
-				a.config.SSHServerPort = int(port)
-				updated = true
-				a.logger.Info().Int("port", int(port)).Msg("Loaded sshServerPort from git")
-			}
-
-			// Update authorizedSSHKeys from git config
-			if keys, ok := gitConfig["authorizedSSHKeys"].([]interface{}); ok {
-				a.config.AuthorizedSSHKeys = []string{}
-				for _, k := range keys {
-					if key, ok := k.(string); ok {
-						a.config.AuthorizedSSHKeys = append(a.config.AuthorizedSSHKeys, key)
-					}
-				}
-				updated = true
-				a.logger.Info().Int("count", len(a.config.AuthorizedSSHKeys)).Msg("Loaded authorizedSSHKeys from git")
-			}
-
-			if updated {
-				// Note: Managed settings are not saved to local config
-				return nil
-			}
+		} else if hasManagedSections(gitConfig) {
+			a.reviewPulledConfig(ref, args, gitConfig, a.applyReloadedConfig)
+			return
 		}
 	}
 
+	before := a.managedSnapshot(managedConfigKeys)
+	summary := reloadSummary{Source: "none"}
+
 	// Fallback to local config
 	configPath := a.configPath
-	if configPath == "" {
-		if a.config.ConfigRepoPath != "" {
-			configPath = filepath.Join(a.config.ConfigRepoPath, "agent.json")
-		} else {
-			// No config file path available, nothing to reload
-			a.logger.Warn().Msg("No config file path available for reload")
-			return nil
-		}
+	if configPath == "" && a.config.ConfigRepoPath != "" {
+		configPath = filepath.Join(a.config.ConfigRepoPath, "agent.json")
 	}
-	
-	// Check if the file exists before trying to reload
-	if !fileExists(configPath) {
+	switch {
+	case configPath == "":
+		// No config file path available, nothing to reload
+		a.logger.Warn().Msg("No config file path available for reload")
+	case !fileExists(configPath):
 		a.logger.Warn().Str("path", configPath).Msg("Config file does not exist, skipping reload")
-		return nil
+	default:
+		summary.Source = "local"
+		if err := a.config.Reload(configPath); err != nil {
+			a.logger.Error().Err(err).Msg("Failed to reload config")
+			a.audit.Record("config.reload", "manager", audit.OutcomeFailure, map[string]interface{}{"error": err.Error()})
+			a.commandFailed(ref, err.Error(), nil)
+			return
+		}
+	}
+	a.reloadWorkflows()
+	a.reportReload(ref, before, summary)
+}
+
+// hasManagedSections reports whether a git config carries any managed section
+func hasManagedSections(gitConfig map[string]interface{}) bool {
+	for _, key := range managedConfigKeys {
+		if _, ok := gitConfig[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applyReloadedConfig applies a git config for reload-config once
+// reviewPulledConfig lets it through
+func (a *Agent) applyReloadedConfig(ref commandRef, gitConfig map[string]interface{}) {
+	before := a.managedSnapshot(managedConfigKeys)
+	a.applyGitConfig(gitConfig)
+	// Note: Managed settings are not saved to local config
+	a.reloadWorkflows()
+
+	summary := reloadSummary{Source: "git"}
+	if hash, message, err := a.gitSync.GetLastCommit(); err == nil {
+		summary.Commit, summary.CommitMessage = hash, message
+	}
+	a.reportReload(ref, before, summary)
+}
+
+// reportReload replies to a reload-config with what changed since before
+func (a *Agent) reportReload(ref commandRef, before map[string]interface{}, summary reloadSummary) {
+	summary.Changes = config.DiffManaged(before, a.managedSnapshot(managedConfigKeys))
+	summary.WorkflowsLoaded, summary.WorkflowsFailed = a.loadedWorkflows()
+	a.logger.Info().
+		Str("source", summary.Source).
+		Str("commit", summary.Commit).
+		Str("changes", summary.Changes.String()).
+		Msg("🔄 Configuration reloaded")
+	a.audit.Record("config.reload", "manager", audit.OutcomeSuccess, map[string]interface{}{
+		"source":  summary.Source,
+		"commit":  summary.Commit,
+		"changes": summary.Changes,
+	})
+	a.commandSucceeded(ref, "config-reloaded", "Configuration reloaded: "+summary.Changes.String(), summary.data())
+}
+
+// reloadConfigUnattended runs reloadConfig outside a manager command (at
+// startup or for a config path update) and logs the outcome
+func (a *Agent) reloadConfigUnattended(reason string) {
+	ref := commandRef{Command: "reload-config", RequestID: reason, reply: &commandReply{}}
+	a.reloadConfig(ref, nil)
+	result, _ := ref.reply.close()
+	switch {
+	case !result.Success:
+		a.logger.Error().Str("reason", reason).Str("error", result.Message).Msg("Failed to reload config")
+	case result.Status == "approval-required":
+		a.logger.Warn().Str("reason", reason).Msg("⏸️ Config from git held for approval, running with the current config")
 	}
-	
-	return a.config.Reload(configPath)
 }
 
 func (a *Agent) reloadWorkflows() {
+	a.applyLogFormats()
+	a.applyProxySettings()
+	a.applyTransferSettings()
+	a.applyCommandPolicy()
+	a.applyVariables()
+
 	if a.executor != nil && a.config != nil {
 		a.logger.Info().Int("count", len(a.config.Workflows)).Msg("Reloading workflows")
 		
-		// Stop existing executor
+		// Load new workflows (an empty list still clears the old ones), then
+		// stop the executor. Loading first means an executor start racing
+		// with this reload already sees the new workflows.
+		a.executor.LoadWorkflows(a.config.Workflows)
 		a.executor.Stop()
-		
-		// Load new workflows
 		if len(a.config.Workflows) > 0 {
-			a.executor.LoadWorkflows(a.config.Workflows)
-			go a.executor.Start()
+			a.startExecutor()
 		}
 	}
 	
@@ -1102,6 +1863,94 @@ func (a *Agent) reloadWorkflows() {
 	}
 }
 
+// applyVariables reloads the secrets file and hands the current global
+// variables to the executor
+func (a *Agent) applyVariables() {
+	if a.executor == nil || a.config == nil {
+		return
+	}
+	if err := a.secrets.Reload(); err != nil {
+		a.logger.Error().Err(err).Msg("Failed to reload secrets file, keeping previous secrets")
+	}
+	a.executor.SetVariables(a.config.GetVariables())
+}
+
+// applyTransferSettings installs the configured agent-wide bandwidth limit
+func (a *Agent) applyTransferSettings() {
+	if a.config == nil {
+		return
+	}
+	limiter := throttle.NewLimiter(a.config.GetTransferSettings().MaxBytesPerSecond)
+	if a.executor != nil {
+		a.executor.SetTransferLimiter(limiter)
+	}
+	if a.fileWatcher != nil {
+		a.fileWatcher.SetTransferLimiter(limiter)
+	}
+	if limiter != nil {
+		a.logger.Info().Int64("maxBytesPerSecond", limiter.Rate()).Msg("🚦 Bandwidth limit applied to copies and uploads")
+	}
+}
+
+// applyCommandPolicy installs the local run-command policy, so edits to
+// commandPolicy take effect on reload
+func (a *Agent) applyCommandPolicy() {
+	if a.executor == nil || a.config == nil {
+		return
+	}
+	a.executor.SetCommandPolicy(a.config.GetCommandPolicy())
+}
+
+// applyProxySettings installs the configured outbound proxy, falling back to
+// the standard proxy environment variables
+func (a *Agent) applyProxySettings() {
+	if a.config == nil {
+		return
+	}
+	settings := a.config.GetProxySettings()
+	if err := proxy.Configure(proxy.Settings{
+		HTTPProxy:  settings.HTTPProxy,
+		HTTPSProxy: settings.HTTPSProxy,
+		NoProxy:    settings.NoProxy,
+	}); err != nil {
+		a.logger.Error().Err(err).Msg("Invalid proxy settings, outbound connections are not proxied")
+		proxy.Configure(proxy.Settings{})
+		return
+	}
+	if effective := proxy.Describe(); effective["httpProxy"] != "" || effective["httpsProxy"] != "" {
+		a.logger.Info().Interface("proxy", effective).Msg("🌐 Outbound proxy configured")
+	}
+}
+
+// applyLogFormats switches the console and file log sinks to the formats in
+// logSettings. An invalid format is logged and the sink keeps its current one.
+func (a *Agent) applyLogFormats() {
+	if a.config == nil || a.consoleLog == nil || a.fileLog == nil {
+		return
+	}
+	settings := a.config.GetLogSettings()
+	for _, sink := range []struct {
+		name   string
+		writer *logrotation.FormatWriter
+		format string
+	}{
+		{"console", a.consoleLog, settings.ConsoleLogFormat()},
+		{"file", a.fileLog, settings.FileLogFormat()},
+	} {
+		if sink.writer.Format() == sink.format {
+			continue
+		}
+		if err := sink.writer.SetFormat(sink.format); err != nil {
+			a.logger.Error().Err(err).Str("sink", sink.name).Msg("Invalid log format, keeping current format")
+			continue
+		}
+		a.logger.Info().Str("sink", sink.name).Str("format", sink.format).Msg("📝 Log format changed")
+		if sink.writer == a.fileLog && sink.format == logrotation.FormatConsole {
+			a.logger.Warn().Msg("⚠️ agent.log is written in console format; the manager log viewer only shows JSON entries")
+		}
+	}
+}
+
 func (a *Agent) sendAlert(level, message string, details map[string]interface{}) {
 	alertPayload := map[string]interface{}{
 		"level":     level,
@@ -1136,6 +1985,39 @@ func (a *Agent) sendAlert(level, message string, details map[string]interface{})
 	}
 }
 
+// sendWorkflowEvent reports workflow lifecycle events to the manager as status
+// messages. Verbosity is controlled by logSettings.workflowEvents so busy agents
+// don't flood the manager: "all" sends every event, "failures" only failures.
+func (a *Agent) sendWorkflowEvent(event string, details map[string]interface{}) {
+	switch strings.ToLower(a.config.LogSettings.WorkflowEvents) {
+	case "all":
+	case "failures":
+		if event != "workflow-failed" {
+			return
+		}
+	default:
+		return
+	}
+
+	if a.wsClient == nil || !a.wsConnected {
+		return
+	}
+
+	if err := a.wsClient.SendStatus(event, details); err != nil {
+		a.logger.Debug().Err(err).Str("event", event).Msg("Failed to send workflow event")
+	}
+}
+
+// sendCustomEvent sends an event from an emit-event step to the manager.
+// Unlike alerts, events are not kept locally while disconnected.
+func (a *Agent) sendCustomEvent(eventType string, event map[string]interface{}) error {
+	if a.wsClient == nil || !a.wsConnected {
+		return fmt.Errorf("not connected to manager")
+	}
+	event["agent_id"] = a.config.AgentID
+	return a.wsClient.SendMessage("event", event)
+}
+
 func (a *Agent) saveLocalAlert(alert map[string]interface{}) {
 	alertsPath := filepath.Join(getDefaultConfigDir(), "alerts.json")
 
@@ -1155,30 +2037,52 @@ func (a *Agent) saveLocalAlert(alert map[string]interface{}) {
 
 	// Save back to file
 	if data, err := json.MarshalIndent(alerts, "", "  "); err == nil {
-		os.WriteFile(alertsPath, data, 0600)
+		if err := config.WriteFileAtomic(alertsPath, data, 0600); err != nil {
+			a.logger.Warn().Err(err).Str("path", alertsPath).Msg("Failed to save alert locally")
+		}
 	}
 }
 
-func (a *Agent) loadFileWatcherRulesFromGit(rulesInterface []interface{}) {
+// loadFileWatcherRulesFromGit applies rules from a git config and returns
+// the entries that could not be parsed
+func (a *Agent) loadFileWatcherRulesFromGit(rulesInterface []interface{}) []config.EntryError {
 	if a.fileWatcher == nil {
-		return
+		return nil
 	}
 
-	var rules []filewatcher.Rule
-	for _, r := range rulesInterface {
-		if ruleData, err := json.Marshal(r); err == nil {
-			var rule filewatcher.Rule
-			if err := json.Unmarshal(ruleData, &rule); err == nil {
-				rules = append(rules, rule)
-			}
-		}
-	}
+	rules, skipped := config.DecodeEntries[filewatcher.Rule](rulesInterface)
+	a.reportConfigEntryErrors("fileWatcherRules", skipped)
 
 	if len(rules) > 0 {
 		a.logger.Info().Int("count", len(rules)).Msg("Loading file watcher rules from git")
+		a.fileWatcherRulesSource = "git"
 		a.fileWatcher.UpdateRules(rules)
-		go a.fileWatcher.Start()
+		if !a.fileWatcher.IsRunning() {
+			go a.fileWatcher.Start()
+		}
+	}
+	return skipped
+}
+
+// reportConfigEntryErrors logs each entry skipped from a config list and
+// alerts the manager, so a bad entry does not silently disappear
+func (a *Agent) reportConfigEntryErrors(section string, errs []config.EntryError) {
+	if len(errs) == 0 {
+		return
 	}
+	for _, e := range errs {
+		a.logger.Error().
+			Str("section", section).
+			Int("index", e.Index).
+			Str("id", e.ID).
+			Str("error", e.Error).
+			Msg("❌ Skipped invalid config entry")
+	}
+	a.sendAlert("error", fmt.Sprintf("%d %s entries could not be parsed and were skipped: %s",
+		len(errs), section, config.SummarizeEntryErrors(errs)), map[string]interface{}{
+		"section": section,
+		"skipped": errs,
+	})
 }
 
 func (a *Agent) loadFileWatcherRules() {
@@ -1196,9 +2100,19 @@ func (a *Agent) loadFileWatcherRules() {
 	if a.config.FileWatcherSettings.MaxConcurrent > 0 {
 		a.fileWatcher.SetMaxConcurrent(a.config.FileWatcherSettings.MaxConcurrent)
 	}
-
-	// Stop existing watcher
-	a.fileWatcher.Stop()
+	if err := a.fileWatcher.SetQueueSettings(a.config.FileWatcherSettings.QueueSize, a.config.FileWatcherSettings.BackpressurePolicy); err != nil {
+		a.logger.Error().Err(err).Msg("Invalid file watcher queue settings, using defaults")
+		a.fileWatcher.SetQueueSettings(a.config.FileWatcherSettings.QueueSize, filewatcher.BackpressureBlock)
+	}
+	if err := a.fileWatcher.SetFileEventLogLevel(a.config.FileWatcherSettings.FileEventLogLevel); err != nil {
+		a.logger.Error().Err(err).Msg("Invalid fileEventLogLevel, logging file events at info")
+		a.fileWatcher.SetFileEventLogLevel("")
+	}
+	pollInterval := time.Duration(a.config.FileWatcherSettings.PollIntervalSecs) * time.Second
+	if err := a.fileWatcher.SetWatchLimits(a.config.FileWatcherSettings.MaxWatches, a.config.FileWatcherSettings.WatchOverflow, pollInterval); err != nil {
+		a.logger.Error().Err(err).Msg("Invalid file watcher watch limit settings, using defaults")
+		a.fileWatcher.SetWatchLimits(a.config.FileWatcherSettings.MaxWatches, filewatcher.OverflowSkip, pollInterval)
+	}
 
 	// Load rules from git config if available
 	var rules []filewatcher.Rule
@@ -1207,41 +2121,82 @@ func (a *Agent) loadFileWatcherRules() {
 		gitConfig, err := a.gitSync.LoadAgentConfig()
 		if err == nil && gitConfig != nil {
 			if fileWatcherRules, ok := gitConfig["fileWatcherRules"].([]interface{}); ok {
-				for _, r := range fileWatcherRules {
-					if ruleData, err := json.Marshal(r); err == nil {
-						var rule filewatcher.Rule
-						if err := json.Unmarshal(ruleData, &rule); err == nil {
-							rules = append(rules, rule)
-						}
-					}
-				}
+				var skipped []config.EntryError
+				rules, skipped = config.DecodeEntries[filewatcher.Rule](fileWatcherRules)
+				a.reportConfigEntryErrors("fileWatcherRules", skipped)
 			}
 		}
 	}
 	
+	source := "git"
+
 	// Fallback to local config
 	if len(rules) == 0 && a.config != nil && a.config.Extra != nil {
+		source = "local"
 		if configData, ok := a.config.Extra["fileWatcherRules"].([]interface{}); ok {
-			for _, r := range configData {
-				if ruleData, err := json.Marshal(r); err == nil {
-					var rule filewatcher.Rule
-					if err := json.Unmarshal(ruleData, &rule); err == nil {
-						rules = append(rules, rule)
-					}
-				}
-			}
+			var skipped []config.EntryError
+			rules, skipped = config.DecodeEntries[filewatcher.Rule](configData)
+			a.reportConfigEntryErrors("fileWatcherRules", skipped)
 		}
 	}
 	
 	if len(rules) > 0 {
 		a.logger.Info().Int("count", len(rules)).Msg("Loading file watcher rules")
+		a.fileWatcherRulesSource = source
+		// Running watchers are updated in place so unchanged rules keep watching
 		a.fileWatcher.UpdateRules(rules)
-		go a.fileWatcher.Start()
+		if !a.fileWatcher.IsRunning() {
+			go a.fileWatcher.Start()
+		} else if a.fileWatcher.PoolChanged() {
+			a.logger.Info().
+				Int("maxConcurrent", a.config.FileWatcherSettings.MaxConcurrent).
+				Int("queueSize", a.config.FileWatcherSettings.QueueSize).
+				Msg("🔄 File watcher pool settings changed, restarting file watcher")
+			go a.fileWatcher.Start()
+		}
 	} else {
 		a.logger.Debug().Msg("No file watcher rules configured")
+		a.fileWatcher.Stop()
 	}
 }
 
+// configProvenance describes where each part of the active configuration came from
+func (a *Agent) configProvenance() map[string]interface{} {
+	provenance := map[string]interface{}{
+		"localConfigFile": a.configPath,
+		"standalone":      a.gitSync == nil,
+	}
+
+	workflows := map[string]interface{}{
+		"count":  len(a.config.Workflows),
+		"source": "local",
+	}
+	if a.gitSync != nil {
+		workflows["source"] = "git"
+		if hash, msg, err := a.gitSync.GetLastCommit(); err == nil {
+			workflows["commit"] = hash
+			workflows["commitMessage"] = msg
+			provenance["summary"] = fmt.Sprintf("workflows loaded from git commit %.7s", hash)
+		}
+	}
+	provenance["workflows"] = workflows
+
+	if a.fileWatcher != nil {
+		rules := a.fileWatcher.GetRules()
+		names := make([]string, 0, len(rules))
+		for _, r := range rules {
+			names = append(names, r.Name)
+		}
+		provenance["fileWatcherRules"] = map[string]interface{}{
+			"count":  len(rules),
+			"source": a.fileWatcherRulesSource,
+			"names":  names,
+		}
+	}
+
+	return provenance
+}
+
 // workflowExecutorAdapter adapts the workflow executor for use by the file watcher
 type workflowExecutorAdapter struct {
 	executor *workflow.Executor
@@ -1286,7 +2241,8 @@ func (w *workflowExecutorAdapter) ExecuteWorkflowSync(name string, context map[s
 				Data: context,
 			}
 
-			// Execute the workflow synchronously (waits for completion)
+			// Execute the workflow synchronously (waits for completion) and
+			// report a failed run so the watcher can route the file
 			return w.executor.ExecuteWorkflowSync(wf.ID, trigger)
 		}
 	}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+
+	"github.com/your-org/controlcenter/nodes/internal/config"
+	"github.com/your-org/controlcenter/nodes/internal/gitsync"
+	"github.com/your-org/controlcenter/nodes/internal/websocket"
+	"github.com/your-org/controlcenter/nodes/internal/workflow"
+)
+
+func TestWaitedWorkflowKeepsHeartbeatsFlowing(t *testing.T) {
+	executor, err := workflow.NewExecutor(filepath.Join(t.TempDir(), "state.json"), zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Runs for 1s, well past the 150ms heartbeat ack window below
+	executor.LoadWorkflows([]config.Workflow{{
+		ID:      "slow",
+		Enabled: true,
+		Trigger: config.Trigger{Type: "manual", StartSteps: []string{"sleep"}},
+		Steps: []config.Step{{ID: "sleep", Type: "run-command", Config: map[string]interface{}{
+			"argv": []interface{}{"sleep", "1"},
+		}}},
+	}})
+
+	var connections atomic.Int32
+	results := make(chan websocket.CommandResult, 1)
+	upgrader := gorilla.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if connections.Add(1) == 1 {
+			conn.WriteJSON(websocket.Message{
+				Type:    websocket.MessageTypeCommand,
+				Payload: json.RawMessage(`{"command": "run-workflow", "requestId": "r1", "args": {"workflowId": "slow", "wait": true}}`),
+			})
+		}
+		for {
+			var msg websocket.Message
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			switch msg.Type {
+			case websocket.MessageTypeHeartbeat:
+				conn.WriteJSON(websocket.Message{Type: websocket.MessageTypeHeartbeatAck, Payload: json.RawMessage(`{}`)})
+			case websocket.MessageTypeCommandResult:
+				var result websocket.CommandResult
+				json.Unmarshal(msg.Payload, &result)
+				results <- result
+			}
+		}
+	}))
+	defer srv.Close()
+
+	client := websocket.NewClient(srv.URL, "agent-1", zerolog.Nop())
+	client.SetIntervals(50*time.Millisecond, 50*time.Millisecond)
+	a := &Agent{wsClient: client, executor: executor, logger: zerolog.Nop()}
+	client.OnMessage(a.handleMessage)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.Start(ctx)
+
+	select {
+	case result := <-results:
+		if !result.Success || result.Status != "workflow-completed" || result.RequestID != "r1" {
+			t.Errorf("result = %+v", result)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no command_result for the waited workflow")
+	}
+	if n := connections.Load(); n != 1 {
+		t.Errorf("connection dropped while the workflow ran: %d connections", n)
+	}
+}
+
+func TestApplyPulledConfigAppliesEveryManagedSection(t *testing.T) {
+	a := &Agent{
+		config:   &config.Config{},
+		wsClient: websocket.NewClient("http://manager:3000", "agent-1", zerolog.Nop()),
+		logger:   zerolog.Nop(),
+	}
+	var gitConfig map[string]interface{}
+	json.Unmarshal([]byte(`{
+		"transferSettings": {"maxBytesPerSecond": 1048576},
+		"fileBrowserSettings": {"enabled": true, "allowedPaths": ["/data"]},
+		"logSettings": {"workflowEvents": "all"},
+		"sshServerPort": 2022,
+		"authorizedSSHKeys": ["ssh-ed25519 AAAA"]
+	}`), &gitConfig)
+
+	diff := a.diffManagedConfig(gitConfig, managedConfigKeys)
+	if !strings.Contains(diff.String(), "transferSettings") || !strings.Contains(diff.String(), "sshServerPort") {
+		t.Errorf("diff = %s", diff)
+	}
+
+	ref := commandRef{Command: "git-pull", reply: &commandReply{}}
+	a.applyPulledConfig(ref, gitConfig)
+	if result, _ := ref.reply.close(); !result.Success || result.Message != "Configuration loaded from git" {
+		t.Errorf("result = %+v", result)
+	}
+	if a.config.TransferSettings.MaxBytesPerSecond != 1048576 || a.config.SSHServerPort != 2022 ||
+		len(a.config.AuthorizedSSHKeys) != 1 || len(a.config.FileBrowserSettings.AllowedPaths) != 1 ||
+		a.config.LogSettings.WorkflowEvents != "all" {
+		t.Errorf("config = %+v", a.config)
+	}
+}
+
+func TestReloadConfigHoldsDestructiveChanges(t *testing.T) {
+	repo := t.TempDir()
+	os.MkdirAll(filepath.Join(repo, "agents"), 0755)
+	os.WriteFile(filepath.Join(repo, "agents", "agent-1.json"), []byte(`{"workflows": []}`), 0644)
+	gitSync := gitsync.New(repo, filepath.Join(t.TempDir(), "missing.git"), "agent-1", "", zerolog.Nop())
+	gitSync.SetRetryPolicy(1, 0, 0)
+
+	a := &Agent{
+		config: &config.Config{
+			ConfigChangePolicy: config.ChangePolicyDestructive,
+			Workflows:          []config.Workflow{{ID: "nightly", Enabled: true}},
+		},
+		gitSync:  gitSync,
+		wsClient: websocket.NewClient("http://manager:3000", "agent-1", zerolog.Nop()),
+		logger:   zerolog.Nop(),
+	}
+	run := func(command string) websocket.CommandResult {
+		ref := commandRef{Command: command, reply: &commandReply{}}
+		a.executeCommand(ref, commandMessage{Command: command}, nil)
+		result, _ := ref.reply.close()
+		return result
+	}
+
+	if result := run("reload-config"); result.Status != "approval-required" {
+		t.Fatalf("reload removing a workflow should be held, got %+v", result)
+	}
+	if len(a.config.Workflows) != 1 {
+		t.Fatalf("held reload changed workflows: %+v", a.config.Workflows)
+	}
+	if result := run("approve-config"); !result.Success {
+		t.Fatalf("approve-config: %+v", result)
+	}
+	if len(a.config.Workflows) != 0 {
+		t.Errorf("approved reload should remove the workflow: %+v", a.config.Workflows)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// staleConfigAlertAfter is how many watchdog pulls in a row must fail before
+// the agent alerts that its config may be stale
+const staleConfigAlertAfter = 3
+
+// staleCheckInterval returns how often the watchdog checks a config that may
+// be at most maxAge old: a quarter of it, between 10 seconds and 5 minutes
+func staleCheckInterval(maxAge time.Duration) time.Duration {
+	interval := maxAge / 4
+	if interval < 10*time.Second {
+		interval = 10 * time.Second
+	}
+	if interval > 5*time.Minute {
+		interval = 5 * time.Minute
+	}
+	return interval
+}
+
+// watchConfigStaleness pulls config from git whenever the last successful
+// sync is older than maxAge, so an agent that lost git connectivity notices
+// instead of running outdated workflows indefinitely. After
+// staleConfigAlertAfter failed pulls in a row it raises an alert, once per
+// stale period.
+func (a *Agent) watchConfigStaleness(ctx context.Context, maxAge time.Duration) error {
+	ticker := time.NewTicker(staleCheckInterval(maxAge))
+	defer ticker.Stop()
+
+	failures := 0
+	alerted := false
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		last := a.gitSync.LastSuccessfulPull()
+		if !last.IsZero() && time.Since(last) < maxAge {
+			if alerted {
+				a.logger.Info().Time("lastSync", last).Msg("✅ Config synced from git again")
+			}
+			failures, alerted = 0, false
+			continue
+		}
+
+		age := "never"
+		if !last.IsZero() {
+			age = time.Since(last).Round(time.Second).String()
+		}
+		a.logger.Warn().Str("lastSync", age).Dur("maxAge", maxAge).Msg("⏰ Config not synced from git recently, pulling")
+		message, ok := a.pullStaleConfig()
+		if ok {
+			failures = 0
+			continue
+		}
+
+		failures++
+		a.logger.Error().Str("error", message).Int("failures", failures).Msg("❌ Pull of stale config failed")
+		if failures >= staleConfigAlertAfter && !alerted {
+			alerted = true
+			a.sendAlert("warning", fmt.Sprintf("Config may be stale: not synced from git for %s", age), map[string]interface{}{
+				"lastSync":      last,
+				"maxAgeSeconds": int(maxAge.Seconds()),
+				"failedPulls":   failures,
+				"lastPullError": message,
+			})
+		}
+	}
+}
+
+// pullStaleConfig runs a git-pull as if the manager had sent it, so the
+// config change policy applies, and reports whether it succeeded
+func (a *Agent) pullStaleConfig() (string, bool) {
+	ref := commandRef{Command: "git-pull", RequestID: "config-staleness", reply: &commandReply{}}
+	a.executeCommand(ref, commandMessage{Command: "git-pull", RequestID: ref.RequestID}, nil)
+	result, ok := ref.reply.close()
+	if !ok {
+		return "", true
+	}
+	return result.Message, result.Success
+}